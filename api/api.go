@@ -0,0 +1,36 @@
+// Package api embeds the OpenAPI 3 specification of the HTTP API.
+//
+// openapi.yaml is the contract: every route registered by the handlers must
+// have an entry, and request and response shapes must match the models.
+package api
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+var defineFormats sync.Once
+
+// Load parses and validates the embedded specification
+func Load() (*openapi3.T, error) {
+	// Formats are registered globally; uuid is not checked by default
+	defineFormats.Do(func() {
+		openapi3.DefineStringFormatValidator("uuid", openapi3.NewRegexpFormatValidator(openapi3.FormatOfStringForUUIDOfRFC4122))
+	})
+
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	return doc, nil
+}
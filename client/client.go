@@ -0,0 +1,331 @@
+// Package client is the Go client for the wallet API. Its methods mirror
+// the service's WalletService:
+//
+//	c, err := client.New("http://wallet:8080", client.WithTimeout(2*time.Second))
+//	wallet, err := c.CreateWallet(ctx, client.CreateWalletRequest{UserID: "u1"})
+//	_, err = c.DebitWallet(ctx, wallet.ID, client.TransactionRequest{Amount: 5})
+//	if errors.Is(err, client.ErrInsufficientBalance) { ... }
+//
+// Failed requests are retried with exponential backoff. Every write carries
+// an Idempotency-Key header that stays the same across the retries of one
+// call, so the server applies it once however often it is sent.
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader carries the key that identifies one logical write
+// across retries
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy bounds retries. The wait before retry n is drawn between half
+// and all of MinBackoff * 2^(n-1), capped at MaxBackoff, unless the server
+// sent Retry-After.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  100 * time.Millisecond,
+	MaxBackoff:  2 * time.Second,
+}
+
+type Client struct {
+	baseURL   *url.URL
+	http      *http.Client
+	timeout   time.Duration
+	retry     RetryPolicy
+	userAgent string
+}
+
+// Option configures optional behaviour of the client
+type Option func(*Client)
+
+// WithHTTPClient sends requests through hc instead of http.DefaultClient
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithTimeout bounds each attempt. The caller's context bounds the call as
+// a whole, retries included.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// New returns a client for the service at baseURL, e.g.
+// "http://wallet:8080"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	c := &Client{
+		baseURL:   u,
+		http:      http.DefaultClient,
+		timeout:   10 * time.Second,
+		retry:     DefaultRetryPolicy,
+		userAgent: "wallet-client-go",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c, nil
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey makes writes issued with the returned context use key
+// instead of a generated one, e.g. to keep the key of a payment stable
+// across restarts of the caller
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+func (c *Client) CreateWallet(ctx context.Context, req CreateWalletRequest) (*Wallet, error) {
+	var wallet Wallet
+	if err := c.do(ctx, http.MethodPost, "/api/v1/wallets", nil, req, &wallet); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (c *Client) GetWallet(ctx context.Context, id uuid.UUID) (*Wallet, error) {
+	var wallet Wallet
+	if err := c.do(ctx, http.MethodGet, "/api/v1/wallets/"+id.String(), nil, nil, &wallet); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (c *Client) GetWalletByUserID(ctx context.Context, userID string) (*Wallet, error) {
+	var wallet Wallet
+	if err := c.do(ctx, http.MethodGet, "/api/v1/users/"+url.PathEscape(userID)+"/wallet", nil, nil, &wallet); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (c *Client) UpdateWallet(ctx context.Context, id uuid.UUID, req CreateWalletRequest) (*Wallet, error) {
+	var wallet Wallet
+	if err := c.do(ctx, http.MethodPut, "/api/v1/wallets/"+id.String(), nil, req, &wallet); err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (c *Client) DeleteWallet(ctx context.Context, id uuid.UUID) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/wallets/"+id.String(), nil, nil, nil)
+}
+
+func (c *Client) CreditWallet(ctx context.Context, id uuid.UUID, req TransactionRequest) (*Transaction, error) {
+	var tx Transaction
+	if err := c.do(ctx, http.MethodPost, "/api/v1/wallets/"+id.String()+"/credit", nil, req, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (c *Client) DebitWallet(ctx context.Context, id uuid.UUID, req TransactionRequest) (*Transaction, error) {
+	var tx Transaction
+	if err := c.do(ctx, http.MethodPost, "/api/v1/wallets/"+id.String()+"/debit", nil, req, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// GetTransactionHistory returns one page of transactions, newest first.
+// page and limit of 0 leave the server defaults.
+func (c *Client) GetTransactionHistory(ctx context.Context, walletID uuid.UUID, page, limit int) ([]Transaction, error) {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var history transactionHistory
+	if err := c.do(ctx, http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/transactions", query, nil, &history); err != nil {
+		return nil, err
+	}
+	return history.Transactions, nil
+}
+
+// do performs a call, retrying failed attempts as the retry policy allows
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	// One key per logical write, reused by every retry of it
+	var key string
+	if method != http.MethodGet {
+		key, _ = ctx.Value(idempotencyKey{}).(string)
+		if key == "" {
+			key = uuid.NewString()
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		retryAfter, err := c.attempt(ctx, method, u.String(), body, key, out)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.retry.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		wait := retryAfter
+		if wait <= 0 {
+			wait = c.backoff(attempt)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends one request and decodes the response into out. It returns
+// the server's Retry-After, if any, with the error.
+func (c *Client) attempt(ctx context.Context, method, u string, body []byte, key string, out any) (time.Duration, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/problem+json, application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+		var payload errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&payload); err == nil {
+			apiErr.Code = cmp.Or(payload.Code, payload.Error)
+			apiErr.Message = cmp.Or(payload.Detail, payload.Message, payload.Title)
+			apiErr.Fields = payload.Errors
+		} else {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return retryAfter(resp.Header.Get("Retry-After")), apiErr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return 0, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("decode %s response: %w", strings.ToLower(method), err)
+	}
+	return 0, nil
+}
+
+// retryable reports whether a failed attempt may be repeated: the server
+// was unreachable, overloaded or timed out, or rolled the request back
+// after a conflict with a concurrent one. Writes are retried too; the
+// server answers a repeated Idempotency-Key with the response to the first
+// attempt, so a write whose response was lost is not applied twice.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		case http.StatusConflict:
+			return apiErr.Code == "conflict"
+		}
+		return false
+	}
+	return true
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.retry.MinBackoff << (attempt - 1)
+	if d <= 0 || d > c.retry.MaxBackoff {
+		d = c.retry.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(v string) time.Duration {
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
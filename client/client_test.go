@@ -0,0 +1,279 @@
+//go:build unit
+// +build unit
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"wallet-microservice/client"
+	"wallet-microservice/internal/app"
+	"wallet-microservice/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer runs the real handlers on the in-memory repository
+func newServer(t *testing.T) http.Handler {
+	t.Helper()
+	cfg := config.Defaults()
+	cfg.Server.Mode = "test"
+	cfg.Server.OpenAPIValidation = true
+	cfg.Database.Driver = "memory"
+	a, err := app.New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { a.Close(context.Background()) })
+	return a.Router
+}
+
+func newClient(t *testing.T, handler http.Handler, opts ...client.Option) *client.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	opts = append([]client.Option{client.WithRetryPolicy(client.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+	})}, opts...)
+	c, err := client.New(srv.URL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClientWalletLifecycle(t *testing.T) {
+	c := newClient(t, newServer(t))
+	ctx := context.Background()
+
+	wallet, err := c.CreateWallet(ctx, client.CreateWalletRequest{UserID: "sdk-user"})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", wallet.Currency)
+
+	got, err := c.GetWalletByUserID(ctx, "sdk-user")
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID, got.ID)
+
+	updated, err := c.UpdateWallet(ctx, wallet.ID, client.CreateWalletRequest{UserID: "sdk-user", Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", updated.Currency)
+
+	credit, err := c.CreditWallet(ctx, wallet.ID, client.TransactionRequest{Amount: 25, Reference: "r1"})
+	require.NoError(t, err)
+	assert.Equal(t, client.Credit, credit.Type)
+	assert.Equal(t, "r1", credit.Reference)
+	_, err = c.DebitWallet(ctx, wallet.ID, client.TransactionRequest{Amount: 10})
+	require.NoError(t, err)
+
+	got, err = c.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 15.0, got.Balance)
+
+	history, err := c.GetTransactionHistory(ctx, wallet.ID, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, client.Debit, history[0].Type)
+	assert.False(t, history[0].CreatedAt.IsZero())
+
+	require.NoError(t, c.DeleteWallet(ctx, wallet.ID))
+	_, err = c.GetWallet(ctx, wallet.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestClientMapsErrors(t *testing.T) {
+	c := newClient(t, newServer(t))
+	ctx := context.Background()
+	wallet, err := c.CreateWallet(ctx, client.CreateWalletRequest{UserID: "sdk-errors"})
+	require.NoError(t, err)
+
+	_, err = c.CreateWallet(ctx, client.CreateWalletRequest{UserID: "sdk-errors"})
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	_, err = c.DebitWallet(ctx, wallet.ID, client.TransactionRequest{Amount: 1})
+	assert.ErrorIs(t, err, client.ErrInsufficientBalance)
+	assert.NotErrorIs(t, err, client.ErrInvalidRequest)
+
+	_, err = c.CreditWallet(ctx, uuid.New(), client.TransactionRequest{Amount: 1})
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	_, err = c.CreditWallet(ctx, wallet.ID, client.TransactionRequest{Amount: -1})
+	assert.ErrorIs(t, err, client.ErrInvalidRequest)
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "validation_error", apiErr.Code)
+	assert.Equal(t, []client.FieldError{{Field: "amount", Message: "number must be more than 0"}}, apiErr.Fields)
+	assert.NotEmpty(t, apiErr.RequestID)
+}
+
+// flaky answers the first failures requests with status and records the
+// idempotency key of every request
+type flaky struct {
+	next     http.Handler
+	status   int
+	failures int
+
+	mu   sync.Mutex
+	keys []string
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.keys = append(f.keys, r.Header.Get(client.IdempotencyKeyHeader))
+	fail := len(f.keys) <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		w.WriteHeader(f.status)
+		return
+	}
+	f.next.ServeHTTP(w, r)
+}
+
+func TestClientRetriesWithStableIdempotencyKey(t *testing.T) {
+	f := &flaky{next: newServer(t), status: http.StatusServiceUnavailable, failures: 2}
+	c := newClient(t, f)
+
+	wallet, err := c.CreateWallet(context.Background(), client.CreateWalletRequest{UserID: "sdk-retry"})
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, wallet.ID)
+
+	require.Len(t, f.keys, 3)
+	assert.NotEmpty(t, f.keys[0])
+	assert.Equal(t, f.keys[0], f.keys[1])
+	assert.Equal(t, f.keys[0], f.keys[2])
+
+	// A caller-supplied key is used as is
+	f.keys, f.failures = nil, 0
+	ctx := client.WithIdempotencyKey(context.Background(), "payment-42")
+	_, err = c.CreditWallet(ctx, wallet.ID, client.TransactionRequest{Amount: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"payment-42"}, f.keys)
+
+	// Reads carry no key
+	f.keys = nil
+	_, err = c.GetWallet(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{""}, f.keys)
+}
+
+// lossy forwards every request but answers the first failures with 502,
+// as a gateway that lost the response would
+type lossy struct {
+	next     http.Handler
+	failures int
+	calls    int
+}
+
+func (l *lossy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.calls++
+	if l.calls > l.failures {
+		l.next.ServeHTTP(w, r)
+		return
+	}
+	l.next.ServeHTTP(httptest.NewRecorder(), r)
+	w.WriteHeader(http.StatusBadGateway)
+}
+
+func TestClientRetriedWritesApplyOnce(t *testing.T) {
+	l := &lossy{next: newServer(t)}
+	c := newClient(t, l)
+	ctx := context.Background()
+	wallet, err := c.CreateWallet(ctx, client.CreateWalletRequest{UserID: "sdk-lossy"})
+	require.NoError(t, err)
+
+	// The first two credits reach the server but their responses are lost
+	l.calls, l.failures = 0, 2
+	credit, err := c.CreditWallet(ctx, wallet.ID, client.TransactionRequest{Amount: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 3, l.calls)
+	assert.Equal(t, 5.0, credit.Amount)
+
+	got, err := c.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, got.Balance)
+	history, err := c.GetTransactionHistory(ctx, wallet.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	// A key reused for a different write is refused
+	keyed := client.WithIdempotencyKey(ctx, "sdk-lossy-1")
+	_, err = c.CreditWallet(keyed, wallet.ID, client.TransactionRequest{Amount: 1})
+	require.NoError(t, err)
+	_, err = c.CreditWallet(keyed, wallet.ID, client.TransactionRequest{Amount: 2})
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	assert.Equal(t, "idempotency_key_reused", apiErr.Code)
+}
+
+func TestClientRetriesConflicts(t *testing.T) {
+	var calls int
+	conflict := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, `{"error":"conflict","message":"conflicting concurrent update"}`)
+	})
+	c := newClient(t, conflict)
+
+	// The server rolled the debit back, so repeating it is safe
+	_, err := c.DebitWallet(context.Background(), uuid.New(), client.TransactionRequest{Amount: 1})
+	assert.ErrorIs(t, err, client.ErrConflict)
+	assert.Equal(t, 3, calls)
+}
+
+func TestClientRetriesRefusedConnections(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	c, err := client.New(url, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+	require.NoError(t, err)
+	_, err = c.CreateWallet(context.Background(), client.CreateWalletRequest{UserID: "sdk-down"})
+	assert.Error(t, err)
+}
+
+func TestClientAttemptTimeout(t *testing.T) {
+	var calls int
+	var mu sync.Mutex
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	c := newClient(t, slow, client.WithTimeout(20*time.Millisecond))
+
+	start := time.Now()
+	_, err := c.GetWallet(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 3, calls)
+	mu.Unlock()
+
+	// The caller's context ends retries early
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetWallet(ctx, uuid.New())
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestNewRejectsInvalidBaseURL(t *testing.T) {
+	_, err := client.New("wallet:8080")
+	assert.Error(t, err)
+	_, err = client.New("ftp://wallet")
+	assert.Error(t, err)
+}
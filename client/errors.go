@@ -0,0 +1,68 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors that an *APIError matches with errors.Is, by what went wrong
+// rather than by HTTP status
+var (
+	ErrNotFound            = errors.New("wallet not found")
+	ErrConflict            = errors.New("conflict")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrInvalidRequest      = errors.New("invalid request")
+	ErrUnavailable         = errors.New("service unavailable")
+)
+
+// APIError is an error response from the API
+type APIError struct {
+	StatusCode int
+	// Code is the machine-readable error field of the response, e.g.
+	// "wallet_not_found"; see the Error schema in api/openapi.yaml
+	Code    string
+	Message string
+	// Fields lists the offending fields when the request failed validation
+	Fields []FieldError
+	// RequestID identifies the request in the service's logs and traces
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wallet api: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is matches the sentinel errors of this package
+func (e *APIError) Is(target error) bool {
+	return e.kind() == target
+}
+
+// kind classifies the error by its code, falling back to the status for
+// responses without one, e.g. from a proxy
+func (e *APIError) kind() error {
+	switch e.Code {
+	case "wallet_not_found":
+		return ErrNotFound
+	case "insufficient_funds":
+		return ErrInsufficientBalance
+	case "duplicate", "conflict":
+		return ErrConflict
+	case "validation_error", "invalid_id", "invalid_user_id", "invalid_parameter", "invalid_amount":
+		return ErrInvalidRequest
+	case "timeout":
+		return ErrUnavailable
+	}
+
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusBadRequest:
+		return ErrInvalidRequest
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrUnavailable
+	}
+	return nil
+}
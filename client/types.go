@@ -0,0 +1,112 @@
+package client
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The types below mirror the JSON payloads of the API; see api/openapi.yaml.
+// They are declared here rather than reused from the service's internal
+// models so that other modules can import them.
+
+type Wallet struct {
+	ID         uuid.UUID     `json:"id"`
+	UserID     string        `json:"user_id"`
+	Balance    float64       `json:"balance"`
+	Buckets    BucketBalance `json:"buckets"`
+	DebitOrder DebitOrder    `json:"debit_order"`
+	Overdraft  Overdraft     `json:"overdraft"`
+	Currency   string        `json:"currency"`
+}
+
+// Overdraft reports how much of a wallet's overdraft limit is in use
+type Overdraft struct {
+	Limit float64 `json:"limit"`
+	Used  float64 `json:"used"`
+}
+
+// BucketBalance splits a wallet's balance into cash and promotional credit
+type BucketBalance struct {
+	Cash  float64 `json:"cash"`
+	Promo float64 `json:"promo"`
+}
+
+type Bucket string
+
+const (
+	CashBucket  Bucket = "cash"
+	PromoBucket Bucket = "promo"
+)
+
+// DebitOrder decides which bucket debits are taken from first
+type DebitOrder string
+
+const (
+	PromoFirst DebitOrder = "promo_first"
+	CashFirst  DebitOrder = "cash_first"
+)
+
+type TransactionType string
+
+const (
+	Credit TransactionType = "CREDIT"
+	Debit  TransactionType = "DEBIT"
+)
+
+type Transaction struct {
+	ID          uuid.UUID       `json:"id"`
+	WalletID    uuid.UUID       `json:"wallet_id"`
+	Type        TransactionType `json:"type"`
+	Amount      float64         `json:"amount"`
+	Description string          `json:"description"`
+	Reference   string          `json:"reference"`
+	// PromoAmount is the part of Amount in promotional credit
+	PromoAmount float64   `json:"promo_amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CreateWalletRequest struct {
+	UserID string `json:"user_id"`
+	// Currency is an ISO 4217 code; the server defaults it to USD
+	Currency string `json:"currency,omitempty"`
+	// DebitOrder defaults to promo_first
+	DebitOrder DebitOrder `json:"debit_order,omitempty"`
+	// OverdraftLimit is how far below zero debits may take the balance.
+	// Leaving it out means none on create and keeps the limit on update.
+	OverdraftLimit *float64 `json:"overdraft_limit,omitempty"`
+}
+
+type TransactionRequest struct {
+	Amount      float64 `json:"amount"`
+	Description string  `json:"description,omitempty"`
+	Reference   string  `json:"reference,omitempty"`
+	// Bucket and ExpiresAt only apply to credits. Promotional credit
+	// expires after the server's default period unless ExpiresAt is set.
+	Bucket    Bucket     `json:"bucket,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type transactionHistory struct {
+	Transactions []Transaction `json:"transactions"`
+	Page         int           `json:"page"`
+	Limit        int           `json:"limit"`
+}
+
+// FieldError is one invalid field of a rejected request
+type FieldError struct {
+	// Field is the JSON name of the field or the name of the parameter
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// errorResponse decodes both problem details and the legacy error shape
+type errorResponse struct {
+	Title  string       `json:"title"`
+	Detail string       `json:"detail"`
+	Code   string       `json:"code"`
+	Errors []FieldError `json:"errors"`
+
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+	"wallet-microservice/internal/accrual"
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/interest"
+	"wallet-microservice/internal/services"
+)
+
+const interestUsage = "usage: interest accrue [YYYY-MM-DD]"
+
+// runInterest implements the interest subcommand and returns the exit code.
+// "accrue" brings every enrolled wallet up to the given date, yesterday by
+// default; dates already accrued are left alone, so it is safe to repeat.
+func runInterest(cfg *config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "accrue" || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, interestUsage)
+		return 2
+	}
+	if cfg.Database.Driver == "memory" {
+		fmt.Fprintln(os.Stderr, "interest needs the postgres or sqlite driver")
+		return 2
+	}
+	through := interest.Date(time.Now()).AddDate(0, 0, -1)
+	if len(args) == 2 {
+		date, err := time.Parse(time.DateOnly, args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, interestUsage)
+			return 2
+		}
+		if !date.Before(interest.Date(time.Now())) {
+			fmt.Fprintln(os.Stderr, "interest can only accrue for days that have ended")
+			return 2
+		}
+		through = date
+	}
+
+	terms, err := interest.NewTerms(cfg.Interest)
+	if err != nil {
+		slog.Error("Invalid interest configuration", "error", err)
+		return 1
+	}
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	defer db.Close()
+
+	timeouts := services.Timeouts{
+		Default:    cfg.Timeouts.Request,
+		Operations: cfg.Timeouts.Operations,
+	}
+	if err := accrual.New(db.Gorm(), terms, timeouts).AccrueThrough(context.Background(), through); err != nil {
+		slog.Error("Interest accrual failed", "error", err)
+		return 1
+	}
+	slog.Info("Interest accrued", "through", through.Format(time.DateOnly))
+	return 0
+}
@@ -1,75 +1,64 @@
 package main
 
 import (
+    "context"
     "log"
-	"os"
-    "wallet-microservice/internal/database"
-    "wallet-microservice/internal/handlers"
-    "wallet-microservice/internal/repositories"
-    "wallet-microservice/internal/services"
+    "log/slog"
+    "os"
+    "os/signal"
+    "syscall"
+    "wallet-microservice/internal/app"
+    "wallet-microservice/internal/config"
+    "wallet-microservice/internal/logging"
     
-    "github.com/gin-gonic/gin"
     "github.com/joho/godotenv"
 )
 
 func main() {
     // Load environment variables
-    if err := godotenv.Load(); err != nil {
-        log.Println("No .env file found")
-    }
-    
-    // Connect to database
-    database.Connect()
-    database.Migrate()
-    
-    // Initialize layers
-    walletRepo := repositories.NewWalletRepository()
-    walletService := services.NewWalletService(walletRepo)
-    walletHandler := handlers.NewWalletHandler(walletService)
-    
-    // Setup Gin router
-    router := gin.Default()
+    envErr := godotenv.Load()
     
-    // Add middleware
-    router.Use(gin.Logger())
-    router.Use(gin.Recovery())
-    
-    // Add CORS middleware
-    router.Use(func(c *gin.Context) {
-        c.Header("Access-Control-Allow-Origin", "*")
-        c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-        c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-        
-        if c.Request.Method == "OPTIONS" {
-            c.AbortWithStatus(204)
-            return
-        }
-        
-        c.Next()
-    })
+    // Load and validate configuration: defaults < flags < config file < environment
+    cfg, err := config.Load(os.Args[1:])
+    if err != nil {
+        log.Fatal(err)
+    }
     
-    // Health check endpoint
-    router.GET("/health", func(c *gin.Context) {
-        c.JSON(200, gin.H{
-            "status": "OK",
-            "service": "wallet-microservice",
-        })
+    // Setup structured logging; this also routes the standard log package through slog
+    logger := logging.New(os.Stdout, logging.Config{
+        Level:        cfg.Log.Level,
+        RedactFields: cfg.Log.RedactFields,
+        RedactMode:   cfg.Log.RedactMode,
     })
+    slog.SetDefault(logger)
+    if envErr != nil {
+        slog.Info("No .env file found")
+    }
+    slog.Info("Effective configuration", "config", cfg.Redacted())
     
-    // Register routes
-    walletHandler.RegisterRoutes(router)
+    // "migrate up|down [n]|status" runs migrations and "interest accrue
+    // [date]" accrues interest; both exit without serving
+    if len(cfg.Args) > 0 {
+        switch cfg.Args[0] {
+        case "migrate":
+            os.Exit(runMigrate(cfg.Database, cfg.Args[1:]))
+        case "interest":
+            os.Exit(runInterest(cfg, cfg.Args[1:]))
+        default:
+            log.Fatalf("Unknown command %q", cfg.Args[0])
+        }
+    }
     
-    // Start server
-    port := getEnv("PORT", "8080")
-    log.Printf("Server starting on port %s", port)
-    if err := router.Run(":" + port); err != nil {
-        log.Fatal("Failed to start server:", err)
+    // Build every component; the container owns their lifecycle
+    application, err := app.New(cfg, logger)
+    if err != nil {
+        log.Fatal("Failed to start:", err)
     }
-}
-
-func getEnv(key, defaultValue string) string {
-    if value := os.Getenv(key); value != "" {
-        return value
+    
+    // Serve until a termination signal, then drain and close everything
+    ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    defer stop()
+    if err := application.Run(ctx); err != nil {
+        os.Exit(1)
     }
-    return defaultValue
 }
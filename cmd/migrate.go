@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// runMigrate implements the migrate subcommand and returns the exit code
+func runMigrate(cfg config.DatabaseConfig, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	if cfg.Driver == "memory" {
+		fmt.Fprintln(os.Stderr, "migrate needs the postgres or sqlite driver")
+		return 2
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		return 1
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db.Gorm())
+	if err != nil {
+		slog.Error("Failed to load migrations", "error", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			slog.Error("Migration failed", "error", err)
+			return 1
+		}
+		slog.Info("Migrations applied", "count", len(applied), "version", migrator.Latest())
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			slog.Error("Rollback failed", "error", err)
+			return 1
+		}
+		slog.Info("Migrations reverted", "count", len(reverted))
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			slog.Error("Failed to read migration status", "error", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			at := "pending"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		w.Flush()
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
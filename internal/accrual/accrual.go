@@ -0,0 +1,164 @@
+// Package accrual accrues interest on enrolled wallets day by day and pays
+// it out at the end of each payout period. Only one instance runs at a
+// time: the one holding a Postgres advisory lock.
+package accrual
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/interest"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/services"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lockKey identifies the Postgres advisory lock held while accruing
+const lockKey int64 = 7_310_021_554_004
+
+// pageSize is the number of accounts listed at a time
+const pageSize = 100
+
+// Accruer accrues interest from the end-of-day balances in the ledger
+type Accruer struct {
+	db       *gorm.DB
+	terms    *interest.Terms
+	timeouts services.Timeouts
+	now      func() time.Time
+}
+
+func New(db *gorm.DB, terms *interest.Terms, timeouts services.Timeouts) *Accruer {
+	return &Accruer{
+		db:       db,
+		terms:    terms,
+		timeouts: timeouts,
+		now:      time.Now,
+	}
+}
+
+// Run accrues through yesterday, the last complete day. It is meant to run
+// periodically.
+func (a *Accruer) Run(ctx context.Context) error {
+	return a.AccrueThrough(ctx, interest.Date(a.now()).AddDate(0, 0, -1))
+}
+
+// AccrueThrough brings every account up to date, accruing each day it has
+// not accrued for yet in order, through date. A day accrues and a period
+// pays out in the same database transaction that records them, so running
+// again for a date, or concurrently with another run, changes nothing.
+func (a *Accruer) AccrueThrough(ctx context.Context, date time.Time) error {
+	date = interest.Date(date)
+	ran, err := database.WithTryLock(ctx, a.db, lockKey, func(conn *gorm.DB) error {
+		for ctx.Err() == nil {
+			ids, err := repositories.NewInterestRepository(conn).AccountsBehind(ctx, date, pageSize)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := a.catchUp(ctx, conn, id, date); err != nil {
+					return fmt.Errorf("accrue interest for wallet %s: %w", id, err)
+				}
+			}
+			// Accounts brought up to date are no longer behind, so the next
+			// page starts with the ones left over
+			if len(ids) < pageSize {
+				return nil
+			}
+		}
+		return ctx.Err()
+	})
+	if err == nil && !ran {
+		slog.DebugContext(ctx, "Interest is accruing elsewhere; skipping")
+	}
+	return err
+}
+
+// catchUp accrues the days an account is behind through date, one
+// transaction per day
+func (a *Accruer) catchUp(ctx context.Context, conn *gorm.DB, walletID uuid.UUID, through time.Time) error {
+	for done := false; !done; {
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			account, err := repositories.NewInterestRepository(tx).LockAccount(ctx, walletID)
+			if err != nil {
+				return err
+			}
+			day := account.EnrolledOn
+			if account.AccruedThrough != nil {
+				day = account.AccruedThrough.AddDate(0, 0, 1)
+			}
+			if day.After(through) {
+				done = true
+				return nil
+			}
+			return a.accrue(ctx, tx, account, interest.Date(day))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accrue adds the interest for day to the account, paying the account out
+// if day ends a payout period
+func (a *Accruer) accrue(ctx context.Context, tx *gorm.DB, account *models.InterestAccount, day time.Time) error {
+	repo := repositories.NewInterestRepository(tx)
+	balance, err := repo.EndOfDayBalance(ctx, account.WalletID, day)
+	if err != nil {
+		return err
+	}
+	accrual := &models.InterestAccrual{
+		WalletID:   account.WalletID,
+		Date:       day,
+		Balance:    balance,
+		AnnualRate: a.terms.RateOn(day),
+		DayCount:   string(a.terms.DayCount),
+		Amount:     a.terms.DailyInterest(balance, day),
+	}
+	account.Accrued = interest.Round(account.Accrued + accrual.Amount)
+	account.AccruedThrough = &day
+
+	var payout *models.InterestPayout
+	if amount := interest.Payable(account.Accrued); a.terms.IsPeriodEnd(day) && amount > 0 {
+		// The wallet service works within tx, so the credit commits or
+		// rolls back with the payout record
+		wallets := services.NewWalletService(repositories.NewWalletRepository(tx), services.WithTimeouts(a.timeouts))
+		credit, err := wallets.CreditWallet(ctx, account.WalletID, models.TransactionRequest{
+			Amount:      amount,
+			Description: "Interest",
+			Reference:   "interest:" + day.Format(time.DateOnly),
+		})
+		if err != nil {
+			return err
+		}
+		payout = &models.InterestPayout{
+			WalletID:      account.WalletID,
+			PeriodEnd:     day,
+			Amount:        amount,
+			TransactionID: credit.ID,
+		}
+		account.Accrued = interest.Round(account.Accrued - amount)
+	}
+
+	if err := repo.RecordAccrual(ctx, account, accrual, payout); err != nil {
+		return err
+	}
+	attrs := []any{
+		"wallet_id", account.WalletID,
+		"date", day.Format(time.DateOnly),
+		"balance", balance,
+		"amount", accrual.Amount,
+		"accrued", account.Accrued,
+	}
+	if payout != nil {
+		attrs = append(attrs, "paid", payout.Amount)
+	}
+	slog.InfoContext(ctx, "Accrued interest", attrs...)
+	return nil
+}
@@ -0,0 +1,172 @@
+//go:build unit
+// +build unit
+
+package accrual
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/interest"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+type fixture struct {
+	db       *gorm.DB
+	accruer  *Accruer
+	interest repositories.InterestRepository
+	wallets  services.WalletService
+	walletID uuid.UUID
+}
+
+// newFixture returns an accruer over a private in-memory SQLite database
+// with one wallet, enrolled from enrolledOn. 3.65% a year on ACT/365 earns
+// a tenth of a thousandth a day.
+func newFixture(t *testing.T, enrolledOn time.Time) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	cfg := config.Defaults()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	db, err := database.Open(cfg.Database)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Migrate(ctx))
+
+	cfg.Interest.Rates = map[string]float64{"2025-01-01": 0.0365}
+	terms, err := interest.NewTerms(cfg.Interest)
+	require.NoError(t, err)
+
+	f := &fixture{
+		db:       db.Gorm(),
+		accruer:  New(db.Gorm(), terms, services.Timeouts{}),
+		interest: repositories.NewInterestRepository(db.Gorm()),
+		wallets:  services.NewWalletService(repositories.NewWalletRepository(db.Gorm())),
+	}
+	w, err := f.wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "saver"})
+	require.NoError(t, err)
+	f.walletID = w.ID
+	_, err = f.interest.Enroll(ctx, w.ID, enrolledOn)
+	require.NoError(t, err)
+	return f
+}
+
+// post books a transaction as if it had been made at at
+func (f *fixture) post(t *testing.T, typ models.TransactionType, amount float64, at time.Time) {
+	t.Helper()
+	signed := amount
+	if typ == models.Debit {
+		signed = -amount
+	}
+	require.NoError(t, f.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Transaction{WalletID: f.walletID, Type: typ, Amount: amount, CreatedAt: at}).Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE wallets SET balance = balance + ? WHERE id = ?", signed, f.walletID).Error
+	}))
+}
+
+func (f *fixture) account(t *testing.T) *models.InterestAccount {
+	t.Helper()
+	account, err := f.interest.GetAccount(context.Background(), f.walletID)
+	require.NoError(t, err)
+	return account
+}
+
+func TestAccrueThroughUsesEndOfDayBalances(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, day("2026-01-01"))
+	f.post(t, models.Credit, 1000, day("2026-01-01").Add(10*time.Hour))
+	f.post(t, models.Debit, 500, day("2026-01-15").Add(12*time.Hour))
+
+	// 14 days on 1000 and 6 on 500
+	require.NoError(t, f.accruer.AccrueThrough(ctx, day("2026-01-20")))
+	account := f.account(t)
+	assert.InDelta(t, 1.7, account.Accrued, 1e-9)
+	assert.Equal(t, day("2026-01-20"), account.AccruedThrough.UTC())
+
+	accruals, err := f.interest.ListAccruals(ctx, f.walletID, 100, 0)
+	require.NoError(t, err)
+	require.Len(t, accruals, 20)
+	assert.Equal(t, 500.0, accruals[0].Balance)
+	assert.Equal(t, 1000.0, accruals[19].Balance)
+	assert.Equal(t, 0.1, accruals[19].Amount)
+	assert.Equal(t, "ACT/365", accruals[19].DayCount)
+
+	// January pays out 1.7 + 11 * 0.05 on its last day. In February the
+	// payout counts towards the balance, though it was credited later.
+	f.accruer.now = func() time.Time { return day("2026-02-03").Add(time.Hour) }
+	require.NoError(t, f.accruer.Run(ctx))
+	account = f.account(t)
+	assert.InDelta(t, 2*0.050225, account.Accrued, 1e-9)
+	assert.Equal(t, day("2026-02-02"), account.AccruedThrough.UTC())
+
+	wallet, err := f.wallets.GetWallet(ctx, f.walletID)
+	require.NoError(t, err)
+	assert.Equal(t, 502.25, wallet.Balance)
+	history, err := f.wallets.GetTransactionHistory(ctx, f.walletID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, models.Credit, history[0].Type)
+	assert.Equal(t, 2.25, history[0].Amount)
+	assert.Equal(t, "interest:2026-01-31", history[0].Reference)
+
+	// Running again, or for a date already accrued, changes nothing
+	require.NoError(t, f.accruer.Run(ctx))
+	require.NoError(t, f.accruer.AccrueThrough(ctx, day("2026-01-31")))
+	assert.InDelta(t, 2*0.050225, f.account(t).Accrued, 1e-9)
+	wallet, err = f.wallets.GetWallet(ctx, f.walletID)
+	require.NoError(t, err)
+	assert.Equal(t, 502.25, wallet.Balance)
+}
+
+func TestAccrueThroughKeepsFractionsOfCents(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, day("2026-03-01"))
+	f.accruer.terms.PayoutFrequency = interest.Daily
+	f.post(t, models.Credit, 30, day("2026-02-01"))
+
+	// 0.003 a day is paid out once it makes a cent
+	require.NoError(t, f.accruer.AccrueThrough(ctx, day("2026-03-04")))
+	assert.InDelta(t, 0.002, f.account(t).Accrued, 1e-9)
+	wallet, err := f.wallets.GetWallet(ctx, f.walletID)
+	require.NoError(t, err)
+	assert.Equal(t, 30.01, wallet.Balance)
+
+	// Days before enrolment earn nothing
+	accruals, err := f.interest.ListAccruals(ctx, f.walletID, 100, 0)
+	require.NoError(t, err)
+	assert.Len(t, accruals, 4)
+}
+
+func TestAccrueThroughIgnoresPromoCredit(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, day("2026-01-01"))
+	f.post(t, models.Credit, 1000, day("2026-01-01"))
+	_, err := f.wallets.CreditWallet(ctx, f.walletID, models.TransactionRequest{Amount: 500, Bucket: models.PromoBucket})
+	require.NoError(t, err)
+
+	require.NoError(t, f.accruer.AccrueThrough(ctx, day("2026-01-01")))
+	accruals, err := f.interest.ListAccruals(ctx, f.walletID, 100, 0)
+	require.NoError(t, err)
+	require.Len(t, accruals, 1)
+	assert.Equal(t, 1000.0, accruals[0].Balance)
+}
@@ -0,0 +1,428 @@
+// Package app wires the service together. The App container builds every
+// component from the configuration, owns their lifecycle and closes them in
+// reverse order of creation.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"wallet-microservice/api"
+	"wallet-microservice/internal/accrual"
+	"wallet-microservice/internal/archive"
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/escrow"
+	"wallet-microservice/internal/grpcapi"
+	"wallet-microservice/internal/handlers"
+	"wallet-microservice/internal/health"
+	"wallet-microservice/internal/interest"
+	"wallet-microservice/internal/logging"
+	"wallet-microservice/internal/openapi"
+	"wallet-microservice/internal/overdraft"
+	"wallet-microservice/internal/problem"
+	"wallet-microservice/internal/promo"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/scheduler"
+	"wallet-microservice/internal/services"
+	"wallet-microservice/internal/tracing"
+	"wallet-microservice/internal/worker"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+type App struct {
+	Config    *config.Config
+	Logger    *slog.Logger
+	DB        *database.DB         // nil with the memory driver
+	Replicas  *database.ReplicaSet // nil without configured replicas
+	Archiver  *archive.Archiver    // nil with the memory driver
+	Wallets   services.WalletService
+	Batches   services.BatchService    // nil with the memory driver
+	Schedules services.ScheduleService // nil with the memory driver
+	Scheduler *scheduler.Runner        // nil with the memory driver
+	Interest  services.InterestService // nil with the memory driver
+	Accruer   *accrual.Accruer         // nil with the memory driver
+	Expirer   *promo.Expirer
+	Overdraft *overdraft.Charger             // nil with the memory driver or without charges
+	Escrows   services.EscrowService         // nil with the memory driver
+	Releaser  *escrow.Releaser               // nil with the memory driver
+	Requests  services.PaymentRequestService // nil with the memory driver
+	Bills     services.BillService           // nil with the memory driver
+	Health    *health.Registry
+	Workers   *worker.Manager
+	Spec      *openapi3.T
+	Router    *gin.Engine
+	Server    *http.Server
+	GRPC      *grpc.Server // nil when the gRPC API is disabled
+
+	// Idempotency remembers the responses to writes made with an
+	// Idempotency-Key
+	Idempotency repositories.IdempotencyRepository
+
+	// closers release resources on Close, last acquired first
+	closers []closer
+}
+
+type closer struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// New builds the application. On error everything created so far has
+// already been closed.
+func New(cfg *config.Config, logger *slog.Logger) (_ *App, err error) {
+	a := &App{Config: cfg, Logger: logger}
+	defer func() {
+		if err != nil {
+			a.Close(context.Background())
+		}
+	}()
+
+	shutdownTracing, err := tracing.Setup(tracing.Config{
+		ServiceName: cfg.Tracing.ServiceName,
+		Exporter:    cfg.Tracing.Exporter,
+		FilePath:    cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("setup tracing: %w", err)
+	}
+	a.onClose("tracing", shutdownTracing)
+
+	// The memory driver keeps wallets in process for demos; there is no
+	// database to connect, migrate or check
+	var walletRepo repositories.WalletRepository
+	if cfg.Database.Driver == "memory" {
+		logger.Warn("Using the in-memory repository; data is lost on exit")
+		walletRepo = repositories.NewMemoryWalletRepository()
+		a.Idempotency = repositories.NewMemoryIdempotencyRepository()
+	} else {
+		a.DB, err = database.Open(cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		a.onClose("database", func(context.Context) error { return a.DB.Close() })
+
+		if cfg.Database.MigrateOnStart {
+			if err := a.DB.Migrate(context.Background()); err != nil {
+				return nil, fmt.Errorf("migrate database: %w", err)
+			}
+			if _, err := database.EnsurePartitions(context.Background(), a.DB.Gorm(), time.Now(), cfg.Transactions.PartitionsAhead); err != nil {
+				return nil, err
+			}
+		}
+
+		var repoOpts []repositories.Option
+		if len(cfg.Database.Replicas) > 0 {
+			a.Replicas, err = database.OpenReplicas(a.DB, cfg.Database)
+			if err != nil {
+				return nil, err
+			}
+			a.onClose("replicas", func(context.Context) error { return a.Replicas.Close() })
+
+			// Put reachable replicas into rotation before serving
+			a.Replicas.Check(context.Background())
+			repoOpts = append(repoOpts, repositories.WithReadRouter(a.Replicas))
+		}
+		walletRepo = repositories.NewWalletRepository(a.DB.Gorm(), repoOpts...)
+		a.Idempotency = repositories.NewIdempotencyRepository(a.DB.Gorm())
+
+		// Partitions are maintained even when archiving is off
+		var store *archive.Store
+		if cfg.Transactions.ArchiveEnabled {
+			store = archive.NewStore(cfg.Transactions.ArchiveDir)
+			walletRepo = archive.WithHistory(walletRepo, store, cfg.Transactions.IncludeArchived)
+		}
+		a.Archiver = archive.NewArchiver(a.DB.Gorm(), store, cfg.Transactions)
+	}
+
+	timeouts := services.Timeouts{
+		Default:    cfg.Timeouts.Request,
+		Operations: cfg.Timeouts.Operations,
+	}
+	a.Wallets = services.NewWalletService(walletRepo, services.WithTimeouts(timeouts),
+		services.WithPromoExpiry(cfg.Promo.DefaultExpiry))
+	a.Expirer = promo.NewExpirer(walletRepo)
+	// Batches, scheduled payments and escrows lock several wallets in one
+	// database transaction, and interest and overdraft charges read end-of-day
+	// balances back from the ledger, which the memory driver cannot do
+	if a.DB != nil {
+		a.Batches = services.NewBatchService(repositories.NewBatchRepository(a.DB.Gorm()), services.BatchLimits{
+			MaxItems:          cfg.Batches.MaxItems,
+			AsyncThreshold:    cfg.Batches.AsyncThreshold,
+			ProcessingTimeout: cfg.Batches.ProcessingTimeout,
+		}, timeouts)
+		// Wallets are looked up on the primary, so a schedule can be set
+		// up right after creating its wallets
+		a.Schedules = services.NewScheduleService(repositories.NewScheduleRepository(a.DB.Gorm()),
+			repositories.NewWalletRepository(a.DB.Gorm()), timeouts)
+		a.Scheduler = scheduler.New(a.DB.Gorm(), cfg.Scheduler, timeouts)
+
+		terms, err := interest.NewTerms(cfg.Interest)
+		if err != nil {
+			return nil, err
+		}
+		a.Interest = services.NewInterestService(repositories.NewInterestRepository(a.DB.Gorm()),
+			repositories.NewWalletRepository(a.DB.Gorm()), terms, timeouts)
+		a.Accruer = accrual.New(a.DB.Gorm(), terms, timeouts)
+
+		if cfg.Overdraft.Rate > 0 || cfg.Overdraft.DailyFee > 0 {
+			a.Overdraft = overdraft.New(a.DB.Gorm(), cfg.Overdraft)
+		}
+
+		a.Escrows = services.NewEscrowService(repositories.NewEscrowRepository(a.DB.Gorm()),
+			repositories.NewWalletRepository(a.DB.Gorm()), timeouts)
+		a.Releaser = escrow.New(a.DB.Gorm())
+		a.Requests = services.NewPaymentRequestService(repositories.NewPaymentRequestRepository(a.DB.Gorm()),
+			repositories.NewWalletRepository(a.DB.Gorm()), cfg.Requests.DefaultExpiry, timeouts)
+		a.Bills = services.NewBillService(repositories.NewBillRepository(a.DB.Gorm()),
+			repositories.NewWalletRepository(a.DB.Gorm()), timeouts)
+	}
+
+	a.Health = health.NewRegistry(cfg.Timeouts.HealthCheck)
+	if a.DB != nil {
+		a.Health.Register("database", health.CheckerFunc(a.DB.Ping), true)
+		a.Health.Register("schema", health.CheckerFunc(a.DB.CheckSchema), true)
+	}
+
+	// Background workers are registered here by the features that need them
+	a.Workers = worker.NewManager(logger)
+
+	if a.Replicas != nil {
+		// Reads fall back to the primary, so a lost replica is not critical
+		for _, r := range a.Replicas.Replicas() {
+			a.Health.Register("replica:"+r.Name, health.CheckerFunc(r.Healthy), false)
+		}
+		a.Workers.Add(worker.Periodic("replica-health", cfg.Database.ReplicaCheckInterval, a.Replicas.Check))
+	}
+	if a.Archiver != nil {
+		a.Workers.Add(worker.Periodic("transactions-maintenance", cfg.Transactions.MaintenanceInterval, a.Archiver.Maintain))
+	}
+	if a.Batches != nil {
+		a.Workers.Add(worker.Periodic("batch-processor", cfg.Batches.PollInterval, a.Batches.ProcessPending))
+	}
+	if a.Scheduler != nil {
+		a.Workers.Add(worker.Periodic("scheduled-payments", cfg.Scheduler.PollInterval, a.Scheduler.RunDue))
+	}
+	if a.Accruer != nil {
+		a.Workers.Add(worker.Periodic("interest-accrual", cfg.Interest.RunInterval, a.Accruer.Run))
+	}
+	a.Workers.Add(worker.Periodic("promo-expiry", cfg.Promo.ExpiryInterval, a.Expirer.Run))
+	if a.Overdraft != nil {
+		a.Workers.Add(worker.Periodic("overdraft-charges", cfg.Overdraft.RunInterval, a.Overdraft.Run))
+	}
+	if a.Releaser != nil {
+		a.Workers.Add(worker.Periodic("escrow-release", cfg.Escrow.ReleaseInterval, a.Releaser.Run))
+	}
+	if a.Requests != nil {
+		a.Workers.Add(worker.Periodic("payment-request-expiry", cfg.Requests.ExpiryInterval, a.Requests.ExpirePaymentRequests))
+	}
+	a.Workers.Add(worker.Periodic("idempotency-key-purge", cfg.Idempotency.PurgeInterval, a.purgeIdempotencyKeys))
+
+	a.Spec, err = api.Load()
+	if err != nil {
+		return nil, err
+	}
+	a.Router, err = a.newRouter()
+	if err != nil {
+		return nil, err
+	}
+	a.Server = &http.Server{
+		Addr:              ":" + cfg.Server.Port,
+		Handler:           a.Router,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+	}
+
+	if cfg.GRPC.Enabled {
+		var opts []grpcapi.Option
+		if cfg.GRPC.Reflection {
+			opts = append(opts, grpcapi.WithReflection())
+		}
+		a.GRPC = grpcapi.NewServer(a.Wallets, a.Health, logger, opts...)
+	}
+	return a, nil
+}
+
+func (a *App) newRouter() (*gin.Engine, error) {
+	gin.SetMode(a.Config.Server.Mode)
+	router := gin.New()
+
+	router.Use(logging.RequestID())
+	router.Use(logging.AccessLog(a.Logger))
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		problem.Write(c, problem.New(http.StatusInternalServerError, problem.CodeInternal, "internal error"))
+	}))
+	router.Use(tracing.Middleware())
+	router.Use(cors)
+	if a.Config.Server.OpenAPIValidation {
+		router.Use(openapi.Middleware(a.Spec, a.Config.Server.Mode == gin.TestMode))
+	}
+	// After validation, so that a rejected request does not take its key
+	router.Use(handlers.Idempotency(a.Idempotency, a.Config.Idempotency.Lease))
+
+	specHandler, err := handlers.NewOpenAPIHandler(a.Spec)
+	if err != nil {
+		return nil, err
+	}
+	specHandler.RegisterRoutes(router)
+	handlers.NewHealthHandler(a.Health).RegisterRoutes(router)
+	handlers.NewWalletHandler(a.Wallets).RegisterRoutes(router)
+	if a.Batches != nil {
+		handlers.NewBatchHandler(a.Batches).RegisterRoutes(router)
+	}
+	if a.Schedules != nil {
+		handlers.NewScheduleHandler(a.Schedules).RegisterRoutes(router)
+	}
+	if a.Interest != nil {
+		handlers.NewInterestHandler(a.Interest).RegisterRoutes(router)
+	}
+	if a.Escrows != nil {
+		handlers.NewEscrowHandler(a.Escrows).RegisterRoutes(router)
+	}
+	if a.Requests != nil {
+		handlers.NewPaymentRequestHandler(a.Requests).RegisterRoutes(router)
+	}
+	if a.Bills != nil {
+		handlers.NewBillHandler(a.Bills).RegisterRoutes(router)
+	}
+	return router, nil
+}
+
+func cors(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, "+handlers.IdempotencyKeyHeader)
+	c.Header("Access-Control-Expose-Headers", "X-Request-ID, Location, "+handlers.MaxStalenessHeader)
+
+	if c.Request.Method == http.MethodOptions {
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	c.Next()
+}
+
+// purgeIdempotencyKeys forgets the keys older than the configured TTL
+func (a *App) purgeIdempotencyKeys(ctx context.Context) error {
+	purged, err := a.Idempotency.Purge(ctx, time.Now().Add(-a.Config.Idempotency.KeyTTL))
+	if purged > 0 {
+		a.Logger.InfoContext(ctx, "Purged idempotency keys", "count", purged)
+	}
+	return err
+}
+
+func (a *App) onClose(name string, fn func(context.Context) error) {
+	a.closers = append(a.closers, closer{name: name, fn: fn})
+}
+
+// Run starts the background workers and serves HTTP, and gRPC when
+// enabled, until ctx is done or a server fails, then shuts down gracefully
+func (a *App) Run(ctx context.Context) error {
+	var grpcListener net.Listener
+	if a.GRPC != nil {
+		var err error
+		grpcListener, err = net.Listen("tcp", ":"+a.Config.GRPC.Port)
+		if err != nil {
+			return fmt.Errorf("listen for grpc: %w", err)
+		}
+	}
+
+	a.Workers.Start()
+
+	serverErr := make(chan error, 2)
+	go func() {
+		a.Logger.Info("Server starting", "port", a.Config.Server.Port)
+		if err := a.Server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+	if a.GRPC != nil {
+		go func() {
+			a.Logger.Info("gRPC server starting", "port", a.Config.GRPC.Port)
+			if err := a.GRPC.Serve(grpcListener); err != nil {
+				serverErr <- fmt.Errorf("grpc: %w", err)
+			}
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		a.Logger.Info("Shutdown signal received")
+	case err := <-serverErr:
+		a.Logger.Error("Server failed", "error", err)
+		runErr = err
+	}
+
+	a.Shutdown()
+	return runErr
+}
+
+// Shutdown drains the service in order: readiness fails first so the load
+// balancer stops routing to us, then in-flight requests complete, then
+// background workers stop and finally resources are closed.
+func (a *App) Shutdown() {
+	cfg := a.Config.Server
+
+	// 1) Fail readiness and give probes time to notice
+	a.Health.Drain()
+	a.Logger.Info("Draining", "delay", cfg.ShutdownDrainDelay.String())
+	time.Sleep(cfg.ShutdownDrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// 2) Stop accepting connections and wait for in-flight requests
+	if err := a.Server.Shutdown(ctx); err != nil {
+		a.Logger.Error("HTTP server did not shut down cleanly", "error", err)
+	}
+	if a.GRPC != nil {
+		a.stopGRPC(ctx)
+	}
+
+	// 3) Stop background workers, last registered first
+	if err := a.Workers.Stop(ctx); err != nil {
+		a.Logger.Error("Background workers did not stop cleanly", "error", err)
+	}
+
+	// 4) Close the connection pool and flush traces once nothing can use them
+	a.Close(ctx)
+	a.Logger.Info("Shutdown complete")
+}
+
+// stopGRPC waits for in-flight calls, including open history streams,
+// until ctx is done and then cancels whatever is left
+func (a *App) stopGRPC(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		a.GRPC.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		a.Logger.Error("gRPC server did not shut down cleanly", "error", ctx.Err())
+		a.GRPC.Stop()
+		<-stopped
+	}
+}
+
+// Close releases every resource the application acquired, last acquired
+// first. It is safe to call more than once.
+func (a *App) Close(ctx context.Context) {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		c := a.closers[i]
+		if err := c.fn(ctx); err != nil {
+			a.Logger.Error("Failed to close "+c.name, "error", err)
+		}
+	}
+	a.closers = nil
+}
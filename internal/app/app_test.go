@@ -0,0 +1,369 @@
+//go:build unit
+// +build unit
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/openapi"
+	"wallet-microservice/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApp(t *testing.T, driver string) *App {
+	t.Helper()
+	cfg := config.Defaults()
+	cfg.Server.Mode = "test"
+	cfg.Database.Driver = driver
+	cfg.Database.Path = ":memory:"
+
+	a, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { a.Close(context.Background()) })
+	return a
+}
+
+func TestNewWiresRoutes(t *testing.T) {
+	for _, driver := range []string{"memory", "sqlite"} {
+		t.Run(driver, func(t *testing.T) {
+			a := newTestApp(t, driver)
+			assert.Equal(t, driver == "sqlite", a.DB != nil)
+
+			rec := httptest.NewRecorder()
+			a.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			rec = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(`{"user_id":"u1"}`))
+			req.Header.Set("Content-Type", "application/json")
+			a.Router.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+			var wallet struct {
+				ID string `json:"id"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &wallet))
+			rec = httptest.NewRecorder()
+			a.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+wallet.ID+"/transactions", nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "0.000", rec.Header().Get("X-Max-Staleness"))
+		})
+	}
+}
+
+func TestNewWithReplicas(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Server.Mode = "test"
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	cfg.Database.Replicas = []string{":memory:"}
+
+	a, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	defer a.Close(context.Background())
+
+	require.NotNil(t, a.Replicas)
+	report, ok := a.Health.Check(context.Background())
+	assert.True(t, ok)
+	assert.Contains(t, report.Checks, "replica::memory:")
+}
+
+func TestNewWithArchive(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Server.Mode = "test"
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	cfg.Transactions.ArchiveEnabled = true
+	cfg.Transactions.ArchiveDir = t.TempDir()
+
+	a, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	defer a.Close(context.Background())
+	require.NotNil(t, a.Archiver)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets", strings.NewReader(`{"user_id":"u1"}`))
+	req.Header.Set("Content-Type", "application/json")
+	a.Router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var wallet struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &wallet))
+
+	for query, status := range map[string]int{
+		"":                       http.StatusOK,
+		"?include_archived=true": http.StatusOK,
+		"?include_archived=0":    http.StatusOK,
+		"?include_archived=some": http.StatusBadRequest,
+	} {
+		rec = httptest.NewRecorder()
+		a.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+wallet.ID+"/transactions"+query, nil))
+		assert.Equal(t, status, rec.Code, query)
+	}
+}
+
+// TestEveryRouteIsDocumented fails when a route is added without an OpenAPI
+// entry, or an entry outlives its route. It uses a database, since some
+// routes are not served with the memory driver.
+func TestEveryRouteIsDocumented(t *testing.T) {
+	a := newTestApp(t, "sqlite")
+
+	registered := make(map[string]bool)
+	for _, r := range a.Router.Routes() {
+		registered[r.Method+" "+openapi.PathFor(r.Path)] = true
+		assert.NotNil(t, openapi.Route(a.Spec, r.Method, r.Path), "%s %s has no OpenAPI entry", r.Method, r.Path)
+	}
+	for path, item := range a.Spec.Paths.Map() {
+		for method := range item.Operations() {
+			assert.True(t, registered[method+" "+path], "OpenAPI entry %s %s has no route", method, path)
+		}
+	}
+}
+
+// TestResponsesMatchSpec walks the API with request and response
+// validation on; any handler drifting from the spec answers 500
+func TestResponsesMatchSpec(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Server.Mode = "test"
+	cfg.Server.OpenAPIValidation = true
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	cfg.Batches.AsyncThreshold = 2
+	a, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	defer a.Close(context.Background())
+
+	var accept string
+	do := func(method, path, body string, want int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		a.Router.ServeHTTP(rec, req)
+		require.Equal(t, want, rec.Code, "%s %s: %s", method, path, rec.Body.String())
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/v1/wallets", `{"user_id":"spec-user"}`, http.StatusCreated)
+	var wallet struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &wallet))
+	base := "/api/v1/wallets/" + wallet.ID
+
+	do(http.MethodPost, "/api/v1/wallets", `{"user_id":"spec-user"}`, http.StatusConflict)
+	do(http.MethodPost, "/api/v1/wallets", `{}`, http.StatusBadRequest)
+	do(http.MethodGet, base, "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/wallets/"+"00000000-0000-0000-0000-000000000000", "", http.StatusNotFound)
+	do(http.MethodGet, "/api/v1/users/spec-user/wallet", "", http.StatusOK)
+	do(http.MethodPut, base, `{"user_id":"spec-user","currency":"EUR"}`, http.StatusOK)
+	do(http.MethodPost, base+"/credit", `{"amount":10,"description":"in","reference":"r1"}`, http.StatusOK)
+	do(http.MethodPost, base+"/debit", `{"amount":4}`, http.StatusOK)
+	do(http.MethodPost, base+"/credit", `{"amount":3,"bucket":"promo","expires_at":"2100-01-01T00:00:00Z"}`, http.StatusOK)
+	do(http.MethodPost, base+"/credit", `{"amount":3,"bucket":"bonus"}`, http.StatusBadRequest)
+	do(http.MethodPost, base+"/debit", `{"amount":1,"bucket":"promo"}`, http.StatusBadRequest)
+	do(http.MethodPut, base, `{"user_id":"spec-user","currency":"EUR","debit_order":"cash_first","overdraft_limit":50}`, http.StatusOK)
+	do(http.MethodPost, base+"/debit", `{"amount":20}`, http.StatusOK)
+	do(http.MethodGet, base, "", http.StatusOK)
+	do(http.MethodPut, base, `{"user_id":"spec-user","overdraft_limit":-1}`, http.StatusBadRequest)
+	do(http.MethodPost, base+"/debit", `{"amount":40}`, http.StatusUnprocessableEntity)
+	do(http.MethodPost, "/api/v1/wallets/00000000-0000-0000-0000-000000000000/credit", `{"amount":1}`, http.StatusNotFound)
+	do(http.MethodPost, base+"/debit", `{"amount":0}`, http.StatusBadRequest)
+	do(http.MethodGet, base+"/transactions?page=1&limit=5", "", http.StatusOK)
+	do(http.MethodGet, base+"/transactions?page=0", "", http.StatusBadRequest)
+	rec = do(http.MethodPost, "/api/v1/batches", `{"mode":"best_effort","items":[`+
+		`{"wallet_id":"`+wallet.ID+`","type":"CREDIT","amount":5},`+
+		`{"wallet_id":"`+wallet.ID+`","type":"DEBIT","amount":500}]}`, http.StatusCreated)
+	var batch struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &batch))
+	do(http.MethodGet, "/api/v1/batches/"+batch.ID, "", http.StatusOK)
+	do(http.MethodPost, "/api/v1/batches", `{"mode":"atomic","items":[`+
+		`{"wallet_id":"`+wallet.ID+`","type":"CREDIT","amount":1},`+
+		`{"wallet_id":"`+wallet.ID+`","type":"CREDIT","amount":1},`+
+		`{"wallet_id":"`+wallet.ID+`","type":"CREDIT","amount":1}]}`, http.StatusAccepted)
+	do(http.MethodPost, "/api/v1/batches", `{"mode":"atomic","items":[]}`, http.StatusBadRequest)
+	do(http.MethodGet, "/api/v1/batches/00000000-0000-0000-0000-000000000000", "", http.StatusNotFound)
+
+	rec = do(http.MethodPost, "/api/v1/wallets", `{"user_id":"spec-payee","currency":"EUR"}`, http.StatusCreated)
+	var payee struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payee))
+	rec = do(http.MethodPost, "/api/v1/schedules", `{"from_wallet_id":"`+wallet.ID+`","to_wallet_id":"`+payee.ID+
+		`","amount":5,"rule":"FREQ=MONTHLY;BYMONTHDAY=1","end_at":"2999-01-01T00:00:00Z"}`, http.StatusCreated)
+	var schedule struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &schedule))
+	scheduleBase := "/api/v1/schedules/" + schedule.ID
+	do(http.MethodPost, "/api/v1/schedules", `{"from_wallet_id":"`+wallet.ID+`","to_wallet_id":"`+wallet.ID+`","amount":5}`, http.StatusBadRequest)
+	do(http.MethodGet, scheduleBase, "", http.StatusOK)
+	do(http.MethodPost, scheduleBase+"/pause", "", http.StatusOK)
+	do(http.MethodPost, scheduleBase+"/pause", "", http.StatusConflict)
+	do(http.MethodPost, scheduleBase+"/resume", "", http.StatusOK)
+	do(http.MethodGet, scheduleBase+"/executions?page=1&limit=5", "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/wallets/"+payee.ID+"/schedules", "", http.StatusOK)
+	do(http.MethodPost, scheduleBase+"/cancel", "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/schedules/00000000-0000-0000-0000-000000000000", "", http.StatusNotFound)
+	interestBase := "/api/v1/wallets/" + payee.ID + "/interest"
+	do(http.MethodGet, interestBase, "", http.StatusNotFound)
+	do(http.MethodPut, interestBase, "", http.StatusOK)
+	do(http.MethodPut, interestBase, "", http.StatusOK)
+	do(http.MethodGet, interestBase, "", http.StatusOK)
+	do(http.MethodGet, interestBase+"/accruals?page=1&limit=5", "", http.StatusOK)
+	rec = do(http.MethodPost, "/api/v1/escrows", `{"buyer_wallet_id":"`+wallet.ID+`","seller_wallet_id":"`+payee.ID+
+		`","amount":5,"description":"order 1","release_at":"2999-01-01T00:00:00Z"}`, http.StatusCreated)
+	var escrow struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &escrow))
+	escrowBase := "/api/v1/escrows/" + escrow.ID
+	do(http.MethodPost, "/api/v1/escrows", `{"buyer_wallet_id":"`+wallet.ID+`","seller_wallet_id":"`+wallet.ID+`","amount":5}`, http.StatusBadRequest)
+	do(http.MethodPost, "/api/v1/escrows", `{"buyer_wallet_id":"`+wallet.ID+`","seller_wallet_id":"`+payee.ID+`","amount":1000}`, http.StatusUnprocessableEntity)
+	do(http.MethodGet, escrowBase, "", http.StatusOK)
+	do(http.MethodPost, escrowBase+"/split", `{"seller_amount":5}`, http.StatusBadRequest)
+	do(http.MethodPost, escrowBase+"/split", `{"seller_amount":2}`, http.StatusOK)
+	do(http.MethodPost, escrowBase+"/release", "", http.StatusConflict)
+	do(http.MethodPost, escrowBase+"/refund", "", http.StatusConflict)
+	do(http.MethodGet, "/api/v1/wallets/"+payee.ID+"/escrows", "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/escrows/00000000-0000-0000-0000-000000000000", "", http.StatusNotFound)
+	rec = do(http.MethodPost, "/api/v1/payment-requests", `{"requester_user_id":"spec-payee","payer_user_id":"spec-user",`+
+		`"amount":2,"description":"lunch"}`, http.StatusCreated)
+	var paymentRequest struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &paymentRequest))
+	requestBase := "/api/v1/payment-requests/" + paymentRequest.ID
+	do(http.MethodPost, "/api/v1/payment-requests", `{"requester_user_id":"spec-payee","payer_user_id":"nobody","amount":2}`, http.StatusBadRequest)
+	do(http.MethodGet, requestBase, "", http.StatusOK)
+	do(http.MethodPost, requestBase+"/pay", "", http.StatusOK)
+	do(http.MethodPost, requestBase+"/decline", "", http.StatusConflict)
+	rec = do(http.MethodPost, "/api/v1/payment-requests", `{"requester_user_id":"spec-payee","payer_user_id":"spec-user","amount":1}`, http.StatusCreated)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &paymentRequest))
+	do(http.MethodPost, "/api/v1/payment-requests/"+paymentRequest.ID+"/cancel", "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/users/spec-user/payment-requests/inbox?status=paid&page=1&limit=5", "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/users/spec-payee/payment-requests/outbox", "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/users/spec-payee/payment-requests/outbox?status=lost", "", http.StatusBadRequest)
+	do(http.MethodGet, "/api/v1/payment-requests/00000000-0000-0000-0000-000000000000", "", http.StatusNotFound)
+	rec = do(http.MethodPost, "/api/v1/bills", `{"payer_wallet_id":"`+payee.ID+`","total":0.03,"description":"coffee","split":"equal",`+
+		`"participants":[{"wallet_id":"`+wallet.ID+`"},{"wallet_id":"`+payee.ID+`"}]}`, http.StatusCreated)
+	var bill struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bill))
+	do(http.MethodGet, "/api/v1/bills/"+bill.ID, "", http.StatusOK)
+	do(http.MethodPost, "/api/v1/bills/"+bill.ID+"/settle", "", http.StatusConflict)
+	rec = do(http.MethodPost, "/api/v1/bills", `{"payer_wallet_id":"`+payee.ID+`","total":10000,"split":"percentage",`+
+		`"participants":[{"wallet_id":"`+wallet.ID+`","percentage":60},{"wallet_id":"`+payee.ID+`","percentage":40}]}`, http.StatusCreated)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &bill))
+	do(http.MethodPost, "/api/v1/bills/"+bill.ID+"/settle", "", http.StatusOK)
+	do(http.MethodPost, "/api/v1/bills", `{"payer_wallet_id":"`+payee.ID+`","total":3,"split":"exact",`+
+		`"participants":[{"wallet_id":"`+wallet.ID+`","amount":1}]}`, http.StatusBadRequest)
+	do(http.MethodGet, "/api/v1/wallets/"+wallet.ID+"/bills", "", http.StatusOK)
+	do(http.MethodGet, "/api/v1/bills/00000000-0000-0000-0000-000000000000", "", http.StatusNotFound)
+	do(http.MethodGet, "/livez", "", http.StatusOK)
+	do(http.MethodGet, "/readyz", "", http.StatusOK)
+	do(http.MethodGet, "/health", "", http.StatusOK)
+	do(http.MethodGet, "/openapi.json", "", http.StatusOK)
+
+	// Legacy error responses match the spec too
+	accept = "application/json"
+	rec = do(http.MethodPost, base+"/debit", `{"amount":400}`, http.StatusUnprocessableEntity)
+	assert.JSONEq(t, `{"error":"insufficient_funds","message":"insufficient balance"}`, rec.Body.String())
+	do(http.MethodPost, base+"/debit", `{}`, http.StatusBadRequest)
+	accept = ""
+
+	// The wallet is party to an escrow
+	do(http.MethodDelete, base, "", http.StatusConflict)
+	rec = do(http.MethodPost, "/api/v1/wallets", `{"user_id":"spec-gone"}`, http.StatusCreated)
+	var gone struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &gone))
+	do(http.MethodDelete, "/api/v1/wallets/"+gone.ID, "", http.StatusNoContent)
+	do(http.MethodDelete, "/api/v1/wallets/"+gone.ID, "", http.StatusNotFound)
+}
+
+func TestRecoveredPanicsAreProblems(t *testing.T) {
+	a := newTestApp(t, "memory")
+	a.Router.GET("/panic", func(*gin.Context) { panic("boom") })
+
+	rec := httptest.NewRecorder()
+	a.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+	assert.NotContains(t, rec.Body.String(), "boom")
+}
+
+func TestBatchesAndSchedulesNeedADatabase(t *testing.T) {
+	memory := newTestApp(t, "memory")
+	assert.Nil(t, memory.Batches)
+	assert.Nil(t, memory.Schedules)
+	assert.Nil(t, memory.Interest)
+	assert.Nil(t, memory.Escrows)
+	assert.Nil(t, memory.Requests)
+	assert.Nil(t, memory.Bills)
+
+	a := newTestApp(t, "sqlite")
+	require.NotNil(t, a.Batches)
+	require.NotNil(t, a.Schedules)
+	require.NotNil(t, a.Escrows)
+	for _, path := range []string{"/api/v1/batches/", "/api/v1/schedules/", "/api/v1/escrows/", "/api/v1/payment-requests/", "/api/v1/bills/"} {
+		rec := httptest.NewRecorder()
+		a.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"00000000-0000-0000-0000-000000000000", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "_not_found")
+	}
+}
+
+func TestNewWithGRPC(t *testing.T) {
+	assert.Nil(t, newTestApp(t, "memory").GRPC, "gRPC is opt-in")
+
+	cfg := config.Defaults()
+	cfg.Server.Mode = "test"
+	cfg.Database.Driver = "memory"
+	cfg.GRPC.Enabled = true
+	a, err := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { a.Close(context.Background()) })
+
+	require.NotNil(t, a.GRPC)
+	services := a.GRPC.GetServiceInfo()
+	assert.Contains(t, services, "wallet.v1.WalletService")
+	assert.Contains(t, services, "grpc.health.v1.Health")
+	assert.Contains(t, services, "grpc.reflection.v1.ServerReflection")
+}
+
+func TestCloseRunsInReverseOrder(t *testing.T) {
+	a := newTestApp(t, "memory")
+	var order []string
+	a.onClose("first", func(context.Context) error { order = append(order, "first"); return nil })
+	a.onClose("second", func(context.Context) error { order = append(order, "second"); return nil })
+
+	a.Close(context.Background())
+	assert.Equal(t, []string{"second", "first"}, order)
+
+	// A second Close is a no-op
+	a.Close(context.Background())
+	assert.Len(t, order, 2)
+}
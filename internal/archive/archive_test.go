@@ -0,0 +1,211 @@
+//go:build unit
+// +build unit
+
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func descriptions(txs []models.Transaction) []string {
+	out := make([]string, len(txs))
+	for i, tx := range txs {
+		out[i] = tx.Description
+	}
+	return out
+}
+
+// seed creates a wallet with one transaction at each of the given times,
+// described by its position
+func seed(t *testing.T, repo repositories.WalletRepository, times ...time.Time) *models.Wallet {
+	t.Helper()
+	ctx := context.Background()
+	wallet := &models.Wallet{UserID: "archive-" + time.Now().Format(time.RFC3339Nano), Currency: "USD"}
+	require.NoError(t, repo.CreateWallet(ctx, wallet))
+	for i, at := range times {
+		require.NoError(t, repo.CreateTransaction(ctx, &models.Transaction{
+			WalletID:    wallet.ID,
+			Type:        models.Credit,
+			Amount:      float64(i + 1),
+			Description: fmt.Sprintf("tx%d", i),
+			Reference:   "ref, with \"quotes\"\nand a newline",
+			CreatedAt:   at,
+		}))
+	}
+	return wallet
+}
+
+func newArchiver(t *testing.T, db *database.DB, now time.Time) (*Archiver, *Store) {
+	store := NewStore(filepath.Join(t.TempDir(), "archive"))
+	cfg := config.Defaults().Transactions
+	cfg.Retention = 90 * 24 * time.Hour
+	a := NewArchiver(db.Gorm(), store, cfg)
+	a.now = func() time.Time { return now }
+	return a, store
+}
+
+func TestArchiveMovesExpiredMonthsToFiles(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewWalletRepository(db.Gorm())
+	ctx := context.Background()
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	wallet := seed(t, repo,
+		time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 20, 8, 0, 0, 0, time.UTC),
+		// Local offsets are compared in UTC: this is still January
+		time.Date(2026, 2, 1, 0, 30, 0, 0, time.FixedZone("CET", 3600)),
+		time.Date(2026, 2, 14, 8, 0, 0, 0, time.UTC),
+		// March ends after the cutoff of 1 March and stays
+		time.Date(2026, 3, 3, 8, 0, 0, 0, time.UTC),
+		time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC),
+	)
+
+	require.NoError(t, db.Gorm().Model(&models.Transaction{}).
+		Where("description = ?", "tx2").Update("promo_amount", 1.5).Error)
+
+	a, store := newArchiver(t, db, now)
+	archived, err := a.Archive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}, archived)
+
+	live, err := repo.GetTransactionsByWalletID(ctx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tx5", "tx4"}, descriptions(live))
+
+	months, err := store.Months()
+	require.NoError(t, err)
+	assert.Len(t, months, 2)
+	january, err := store.read(months[1], wallet.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tx2", "tx1", "tx0"}, descriptions(january))
+	assert.Equal(t, 3.0, january[0].Amount)
+	assert.Equal(t, 1.5, january[0].PromoAmount)
+	assert.Zero(t, january[1].PromoAmount)
+	assert.Equal(t, "ref, with \"quotes\"\nand a newline", january[0].Reference)
+	assert.True(t, january[0].CreatedAt.Equal(time.Date(2026, 1, 31, 23, 30, 0, 0, time.UTC)))
+
+	// Nothing left to archive
+	archived, err = a.Archive(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, archived)
+
+	// SQLite has no partitions to maintain
+	require.NoError(t, a.Maintain(ctx))
+}
+
+func TestStoreReadsArchivesWithoutPromoAmount(t *testing.T) {
+	store := NewStore(t.TempDir())
+	month := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	wallet := uuid.New()
+
+	// An archive written before the promo_amount column was added
+	f, err := os.Create(store.path(month))
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	w := csv.NewWriter(gz)
+	require.NoError(t, w.WriteAll([][]string{
+		{"id", "wallet_id", "type", "amount", "description", "reference", "created_at"},
+		{uuid.NewString(), wallet.String(), "CREDIT", "5.00", "old", "", "2025-11-02T08:00:00Z"},
+	}))
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	txs, err := store.read(month, wallet)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "old", txs[0].Description)
+	assert.Equal(t, 5.0, txs[0].Amount)
+	assert.Zero(t, txs[0].PromoAmount)
+}
+
+func TestArchiveFailureKeepsRows(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewWalletRepository(db.Gorm())
+	ctx := context.Background()
+	wallet := seed(t, repo, time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC))
+
+	a, store := newArchiver(t, db, time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC))
+	// A file where the archive directory should be makes writing fail
+	require.NoError(t, os.WriteFile(store.dir, nil, 0o644))
+
+	_, err := a.Archive(ctx)
+	assert.Error(t, err)
+	count, err := repo.CountTransactionsByWalletID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestHistoryContinuesIntoArchive(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewWalletRepository(db.Gorm())
+	ctx := context.Background()
+
+	wallet := seed(t, repo,
+		time.Date(2025, 11, 5, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, 12, 5, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, 12, 6, 8, 0, 0, 0, time.UTC),
+		time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC),
+		time.Date(2026, 6, 2, 8, 0, 0, 0, time.UTC),
+	)
+	a, store := newArchiver(t, db, time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC))
+	_, err := a.Archive(ctx)
+	require.NoError(t, err)
+
+	history := WithHistory(repo, store, true)
+	var pages [][]string
+	for offset := 0; offset < 6; offset += 2 {
+		page, err := history.GetTransactionsByWalletID(ctx, wallet.ID, 2, offset)
+		require.NoError(t, err)
+		pages = append(pages, descriptions(page))
+	}
+	assert.Equal(t, [][]string{{"tx4", "tx3"}, {"tx2", "tx1"}, {"tx0"}}, pages)
+
+	// A page that starts past the live rows needs their count
+	page, err := history.GetTransactionsByWalletID(ctx, wallet.ID, 10, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tx1", "tx0"}, descriptions(page))
+
+	// Requests can opt out, or in when the default is off
+	page, err = history.GetTransactionsByWalletID(IncludeArchived(ctx, false), wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tx4", "tx3"}, descriptions(page))
+
+	optIn := WithHistory(repo, store, false)
+	page, err = optIn.GetTransactionsByWalletID(ctx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, page, 2)
+	page, err = optIn.GetTransactionsByWalletID(IncludeArchived(ctx, true), wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, page, 5)
+}
+
+func TestStoreHistoryWithoutArchive(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "missing"))
+	months, err := store.Months()
+	require.NoError(t, err)
+	assert.Empty(t, months)
+
+	page, err := store.History(context.Background(), [16]byte{}, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// archiveLockKey identifies the Postgres advisory lock held while
+// archiving, so that only one instance exports and drops a month
+const archiveLockKey int64 = 7_310_021_554_002
+
+// Archiver maintains the transactions table: it creates partitions for the
+// coming months and moves months past retention into the Store.
+type Archiver struct {
+	db        *gorm.DB
+	store     *Store // nil when archiving is disabled
+	ahead     int
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewArchiver returns an Archiver for db. With a nil store it only
+// maintains partitions.
+func NewArchiver(db *gorm.DB, store *Store, cfg config.TransactionsConfig) *Archiver {
+	return &Archiver{
+		db:        db,
+		store:     store,
+		ahead:     cfg.PartitionsAhead,
+		retention: cfg.Retention,
+		now:       time.Now,
+	}
+}
+
+// Maintain creates missing partitions and then archives expired months. It
+// is meant to run periodically.
+func (a *Archiver) Maintain(ctx context.Context) error {
+	created, err := database.EnsurePartitions(ctx, a.db, a.now(), a.ahead)
+	for _, p := range created {
+		slog.InfoContext(ctx, "Created transactions partition", "partition", p.Name)
+	}
+	if err != nil || a.store == nil {
+		return err
+	}
+
+	archived, err := a.Archive(ctx)
+	for _, month := range archived {
+		slog.InfoContext(ctx, "Archived transactions", "month", month.Format("2006-01"))
+	}
+	return err
+}
+
+// Archive moves every month that ended before the retention period into the
+// store, oldest first, and returns the months it archived. A month's file is
+// written before its rows are removed, in the same database transaction, so
+// a failure at any point leaves the rows in the database and the month is
+// retried on the next run.
+func (a *Archiver) Archive(ctx context.Context) ([]time.Time, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("archiving is disabled")
+	}
+	cutoff := database.MonthStart(a.now().Add(-a.retention))
+
+	var done []time.Time
+	err := a.withLock(ctx, func(conn *gorm.DB) error {
+		months, err := a.expiredMonths(ctx, conn, cutoff)
+		if err != nil {
+			return err
+		}
+		for _, month := range months {
+			if err := a.archiveMonth(conn, month); err != nil {
+				return fmt.Errorf("archive %s: %w", month.Format("2006-01"), err)
+			}
+			done = append(done, month)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// withLock runs fn on a single connection holding the archive lock. If
+// another instance holds it, fn is skipped.
+func (a *Archiver) withLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	ran, err := database.WithTryLock(ctx, a.db, archiveLockKey, fn)
+	if err == nil && !ran {
+		slog.InfoContext(ctx, "Archiving is already running elsewhere; skipping")
+	}
+	return err
+}
+
+// expiredMonths returns the months that ended on or before cutoff and still
+// have rows in the database, oldest first. On Postgres these are the
+// attached partitions; on SQLite the months found in the table.
+func (a *Archiver) expiredMonths(ctx context.Context, conn *gorm.DB, cutoff time.Time) ([]time.Time, error) {
+	var months []time.Time
+	if database.Partitionable(conn) {
+		partitions, err := database.Partitions(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range partitions {
+			if !p.To.After(cutoff) {
+				months = append(months, p.From)
+			}
+		}
+		return months, nil
+	}
+
+	// SQLite stores timestamps as text with an offset; the date functions
+	// normalise them to UTC before comparing
+	var found []string
+	err := conn.Raw(`SELECT DISTINCT strftime('%Y%m', created_at) FROM transactions
+		WHERE julianday(created_at) < julianday(?) ORDER BY 1`, cutoff).Scan(&found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range found {
+		month, err := time.Parse("200601", f)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected month %q: %w", f, err)
+		}
+		months = append(months, month)
+	}
+	return months, nil
+}
+
+// archiveMonth writes one month to the store and removes it from the
+// database
+func (a *Archiver) archiveMonth(conn *gorm.DB, month time.Time) error {
+	p := database.PartitionFor(month)
+	return conn.Transaction(func(tx *gorm.DB) error {
+		// On Postgres read the partition itself, so that stray rows for the
+		// month in the default partition stay live rather than being archived
+		// and kept
+		query := tx.Model(&models.Transaction{})
+		if database.Partitionable(tx) {
+			query = query.Table(p.Name)
+		} else {
+			query = query.Where("julianday(created_at) >= julianday(?) AND julianday(created_at) < julianday(?)", p.From, p.To)
+		}
+		rows, err := query.Order("created_at, id").Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		err = a.store.write(month, func(emit func(models.Transaction) error) error {
+			for rows.Next() {
+				var t models.Transaction
+				if err := tx.ScanRows(rows, &t); err != nil {
+					return err
+				}
+				if err := emit(t); err != nil {
+					return err
+				}
+			}
+			return rows.Err()
+		})
+		if err != nil {
+			return err
+		}
+		rows.Close()
+
+		if database.Partitionable(tx) {
+			return database.DropPartition(tx, p)
+		}
+		return tx.Where("julianday(created_at) >= julianday(?) AND julianday(created_at) < julianday(?)", p.From, p.To).
+			Delete(&models.Transaction{}).Error
+	})
+}
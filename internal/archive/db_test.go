@@ -0,0 +1,27 @@
+//go:build unit
+// +build unit
+
+package archive
+
+import (
+	"context"
+	"testing"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB returns a private, migrated in-memory SQLite database
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	cfg := config.Defaults().Database
+	cfg.Driver = "sqlite"
+	cfg.Path = ":memory:"
+	db, err := database.Open(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Migrate(context.Background()))
+	return db
+}
@@ -0,0 +1,78 @@
+package archive
+
+import (
+	"context"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("wallet-microservice/internal/archive")
+
+type includeKey struct{}
+
+// IncludeArchived overrides, for reads made with the returned context,
+// whether transaction history continues into archived months
+func IncludeArchived(ctx context.Context, include bool) context.Context {
+	return context.WithValue(ctx, includeKey{}, include)
+}
+
+// historyRepository serves transaction history from the database and, once
+// that runs out, from the archive. Archived months are all older than any
+// month still in the database, so the two concatenate in order.
+type historyRepository struct {
+	repositories.WalletRepository
+	store   *Store
+	include bool
+}
+
+// WithHistory returns repo with transaction history that continues into the
+// months archived in store. includeByDefault applies unless a request
+// overrides it with IncludeArchived.
+func WithHistory(repo repositories.WalletRepository, store *Store, includeByDefault bool) repositories.WalletRepository {
+	return &historyRepository{WalletRepository: repo, store: store, include: includeByDefault}
+}
+
+func (r *historyRepository) includeArchived(ctx context.Context) bool {
+	if include, ok := ctx.Value(includeKey{}).(bool); ok {
+		return include
+	}
+	return r.include
+}
+
+func (r *historyRepository) GetTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]models.Transaction, error) {
+	live, err := r.WalletRepository.GetTransactionsByWalletID(ctx, walletID, limit, offset)
+	if err != nil || !r.includeArchived(ctx) || (limit >= 0 && len(live) >= limit) {
+		return live, err
+	}
+
+	// The page reaches past the live rows. Rows before it that are not live
+	// are archived rows to skip; an empty live page needs the live count to
+	// know how many.
+	liveCount := int64(offset + len(live))
+	if len(live) == 0 && offset > 0 {
+		if liveCount, err = r.WalletRepository.CountTransactionsByWalletID(ctx, walletID); err != nil {
+			return nil, err
+		}
+	}
+	archivedLimit := -1
+	if limit >= 0 {
+		archivedLimit = limit - len(live)
+	}
+	archived, err := r.history(ctx, walletID, archivedLimit, offset+len(live)-int(liveCount))
+	if err != nil {
+		return nil, err
+	}
+	return append(live, archived...), nil
+}
+
+func (r *historyRepository) history(ctx context.Context, walletID uuid.UUID, limit, offset int) (_ []models.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "archive.History")
+	defer tracing.End(span, &err)
+
+	return r.store.History(ctx, walletID, limit, offset)
+}
@@ -0,0 +1,238 @@
+// Package archive moves old months of the transactions table out of the
+// database into compressed files on local disk and reads them back.
+//
+// Each archived month is one gzip'd CSV file, transactions_pYYYYMM.csv.gz,
+// named after the partition it came from. Files are immutable once written:
+// a month is only archived after it has ended and its retention has passed.
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"wallet-microservice/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	fileNameRe = regexp.MustCompile(`^transactions_p(\d{6})\.csv\.gz$`)
+	// Archives written before promo_amount was added lack the last column
+	header = []string{"id", "wallet_id", "type", "amount", "description", "reference", "created_at", "promo_amount"}
+)
+
+// Store keeps archived months as files in a directory
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (s *Store) path(month time.Time) string {
+	return filepath.Join(s.dir, "transactions_p"+month.UTC().Format("200601")+".csv.gz")
+}
+
+// Months lists the archived months, newest first
+func (s *Store) Months() ([]time.Time, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var months []time.Time
+	for _, e := range entries {
+		m := fileNameRe.FindStringSubmatch(e.Name())
+		if m == nil || e.IsDir() {
+			continue
+		}
+		month, err := time.Parse("200601", m[1])
+		if err != nil {
+			continue
+		}
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].After(months[j]) })
+	return months, nil
+}
+
+// write stores a month's transactions, replacing any earlier file for it.
+// fn streams the rows, oldest first; the file only becomes visible once fn
+// has succeeded and the data is on disk.
+func (s *Store) write(month time.Time, fn func(emit func(models.Transaction) error) error) (err error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.dir, ".transactions-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(f)
+	w := csv.NewWriter(gz)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	err = fn(func(t models.Transaction) error {
+		return w.Write([]string{
+			t.ID.String(),
+			t.WalletID.String(),
+			string(t.Type),
+			strconv.FormatFloat(t.Amount, 'f', 2, 64),
+			t.Description,
+			t.Reference,
+			t.CreatedAt.UTC().Format(time.RFC3339Nano),
+			strconv.FormatFloat(t.PromoAmount, 'f', 2, 64),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(month))
+}
+
+// read returns every transaction of wallet walletID archived for month,
+// newest first
+func (s *Store) read(month time.Time, walletID uuid.UUID) ([]models.Transaction, error) {
+	f, err := os.Open(s.path(month))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	defer gz.Close()
+
+	// Every record has as many fields as the file's header
+	r := csv.NewReader(gz)
+	r.FieldsPerRecord = 0
+	r.ReuseRecord = true
+	if _, err := r.Read(); err != nil {
+		return nil, fmt.Errorf("%s: read header: %w", f.Name(), err)
+	}
+
+	want := walletID.String()
+	var out []models.Transaction
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		if rec[1] != want {
+			continue
+		}
+		t, err := parseRecord(rec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name(), err)
+		}
+		out = append(out, t)
+	}
+
+	// Files are written oldest first
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
+
+func parseRecord(rec []string) (models.Transaction, error) {
+	var t models.Transaction
+	var err error
+	if t.ID, err = uuid.Parse(rec[0]); err != nil {
+		return t, err
+	}
+	if t.WalletID, err = uuid.Parse(rec[1]); err != nil {
+		return t, err
+	}
+	t.Type = models.TransactionType(rec[2])
+	if t.Amount, err = strconv.ParseFloat(rec[3], 64); err != nil {
+		return t, err
+	}
+	t.Description = rec[4]
+	t.Reference = rec[5]
+	if t.CreatedAt, err = time.Parse(time.RFC3339Nano, rec[6]); err != nil {
+		return t, err
+	}
+	if len(rec) > 7 {
+		if t.PromoAmount, err = strconv.ParseFloat(rec[7], 64); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+// History returns a page of a wallet's archived transactions, newest first.
+// Archived months have no index: every file newer than the page is read in
+// full, so deep pages into old history are slow.
+func (s *Store) History(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]models.Transaction, error) {
+	months, err := s.Months()
+	if err != nil {
+		return nil, err
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+	out := []models.Transaction{}
+	for _, month := range months {
+		if limit >= 0 && len(out) >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		rows, err := s.read(month, walletID)
+		if err != nil {
+			return nil, err
+		}
+		if offset >= len(rows) {
+			offset -= len(rows)
+			continue
+		}
+		rows = rows[offset:]
+		offset = 0
+		if limit >= 0 && len(rows) > limit-len(out) {
+			rows = rows[:limit-len(out)]
+		}
+		out = append(out, rows...)
+	}
+	return out, nil
+}
@@ -0,0 +1,430 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config is the complete service configuration. Every leaf field carries a
+// `key` (dotted path used in config files and as the flag name) and an
+// `env` tag; fields tagged `secret` are redacted when the config is dumped.
+type Config struct {
+	Server   ServerConfig   `key:"server"`
+	GRPC     GRPCConfig     `key:"grpc"`
+	Database DatabaseConfig `key:"database"`
+	Log      LogConfig      `key:"log"`
+	Tracing  TracingConfig  `key:"tracing"`
+	Timeouts TimeoutsConfig `key:"timeouts"`
+	// Transactions configures partition maintenance and archival of the
+	// transactions table
+	Transactions TransactionsConfig `key:"transactions"`
+	Idempotency  IdempotencyConfig  `key:"idempotency"`
+	Batches      BatchesConfig      `key:"batches"`
+	Scheduler    SchedulerConfig    `key:"scheduler"`
+	Interest     InterestConfig     `key:"interest"`
+	Promo        PromoConfig        `key:"promo"`
+	Overdraft    OverdraftConfig    `key:"overdraft"`
+	Escrow       EscrowConfig       `key:"escrow"`
+	Requests     RequestsConfig     `key:"payment_requests"`
+	Features     map[string]bool    `key:"features" env:"FEATURES"`
+
+	// Args holds the positional arguments left after flag parsing, e.g. a
+	// subcommand such as "migrate up"
+	Args []string
+}
+
+type ServerConfig struct {
+	Port               string        `key:"port" env:"PORT"`
+	Mode               string        `key:"mode" env:"GIN_MODE"`
+	ReadHeaderTimeout  time.Duration `key:"read_header_timeout" env:"READ_HEADER_TIMEOUT"`
+	ShutdownDrainDelay time.Duration `key:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	ShutdownTimeout    time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// OpenAPIValidation rejects requests that do not match the OpenAPI
+	// spec. In test mode responses are checked as well.
+	OpenAPIValidation bool `key:"openapi_validation" env:"OPENAPI_VALIDATION"`
+}
+
+// GRPCConfig configures the gRPC API. When enabled it is served on its own
+// port next to the REST endpoints.
+type GRPCConfig struct {
+	Enabled bool   `key:"enabled" env:"GRPC_ENABLED"`
+	Port    string `key:"port" env:"GRPC_PORT"`
+	// Reflection lets tools such as grpcurl discover the API
+	Reflection bool `key:"reflection" env:"GRPC_REFLECTION"`
+}
+
+type DatabaseConfig struct {
+	// Driver selects the backend: postgres; sqlite for offline local
+	// development and tests; or memory, which needs no database at all
+	Driver             string        `key:"driver" env:"DB_DRIVER"`
+	Path               string        `key:"path" env:"DB_PATH"`
+	Host               string        `key:"host" env:"DB_HOST"`
+	Port               int           `key:"port" env:"DB_PORT"`
+	User               string        `key:"user" env:"DB_USER"`
+	Password           string        `key:"password" env:"DB_PASSWORD" secret:"true"`
+	Name               string        `key:"name" env:"DB_NAME"`
+	SSLMode            string        `key:"sslmode" env:"DB_SSLMODE"`
+	MaxOpenConns       int           `key:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns       int           `key:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime    time.Duration `key:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime    time.Duration `key:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	SlowQueryThreshold time.Duration `key:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD"`
+	LogParams          bool          `key:"log_params" env:"LOG_SQL_PARAMS"`
+	// MigrateOnStart applies pending migrations when the server starts.
+	// Production deployments usually disable it and run "migrate up" as a
+	// separate release step.
+	MigrateOnStart bool `key:"migrate_on_start" env:"DB_MIGRATE_ON_START"`
+	// Replicas serve read-only queries such as transaction history. Each is
+	// host[:port] sharing the primary's credentials, or a path for sqlite.
+	Replicas             []string      `key:"replicas" env:"DB_REPLICAS"`
+	ReplicaMaxLag        time.Duration `key:"replica_max_lag" env:"DB_REPLICA_MAX_LAG"`
+	ReplicaCheckInterval time.Duration `key:"replica_check_interval" env:"DB_REPLICA_CHECK_INTERVAL"`
+}
+
+type LogConfig struct {
+	Level        string   `key:"level" env:"LOG_LEVEL"`
+	RedactFields []string `key:"redact_fields" env:"LOG_REDACT_FIELDS"`
+	RedactMode   string   `key:"redact_mode" env:"LOG_REDACT_MODE"`
+}
+
+type TracingConfig struct {
+	ServiceName string  `key:"service_name" env:"OTEL_SERVICE_NAME"`
+	Exporter    string  `key:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	File        string  `key:"file" env:"OTEL_TRACES_FILE"`
+	SampleRatio float64 `key:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG"`
+}
+
+type TimeoutsConfig struct {
+	Request     time.Duration            `key:"request" env:"REQUEST_TIMEOUT"`
+	Operations  map[string]time.Duration `key:"operations" env:"OPERATION_TIMEOUTS"`
+	HealthCheck time.Duration            `key:"health_check" env:"HEALTH_CHECK_TIMEOUT"`
+}
+
+type TransactionsConfig struct {
+	// PartitionsAhead is how many months beyond the current one always have
+	// a partition (Postgres only)
+	PartitionsAhead     int           `key:"partitions_ahead" env:"TX_PARTITIONS_AHEAD"`
+	MaintenanceInterval time.Duration `key:"maintenance_interval" env:"TX_MAINTENANCE_INTERVAL"`
+	// ArchiveEnabled moves months older than Retention out of the database
+	// into gzip'd CSV files under ArchiveDir
+	ArchiveEnabled bool          `key:"archive_enabled" env:"TX_ARCHIVE_ENABLED"`
+	ArchiveDir     string        `key:"archive_dir" env:"TX_ARCHIVE_DIR"`
+	Retention      time.Duration `key:"retention" env:"TX_RETENTION"`
+	// IncludeArchived makes history pages continue into archived months when
+	// a request does not say otherwise
+	IncludeArchived bool `key:"include_archived" env:"TX_INCLUDE_ARCHIVED"`
+}
+
+// IdempotencyConfig configures how long writes made with an
+// Idempotency-Key header are remembered
+type IdempotencyConfig struct {
+	// KeyTTL is how long a key answers retries with its first response
+	KeyTTL time.Duration `key:"key_ttl" env:"IDEMPOTENCY_KEY_TTL"`
+	// PurgeInterval is how often keys older than KeyTTL are forgotten
+	PurgeInterval time.Duration `key:"purge_interval" env:"IDEMPOTENCY_PURGE_INTERVAL"`
+	// Lease is how long a request holds its key before storing a response;
+	// after that a retry of it may take the key over
+	Lease time.Duration `key:"lease" env:"IDEMPOTENCY_LEASE"`
+}
+
+// BatchesConfig configures the batch credit/debit API
+type BatchesConfig struct {
+	MaxItems int `key:"max_items" env:"BATCH_MAX_ITEMS"`
+	// Batches with more items than AsyncThreshold are accepted right away
+	// and processed in the background; 0 processes every batch that way
+	AsyncThreshold int           `key:"async_threshold" env:"BATCH_ASYNC_THRESHOLD"`
+	PollInterval   time.Duration `key:"poll_interval" env:"BATCH_POLL_INTERVAL"`
+	// ProcessingTimeout is how long a batch may stay processing before
+	// another instance takes it over
+	ProcessingTimeout time.Duration `key:"processing_timeout" env:"BATCH_PROCESSING_TIMEOUT"`
+}
+
+// SchedulerConfig configures the runner of scheduled payments
+type SchedulerConfig struct {
+	PollInterval time.Duration `key:"poll_interval" env:"SCHEDULER_POLL_INTERVAL"`
+	// MaxRetries bounds how often an occurrence that failed on insufficient
+	// funds is retried, for schedules that retry, before it is skipped
+	MaxRetries    int           `key:"max_retries" env:"SCHEDULER_MAX_RETRIES"`
+	RetryInterval time.Duration `key:"retry_interval" env:"SCHEDULER_RETRY_INTERVAL"`
+	// BatchSize is how many due schedules are loaded at a time
+	BatchSize int `key:"batch_size" env:"SCHEDULER_BATCH_SIZE"`
+}
+
+// InterestConfig configures interest on enrolled wallets
+type InterestConfig struct {
+	// Rates maps the date, YYYY-MM-DD, from which an annual rate applies to
+	// the rate, e.g. 0.025 for 2.5%. Before the first date no interest
+	// accrues.
+	Rates    map[string]float64 `key:"rates" env:"INTEREST_RATES"`
+	DayCount string             `key:"day_count" env:"INTEREST_DAY_COUNT"`
+	// PayoutFrequency is daily, weekly (after Sundays) or monthly (after
+	// the last day of the month)
+	PayoutFrequency string        `key:"payout_frequency" env:"INTEREST_PAYOUT_FREQUENCY"`
+	RunInterval     time.Duration `key:"run_interval" env:"INTEREST_RUN_INTERVAL"`
+}
+
+// PromoConfig configures promotional credits
+type PromoConfig struct {
+	// DefaultExpiry is how long a promo credit lasts when the request does
+	// not say; 0 keeps it until it is spent
+	DefaultExpiry time.Duration `key:"default_expiry" env:"PROMO_DEFAULT_EXPIRY"`
+	// ExpiryInterval is how often lapsed promo credits are debited
+	ExpiryInterval time.Duration `key:"expiry_interval" env:"PROMO_EXPIRY_INTERVAL"`
+}
+
+// OverdraftConfig configures what overdrawn wallets are charged. Each day
+// a wallet ends overdrawn it is charged interest on the overdrawn amount at
+// Rate a year, ACT/365, and DailyFee.
+type OverdraftConfig struct {
+	Rate        float64       `key:"rate" env:"OVERDRAFT_RATE"`
+	DailyFee    float64       `key:"daily_fee" env:"OVERDRAFT_DAILY_FEE"`
+	RunInterval time.Duration `key:"run_interval" env:"OVERDRAFT_RUN_INTERVAL"`
+}
+
+// EscrowConfig configures escrow agreements
+type EscrowConfig struct {
+	// ReleaseInterval is how often escrows past their deadline are released
+	ReleaseInterval time.Duration `key:"release_interval" env:"ESCROW_RELEASE_INTERVAL"`
+}
+
+// RequestsConfig configures payment requests between users
+type RequestsConfig struct {
+	// DefaultExpiry is how long a request waits for the payer when it does
+	// not say
+	DefaultExpiry time.Duration `key:"default_expiry" env:"PAYMENT_REQUEST_DEFAULT_EXPIRY"`
+	// ExpiryInterval is how often lapsed requests are marked expired
+	ExpiryInterval time.Duration `key:"expiry_interval" env:"PAYMENT_REQUEST_EXPIRY_INTERVAL"`
+}
+
+// Built-in development credentials; refused in release mode
+const (
+	defaultDBUser     = "postgres"
+	defaultDBPassword = "password"
+)
+
+// Defaults returns the configuration used when nothing else is set. It is
+// suitable for local development only.
+func Defaults() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:               "8080",
+			Mode:               "debug",
+			ReadHeaderTimeout:  10 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+			ShutdownTimeout:    30 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Port:       "9090",
+			Reflection: true,
+		},
+		Database: DatabaseConfig{
+			Driver:               "postgres",
+			Path:                 "wallet.db",
+			Host:                 "localhost",
+			Port:                 5432,
+			User:                 defaultDBUser,
+			Password:             defaultDBPassword,
+			Name:                 "wallet_db",
+			SSLMode:              "disable",
+			MaxOpenConns:         25,
+			MaxIdleConns:         5,
+			ConnMaxLifetime:      30 * time.Minute,
+			ConnMaxIdleTime:      5 * time.Minute,
+			SlowQueryThreshold:   200 * time.Millisecond,
+			MigrateOnStart:       true,
+			ReplicaMaxLag:        5 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+		},
+		Log: LogConfig{
+			Level:        "info",
+			RedactFields: []string{"user_id", "reference", "description"},
+			RedactMode:   "mask",
+		},
+		Tracing: TracingConfig{
+			ServiceName: "wallet-microservice",
+			Exporter:    "none",
+			File:        "traces.json",
+			SampleRatio: 1,
+		},
+		Timeouts: TimeoutsConfig{
+			Request:     10 * time.Second,
+			Operations:  map[string]time.Duration{},
+			HealthCheck: 2 * time.Second,
+		},
+		Transactions: TransactionsConfig{
+			PartitionsAhead:     3,
+			MaintenanceInterval: 24 * time.Hour,
+			ArchiveDir:          "archive",
+			Retention:           365 * 24 * time.Hour,
+			IncludeArchived:     true,
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL:        24 * time.Hour,
+			PurgeInterval: time.Hour,
+			Lease:         time.Minute,
+		},
+		Batches: BatchesConfig{
+			MaxItems:          1000,
+			AsyncThreshold:    100,
+			PollInterval:      time.Second,
+			ProcessingTimeout: 5 * time.Minute,
+		},
+		Scheduler: SchedulerConfig{
+			PollInterval:  30 * time.Second,
+			MaxRetries:    3,
+			RetryInterval: time.Hour,
+			BatchSize:     100,
+		},
+		Interest: InterestConfig{
+			Rates:           map[string]float64{},
+			DayCount:        "ACT/365",
+			PayoutFrequency: "monthly",
+			RunInterval:     time.Hour,
+		},
+		Promo: PromoConfig{
+			DefaultExpiry:  30 * 24 * time.Hour,
+			ExpiryInterval: time.Minute,
+		},
+		Overdraft: OverdraftConfig{
+			RunInterval: time.Hour,
+		},
+		Escrow: EscrowConfig{
+			ReleaseInterval: time.Minute,
+		},
+		Requests: RequestsConfig{
+			DefaultExpiry:  7 * 24 * time.Hour,
+			ExpiryInterval: time.Minute,
+		},
+		Features: map[string]bool{},
+	}
+}
+
+// Feature reports whether the named feature toggle is on
+func (c *Config) Feature(name string) bool {
+	return c.Features[name]
+}
+
+// Validate checks the configuration for missing or inconsistent values and
+// reports every problem at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port != "", "server.port is required")
+	check(oneOf(c.Server.Mode, "debug", "release", "test"), "server.mode must be debug, release or test, got %q", c.Server.Mode)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ShutdownDrainDelay >= 0, "server.shutdown_drain_delay must not be negative")
+
+	check(!c.GRPC.Enabled || c.GRPC.Port != "", "grpc.port is required when grpc is enabled")
+	check(!c.GRPC.Enabled || c.GRPC.Port != c.Server.Port, "grpc.port must differ from server.port")
+
+	db := c.Database
+	check(oneOf(db.Driver, "postgres", "sqlite", "memory"), "database.driver must be postgres, sqlite or memory, got %q", db.Driver)
+	switch db.Driver {
+	case "sqlite":
+		check(db.Path != "", "database.path is required for the sqlite driver")
+	case "memory":
+	default:
+		check(db.Host != "", "database.host is required")
+		check(db.Port > 0 && db.Port < 65536, "database.port must be between 1 and 65535, got %d", db.Port)
+		check(db.User != "", "database.user is required")
+		check(db.Name != "", "database.name is required")
+		check(oneOf(db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+			"database.sslmode %q is not a valid libpq sslmode", db.SSLMode)
+	}
+	check(db.Driver != "memory" || len(db.Replicas) == 0, "database.replicas are not supported by the memory driver")
+	check(len(db.Replicas) == 0 || db.ReplicaCheckInterval > 0, "database.replica_check_interval must be positive")
+	check(db.ReplicaMaxLag >= 0, "database.replica_max_lag must not be negative")
+	check(db.MaxOpenConns > 0, "database.max_open_conns must be positive")
+	check(db.MaxIdleConns >= 0 && db.MaxIdleConns <= db.MaxOpenConns,
+		"database.max_idle_conns must be between 0 and database.max_open_conns")
+
+	if c.Server.Mode == "release" && db.Driver != "postgres" {
+		check(false, "database.driver %s is for development and tests only and is refused in release mode", db.Driver)
+	} else if c.Server.Mode == "release" {
+		check(db.Password != "" && db.Password != defaultDBPassword,
+			"database.password must be set to a non-default value in release mode")
+		check(!(db.User == defaultDBUser && db.Password == defaultDBUser),
+			"database credentials must not be the postgres defaults in release mode")
+		check(db.SSLMode != "disable", "database.sslmode must not be disable in release mode")
+	}
+
+	check(oneOf(strings.ToLower(c.Log.Level), "debug", "info", "warn", "warning", "error"),
+		"log.level must be debug, info, warn or error, got %q", c.Log.Level)
+	check(oneOf(c.Log.RedactMode, "mask", "hash"), "log.redact_mode must be mask or hash, got %q", c.Log.RedactMode)
+
+	check(oneOf(c.Tracing.Exporter, "none", "stdout", "file", "otlp"),
+		"tracing.exporter must be none, stdout, file or otlp, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file is required for the file exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	check(c.Timeouts.Request >= 0, "timeouts.request must not be negative")
+	for op, d := range c.Timeouts.Operations {
+		check(d >= 0, "timeouts.operations.%s must not be negative", op)
+	}
+
+	tx := c.Transactions
+	check(tx.PartitionsAhead >= 0, "transactions.partitions_ahead must not be negative")
+	check(tx.MaintenanceInterval > 0, "transactions.maintenance_interval must be positive")
+	if tx.ArchiveEnabled {
+		check(db.Driver != "memory", "transactions.archive_enabled is not supported by the memory driver")
+		check(tx.ArchiveDir != "", "transactions.archive_dir is required when archiving is enabled")
+		check(tx.Retention >= 24*time.Hour, "transactions.retention must be at least 24h")
+	}
+
+	check(c.Idempotency.KeyTTL > 0, "idempotency.key_ttl must be positive")
+	check(c.Idempotency.PurgeInterval > 0, "idempotency.purge_interval must be positive")
+	check(c.Idempotency.Lease > c.Timeouts.Request, "idempotency.lease must be longer than timeouts.request")
+
+	b := c.Batches
+	check(b.MaxItems > 0, "batches.max_items must be positive")
+	check(b.AsyncThreshold >= 0, "batches.async_threshold must not be negative")
+	check(b.PollInterval > 0, "batches.poll_interval must be positive")
+	check(b.ProcessingTimeout > 0, "batches.processing_timeout must be positive")
+
+	sc := c.Scheduler
+	check(sc.PollInterval > 0, "scheduler.poll_interval must be positive")
+	check(sc.MaxRetries >= 0, "scheduler.max_retries must not be negative")
+	check(sc.RetryInterval > 0, "scheduler.retry_interval must be positive")
+	check(sc.BatchSize > 0, "scheduler.batch_size must be positive")
+
+	in := c.Interest
+	for from, rate := range in.Rates {
+		_, err := time.Parse(time.DateOnly, from)
+		check(err == nil, "interest.rates: %q is not a date (YYYY-MM-DD)", from)
+		check(rate >= 0 && rate < 1, "interest.rates: rate %v from %s must be at least 0 and below 1", rate, from)
+	}
+	check(oneOf(in.DayCount, "ACT/365", "ACT/360"), "interest.day_count must be ACT/365 or ACT/360, got %q", in.DayCount)
+	check(oneOf(in.PayoutFrequency, "daily", "weekly", "monthly"), "interest.payout_frequency must be daily, weekly or monthly, got %q", in.PayoutFrequency)
+	check(in.RunInterval > 0, "interest.run_interval must be positive")
+
+	check(c.Promo.DefaultExpiry >= 0, "promo.default_expiry must not be negative")
+	check(c.Promo.ExpiryInterval > 0, "promo.expiry_interval must be positive")
+
+	od := c.Overdraft
+	check(od.Rate >= 0 && od.Rate < 1, "overdraft.rate must be at least 0 and below 1")
+	check(od.DailyFee >= 0, "overdraft.daily_fee must not be negative")
+	check(od.RunInterval > 0, "overdraft.run_interval must be positive")
+
+	check(c.Escrow.ReleaseInterval > 0, "escrow.release_interval must be positive")
+
+	check(c.Requests.DefaultExpiry > 0, "payment_requests.default_expiry must be positive")
+	check(c.Requests.ExpiryInterval > 0, "payment_requests.expiry_interval must be positive")
+
+	return errors.Join(errs...)
+}
+
+func oneOf(v string, allowed ...string) bool {
+	for _, a := range allowed {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
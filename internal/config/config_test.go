@@ -0,0 +1,241 @@
+//go:build unit
+// +build unit
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "8080", cfg.Server.Port)
+	assert.Equal(t, 5432, cfg.Database.Port)
+	assert.Equal(t, 10*time.Second, cfg.Timeouts.Request)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+database:
+  host: file-host
+  name: file-db
+  max_open_conns: 50
+timeouts:
+  operations:
+    DebitWallet: 2s
+features:
+  batches: true
+`)
+
+	t.Setenv("DB_HOST", "env-host")
+	cfg, err := Load([]string{
+		"-config", path,
+		"-database.host", "flag-host",
+		"-database.name", "flag-db",
+		"-database.user", "flag-user",
+	})
+	require.NoError(t, err)
+
+	// env beats file beats flags
+	assert.Equal(t, "env-host", cfg.Database.Host)
+	assert.Equal(t, "file-db", cfg.Database.Name)
+	assert.Equal(t, "flag-user", cfg.Database.User)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 2*time.Second, cfg.Timeouts.Operations["DebitWallet"])
+	assert.True(t, cfg.Feature("batches"))
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[server]
+port = "9090"
+
+[log]
+redact_fields = ["user_id"]
+`)
+	cfg, err := Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "9090", cfg.Server.Port)
+	assert.Equal(t, []string{"user_id"}, cfg.Log.RedactFields)
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := writeFile(t, "config.yaml", "database:\n  hots: typo\n")
+	_, err := Load([]string{"-config", path})
+	assert.ErrorContains(t, err, `unknown key "database.hots"`)
+}
+
+func TestLoadParsesMapsFromEnv(t *testing.T) {
+	t.Setenv("OPERATION_TIMEOUTS", "DebitWallet=2s, GetTransactionHistory=10s")
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"DebitWallet":           2 * time.Second,
+		"GetTransactionHistory": 10 * time.Second,
+	}, cfg.Timeouts.Operations)
+
+	t.Setenv("OPERATION_TIMEOUTS", "DebitWallet")
+	_, err = Load(nil)
+	assert.Error(t, err)
+}
+
+func TestValidateReleaseMode(t *testing.T) {
+	t.Setenv("GIN_MODE", "release")
+
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "database.password must be set to a non-default value")
+	assert.ErrorContains(t, err, "database.sslmode must not be disable")
+
+	t.Setenv("DB_PASSWORD", "s3cret")
+	t.Setenv("DB_SSLMODE", "require")
+	_, err = Load(nil)
+	assert.NoError(t, err)
+}
+
+func TestRedacted(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "s3cret")
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+
+	dump := cfg.Redacted()
+	db := dump["database"].(map[string]any)
+	assert.Equal(t, "[REDACTED]", db["password"])
+	assert.Equal(t, "localhost", db["host"])
+	assert.Equal(t, "200ms", db["slow_query_threshold"])
+}
+
+func TestValidateSQLite(t *testing.T) {
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_HOST", "")
+	t.Setenv("DB_PATH", ":memory:")
+	cfg, err := Load([]string{"-database.host="})
+	require.NoError(t, err)
+	assert.Equal(t, ":memory:", cfg.Database.Path)
+
+	t.Setenv("GIN_MODE", "release")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, "sqlite is for development and tests only")
+}
+
+func TestValidateArchive(t *testing.T) {
+	t.Setenv("TX_ARCHIVE_ENABLED", "true")
+	t.Setenv("TX_RETENTION", "1h")
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "transactions.retention must be at least 24h")
+
+	t.Setenv("TX_RETENTION", "2160h")
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 90*24*time.Hour, cfg.Transactions.Retention)
+	assert.Equal(t, "archive", cfg.Transactions.ArchiveDir)
+
+	t.Setenv("DB_DRIVER", "memory")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, "archive_enabled is not supported by the memory driver")
+}
+
+func TestValidateIdempotency(t *testing.T) {
+	t.Setenv("IDEMPOTENCY_KEY_TTL", "0s")
+	t.Setenv("IDEMPOTENCY_PURGE_INTERVAL", "-1m")
+	t.Setenv("IDEMPOTENCY_LEASE", "10s")
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "idempotency.key_ttl must be positive")
+	assert.ErrorContains(t, err, "idempotency.purge_interval must be positive")
+	assert.ErrorContains(t, err, "idempotency.lease must be longer than timeouts.request")
+}
+
+func TestValidateGRPC(t *testing.T) {
+	t.Setenv("GRPC_PORT", "8080")
+	cfg, err := Load(nil)
+	require.NoError(t, err, "the port is only checked when gRPC is enabled")
+	assert.False(t, cfg.GRPC.Enabled)
+
+	t.Setenv("GRPC_ENABLED", "true")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, "grpc.port must differ from server.port")
+}
+
+func TestValidateBatches(t *testing.T) {
+	t.Setenv("BATCH_MAX_ITEMS", "0")
+	t.Setenv("BATCH_ASYNC_THRESHOLD", "-1")
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "batches.max_items must be positive")
+	assert.ErrorContains(t, err, "batches.async_threshold must not be negative")
+}
+
+func TestInterestRates(t *testing.T) {
+	t.Setenv("INTEREST_RATES", "2026-01-01=0.02, 2026-07-01=0.025")
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"2026-01-01": 0.02, "2026-07-01": 0.025}, cfg.Interest.Rates)
+
+	t.Setenv("INTEREST_RATES", "January=0.02,2026-07-01=2")
+	t.Setenv("INTEREST_DAY_COUNT", "30/360")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, `interest.rates: "January" is not a date`)
+	assert.ErrorContains(t, err, "interest.rates: rate 2 from 2026-07-01 must be at least 0 and below 1")
+	assert.ErrorContains(t, err, `interest.day_count must be ACT/365 or ACT/360, got "30/360"`)
+}
+
+func TestValidatePromo(t *testing.T) {
+	t.Setenv("PROMO_DEFAULT_EXPIRY", "0s")
+	cfg, err := Load(nil)
+	require.NoError(t, err, "promo credits may never expire")
+	assert.Zero(t, cfg.Promo.DefaultExpiry)
+
+	t.Setenv("PROMO_DEFAULT_EXPIRY", "-1h")
+	t.Setenv("PROMO_EXPIRY_INTERVAL", "0s")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, "promo.default_expiry must not be negative")
+	assert.ErrorContains(t, err, "promo.expiry_interval must be positive")
+}
+
+func TestValidateOverdraft(t *testing.T) {
+	t.Setenv("OVERDRAFT_RATE", "0.18")
+	t.Setenv("OVERDRAFT_DAILY_FEE", "0.5")
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0.18, cfg.Overdraft.Rate)
+	assert.Equal(t, 0.5, cfg.Overdraft.DailyFee)
+
+	t.Setenv("OVERDRAFT_RATE", "18")
+	t.Setenv("OVERDRAFT_DAILY_FEE", "-1")
+	_, err = Load(nil)
+	assert.ErrorContains(t, err, "overdraft.rate must be at least 0 and below 1")
+	assert.ErrorContains(t, err, "overdraft.daily_fee must not be negative")
+}
+
+func TestValidateEscrow(t *testing.T) {
+	t.Setenv("ESCROW_RELEASE_INTERVAL", "0s")
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "escrow.release_interval must be positive")
+}
+
+func TestValidatePaymentRequests(t *testing.T) {
+	t.Setenv("PAYMENT_REQUEST_DEFAULT_EXPIRY", "0s")
+	t.Setenv("PAYMENT_REQUEST_EXPIRY_INTERVAL", "-1m")
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "payment_requests.default_expiry must be positive")
+	assert.ErrorContains(t, err, "payment_requests.expiry_interval must be positive")
+}
+
+func TestValidateScheduler(t *testing.T) {
+	t.Setenv("SCHEDULER_MAX_RETRIES", "-1")
+	t.Setenv("SCHEDULER_RETRY_INTERVAL", "0s")
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "scheduler.max_retries must not be negative")
+	assert.ErrorContains(t, err, "scheduler.retry_interval must be positive")
+}
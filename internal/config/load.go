@@ -0,0 +1,293 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the configuration from defaults, command-line flags, an
+// optional YAML or TOML file and the environment. Later sources win:
+// environment variables override the file, which overrides flags. The file
+// is taken from -config or CONFIG_FILE. The result is validated.
+func Load(args []string) (*Config, error) {
+	cfg := Defaults()
+	fields := cfg.fields()
+
+	fs := flag.NewFlagSet("wallet-microservice", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		key := f.key
+		fs.Func(key, "env "+f.env, func(s string) error {
+			flagValues[key] = s
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// 1) Flags
+	for _, f := range fields {
+		if v, ok := flagValues[f.key]; ok {
+			if err := setValue(f.value, v); err != nil {
+				return nil, fmt.Errorf("flag -%s: %w", f.key, err)
+			}
+		}
+	}
+
+	// 2) Config file
+	path := *configFile
+	if env, ok := os.LookupEnv("CONFIG_FILE"); ok && env != "" {
+		path = env
+	}
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		byKey := make(map[string]field, len(fields))
+		for _, f := range fields {
+			byKey[f.key] = f
+		}
+		for key, v := range values {
+			f, ok := byKey[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown key %q", path, key)
+			}
+			if err := setValue(f.value, v); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, key, err)
+			}
+		}
+	}
+
+	// 3) Environment
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(f.env); ok && v != "" {
+			if err := setValue(f.value, v); err != nil {
+				return nil, fmt.Errorf("env %s: %w", f.env, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	cfg.Args = fs.Args()
+	return cfg, nil
+}
+
+// Redacted returns the effective configuration as a nested map with secret
+// values masked, suitable for logging at startup.
+func (c *Config) Redacted() map[string]any {
+	out := make(map[string]any)
+	for _, f := range c.fields() {
+		var v any = f.value.Interface()
+		switch d := v.(type) {
+		case time.Duration:
+			v = d.String()
+		case map[string]time.Duration:
+			m := make(map[string]string, len(d))
+			for k, dur := range d {
+				m[k] = dur.String()
+			}
+			v = m
+		}
+		if f.secret && !f.value.IsZero() {
+			v = "[REDACTED]"
+		}
+
+		parts := strings.Split(f.key, ".")
+		m := out
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := m[p].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				m[p] = next
+			}
+			m = next
+		}
+		m[parts[len(parts)-1]] = v
+	}
+	return out
+}
+
+type field struct {
+	key    string
+	env    string
+	secret bool
+	value  reflect.Value
+}
+
+// fields flattens the tagged struct tree into addressable leaf fields
+func (c *Config) fields() []field {
+	var out []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := sf.Tag.Get("key")
+			if key == "" {
+				continue
+			}
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), key)
+				continue
+			}
+			out = append(out, field{
+				key:    key,
+				env:    sf.Tag.Get("env"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return out
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue parses s into v according to v's type. Lists are comma
+// separated and maps use "key=value,key=value".
+func setValue(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(s, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid entry %q, expected key=value", pair)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, val); err != nil {
+				return fmt.Errorf("%s: %w", strings.TrimSpace(k), err)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)), elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+// readFile decodes a YAML or TOML file and flattens it to dotted keys with
+// string values, so that file values go through the same parsing as flags
+// and environment variables.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	out := make(map[string]string)
+	flatten(raw, "", out)
+	return out, nil
+}
+
+// leafMaps are keys whose value is a map of user-defined names rather than
+// a nested section
+var leafMaps = map[string]bool{
+	"features":            true,
+	"interest.rates":      true,
+	"timeouts.operations": true,
+}
+
+func flatten(m map[string]any, prefix string, out map[string]string) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			if leafMaps[key] {
+				pairs := make([]string, 0, len(val))
+				for mk, mv := range val {
+					pairs = append(pairs, fmt.Sprintf("%s=%v", mk, mv))
+				}
+				sort.Strings(pairs)
+				out[key] = strings.Join(pairs, ",")
+				continue
+			}
+			flatten(val, key, out)
+		case []any:
+			items := make([]string, len(val))
+			for i, item := range val {
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, ",")
+		default:
+			out[key] = fmt.Sprint(val)
+		}
+	}
+}
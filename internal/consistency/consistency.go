@@ -0,0 +1,48 @@
+// Package consistency lets read paths report how stale the data they served
+// may be, so that handlers can pass the bound on to clients.
+package consistency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tracker collects the staleness of every read made with its context
+type Tracker struct {
+	mu       sync.Mutex
+	recorded bool
+	max      time.Duration
+}
+
+type trackerKey struct{}
+
+// Track returns a context whose reads are recorded in the returned tracker
+func Track(ctx context.Context) (context.Context, *Tracker) {
+	t := &Tracker{}
+	return context.WithValue(ctx, trackerKey{}, t), t
+}
+
+// Record notes that a read made with ctx may be up to staleness behind the
+// primary. Reads from the primary record zero. It is a no-op when ctx is not
+// tracked.
+func Record(ctx context.Context, staleness time.Duration) {
+	t, ok := ctx.Value(trackerKey{}).(*Tracker)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recorded = true
+	if staleness > t.max {
+		t.max = staleness
+	}
+}
+
+// MaxStaleness returns the largest staleness recorded and whether any read
+// was recorded at all
+func (t *Tracker) MaxStaleness() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.max, t.recorded
+}
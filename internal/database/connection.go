@@ -1,90 +1,153 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
-	"os"
-	"wallet-microservice/internal/models"
-
+	"log/slog"
+	"strings"
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/logging"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
-
-func Connect() {
-	var err error
+// DB is an open database handle. It owns the connection pool; several can
+// be open in one process, e.g. a primary and a replica, or one per test.
+type DB struct {
+	gorm *gorm.DB
 
-	host := getEnv("DB_HOST", "localhost")
-	port := getEnv("DB_PORT", "5432")
-	user := getEnv("DB_USER", "postgres")
-	password := getEnv("DB_PASSWORD", "password")
-	dbname := getEnv("DB_NAME", "wallet_db")
+	// keepAlive holds a connection to a shared in-memory SQLite database.
+	// database/sql discards a connection whose transaction context is
+	// cancelled, which would otherwise drop the whole database with it.
+	keepAlive *sql.DB
+}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		host, user, password, dbname, port)
+// Open connects to the configured database and sizes the connection pool
+func Open(cfg config.DatabaseConfig) (*DB, error) {
+	db := &DB{}
+	dialector, err := db.dialector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
 
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	db.gorm, err = gorm.Open(dialector, &gorm.Config{
+		// Bound values are left out of logged SQL unless explicitly enabled
+		Logger: logging.NewGormLogger(slog.Default(), cfg.SlowQueryThreshold, cfg.LogParams),
 	})
+	if err != nil {
+		db.closeKeepAlive()
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
 
+	// Size the connection pool
+	sqlDB, err := db.gorm.DB()
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		db.Close()
+		return nil, fmt.Errorf("access connection pool: %w", err)
+	}
+	if cfg.Driver == "sqlite" {
+		// SQLite allows a single writer. One connection makes writers queue
+		// in the pool, where waits honour the context, instead of failing
+		// with SQLITE_BUSY.
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+	} else {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	// Emit a span for every query, parented to the caller's context
+	if err := db.gorm.Use(tracing.GormPlugin{}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("register tracing plugin: %w", err)
+	}
+
+	if cfg.Driver == "sqlite" {
+		slog.Info("Database connected successfully", "driver", cfg.Driver, "path", cfg.Path)
+	} else {
+		slog.Info("Database connected successfully", "driver", cfg.Driver, "host", cfg.Host, "dbname", cfg.Name)
 	}
+	return db, nil
+}
 
-	log.Println("Database connected successfully")
+// Gorm returns the GORM handle that repositories are built on
+func (db *DB) Gorm() *gorm.DB {
+	return db.gorm
 }
 
-func Migrate() {
-	// Enable UUID extension
-	DB.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`)
+// dialector returns the GORM dialector for the configured driver
+func (db *DB) dialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	if cfg.Driver != "sqlite" {
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+			dsnQuote(cfg.Host), dsnQuote(cfg.User), dsnQuote(cfg.Password), dsnQuote(cfg.Name), cfg.Port, cfg.SSLMode)
+		return postgres.Open(dsn), nil
+	}
+
+	// Foreign keys are off by default in SQLite. Transactions begin
+	// IMMEDIATE so that a read-then-write takes the write lock up front, the
+	// closest equivalent of SELECT ... FOR UPDATE.
+	params := "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	if cfg.Path != ":memory:" {
+		return sqlite.Open("file:" + cfg.Path + "?" + params + "&_pragma=journal_mode(WAL)"), nil
+	}
 
-	// Auto-migrate the models with all GORM tags
-	err := DB.AutoMigrate(&models.Wallet{}, &models.Transaction{})
+	// Give the in-memory database a unique name so it can be shared with the
+	// keep-alive connection but not with other handles
+	dsn := "file:wallet-" + uuid.NewString() + "?mode=memory&cache=shared&" + params
+	keepAlive, err := sql.Open("sqlite", dsn)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		return nil, err
 	}
+	if err := keepAlive.Ping(); err != nil {
+		keepAlive.Close()
+		return nil, err
+	}
+	db.keepAlive = keepAlive
+	return sqlite.Open(dsn), nil
+}
 
-	// Create updated_at trigger for wallets table
-	createUpdatedAtTrigger()
-
-	log.Println("Database migration completed")
+// dsnQuote quotes a libpq keyword/value so that passwords containing
+// spaces or quotes survive
+func dsnQuote(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
 }
 
-func createUpdatedAtTrigger() {
-	// Create the trigger function if it doesn't exist
-	triggerFunction := `
-        CREATE OR REPLACE FUNCTION update_updated_at_column()
-        RETURNS TRIGGER AS $$
-        BEGIN
-            NEW.updated_at = CURRENT_TIMESTAMP;
-            RETURN NEW;
-        END;
-        $$ language 'plpgsql';
-    `
-
-	if err := DB.Exec(triggerFunction).Error; err != nil {
-		log.Printf("Warning: Failed to create trigger function: %v", err)
-		return
+// Migrate applies all pending versioned migrations
+func (db *DB) Migrate(ctx context.Context) error {
+	migrator, err := NewMigrator(db.gorm)
+	if err != nil {
+		return err
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		return err
 	}
 
-	// Create the trigger if it doesn't exist
-	trigger := `
-        DROP TRIGGER IF EXISTS update_wallets_updated_at ON wallets;
-        CREATE TRIGGER update_wallets_updated_at 
-        BEFORE UPDATE ON wallets
-        FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
-    `
+	slog.Info("Database migration completed", "version", migrator.Latest())
+	return nil
+}
 
-	if err := DB.Exec(trigger).Error; err != nil {
-		log.Printf("Warning: Failed to create trigger: %v", err)
+// Close closes the underlying connection pool
+func (db *DB) Close() error {
+	sqlDB, err := db.gorm.DB()
+	if err == nil {
+		err = sqlDB.Close()
 	}
+	db.closeKeepAlive()
+	return err
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func (db *DB) closeKeepAlive() {
+	if db.keepAlive != nil {
+		db.keepAlive.Close()
+		db.keepAlive = nil
 	}
-	return defaultValue
 }
@@ -0,0 +1,60 @@
+//go:build unit
+// +build unit
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openMemory(t *testing.T) *DB {
+	t.Helper()
+	cfg := config.Defaults().Database
+	cfg.Driver = "sqlite"
+	cfg.Path = ":memory:"
+	db, err := Open(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Migrate(context.Background()))
+	return db
+}
+
+func TestOpenReturnsIndependentHandles(t *testing.T) {
+	first := openMemory(t)
+	second := openMemory(t)
+
+	require.NoError(t, first.Gorm().Exec("INSERT INTO wallets (user_id) VALUES ('only-in-first')").Error)
+
+	var count int64
+	require.NoError(t, first.Gorm().Table("wallets").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, second.Gorm().Table("wallets").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	// Closing one handle leaves the other usable
+	require.NoError(t, first.Close())
+	assert.Error(t, first.Ping(context.Background()))
+	assert.NoError(t, second.Ping(context.Background()))
+	assert.NoError(t, second.CheckSchema(context.Background()))
+}
+
+func TestCheckSchemaAcceptsNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	db := openMemory(t)
+	m, err := NewMigrator(db.Gorm())
+	require.NoError(t, err)
+
+	// A rolling deploy has migrated ahead of this build
+	require.NoError(t, db.Gorm().Create(&schemaMigration{Version: m.Latest() + 1, Name: "next", AppliedAt: time.Now()}).Error)
+	assert.NoError(t, db.CheckSchema(ctx))
+
+	require.NoError(t, db.Gorm().Where("version >= ?", m.Latest()).Delete(&schemaMigration{}).Error)
+	assert.Error(t, db.CheckSchema(ctx))
+}
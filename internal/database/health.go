@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// Ping verifies that a connection to the database can be established
+func (db *DB) Ping(ctx context.Context) error {
+	sqlDB, err := db.gorm.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckSchema verifies that the database has been migrated at least to the
+// version this build expects, so a pod pointed at an unmigrated database
+// never reports ready. A newer schema is fine: during a rolling deploy the
+// new release migrates while the old pods still serve.
+func (db *DB) CheckSchema(ctx context.Context) error {
+	migrator, err := NewMigrator(db.gorm)
+	if err != nil {
+		return err
+	}
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version < migrator.Latest() {
+		return fmt.Errorf("schema version is %d, expected at least %d", version, migrator.Latest())
+	}
+	return nil
+}
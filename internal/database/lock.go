@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// WithTryLock runs fn on a single connection holding the Postgres advisory
+// lock key, so that only one instance runs it at a time. If another session
+// holds the lock, fn is skipped and WithTryLock reports false. SQLite has a
+// single writer and needs no lock; fn always runs.
+func WithTryLock(ctx context.Context, db *gorm.DB, key int64, fn func(conn *gorm.DB) error) (bool, error) {
+	ran := false
+	err := db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if conn.Dialector.Name() != "postgres" {
+			ran = true
+			return fn(conn)
+		}
+
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("acquire advisory lock %d: %w", key, err)
+		}
+		if !locked {
+			return nil
+		}
+		defer func() {
+			// Use a fresh context so the lock is released even if ctx was cancelled
+			if err := conn.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", key).Error; err != nil {
+				slog.Warn("Failed to release advisory lock", "key", key, "error", err)
+			}
+		}()
+		ran = true
+		return fn(conn)
+	})
+	return ran, err
+}
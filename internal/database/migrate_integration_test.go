@@ -0,0 +1,84 @@
+//go:build integration
+// +build integration
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateUpDown(t *testing.T) {
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+	db, err := Open(cfg.Database)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	m, err := NewMigrator(db.Gorm())
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	version, err := m.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, m.Latest(), version)
+	require.NoError(t, db.CheckSchema(ctx))
+
+	// Running again is a no-op
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Error(t, db.CheckSchema(ctx))
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	status, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range status {
+		assert.NotNil(t, s.AppliedAt, "migration %d not applied", s.Version)
+	}
+}
+
+func TestEnsurePartitions(t *testing.T) {
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+	db, err := Open(cfg.Database)
+	require.NoError(t, err)
+	defer db.Close()
+	if !Partitionable(db.Gorm()) {
+		t.Skip("partitioning needs Postgres")
+	}
+
+	ctx := context.Background()
+	require.NoError(t, db.Migrate(ctx))
+
+	now := time.Now()
+	_, err = EnsurePartitions(ctx, db.Gorm(), now, 6)
+	require.NoError(t, err)
+	partitions, err := Partitions(ctx, db.Gorm())
+	require.NoError(t, err)
+	names := make(map[string]bool)
+	for _, p := range partitions {
+		names[p.Name] = true
+	}
+	for i := 0; i <= 6; i++ {
+		assert.True(t, names[PartitionFor(MonthStart(now).AddDate(0, i, 0)).Name], "month +%d", i)
+	}
+
+	// Running again creates nothing
+	created, err := EnsurePartitions(ctx, db.Gorm(), now, 6)
+	require.NoError(t, err)
+	assert.Empty(t, created)
+}
@@ -0,0 +1,88 @@
+//go:build unit
+// +build unit
+
+package database
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"wallet-microservice/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrationsEmbedded(t *testing.T) {
+	var names [][]string
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		fsys, err := migrations.For(dialect)
+		require.NoError(t, err)
+		migs, err := LoadMigrations(fsys)
+		require.NoError(t, err)
+		require.NotEmpty(t, migs)
+		assert.Equal(t, int64(1), migs[0].Version)
+		assert.Equal(t, "initial_schema", migs[0].Name)
+
+		var list []string
+		for _, m := range migs {
+			list = append(list, fmt.Sprintf("%d_%s", m.Version, m.Name))
+		}
+		names = append(names, list)
+	}
+	// Every dialect must carry the same migrations
+	assert.Equal(t, names[0], names[1])
+
+	_, err := migrations.For("mysql")
+	assert.Error(t, err)
+}
+
+func TestLoadMigrationsOrdersAndPairs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_add_index.up.sql":   {Data: []byte("CREATE INDEX x")},
+		"0010_add_index.down.sql": {Data: []byte("DROP INDEX x")},
+		"0002_second.up.sql":      {Data: []byte("up 2")},
+		"0002_second.down.sql":    {Data: []byte("down 2")},
+	}
+	migs, err := LoadMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migs, 2)
+	assert.Equal(t, int64(2), migs[0].Version)
+	assert.Equal(t, "down 2", migs[0].Down)
+	assert.Equal(t, int64(10), migs[1].Version)
+	assert.Equal(t, "add_index", migs[1].Name)
+}
+
+func TestLoadMigrationsRejectsInvalidSets(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name:  "missing down",
+			files: fstest.MapFS{"0001_a.up.sql": {Data: []byte("x")}},
+			want:  "must have both up and down",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"0001_a.up.sql":   {Data: []byte("x")},
+				"0001_b.down.sql": {Data: []byte("x")},
+			},
+			want: "conflicting names",
+		},
+		{
+			name:  "bad file name",
+			files: fstest.MapFS{"initial.sql": {Data: []byte("x")}},
+			want:  "does not match",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(tt.files)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Partition is one monthly partition of the transactions table, holding
+// rows with From <= created_at < To
+type Partition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+var partitionNameRe = regexp.MustCompile(`^transactions_p(\d{6})$`)
+
+const partitionBoundFormat = "2006-01-02 15:04:05-07"
+
+// MonthStart returns the first instant of t's calendar month in UTC.
+// Partitions and archives are cut on UTC month boundaries.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// PartitionFor returns the monthly partition that holds rows created at t
+func PartitionFor(t time.Time) Partition {
+	from := MonthStart(t)
+	return Partition{
+		Name: "transactions_p" + from.Format("200601"),
+		From: from,
+		To:   from.AddDate(0, 1, 0),
+	}
+}
+
+// Partitionable reports whether db supports partitioning the transactions
+// table. Only Postgres does; on SQLite the partition functions are no-ops.
+func Partitionable(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// EnsurePartitions creates the partitions for the month containing now and
+// the following ahead months, returning the ones it had to create. Creating
+// a month fails if rows for it already sit in the default partition; move
+// them out by hand.
+func EnsurePartitions(ctx context.Context, db *gorm.DB, now time.Time, ahead int) ([]Partition, error) {
+	if !Partitionable(db) {
+		return nil, nil
+	}
+	existing, err := Partitions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		have[p.Name] = true
+	}
+
+	var created []Partition
+	for i := 0; i <= ahead; i++ {
+		p := PartitionFor(MonthStart(now).AddDate(0, i, 0))
+		if have[p.Name] {
+			continue
+		}
+		// DDL takes no bind parameters; the name and bounds are built from
+		// digits only, so they are safe to inline
+		err := db.WithContext(ctx).Exec(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s PARTITION OF transactions FOR VALUES FROM ('%s') TO ('%s')",
+			p.Name, p.From.Format(partitionBoundFormat), p.To.Format(partitionBoundFormat))).Error
+		if err != nil {
+			return created, fmt.Errorf("create partition %s: %w", p.Name, err)
+		}
+		created = append(created, p)
+	}
+	return created, nil
+}
+
+// Partitions lists the monthly partitions attached to the transactions
+// table, oldest first. The default partition is not included.
+func Partitions(ctx context.Context, db *gorm.DB) ([]Partition, error) {
+	if !Partitionable(db) {
+		return nil, nil
+	}
+	var names []string
+	err := db.WithContext(ctx).Raw(`SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'transactions'::regclass`).Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Partition
+	for _, name := range names {
+		m := partitionNameRe.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		month, err := time.Parse("200601", m[1])
+		if err != nil {
+			continue
+		}
+		out = append(out, PartitionFor(month))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].From.Before(out[j].From) })
+	return out, nil
+}
+
+// DropPartition detaches a partition from the transactions table and drops
+// it. Run it in the transaction that exported its rows.
+func DropPartition(tx *gorm.DB, p Partition) error {
+	if !partitionNameRe.MatchString(p.Name) {
+		return fmt.Errorf("invalid partition name %q", p.Name)
+	}
+	if err := tx.Exec("ALTER TABLE transactions DETACH PARTITION " + p.Name).Error; err != nil {
+		return fmt.Errorf("detach partition %s: %w", p.Name, err)
+	}
+	if err := tx.Exec("DROP TABLE " + p.Name).Error; err != nil {
+		return fmt.Errorf("drop partition %s: %w", p.Name, err)
+	}
+	return nil
+}
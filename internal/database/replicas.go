@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"wallet-microservice/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// ReplicaSet routes read-only queries to healthy read replicas and falls
+// back to the primary when none is available. Replicas are checked
+// periodically; one that cannot be reached or lags more than maxLag is taken
+// out of rotation until a later check passes.
+type ReplicaSet struct {
+	primary  *DB
+	replicas []*Replica
+	maxLag   time.Duration
+	next     atomic.Uint64
+}
+
+// Replica is one read replica and the result of its last check
+type Replica struct {
+	Name string
+	db   *DB
+
+	mu        sync.RWMutex
+	healthy   bool
+	lag       time.Duration
+	checkedAt time.Time
+}
+
+// OpenReplicas connects to every replica in cfg.Replicas. For Postgres each
+// entry is host or host:port and shares the primary's credentials and
+// database name; for SQLite it is a file path. Replicas are out of rotation
+// until Check has run.
+func OpenReplicas(primary *DB, cfg config.DatabaseConfig) (*ReplicaSet, error) {
+	set := &ReplicaSet{primary: primary, maxLag: cfg.ReplicaMaxLag}
+	for _, addr := range cfg.Replicas {
+		rcfg, err := replicaConfig(cfg, addr)
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		db, err := Open(rcfg)
+		if err != nil {
+			set.Close()
+			return nil, fmt.Errorf("replica %s: %w", addr, err)
+		}
+		set.replicas = append(set.replicas, &Replica{Name: addr, db: db})
+	}
+	return set, nil
+}
+
+func replicaConfig(cfg config.DatabaseConfig, addr string) (config.DatabaseConfig, error) {
+	if cfg.Driver == "sqlite" {
+		cfg.Path = addr
+		return cfg, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// No port given
+		cfg.Host = addr
+		return cfg, nil
+	}
+	cfg.Host = host
+	if cfg.Port, err = strconv.Atoi(port); err != nil {
+		return cfg, fmt.Errorf("replica %s: invalid port", addr)
+	}
+	return cfg, nil
+}
+
+// Replicas returns the configured replicas
+func (s *ReplicaSet) Replicas() []*Replica {
+	return s.replicas
+}
+
+// Reader returns a handle for a read-only query together with an upper
+// bound on how far behind the primary its data may be. Healthy replicas are
+// used round-robin; without one the primary is returned with zero staleness.
+func (s *ReplicaSet) Reader() (*gorm.DB, time.Duration) {
+	n := len(s.replicas)
+	if n > 0 {
+		start := int(s.next.Add(1) % uint64(n))
+		for i := 0; i < n; i++ {
+			r := s.replicas[(start+i)%n]
+			if staleness, ok := r.staleness(); ok {
+				return r.db.Gorm(), staleness
+			}
+		}
+	}
+	return s.primary.Gorm(), 0
+}
+
+// Check refreshes the health of every replica in parallel
+func (s *ReplicaSet) Check(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, r := range s.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wasHealthy := r.isHealthy()
+			err := r.refresh(ctx, s.maxLag)
+			switch {
+			case err != nil && wasHealthy:
+				slog.WarnContext(ctx, "Replica taken out of rotation", "replica", r.Name, "error", err)
+			case err == nil && !wasHealthy:
+				slog.InfoContext(ctx, "Replica in rotation", "replica", r.Name)
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Close closes every replica connection pool
+func (s *ReplicaSet) Close() error {
+	var errs []error
+	for _, r := range s.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Healthy reports the result of the last check, for readiness reporting.
+// It does not query the replica.
+func (r *Replica) Healthy(context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.checkedAt.IsZero() {
+		return errors.New("not checked yet")
+	}
+	if !r.healthy {
+		return fmt.Errorf("out of rotation, last lag %s", r.lag)
+	}
+	return nil
+}
+
+func (r *Replica) isHealthy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.healthy
+}
+
+// staleness bounds the replica's lag: the lag measured at the last check
+// plus the time since, during which it may have fallen further behind
+func (r *Replica) staleness() (time.Duration, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.healthy {
+		return 0, false
+	}
+	return r.lag + time.Since(r.checkedAt), true
+}
+
+func (r *Replica) refresh(ctx context.Context, maxLag time.Duration) error {
+	lag, err := r.measureLag(ctx)
+	if err == nil && maxLag > 0 && lag > maxLag {
+		err = fmt.Errorf("replication lag %s exceeds %s", lag, maxLag)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.healthy = err == nil
+	r.lag = lag
+	r.checkedAt = time.Now()
+	return err
+}
+
+// measureLag pings the replica and asks how long ago it replayed the last
+// transaction from the primary. On an idle primary this overstates the lag,
+// which errs on the safe side.
+func (r *Replica) measureLag(ctx context.Context) (time.Duration, error) {
+	if err := r.db.Ping(ctx); err != nil {
+		return 0, err
+	}
+	if r.db.Gorm().Dialector.Name() != "postgres" {
+		return 0, nil
+	}
+
+	var seconds float64
+	err := r.db.Gorm().WithContext(ctx).Raw(`SELECT CASE WHEN pg_is_in_recovery()
+		THEN COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		ELSE 0 END`).Scan(&seconds).Error
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,78 @@
+//go:build unit
+// +build unit
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaSetRoutingAndFailover(t *testing.T) {
+	primary := openMemory(t)
+	cfg := config.Defaults().Database
+	cfg.Driver = "sqlite"
+	cfg.Replicas = []string{":memory:", ":memory:"}
+
+	set, err := OpenReplicas(primary, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { set.Close() })
+	ctx := context.Background()
+
+	// Not checked yet: reads stay on the primary
+	db, staleness := set.Reader()
+	assert.Same(t, primary.Gorm(), db)
+	assert.Zero(t, staleness)
+	assert.Error(t, set.Replicas()[0].Healthy(ctx))
+
+	// Healthy replicas are used in turn
+	require.NoError(t, set.Check(ctx))
+	first, staleness := set.Reader()
+	second, _ := set.Reader()
+	assert.NotSame(t, primary.Gorm(), first)
+	assert.NotSame(t, first, second)
+	assert.Greater(t, staleness, time.Duration(0))
+	assert.Less(t, staleness, time.Second)
+
+	// A replica that fails its check leaves the rotation
+	require.NoError(t, set.Replicas()[0].db.Close())
+	require.NoError(t, set.Check(ctx))
+	assert.Error(t, set.Replicas()[0].Healthy(ctx))
+	assert.NoError(t, set.Replicas()[1].Healthy(ctx))
+	for i := 0; i < 4; i++ {
+		db, _ := set.Reader()
+		assert.Same(t, set.Replicas()[1].db.Gorm(), db)
+	}
+
+	// With none left, reads fall back to the primary
+	require.NoError(t, set.Replicas()[1].db.Close())
+	require.NoError(t, set.Check(ctx))
+	db, staleness = set.Reader()
+	assert.Same(t, primary.Gorm(), db)
+	assert.Zero(t, staleness)
+}
+
+func TestReplicaConfig(t *testing.T) {
+	cfg := config.Defaults().Database
+	cfg.Host, cfg.Port = "primary", 5432
+
+	r, err := replicaConfig(cfg, "replica-1:6432")
+	require.NoError(t, err)
+	assert.Equal(t, "replica-1", r.Host)
+	assert.Equal(t, 6432, r.Port)
+	assert.Equal(t, cfg.User, r.User)
+
+	r, err = replicaConfig(cfg, "replica-2")
+	require.NoError(t, err)
+	assert.Equal(t, "replica-2", r.Host)
+	assert.Equal(t, 5432, r.Port)
+
+	_, err = replicaConfig(cfg, "replica-3:http")
+	assert.Error(t, err)
+}
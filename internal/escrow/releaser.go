@@ -0,0 +1,85 @@
+// Package escrow releases escrows to their sellers once their deadline has
+// passed. Only one instance runs at a time: the one holding a Postgres
+// advisory lock.
+package escrow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lockKey identifies the Postgres advisory lock held while releasing
+const lockKey int64 = 7_310_021_554_006
+
+// pageSize is the number of due escrows listed at a time
+const pageSize = 100
+
+// Releaser releases escrows whose release_at has passed
+type Releaser struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func New(db *gorm.DB) *Releaser {
+	return &Releaser{
+		db:  db,
+		now: time.Now,
+	}
+}
+
+// Run releases every funded escrow due now, most overdue first. It is meant
+// to run periodically. Each release is its own database transaction, so one
+// settled through the API in the meantime is left alone.
+func (r *Releaser) Run(ctx context.Context) error {
+	ran, err := database.WithTryLock(ctx, r.db, lockKey, func(conn *gorm.DB) error {
+		escrows := repositories.NewEscrowRepository(conn)
+		now := r.now().UTC()
+		for ctx.Err() == nil {
+			ids, err := escrows.DueEscrows(ctx, now, pageSize)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := r.release(ctx, escrows, id); err != nil {
+					return fmt.Errorf("release escrow %s: %w", id, err)
+				}
+			}
+			// Released escrows are no longer due, so the next page starts
+			// with the ones left over
+			if len(ids) < pageSize {
+				return nil
+			}
+		}
+		return ctx.Err()
+	})
+	if err == nil && !ran {
+		slog.DebugContext(ctx, "Escrows are being released elsewhere; skipping")
+	}
+	return err
+}
+
+func (r *Releaser) release(ctx context.Context, escrows repositories.EscrowRepository, id uuid.UUID) error {
+	escrow, err := escrows.SettleEscrow(ctx, id, models.EscrowSettlement{Status: models.EscrowReleased, Auto: true})
+	if errors.Is(err, models.ErrInvalidState) {
+		// Settled since it was listed
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Released escrow at its deadline",
+		"escrow_id", escrow.ID,
+		"seller_wallet_id", escrow.SellerWalletID,
+		"amount", escrow.SellerAmount)
+	return nil
+}
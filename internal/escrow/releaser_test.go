@@ -0,0 +1,92 @@
+//go:build unit
+// +build unit
+
+package escrow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReleasesDueEscrows(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Defaults()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	db, err := database.Open(cfg.Database)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Migrate(ctx))
+
+	walletRepo := repositories.NewWalletRepository(db.Gorm())
+	wallets := services.NewWalletService(walletRepo)
+	escrows := services.NewEscrowService(repositories.NewEscrowRepository(db.Gorm()), walletRepo, services.Timeouts{})
+	buyer, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "buyer"})
+	require.NoError(t, err)
+	seller, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "seller"})
+	require.NoError(t, err)
+	_, err = wallets.CreditWallet(ctx, buyer.ID, models.TransactionRequest{Amount: 100})
+	require.NoError(t, err)
+
+	deadline := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	create := func(amount float64, releaseAt *time.Time) *models.EscrowResponse {
+		e, err := escrows.CreateEscrow(ctx, models.CreateEscrowRequest{
+			BuyerWalletID:  buyer.ID.String(),
+			SellerWalletID: seller.ID.String(),
+			Amount:         amount,
+			ReleaseAt:      releaseAt,
+		})
+		require.NoError(t, err)
+		return e
+	}
+	later := deadline.Add(time.Hour)
+	due := create(10, &deadline)
+	refunded := create(20, &deadline)
+	notYet := create(30, &later)
+	open := create(40, nil)
+	_, err = escrows.RefundEscrow(ctx, refunded.ID)
+	require.NoError(t, err)
+
+	r := New(db.Gorm())
+	r.now = func() time.Time { return deadline.Add(-time.Second) }
+	require.NoError(t, r.Run(ctx))
+	e, err := escrows.GetEscrow(ctx, due.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EscrowFunded, e.Status)
+
+	r.now = func() time.Time { return deadline }
+	require.NoError(t, r.Run(ctx))
+	e, err = escrows.GetEscrow(ctx, due.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EscrowReleased, e.Status)
+	assert.True(t, e.AutoReleased)
+	assert.Equal(t, 10.0, e.SellerAmount)
+
+	// The refund, the later deadline and the escrow without one are left alone
+	for id, want := range map[uuid.UUID]models.EscrowStatus{
+		refunded.ID: models.EscrowRefunded,
+		notYet.ID:   models.EscrowFunded,
+		open.ID:     models.EscrowFunded,
+	} {
+		e, err := escrows.GetEscrow(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, want, e.Status, id)
+	}
+
+	// Running again pays nothing twice
+	require.NoError(t, r.Run(ctx))
+	w, err := wallets.GetWallet(ctx, seller.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, w.Balance)
+}
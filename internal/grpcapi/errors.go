@@ -0,0 +1,75 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"wallet-microservice/internal/models"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type errorMapping struct {
+	err  error
+	code codes.Code
+}
+
+// errorMappings translates domain errors to gRPC, first match wins. It
+// mirrors the HTTP mapping in package handlers.
+var errorMappings = []errorMapping{
+	{models.ErrWalletNotFound, codes.NotFound},
+	{models.ErrInsufficientFunds, codes.FailedPrecondition},
+	{models.ErrWalletExists, codes.AlreadyExists},
+	{models.ErrDuplicate, codes.AlreadyExists},
+	{models.ErrConflict, codes.Aborted},
+	{models.ErrInvalidAmount, codes.InvalidArgument},
+	{models.ErrBatchNotFound, codes.NotFound},
+	{models.ErrScheduleNotFound, codes.NotFound},
+	{models.ErrEscrowNotFound, codes.NotFound},
+	{models.ErrPaymentRequestNotFound, codes.NotFound},
+	{models.ErrBillNotFound, codes.NotFound},
+	{models.ErrInterestAccountNotFound, codes.NotFound},
+	{models.ErrCurrencyMismatch, codes.FailedPrecondition},
+	{models.ErrInvalidState, codes.FailedPrecondition},
+	{models.ErrIdempotencyKeyReused, codes.FailedPrecondition},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+	{context.Canceled, codes.Canceled},
+}
+
+// toStatus maps an error from the service layer to a gRPC status. Errors
+// without a mapping become INTERNAL with a generic message, since theirs
+// may come from the database driver; the access log records the original.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		return status.Error(codes.InvalidArgument, validationErr.Error())
+	}
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			return status.Error(m.code, m.err.Error())
+		}
+	}
+	return &internalError{err: err}
+}
+
+// internalError reports INTERNAL to the client while keeping the cause for
+// the access log
+type internalError struct {
+	err error
+}
+
+func (e *internalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *internalError) Unwrap() error {
+	return e.err
+}
+
+func (e *internalError) GRPCStatus() *status.Status {
+	return status.New(codes.Internal, "internal error")
+}
@@ -0,0 +1,34 @@
+package grpcapi
+
+import (
+	"context"
+
+	walletv1 "wallet-microservice/api/proto/wallet/v1"
+	"wallet-microservice/internal/health"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthServer implements grpc.health.v1.Health on top of the same checks
+// as /readyz. The whole server ("") and the wallet service are SERVING
+// exactly when the service is ready. Watch is not supported.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	checks *health.Registry
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	switch req.GetService() {
+	case "", walletv1.WalletService_ServiceDesc.ServiceName:
+	default:
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+
+	resp := &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}
+	if _, ready := h.checks.Check(ctx); ready {
+		resp.Status = healthpb.HealthCheckResponse_SERVING
+	}
+	return resp, nil
+}
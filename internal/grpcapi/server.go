@@ -0,0 +1,268 @@
+// Package grpcapi serves the wallet API over gRPC, next to the REST
+// endpoints and on top of the same WalletService. The protobuf definition
+// lives in api/proto/wallet/v1.
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+
+	walletv1 "wallet-microservice/api/proto/wallet/v1"
+	"wallet-microservice/internal/health"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/services"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// streamPageSize is how many transactions ListTransactions reads per call
+// to the service; it is the largest page the service hands out
+const streamPageSize = 100
+
+type options struct {
+	reflection bool
+}
+
+// Option configures optional behaviour of the server
+type Option func(*options)
+
+// WithReflection registers the server reflection service so that tools
+// such as grpcurl can discover the API
+func WithReflection() Option {
+	return func(o *options) {
+		o.reflection = true
+	}
+}
+
+// NewServer returns a gRPC server exposing wallets as
+// wallet.v1.WalletService together with the standard health service,
+// which answers from checks
+func NewServer(wallets services.WalletService, checks *health.Registry, logger *slog.Logger, opts ...Option) *grpc.Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryRequestID, unaryAccessLog(logger)),
+		grpc.ChainStreamInterceptor(streamRequestID, streamAccessLog(logger)),
+	)
+	walletv1.RegisterWalletServiceServer(srv, &walletServer{wallets: wallets})
+	healthpb.RegisterHealthServer(srv, &healthServer{checks: checks})
+	if o.reflection {
+		reflection.Register(srv)
+	}
+	return srv
+}
+
+type walletServer struct {
+	walletv1.UnimplementedWalletServiceServer
+	wallets services.WalletService
+}
+
+func (s *walletServer) CreateWallet(ctx context.Context, req *walletv1.CreateWalletRequest) (*walletv1.Wallet, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	wr, err := walletRequest(req.GetUserId(), req.GetCurrency(), req.GetDebitOrder(), req.OverdraftLimit)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.CreateWallet(ctx, wr)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return walletToProto(wallet), nil
+}
+
+func (s *walletServer) GetWallet(ctx context.Context, req *walletv1.GetWalletRequest) (*walletv1.Wallet, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.GetWallet(ctx, id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return walletToProto(wallet), nil
+}
+
+func (s *walletServer) GetWalletByUserId(ctx context.Context, req *walletv1.GetWalletByUserIdRequest) (*walletv1.Wallet, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	wallet, err := s.wallets.GetWalletByUserID(ctx, req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return walletToProto(wallet), nil
+}
+
+func (s *walletServer) UpdateWallet(ctx context.Context, req *walletv1.UpdateWalletRequest) (*walletv1.Wallet, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	wr, err := walletRequest(req.GetUserId(), req.GetCurrency(), req.GetDebitOrder(), req.OverdraftLimit)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.UpdateWallet(ctx, id, wr)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return walletToProto(wallet), nil
+}
+
+func (s *walletServer) DeleteWallet(ctx context.Context, req *walletv1.DeleteWalletRequest) (*walletv1.DeleteWalletResponse, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.wallets.DeleteWallet(ctx, id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &walletv1.DeleteWalletResponse{}, nil
+}
+
+func (s *walletServer) CreditWallet(ctx context.Context, req *walletv1.CreditWalletRequest) (*walletv1.Transaction, error) {
+	id, err := parseID("wallet_id", req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.wallets.CreditWallet(ctx, id, models.TransactionRequest{
+		Amount:      req.GetAmount(),
+		Description: req.GetDescription(),
+		Reference:   req.GetReference(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return transactionToProto(tx), nil
+}
+
+func (s *walletServer) DebitWallet(ctx context.Context, req *walletv1.DebitWalletRequest) (*walletv1.Transaction, error) {
+	id, err := parseID("wallet_id", req.GetWalletId())
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.wallets.DebitWallet(ctx, id, models.TransactionRequest{
+		Amount:      req.GetAmount(),
+		Description: req.GetDescription(),
+		Reference:   req.GetReference(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return transactionToProto(tx), nil
+}
+
+// ListTransactions pages through the history and streams it one
+// transaction at a time. Unlike the REST endpoint, a missing wallet is
+// reported as NOT_FOUND rather than as an empty history.
+func (s *walletServer) ListTransactions(req *walletv1.ListTransactionsRequest, stream grpc.ServerStreamingServer[walletv1.Transaction]) error {
+	id, err := parseID("wallet_id", req.GetWalletId())
+	if err != nil {
+		return err
+	}
+	if req.GetLimit() < 0 {
+		return status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+	ctx := stream.Context()
+	if _, err := s.wallets.GetWallet(ctx, id); err != nil {
+		return toStatus(err)
+	}
+
+	remaining := int(req.GetLimit())
+	for page := 1; ; page++ {
+		txs, err := s.wallets.GetTransactionHistory(ctx, id, page, streamPageSize)
+		if err != nil {
+			return toStatus(err)
+		}
+		for i := range txs {
+			if err := stream.Send(transactionToProto(&txs[i])); err != nil {
+				return err
+			}
+			if remaining--; remaining == 0 {
+				return nil
+			}
+		}
+		if len(txs) < streamPageSize {
+			return nil
+		}
+	}
+}
+
+func parseID(field, v string) (uuid.UUID, error) {
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "%s must be a UUID", field)
+	}
+	return id, nil
+}
+
+// walletRequest checks the fields a wallet is created or updated with, as
+// the REST binding does
+func walletRequest(userID, currency string, order walletv1.DebitOrder, overdraftLimit *float64) (models.CreateWalletRequest, error) {
+	req := models.CreateWalletRequest{UserID: userID, Currency: currency, OverdraftLimit: overdraftLimit}
+	switch order {
+	case walletv1.DebitOrder_DEBIT_ORDER_UNSPECIFIED:
+	case walletv1.DebitOrder_DEBIT_ORDER_PROMO_FIRST:
+		req.DebitOrder = models.PromoFirst
+	case walletv1.DebitOrder_DEBIT_ORDER_CASH_FIRST:
+		req.DebitOrder = models.CashFirst
+	default:
+		return req, status.Error(codes.InvalidArgument, "debit_order is not a known order")
+	}
+	if overdraftLimit != nil && *overdraftLimit < 0 {
+		return req, status.Error(codes.InvalidArgument, "overdraft_limit must not be negative")
+	}
+	return req, nil
+}
+
+func walletToProto(w *models.WalletResponse) *walletv1.Wallet {
+	order := walletv1.DebitOrder_DEBIT_ORDER_UNSPECIFIED
+	switch w.DebitOrder {
+	case models.PromoFirst:
+		order = walletv1.DebitOrder_DEBIT_ORDER_PROMO_FIRST
+	case models.CashFirst:
+		order = walletv1.DebitOrder_DEBIT_ORDER_CASH_FIRST
+	}
+	return &walletv1.Wallet{
+		Id:         w.ID.String(),
+		UserId:     w.UserID,
+		Balance:    w.Balance,
+		Currency:   w.Currency,
+		Buckets:    &walletv1.Buckets{Cash: w.Buckets.Cash, Promo: w.Buckets.Promo},
+		DebitOrder: order,
+		Overdraft:  &walletv1.Overdraft{Limit: w.Overdraft.Limit, Used: w.Overdraft.Used},
+	}
+}
+
+func transactionToProto(t *models.TransactionResponse) *walletv1.Transaction {
+	typ := walletv1.TransactionType_TRANSACTION_TYPE_UNSPECIFIED
+	switch t.Type {
+	case models.Credit:
+		typ = walletv1.TransactionType_TRANSACTION_TYPE_CREDIT
+	case models.Debit:
+		typ = walletv1.TransactionType_TRANSACTION_TYPE_DEBIT
+	}
+	return &walletv1.Transaction{
+		Id:          t.ID.String(),
+		WalletId:    t.WalletID.String(),
+		Type:        typ,
+		Amount:      t.Amount,
+		Description: t.Description,
+		Reference:   t.Reference,
+		PromoAmount: t.PromoAmount,
+		CreatedAt:   timestamppb.New(t.CreatedAt),
+	}
+}
@@ -0,0 +1,298 @@
+//go:build unit
+// +build unit
+
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	walletv1 "wallet-microservice/api/proto/wallet/v1"
+	"wallet-microservice/internal/health"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newConn serves the API on the in-memory repository over an in-process
+// listener
+func newConn(t *testing.T, checks *health.Registry) *grpc.ClientConn {
+	t.Helper()
+	return serve(t, services.NewWalletService(repositories.NewMemoryWalletRepository()), checks)
+}
+
+// serve serves the API on wallets over an in-process listener
+func serve(t *testing.T, wallets services.WalletService, checks *health.Registry) *grpc.ClientConn {
+	t.Helper()
+	srv := NewServer(wallets, checks, slog.New(slog.NewTextHandler(io.Discard, nil)), WithReflection())
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestWalletLifecycle(t *testing.T) {
+	c := walletv1.NewWalletServiceClient(newConn(t, health.NewRegistry(0)))
+	ctx := context.Background()
+
+	var header metadata.MD
+	wallet, err := c.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: "grpc-user"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "USD", wallet.GetCurrency())
+	assert.NotEmpty(t, header.Get(requestIDKey))
+
+	got, err := c.GetWalletByUserId(ctx, &walletv1.GetWalletByUserIdRequest{UserId: "grpc-user"})
+	require.NoError(t, err)
+	assert.Equal(t, wallet.GetId(), got.GetId())
+
+	updated, err := c.UpdateWallet(ctx, &walletv1.UpdateWalletRequest{Id: wallet.GetId(), Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", updated.GetCurrency())
+
+	credit, err := c.CreditWallet(ctx, &walletv1.CreditWalletRequest{WalletId: wallet.GetId(), Amount: 25, Reference: "r1"})
+	require.NoError(t, err)
+	assert.Equal(t, walletv1.TransactionType_TRANSACTION_TYPE_CREDIT, credit.GetType())
+	assert.Equal(t, "r1", credit.GetReference())
+	assert.False(t, credit.GetCreatedAt().AsTime().IsZero())
+
+	debit, err := c.DebitWallet(ctx, &walletv1.DebitWalletRequest{WalletId: wallet.GetId(), Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, walletv1.TransactionType_TRANSACTION_TYPE_DEBIT, debit.GetType())
+
+	got, err = c.GetWallet(ctx, &walletv1.GetWalletRequest{Id: wallet.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, 15.0, got.GetBalance())
+
+	_, err = c.DeleteWallet(ctx, &walletv1.DeleteWalletRequest{Id: wallet.GetId()})
+	require.NoError(t, err)
+	_, err = c.GetWallet(ctx, &walletv1.GetWalletRequest{Id: wallet.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestErrorCodes(t *testing.T) {
+	c := walletv1.NewWalletServiceClient(newConn(t, health.NewRegistry(0)))
+	ctx := context.Background()
+	wallet, err := c.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: "grpc-errors"})
+	require.NoError(t, err)
+
+	_, err = c.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: "grpc-errors"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = c.DebitWallet(ctx, &walletv1.DebitWalletRequest{WalletId: wallet.GetId(), Amount: 1})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = c.CreditWallet(ctx, &walletv1.CreditWalletRequest{WalletId: uuid.NewString(), Amount: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = c.CreditWallet(ctx, &walletv1.CreditWalletRequest{WalletId: wallet.GetId(), Amount: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = c.GetWallet(ctx, &walletv1.GetWalletRequest{Id: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = c.CreateWallet(ctx, &walletv1.CreateWalletRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestToStatus(t *testing.T) {
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(toStatus(context.DeadlineExceeded)))
+	assert.Equal(t, codes.Canceled, status.Code(toStatus(context.Canceled)))
+	assert.Equal(t, codes.Aborted, status.Code(toStatus(models.ErrConflict)))
+
+	// Unmapped errors keep their cause for the log but not for the client
+	err := toStatus(errors.New("connection reset by peer"))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "internal error", status.Convert(err).Message())
+	assert.Equal(t, "connection reset by peer", err.Error())
+
+	// Statuses pass through untouched
+	assert.Equal(t, codes.Unavailable, status.Code(toStatus(status.Error(codes.Unavailable, "down"))))
+}
+
+func TestListTransactionsStreamsEveryPage(t *testing.T) {
+	c := walletv1.NewWalletServiceClient(newConn(t, health.NewRegistry(0)))
+	ctx := context.Background()
+	wallet, err := c.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: "grpc-history"})
+	require.NoError(t, err)
+
+	const n = 2*streamPageSize + 5
+	for i := 0; i < n; i++ {
+		_, err := c.CreditWallet(ctx, &walletv1.CreditWalletRequest{WalletId: wallet.GetId(), Amount: 1})
+		require.NoError(t, err)
+	}
+
+	collect := func(limit int32) []*walletv1.Transaction {
+		stream, err := c.ListTransactions(ctx, &walletv1.ListTransactionsRequest{WalletId: wallet.GetId(), Limit: limit})
+		require.NoError(t, err)
+		var txs []*walletv1.Transaction
+		for {
+			tx, err := stream.Recv()
+			if err == io.EOF {
+				return txs
+			}
+			require.NoError(t, err)
+			txs = append(txs, tx)
+		}
+	}
+
+	all := collect(0)
+	require.Len(t, all, n)
+	seen := make(map[string]bool, n)
+	for _, tx := range all {
+		seen[tx.GetId()] = true
+	}
+	assert.Len(t, seen, n, "no transaction is streamed twice")
+
+	assert.Len(t, collect(streamPageSize+1), streamPageSize+1)
+
+	stream, err := c.ListTransactions(ctx, &walletv1.ListTransactionsRequest{WalletId: uuid.NewString()})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestTransactionsCarryPromoAmount(t *testing.T) {
+	wallets := services.NewWalletService(repositories.NewMemoryWalletRepository())
+	c := walletv1.NewWalletServiceClient(serve(t, wallets, health.NewRegistry(0)))
+	ctx := context.Background()
+	wallet, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "grpc-promo"})
+	require.NoError(t, err)
+	_, err = wallets.CreditWallet(ctx, wallet.ID, models.TransactionRequest{Amount: 4, Bucket: models.PromoBucket})
+	require.NoError(t, err)
+
+	_, err = c.CreditWallet(ctx, &walletv1.CreditWalletRequest{WalletId: wallet.ID.String(), Amount: 10})
+	require.NoError(t, err)
+
+	// Promo credit is spent first
+	debit, err := c.DebitWallet(ctx, &walletv1.DebitWalletRequest{WalletId: wallet.ID.String(), Amount: 6})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, debit.GetPromoAmount())
+
+	stream, err := c.ListTransactions(ctx, &walletv1.ListTransactionsRequest{WalletId: wallet.ID.String()})
+	require.NoError(t, err)
+	var promo []float64
+	for {
+		tx, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		promo = append(promo, tx.GetPromoAmount())
+	}
+	assert.Equal(t, []float64{4, 0, 4}, promo)
+}
+
+func TestWalletsCarryBucketsAndOverdraft(t *testing.T) {
+	wallets := services.NewWalletService(repositories.NewMemoryWalletRepository())
+	c := walletv1.NewWalletServiceClient(serve(t, wallets, health.NewRegistry(0)))
+	ctx := context.Background()
+
+	limit := 20.0
+	wallet, err := c.CreateWallet(ctx, &walletv1.CreateWalletRequest{
+		UserId: "grpc-overdraft", DebitOrder: walletv1.DebitOrder_DEBIT_ORDER_CASH_FIRST, OverdraftLimit: &limit,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, walletv1.DebitOrder_DEBIT_ORDER_CASH_FIRST, wallet.GetDebitOrder())
+	assert.Equal(t, 20.0, wallet.GetOverdraft().GetLimit())
+	id := uuid.MustParse(wallet.GetId())
+	_, err = wallets.CreditWallet(ctx, id, models.TransactionRequest{Amount: 4, Bucket: models.PromoBucket})
+	require.NoError(t, err)
+	_, err = c.CreditWallet(ctx, &walletv1.CreditWalletRequest{WalletId: wallet.GetId(), Amount: 10})
+	require.NoError(t, err)
+
+	// Cash first, then promo credit, then the overdraft
+	_, err = c.DebitWallet(ctx, &walletv1.DebitWalletRequest{WalletId: wallet.GetId(), Amount: 17})
+	require.NoError(t, err)
+	got, err := c.GetWallet(ctx, &walletv1.GetWalletRequest{Id: wallet.GetId()})
+	require.NoError(t, err)
+	want, err := wallets.GetWallet(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, -3.0, got.GetBalance())
+	assert.Equal(t, want.Buckets.Cash, got.GetBuckets().GetCash())
+	assert.Equal(t, 0.0, got.GetBuckets().GetPromo())
+	assert.Equal(t, 3.0, got.GetOverdraft().GetUsed())
+
+	limit = 50
+	updated, err := c.UpdateWallet(ctx, &walletv1.UpdateWalletRequest{
+		Id: wallet.GetId(), UserId: "grpc-overdraft", DebitOrder: walletv1.DebitOrder_DEBIT_ORDER_PROMO_FIRST, OverdraftLimit: &limit,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, walletv1.DebitOrder_DEBIT_ORDER_PROMO_FIRST, updated.GetDebitOrder())
+	assert.Equal(t, 50.0, updated.GetOverdraft().GetLimit())
+	updated, err = c.UpdateWallet(ctx, &walletv1.UpdateWalletRequest{Id: wallet.GetId(), UserId: "grpc-overdraft"})
+	require.NoError(t, err)
+	assert.Equal(t, walletv1.DebitOrder_DEBIT_ORDER_PROMO_FIRST, updated.GetDebitOrder(), "leaving the order out keeps it")
+	assert.Equal(t, 50.0, updated.GetOverdraft().GetLimit(), "leaving the limit out keeps it")
+
+	limit = -1
+	_, err = c.UpdateWallet(ctx, &walletv1.UpdateWalletRequest{Id: wallet.GetId(), OverdraftLimit: &limit})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = c.CreateWallet(ctx, &walletv1.CreateWalletRequest{UserId: "grpc-order", DebitOrder: 7})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestHealth(t *testing.T) {
+	checks := health.NewRegistry(0)
+	c := healthpb.NewHealthClient(newConn(t, checks))
+	ctx := context.Background()
+
+	for _, service := range []string{"", "wallet.v1.WalletService"} {
+		resp, err := c.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	}
+
+	_, err := c.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown.v1.Service"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	checks.Register("database", health.CheckerFunc(func(context.Context) error {
+		return errors.New("connection refused")
+	}), true)
+	resp, err := c.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+}
+
+func TestReflection(t *testing.T) {
+	c := reflectionpb.NewServerReflectionClient(newConn(t, health.NewRegistry(0)))
+	stream, err := c.ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+
+	var names []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		names = append(names, s.GetName())
+	}
+	assert.Contains(t, names, "wallet.v1.WalletService")
+	assert.Contains(t, names, "grpc.health.v1.Health")
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+	"wallet-microservice/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BatchHandler struct {
+	batchService services.BatchService
+}
+
+func NewBatchHandler(batchService services.BatchService) *BatchHandler {
+	return &BatchHandler{
+		batchService: batchService,
+	}
+}
+
+// CreateBatch answers 201 with the results of a batch processed within the
+// request, or 202 with a Location to poll while it is processed in the
+// background
+func (h *BatchHandler) CreateBatch(c *gin.Context) {
+	var req models.BatchRequest
+	if err := bindJSON(c, &req); err != nil {
+		problem.Write(c, bindingProblem(err))
+		return
+	}
+
+	batch, err := h.batchService.CreateBatch(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if batch.Status == models.BatchPending || batch.Status == models.BatchProcessing {
+		status = http.StatusAccepted
+	}
+	c.Header("Location", "/api/v1/batches/"+batch.ID.String())
+	c.JSON(status, batch)
+}
+
+func (h *BatchHandler) GetBatch(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	batch, err := h.batchService.GetBatch(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *BatchHandler) RegisterRoutes(router *gin.Engine) {
+	batches := router.Group("/api/v1/batches")
+	batches.POST("", h.CreateBatch)
+	batches.GET("/:id", h.GetBatch)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+	"wallet-microservice/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BillHandler struct {
+	billService services.BillService
+}
+
+func NewBillHandler(billService services.BillService) *BillHandler {
+	return &BillHandler{
+		billService: billService,
+	}
+}
+
+// CreateBill splits a bill and settles the shares it can; the response
+// tells which are left pending and why
+func (h *BillHandler) CreateBill(c *gin.Context) {
+	var req models.CreateBillRequest
+	if err := bindJSON(c, &req); err != nil {
+		problem.Write(c, bindingProblem(err))
+		return
+	}
+
+	bill, err := h.billService.CreateBill(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/bills/"+bill.ID.String())
+	c.JSON(http.StatusCreated, bill)
+}
+
+func (h *BillHandler) GetBill(c *gin.Context) {
+	h.withBill(c, h.billService.GetBill)
+}
+
+func (h *BillHandler) SettleBill(c *gin.Context) {
+	h.withBill(c, h.billService.SettleBill)
+}
+
+// withBill answers with the bill that fn returns for the ID in the path
+func (h *BillHandler) withBill(c *gin.Context, fn func(context.Context, uuid.UUID) (*models.BillResponse, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	bill, err := fn(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bill)
+}
+
+// ListWalletBills lists the bills a wallet paid or has a share in
+func (h *BillHandler) ListWalletBills(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	bills, err := h.billService.ListBills(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bills": bills})
+}
+
+func (h *BillHandler) RegisterRoutes(router *gin.Engine) {
+	bills := router.Group("/api/v1/bills")
+	bills.POST("", h.CreateBill)
+	bills.GET("/:id", h.GetBill)
+	bills.POST("/:id/settle", h.SettleBill)
+
+	router.GET("/api/v1/wallets/:id/bills", h.ListWalletBills)
+}
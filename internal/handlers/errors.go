@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest is nginx's non-standard status for a client
+// that went away before the response; nobody reads it, it only shows up in
+// the access log
+const statusClientClosedRequest = 499
+
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// errorMappings translates domain errors to HTTP, first match wins
+var errorMappings = []errorMapping{
+	{models.ErrWalletNotFound, http.StatusNotFound, problem.CodeWalletNotFound},
+	{models.ErrInsufficientFunds, http.StatusUnprocessableEntity, problem.CodeInsufficientFunds},
+	{models.ErrWalletExists, http.StatusConflict, problem.CodeDuplicate},
+	{models.ErrDuplicate, http.StatusConflict, problem.CodeDuplicate},
+	{models.ErrConflict, http.StatusConflict, problem.CodeConflict},
+	{models.ErrInvalidAmount, http.StatusBadRequest, problem.CodeInvalidAmount},
+	{models.ErrBatchNotFound, http.StatusNotFound, problem.CodeBatchNotFound},
+	{models.ErrScheduleNotFound, http.StatusNotFound, problem.CodeScheduleNotFound},
+	{models.ErrEscrowNotFound, http.StatusNotFound, problem.CodeEscrowNotFound},
+	{models.ErrPaymentRequestNotFound, http.StatusNotFound, problem.CodeRequestNotFound},
+	{models.ErrBillNotFound, http.StatusNotFound, problem.CodeBillNotFound},
+	{models.ErrInterestAccountNotFound, http.StatusNotFound, problem.CodeNotEnrolled},
+	{models.ErrCurrencyMismatch, http.StatusUnprocessableEntity, problem.CodeCurrencyMismatch},
+	{models.ErrInvalidState, http.StatusConflict, problem.CodeInvalidState},
+	{models.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, problem.CodeIdempotencyReused},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, problem.CodeTimeout},
+	{context.Canceled, statusClientClosedRequest, problem.CodeTimeout},
+}
+
+// writeError responds with the status and code that errorMappings assigns
+// to err, or 400 with the violations of a *models.ValidationError. Any
+// other error is a 500 with a generic message: it may come straight from
+// the database driver, so it is only recorded on the context for the
+// access log.
+func writeError(c *gin.Context, err error) {
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		violations := make([]problem.Violation, len(validationErr.Violations))
+		for i, v := range validationErr.Violations {
+			violations[i] = problem.Violation{Field: v.Field, Message: v.Message}
+		}
+		problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeValidation, "", violations...))
+		return
+	}
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			problem.Write(c, problem.New(m.status, m.code, m.err.Error()))
+			return
+		}
+	}
+	c.Error(err)
+	problem.Write(c, problem.New(http.StatusInternalServerError, problem.CodeInternal, "internal error"))
+}
+
+// writeInvalidID rejects a wallet ID path parameter that is not a UUID
+func writeInvalidID(c *gin.Context) {
+	problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeInvalidID, "",
+		problem.Violation{Field: "id", Message: "must be a UUID"}))
+}
@@ -0,0 +1,146 @@
+//go:build unit
+// +build unit
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContext(req *http.Request) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = req
+	return c, rec
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) problem.Details {
+	t.Helper()
+	assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+	var p problem.Details
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	return p
+}
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{models.ErrWalletNotFound, http.StatusNotFound, problem.CodeWalletNotFound, "wallet not found"},
+		{fmt.Errorf("credit: %w", models.ErrWalletNotFound), http.StatusNotFound, problem.CodeWalletNotFound, "wallet not found"},
+		{models.ErrInsufficientFunds, http.StatusUnprocessableEntity, problem.CodeInsufficientFunds, "insufficient balance"},
+		{models.ErrWalletExists, http.StatusConflict, problem.CodeDuplicate, "wallet already exists for this user"},
+		{models.ErrDuplicate, http.StatusConflict, problem.CodeDuplicate, "resource already exists"},
+		{models.ErrConflict, http.StatusConflict, problem.CodeConflict, "conflicting concurrent update"},
+		{models.ErrInvalidAmount, http.StatusBadRequest, problem.CodeInvalidAmount, "amount must be positive"},
+		{models.ErrBatchNotFound, http.StatusNotFound, problem.CodeBatchNotFound, "batch not found"},
+		{models.ErrScheduleNotFound, http.StatusNotFound, problem.CodeScheduleNotFound, "schedule not found"},
+		{models.ErrEscrowNotFound, http.StatusNotFound, problem.CodeEscrowNotFound, "escrow not found"},
+		{models.ErrPaymentRequestNotFound, http.StatusNotFound, problem.CodeRequestNotFound, "payment request not found"},
+		{models.ErrBillNotFound, http.StatusNotFound, problem.CodeBillNotFound, "bill not found"},
+		{models.ErrInterestAccountNotFound, http.StatusNotFound, problem.CodeNotEnrolled, "wallet does not earn interest"},
+		{models.ErrCurrencyMismatch, http.StatusUnprocessableEntity, problem.CodeCurrencyMismatch, "wallets have different currencies"},
+		{models.ErrInvalidState, http.StatusConflict, problem.CodeInvalidState, "not allowed in the current state"},
+		{models.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, problem.CodeIdempotencyReused, "idempotency key was used for a different request"},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, problem.CodeTimeout, "context deadline exceeded"},
+		{errors.New(`ERROR: relation "wallets" does not exist (SQLSTATE 42P01)`), http.StatusInternalServerError, problem.CodeInternal, "internal error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			c, rec := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+			writeError(c, tt.err)
+
+			assert.Equal(t, tt.status, rec.Code)
+			p := decodeProblem(t, rec)
+			assert.Equal(t, tt.code, p.Code)
+			assert.Equal(t, "/problems/"+tt.code, p.Type)
+			assert.Equal(t, tt.status, p.Status)
+			assert.Equal(t, tt.message, p.Detail)
+		})
+	}
+}
+
+func TestWriteErrorKeepsUnknownErrorsForTheLog(t *testing.T) {
+	c, rec := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	writeError(c, errors.New("connection reset by peer"))
+	assert.Equal(t, "Error #01: connection reset by peer\n", c.Errors.String())
+	assert.NotContains(t, rec.Body.String(), "connection reset")
+}
+
+func TestWriteErrorReportsViolations(t *testing.T) {
+	c, rec := newTestContext(httptest.NewRequest(http.MethodPost, "/", nil))
+	writeError(c, fmt.Errorf("create batch: %w", &models.ValidationError{Violations: []models.Violation{
+		{Field: "items[1].wallet_id", Message: "does not match a wallet"},
+	}}))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	p := decodeProblem(t, rec)
+	assert.Equal(t, problem.CodeValidation, p.Code)
+	assert.Equal(t, "items[1].wallet_id does not match a wallet", p.Detail)
+	assert.Equal(t, []problem.Violation{{Field: "items[1].wallet_id", Message: "does not match a wallet"}}, p.Errors)
+}
+
+func TestBindingProblem(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		detail string
+		errors []problem.Violation
+	}{
+		{
+			name:   "missing field",
+			body:   `{}`,
+			detail: "amount is required",
+			errors: []problem.Violation{{Field: "amount", Message: "is required"}},
+		},
+		{
+			name:   "out of range",
+			body:   `{"amount":-5}`,
+			detail: "amount must be greater than 0",
+			errors: []problem.Violation{{Field: "amount", Message: "must be greater than 0"}},
+		},
+		{
+			name:   "wrong type",
+			body:   `{"amount":"ten"}`,
+			detail: "amount must be a number",
+			errors: []problem.Violation{{Field: "amount", Message: "must be a number"}},
+		},
+		{name: "malformed", body: `{"amount":`, detail: "request body is not valid JSON"},
+		{name: "empty", body: ``, detail: "request body is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			c, _ := newTestContext(req)
+
+			var body models.TransactionRequest
+			err := c.ShouldBindJSON(&body)
+			require.Error(t, err)
+
+			p := bindingProblem(err)
+			assert.Equal(t, http.StatusBadRequest, p.Status)
+			assert.Equal(t, problem.CodeValidation, p.Code)
+			assert.Equal(t, tt.detail, p.Detail)
+			assert.Equal(t, tt.errors, p.Errors)
+		})
+	}
+}
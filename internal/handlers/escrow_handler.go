@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+	"wallet-microservice/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type EscrowHandler struct {
+	escrowService services.EscrowService
+}
+
+func NewEscrowHandler(escrowService services.EscrowService) *EscrowHandler {
+	return &EscrowHandler{
+		escrowService: escrowService,
+	}
+}
+
+func (h *EscrowHandler) CreateEscrow(c *gin.Context) {
+	var req models.CreateEscrowRequest
+	if err := bindJSON(c, &req); err != nil {
+		problem.Write(c, bindingProblem(err))
+		return
+	}
+
+	escrow, err := h.escrowService.CreateEscrow(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/escrows/"+escrow.ID.String())
+	c.JSON(http.StatusCreated, escrow)
+}
+
+func (h *EscrowHandler) GetEscrow(c *gin.Context) {
+	h.withEscrow(c, h.escrowService.GetEscrow)
+}
+
+func (h *EscrowHandler) ReleaseEscrow(c *gin.Context) {
+	h.withEscrow(c, h.escrowService.ReleaseEscrow)
+}
+
+func (h *EscrowHandler) RefundEscrow(c *gin.Context) {
+	h.withEscrow(c, h.escrowService.RefundEscrow)
+}
+
+func (h *EscrowHandler) SplitEscrow(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	var req models.SplitEscrowRequest
+	if err := bindJSON(c, &req); err != nil {
+		problem.Write(c, bindingProblem(err))
+		return
+	}
+
+	escrow, err := h.escrowService.SplitEscrow(c.Request.Context(), id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, escrow)
+}
+
+// withEscrow answers with the escrow that fn returns for the ID in the path
+func (h *EscrowHandler) withEscrow(c *gin.Context, fn func(context.Context, uuid.UUID) (*models.EscrowResponse, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	escrow, err := fn(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, escrow)
+}
+
+// ListWalletEscrows lists the escrows a wallet buys or sells in
+func (h *EscrowHandler) ListWalletEscrows(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	escrows, err := h.escrowService.ListEscrows(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"escrows": escrows})
+}
+
+func (h *EscrowHandler) RegisterRoutes(router *gin.Engine) {
+	escrows := router.Group("/api/v1/escrows")
+	escrows.POST("", h.CreateEscrow)
+	escrows.GET("/:id", h.GetEscrow)
+	escrows.POST("/:id/release", h.ReleaseEscrow)
+	escrows.POST("/:id/refund", h.RefundEscrow)
+	escrows.POST("/:id/split", h.SplitEscrow)
+
+	router.GET("/api/v1/wallets/:id/escrows", h.ListWalletEscrows)
+}
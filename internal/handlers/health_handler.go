@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"wallet-microservice/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+	}
+}
+
+// Livez only reports that the process is able to serve HTTP. It must not
+// depend on the database, otherwise an outage would restart every pod.
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  health.StatusUp,
+		"service": "wallet-microservice",
+	})
+}
+
+// Readyz runs the registered dependency checks and returns 503 if any
+// critical check fails or the service is draining.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report, ready := h.registry.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+func (h *HealthHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/livez", h.Livez)
+	router.GET("/readyz", h.Readyz)
+	// Kept for existing monitors; reports readiness rather than a constant OK
+	router.GET("/health", h.Readyz)
+}
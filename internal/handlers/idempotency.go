@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader carries a client-chosen key identifying a write, so
+// that retrying it after a lost response does not apply it twice
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the size of the key column
+const maxIdempotencyKeyLength = 255
+
+// Idempotency answers a write that repeats the Idempotency-Key of an
+// earlier one with the stored response of the earlier one instead of
+// running it again. The first request with a key reserves it; a retry
+// arriving while it runs gets 409 conflict, and one for a different method,
+// path or body gets 422 idempotency_key_reused. Responses to server errors,
+// conflicts and throttling are not stored, so a retry of those runs anew.
+// A key whose request has not stored a response within lease, say because
+// the instance running it died, is given to the next retry of the same
+// request. Writes without the header, and reads, pass through.
+func Idempotency(store repositories.IdempotencyRepository, lease time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeValidation, "",
+				problem.Violation{Field: IdempotencyKeyHeader, Message: "must be at most 255 characters"}))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeValidation, "request body could not be read"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		fingerprint := requestFingerprint(c.Request, body)
+		existing, err := store.Reserve(ctx, key, fingerprint, time.Now(), lease)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				writeError(c, models.ErrIdempotencyKeyReused)
+			case !existing.Completed():
+				writeError(c, models.ErrConflict)
+			default:
+				replay(c, existing)
+			}
+			return
+		}
+
+		defer func() {
+			// A handler that panicked has not answered; let a retry try again
+			if r := recover(); r != nil {
+				store.Release(context.WithoutCancel(ctx), key)
+				panic(r)
+			}
+		}()
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		// The request is done with, whether or not the client is still there
+		ctx = context.WithoutCancel(ctx)
+		status := w.Status()
+		if !storable(status) {
+			if err := store.Release(ctx, key); err != nil {
+				slog.ErrorContext(ctx, "Could not release idempotency key", "error", err)
+			}
+			return
+		}
+		err = store.Complete(ctx, key, models.IdempotencyKey{
+			StatusCode:  status,
+			ContentType: w.Header().Get("Content-Type"),
+			Location:    w.Header().Get("Location"),
+			Body:        w.body.Bytes(),
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Could not store idempotent response", "error", err)
+		}
+	}
+}
+
+// storable reports whether a response is final for its request. Anything
+// else may change on a retry, and the key is released for it.
+func storable(status int) bool {
+	switch status {
+	case http.StatusConflict, http.StatusTooManyRequests, statusClientClosedRequest:
+		return false
+	}
+	return status < http.StatusInternalServerError
+}
+
+// requestFingerprint identifies a request by its method, target and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay responds with the stored response of an earlier request in place
+// of the handler
+func replay(c *gin.Context, stored *models.IdempotencyKey) {
+	c.Abort()
+	if stored.Location != "" {
+		c.Header("Location", stored.Location)
+	}
+	if stored.ContentType == "" {
+		c.Status(stored.StatusCode)
+		c.Writer.Write(stored.Body)
+		return
+	}
+	c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+}
+
+// recordingWriter copies the body of a response while it is written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
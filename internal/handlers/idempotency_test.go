@@ -0,0 +1,117 @@
+//go:build unit
+// +build unit
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := repositories.NewMemoryIdempotencyRepository()
+	router := gin.New()
+	router.Use(Idempotency(store, time.Minute))
+	var calls int
+	status := http.StatusCreated
+	router.POST("/things", func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/things/1")
+		c.JSON(status, gin.H{"call": calls})
+	})
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send("k1", `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	retry := send("k1", `{"a":1}`)
+	assert.Equal(t, 1, calls, "a retry is answered without running the handler")
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "/things/1", retry.Header().Get("Location"))
+	assert.Equal(t, first.Header().Get("Content-Type"), retry.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+
+	reused := send("k1", `{"a":2}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Equal(t, problem.CodeIdempotencyReused, decodeProblem(t, reused).Code)
+
+	send("", `{"a":1}`)
+	send("", `{"a":1}`)
+	assert.Equal(t, 3, calls, "writes without a key are not deduplicated")
+
+	// A server error releases the key for the retry
+	status = http.StatusInternalServerError
+	assert.Equal(t, http.StatusInternalServerError, send("k2", `{}`).Code)
+	status = http.StatusCreated
+	assert.Equal(t, http.StatusCreated, send("k2", `{}`).Code)
+	assert.Equal(t, 5, calls)
+
+	// A retry arriving while the first request runs is told to try again
+	_, err := store.Reserve(context.Background(), "k3", requestFingerprint(httptest.NewRequest(http.MethodPost, "/things", nil), []byte(`{}`)), time.Now(), time.Minute)
+	require.NoError(t, err)
+	inProgress := send("k3", `{}`)
+	assert.Equal(t, http.StatusConflict, inProgress.Code)
+	assert.Equal(t, problem.CodeConflict, decodeProblem(t, inProgress).Code)
+	assert.Equal(t, 5, calls)
+
+	tooLong := send(strings.Repeat("k", maxIdempotencyKeyLength+1), `{}`)
+	assert.Equal(t, http.StatusBadRequest, tooLong.Code)
+}
+
+// dyingStore never stores a response, as if the instance running the
+// request died between the handler and Complete
+type dyingStore struct {
+	repositories.IdempotencyRepository
+}
+
+func (dyingStore) Complete(context.Context, string, models.IdempotencyKey) error {
+	return errors.New("instance died")
+}
+
+func TestIdempotencyLeaseLapses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const lease = 20 * time.Millisecond
+	router := gin.New()
+	router.Use(Idempotency(dyingStore{repositories.NewMemoryIdempotencyRepository()}, lease))
+	var calls int
+	router.POST("/things", func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"call": calls})
+	})
+	send := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, send(`{}`))
+	assert.Equal(t, http.StatusConflict, send(`{}`), "the key is held while the lease runs")
+
+	time.Sleep(lease)
+	assert.Equal(t, http.StatusUnprocessableEntity, send(`{"a":1}`), "another request cannot take the key over")
+	assert.Equal(t, http.StatusCreated, send(`{}`), "a retry takes the key over")
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type InterestHandler struct {
+	interestService services.InterestService
+}
+
+func NewInterestHandler(interestService services.InterestService) *InterestHandler {
+	return &InterestHandler{
+		interestService: interestService,
+	}
+}
+
+// EnrollWallet starts a wallet earning interest; it is idempotent
+func (h *InterestHandler) EnrollWallet(c *gin.Context) {
+	h.withAccount(c, h.interestService.EnrollWallet)
+}
+
+func (h *InterestHandler) GetInterest(c *gin.Context) {
+	h.withAccount(c, h.interestService.GetInterest)
+}
+
+// withAccount answers with the interest account that fn returns for the
+// wallet ID in the path
+func (h *InterestHandler) withAccount(c *gin.Context, fn func(context.Context, uuid.UUID) (*models.InterestAccountResponse, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	account, err := fn(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *InterestHandler) GetAccruals(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	accruals, err := h.interestService.GetAccruals(c.Request.Context(), id, page, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accruals": accruals,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+func (h *InterestHandler) RegisterRoutes(router *gin.Engine) {
+	wallets := router.Group("/api/v1/wallets/:id/interest")
+	wallets.PUT("", h.EnrollWallet)
+	wallets.GET("", h.GetInterest)
+	wallets.GET("/accruals", h.GetAccruals)
+}
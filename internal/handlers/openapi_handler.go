@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIHandler serves the API specification so that clients and tools
+// can discover the routes and payload shapes
+type OpenAPIHandler struct {
+	spec []byte
+}
+
+func NewOpenAPIHandler(doc *openapi3.T) (*OpenAPIHandler, error) {
+	spec, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{spec: spec}, nil
+}
+
+func (h *OpenAPIHandler) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+func (h *OpenAPIHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/openapi.json", h.Spec)
+}
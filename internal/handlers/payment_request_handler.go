@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+	"wallet-microservice/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PaymentRequestHandler struct {
+	requestService services.PaymentRequestService
+}
+
+func NewPaymentRequestHandler(requestService services.PaymentRequestService) *PaymentRequestHandler {
+	return &PaymentRequestHandler{
+		requestService: requestService,
+	}
+}
+
+func (h *PaymentRequestHandler) CreatePaymentRequest(c *gin.Context) {
+	var req models.CreatePaymentRequestRequest
+	if err := bindJSON(c, &req); err != nil {
+		problem.Write(c, bindingProblem(err))
+		return
+	}
+
+	request, err := h.requestService.CreatePaymentRequest(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/payment-requests/"+request.ID.String())
+	c.JSON(http.StatusCreated, request)
+}
+
+func (h *PaymentRequestHandler) GetPaymentRequest(c *gin.Context) {
+	h.withRequest(c, h.requestService.GetPaymentRequest)
+}
+
+func (h *PaymentRequestHandler) PayPaymentRequest(c *gin.Context) {
+	h.withRequest(c, h.requestService.PayPaymentRequest)
+}
+
+func (h *PaymentRequestHandler) DeclinePaymentRequest(c *gin.Context) {
+	h.withRequest(c, h.requestService.DeclinePaymentRequest)
+}
+
+func (h *PaymentRequestHandler) CancelPaymentRequest(c *gin.Context) {
+	h.withRequest(c, h.requestService.CancelPaymentRequest)
+}
+
+// withRequest answers with the payment request that fn returns for the ID
+// in the path
+func (h *PaymentRequestHandler) withRequest(c *gin.Context, fn func(context.Context, uuid.UUID) (*models.PaymentRequestResponse, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	request, err := fn(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// ListInbox lists the requests a user is asked to pay
+func (h *PaymentRequestHandler) ListInbox(c *gin.Context) {
+	h.list(c, h.requestService.ListInbox)
+}
+
+// ListOutbox lists the requests a user has made
+func (h *PaymentRequestHandler) ListOutbox(c *gin.Context) {
+	h.list(c, h.requestService.ListOutbox)
+}
+
+func (h *PaymentRequestHandler) list(c *gin.Context, fn func(context.Context, string, models.PaymentRequestStatus, int, int) ([]models.PaymentRequestResponse, error)) {
+	userID := c.Param("userId")
+
+	status := models.PaymentRequestStatus(c.Query("status"))
+	switch status {
+	case "", models.PaymentRequestPending, models.PaymentRequestPaid, models.PaymentRequestDeclined,
+		models.PaymentRequestExpired, models.PaymentRequestCancelled:
+	default:
+		problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeInvalidParameter, "",
+			problem.Violation{Field: "status", Message: "must be one of pending, paid, declined, expired, cancelled"}))
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	requests, err := fn(c.Request.Context(), userID, status, page, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payment_requests": requests,
+		"page":             page,
+		"limit":            limit,
+	})
+}
+
+func (h *PaymentRequestHandler) RegisterRoutes(router *gin.Engine) {
+	requests := router.Group("/api/v1/payment-requests")
+	requests.POST("", h.CreatePaymentRequest)
+	requests.GET("/:id", h.GetPaymentRequest)
+	requests.POST("/:id/pay", h.PayPaymentRequest)
+	requests.POST("/:id/decline", h.DeclinePaymentRequest)
+	requests.POST("/:id/cancel", h.CancelPaymentRequest)
+
+	users := router.Group("/api/v1/users/:userId/payment-requests")
+	users.GET("/inbox", h.ListInbox)
+	users.GET("/outbox", h.ListOutbox)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/problem"
+	"wallet-microservice/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ScheduleHandler struct {
+	scheduleService services.ScheduleService
+}
+
+func NewScheduleHandler(scheduleService services.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+	}
+}
+
+func (h *ScheduleHandler) CreateSchedule(c *gin.Context) {
+	var req models.CreateScheduleRequest
+	if err := bindJSON(c, &req); err != nil {
+		problem.Write(c, bindingProblem(err))
+		return
+	}
+
+	schedule, err := h.scheduleService.CreateSchedule(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Header("Location", "/api/v1/schedules/"+schedule.ID.String())
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	h.withSchedule(c, h.scheduleService.GetSchedule)
+}
+
+func (h *ScheduleHandler) PauseSchedule(c *gin.Context) {
+	h.withSchedule(c, h.scheduleService.PauseSchedule)
+}
+
+func (h *ScheduleHandler) ResumeSchedule(c *gin.Context) {
+	h.withSchedule(c, h.scheduleService.ResumeSchedule)
+}
+
+func (h *ScheduleHandler) CancelSchedule(c *gin.Context) {
+	h.withSchedule(c, h.scheduleService.CancelSchedule)
+}
+
+// withSchedule answers with the schedule that fn returns for the ID in the
+// path
+func (h *ScheduleHandler) withSchedule(c *gin.Context, fn func(context.Context, uuid.UUID) (*models.ScheduleResponse, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	schedule, err := fn(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *ScheduleHandler) GetExecutions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	executions, err := h.scheduleService.GetExecutions(c.Request.Context(), id, page, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"executions": executions,
+		"page":       page,
+		"limit":      limit,
+	})
+}
+
+// ListWalletSchedules lists the schedules paying from or to a wallet
+func (h *ScheduleHandler) ListWalletSchedules(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeInvalidID(c)
+		return
+	}
+
+	schedules, err := h.scheduleService.ListSchedules(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+func (h *ScheduleHandler) RegisterRoutes(router *gin.Engine) {
+	schedules := router.Group("/api/v1/schedules")
+	schedules.POST("", h.CreateSchedule)
+	schedules.GET("/:id", h.GetSchedule)
+	schedules.POST("/:id/pause", h.PauseSchedule)
+	schedules.POST("/:id/resume", h.ResumeSchedule)
+	schedules.POST("/:id/cancel", h.CancelSchedule)
+	schedules.GET("/:id/executions", h.GetExecutions)
+
+	router.GET("/api/v1/wallets/:id/schedules", h.ListWalletSchedules)
+}
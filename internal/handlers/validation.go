@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"wallet-microservice/internal/problem"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report fields by their JSON names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// bindingProblem describes why a request body could not be bound, with a
+// violation per invalid field
+func bindingProblem(err error) *problem.Details {
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+		syntaxErr      *json.SyntaxError
+	)
+	switch {
+	case errors.As(err, &validationErrs):
+		violations := make([]problem.Violation, len(validationErrs))
+		for i, fe := range validationErrs {
+			violations[i] = problem.Violation{Field: fieldPath(fe), Message: violationMessage(fe)}
+		}
+		return problem.New(http.StatusBadRequest, problem.CodeValidation, "", violations...)
+	case errors.As(err, &typeErr):
+		return problem.New(http.StatusBadRequest, problem.CodeValidation, "",
+			problem.Violation{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return problem.New(http.StatusBadRequest, problem.CodeValidation, "request body is not valid JSON")
+	case errors.Is(err, io.EOF):
+		return problem.New(http.StatusBadRequest, problem.CodeValidation, "request body is empty")
+	}
+	return problem.New(http.StatusBadRequest, problem.CodeValidation, "request body is invalid")
+}
+
+// fieldPath drops the struct name from the validator's namespace, leaving
+// the dotted JSON path of the field
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+var sizeBounds = map[string]string{"min": "at least", "max": "at most", "len": "exactly"}
+
+func violationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "min", "max", "len":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must have %s %s characters", sizeBounds[fe.Tag()], fe.Param())
+		}
+		return fmt.Sprintf("must be %s %s", sizeBounds[fe.Tag()], fe.Param())
+	case "uuid":
+		return "must be a UUID"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	}
+	return "is invalid (" + fe.Tag() + ")"
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
@@ -3,13 +3,20 @@ package handlers
 import (
     "net/http"
     "strconv"
+    "wallet-microservice/internal/archive"
+    "wallet-microservice/internal/consistency"
     "wallet-microservice/internal/models"
+    "wallet-microservice/internal/problem"
     "wallet-microservice/internal/services"
+    "wallet-microservice/internal/tracing"
     
     "github.com/gin-gonic/gin"
     "github.com/google/uuid"
+    "go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("wallet-microservice/internal/handlers")
+
 type WalletHandler struct {
     walletService services.WalletService
 }
@@ -22,20 +29,14 @@ func NewWalletHandler(walletService services.WalletService) *WalletHandler {
 
 func (h *WalletHandler) CreateWallet(c *gin.Context) {
     var req models.CreateWalletRequest
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "validation_error",
-            Message: err.Error(),
-        })
+    if err := bindJSON(c, &req); err != nil {
+        problem.Write(c, bindingProblem(err))
         return
     }
     
-    wallet, err := h.walletService.CreateWallet(req)
+    wallet, err := h.walletService.CreateWallet(c.Request.Context(), req)
     if err != nil {
-        c.JSON(http.StatusConflict, models.ErrorResponse{
-            Error:   "creation_failed",
-            Message: err.Error(),
-        })
+        writeError(c, err)
         return
     }
     
@@ -46,19 +47,13 @@ func (h *WalletHandler) GetWallet(c *gin.Context) {
     idStr := c.Param("id")
     id, err := uuid.Parse(idStr)
     if err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "invalid_id",
-            Message: "Invalid wallet ID format",
-        })
+        writeInvalidID(c)
         return
     }
     
-    wallet, err := h.walletService.GetWallet(id)
+    wallet, err := h.walletService.GetWallet(c.Request.Context(), id)
     if err != nil {
-        c.JSON(http.StatusNotFound, models.ErrorResponse{
-            Error:   "not_found",
-            Message: err.Error(),
-        })
+        writeError(c, err)
         return
     }
     
@@ -68,19 +63,14 @@ func (h *WalletHandler) GetWallet(c *gin.Context) {
 func (h *WalletHandler) GetWalletByUserID(c *gin.Context) {
     userID := c.Param("userId")
     if userID == "" {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "invalid_user_id",
-            Message: "User ID is required",
-        })
+        problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeInvalidUserID, "",
+            problem.Violation{Field: "userId", Message: "is required"}))
         return
     }
     
-    wallet, err := h.walletService.GetWalletByUserID(userID)
+    wallet, err := h.walletService.GetWalletByUserID(c.Request.Context(), userID)
     if err != nil {
-        c.JSON(http.StatusNotFound, models.ErrorResponse{
-            Error:   "not_found",
-            Message: err.Error(),
-        })
+        writeError(c, err)
         return
     }
     
@@ -91,28 +81,19 @@ func (h *WalletHandler) UpdateWallet(c *gin.Context) {
     idStr := c.Param("id")
     id, err := uuid.Parse(idStr)
     if err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "invalid_id",
-            Message: "Invalid wallet ID format",
-        })
+        writeInvalidID(c)
         return
     }
     
     var req models.CreateWalletRequest
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "validation_error",
-            Message: err.Error(),
-        })
+    if err := bindJSON(c, &req); err != nil {
+        problem.Write(c, bindingProblem(err))
         return
     }
     
-    wallet, err := h.walletService.UpdateWallet(id, req)
+    wallet, err := h.walletService.UpdateWallet(c.Request.Context(), id, req)
     if err != nil {
-        c.JSON(http.StatusNotFound, models.ErrorResponse{
-            Error:   "update_failed",
-            Message: err.Error(),
-        })
+        writeError(c, err)
         return
     }
     
@@ -123,19 +104,13 @@ func (h *WalletHandler) DeleteWallet(c *gin.Context) {
     idStr := c.Param("id")
     id, err := uuid.Parse(idStr)
     if err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "invalid_id",
-            Message: "Invalid wallet ID format",
-        })
+        writeInvalidID(c)
         return
     }
     
-    err = h.walletService.DeleteWallet(id)
+    err = h.walletService.DeleteWallet(c.Request.Context(), id)
     if err != nil {
-        c.JSON(http.StatusNotFound, models.ErrorResponse{
-            Error:   "deletion_failed",
-            Message: err.Error(),
-        })
+        writeError(c, err)
         return
     }
     
@@ -146,28 +121,19 @@ func (h *WalletHandler) CreditWallet(c *gin.Context) {
     idStr := c.Param("id")
     id, err := uuid.Parse(idStr)
     if err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "invalid_id",
-            Message: "Invalid wallet ID format",
-        })
+        writeInvalidID(c)
         return
     }
     
     var req models.TransactionRequest
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "validation_error",
-            Message: err.Error(),
-        })
+    if err := bindJSON(c, &req); err != nil {
+        problem.Write(c, bindingProblem(err))
         return
     }
     
-    transaction, err := h.walletService.CreditWallet(id, req)
+    transaction, err := h.walletService.CreditWallet(c.Request.Context(), id, req)
     if err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "credit_failed",
-            Message: err.Error(),
-        })
+        writeError(c, err)
         return
     }
     
@@ -178,28 +144,19 @@ func (h *WalletHandler) DebitWallet(c *gin.Context) {
     idStr := c.Param("id")
     id, err := uuid.Parse(idStr)
     if err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "invalid_id",
-            Message: "Invalid wallet ID format",
-        })
+        writeInvalidID(c)
         return
     }
     
     var req models.TransactionRequest
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "validation_error",
-            Message: err.Error(),
-        })
+    if err := bindJSON(c, &req); err != nil {
+        problem.Write(c, bindingProblem(err))
         return
     }
     
-    transaction, err := h.walletService.DebitWallet(id, req)
+    transaction, err := h.walletService.DebitWallet(c.Request.Context(), id, req)
     if err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "debit_failed",
-            Message: err.Error(),
-        })
+        writeError(c, err)
         return
     }
     
@@ -210,10 +167,7 @@ func (h *WalletHandler) GetTransactionHistory(c *gin.Context) {
     idStr := c.Param("id")
     id, err := uuid.Parse(idStr)
     if err != nil {
-        c.JSON(http.StatusBadRequest, models.ErrorResponse{
-            Error:   "invalid_id",
-            Message: "Invalid wallet ID format",
-        })
+        writeInvalidID(c)
         return
     }
     
@@ -232,14 +186,25 @@ func (h *WalletHandler) GetTransactionHistory(c *gin.Context) {
         }
     }
     
-    transactions, err := h.walletService.GetTransactionHistory(id, page, limit)
+    ctx, reads := consistency.Track(c.Request.Context())
+    // include_archived overrides whether the history continues into
+    // archived months
+    if v := c.Query("include_archived"); v != "" {
+        include, err := strconv.ParseBool(v)
+        if err != nil {
+            problem.Write(c, problem.New(http.StatusBadRequest, problem.CodeInvalidParameter, "",
+                problem.Violation{Field: "include_archived", Message: "must be true or false"}))
+            return
+        }
+        ctx = archive.IncludeArchived(ctx, include)
+    }
+    
+    transactions, err := h.walletService.GetTransactionHistory(ctx, id, page, limit)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-            Error:   "fetch_failed",
-            Message: err.Error(),
-        })
+        writeError(c, err)
         return
     }
+    setMaxStaleness(c, reads)
     
     c.JSON(http.StatusOK, gin.H{
         "transactions": transactions,
@@ -248,6 +213,26 @@ func (h *WalletHandler) GetTransactionHistory(c *gin.Context) {
     })
 }
 
+// MaxStalenessHeader tells clients how far, in seconds, the data in a
+// response may lag behind the latest committed state. Zero means it was
+// read from the primary.
+const MaxStalenessHeader = "X-Max-Staleness"
+
+func setMaxStaleness(c *gin.Context, reads *consistency.Tracker) {
+    if staleness, ok := reads.MaxStaleness(); ok {
+        c.Header(MaxStalenessHeader, strconv.FormatFloat(staleness.Seconds(), 'f', 3, 64))
+    }
+}
+
+// bindJSON wraps ShouldBindJSON in its own span so that time spent decoding
+// and validating the payload is visible separately from the service call.
+func bindJSON(c *gin.Context, obj any) (err error) {
+    _, span := tracer.Start(c.Request.Context(), "gin.bind")
+    defer tracing.End(span, &err)
+    
+    return c.ShouldBindJSON(obj)
+}
+
 func (h *WalletHandler) RegisterRoutes(router *gin.Engine) {
     api := router.Group("/api/v1")
     {
@@ -0,0 +1,141 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Checker reports whether a dependency is usable.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a plain function to the Checker interface
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDraining = "draining"
+)
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type registration struct {
+	checker  Checker
+	critical bool
+}
+
+// Registry holds the dependency checks that make up readiness. Critical
+// checks fail readiness when they fail; non-critical ones are reported
+// but don't take the pod out of rotation.
+type Registry struct {
+	mu       sync.RWMutex
+	checks   map[string]registration
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		checks:  make(map[string]registration),
+		timeout: timeout,
+	}
+}
+
+// Register adds or replaces a named check
+func (r *Registry) Register(name string, checker Checker, critical bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = registration{checker: checker, critical: critical}
+}
+
+// Drain makes readiness fail from now on so that load balancers stop
+// sending new traffic before the server shuts down.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+func (r *Registry) Draining() bool {
+	return r.draining.Load()
+}
+
+// Check runs all registered checks concurrently, each bounded by the
+// registry timeout, and reports whether the service is ready.
+func (r *Registry) Check(ctx context.Context) (Report, bool) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	regs := make([]registration, len(names))
+	for i, name := range names {
+		regs[i] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = r.run(ctx, regs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]CheckResult, len(names))}
+	ready := true
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusUp && results[i].Critical {
+			ready = false
+			report.Status = StatusDown
+		}
+	}
+	if r.Draining() {
+		ready = false
+		report.Status = StatusDraining
+	}
+	return report, ready
+}
+
+func (r *Registry) run(ctx context.Context, reg registration) CheckResult {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := reg.checker.Check(ctx)
+	res := CheckResult{
+		Status:   StatusUp,
+		Critical: reg.critical,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,68 @@
+//go:build unit
+// +build unit
+
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func up(context.Context) error { return nil }
+
+func TestRegistryCheck(t *testing.T) {
+	t.Run("ready when all checks pass", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register("database", CheckerFunc(up), true)
+
+		report, ready := r.Check(context.Background())
+		assert.True(t, ready)
+		assert.Equal(t, StatusUp, report.Status)
+		assert.Equal(t, StatusUp, report.Checks["database"].Status)
+	})
+
+	t.Run("critical failure fails readiness", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register("database", CheckerFunc(func(context.Context) error { return errors.New("connection refused") }), true)
+
+		report, ready := r.Check(context.Background())
+		assert.False(t, ready)
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	})
+
+	t.Run("non-critical failure is reported only", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register("database", CheckerFunc(up), true)
+		r.Register("fx-rates", CheckerFunc(func(context.Context) error { return errors.New("timeout") }), false)
+
+		report, ready := r.Check(context.Background())
+		assert.True(t, ready)
+		assert.Equal(t, StatusDown, report.Checks["fx-rates"].Status)
+	})
+
+	t.Run("slow check is bounded by timeout", func(t *testing.T) {
+		r := NewRegistry(20 * time.Millisecond)
+		r.Register("slow", CheckerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}), true)
+
+		_, ready := r.Check(context.Background())
+		assert.False(t, ready)
+	})
+
+	t.Run("draining fails readiness", func(t *testing.T) {
+		r := NewRegistry(time.Second)
+		r.Register("database", CheckerFunc(up), true)
+		r.Drain()
+
+		report, ready := r.Check(context.Background())
+		assert.False(t, ready)
+		assert.Equal(t, StatusDraining, report.Status)
+	})
+}
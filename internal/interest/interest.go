@@ -0,0 +1,116 @@
+// Package interest holds the terms on which savings wallets earn interest:
+// a schedule of annual rates, a day-count convention and a payout
+// frequency. Dates are midnight UTC.
+package interest
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"wallet-microservice/internal/config"
+)
+
+type DayCount string
+
+const (
+	// Act365 divides the annual rate over 365 days, in leap years too
+	Act365 DayCount = "ACT/365"
+	// Act360 divides the annual rate over 360 days
+	Act360 DayCount = "ACT/360"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+)
+
+// Rate is an annual rate in force from a date until the next rate's
+type Rate struct {
+	From   time.Time
+	Annual float64
+}
+
+type Terms struct {
+	// Rates is sorted by From
+	Rates           []Rate
+	DayCount        DayCount
+	PayoutFrequency Frequency
+}
+
+// NewTerms builds the terms from validated configuration
+func NewTerms(cfg config.InterestConfig) (*Terms, error) {
+	t := &Terms{
+		DayCount:        DayCount(cfg.DayCount),
+		PayoutFrequency: Frequency(cfg.PayoutFrequency),
+	}
+	for from, annual := range cfg.Rates {
+		date, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			return nil, fmt.Errorf("rate from %q: %w", from, err)
+		}
+		t.Rates = append(t.Rates, Rate{From: date, Annual: annual})
+	}
+	slices.SortFunc(t.Rates, func(a, b Rate) int { return a.From.Compare(b.From) })
+	return t, nil
+}
+
+// RateOn returns the annual rate in force on date, zero before the first
+// rate
+func (t *Terms) RateOn(date time.Time) float64 {
+	rate := 0.0
+	for _, r := range t.Rates {
+		if r.From.After(date) {
+			break
+		}
+		rate = r.Annual
+	}
+	return rate
+}
+
+// DailyInterest returns the interest on balance for date, rounded to 8
+// decimal places. Negative balances earn nothing.
+func (t *Terms) DailyInterest(balance float64, date time.Time) float64 {
+	if balance <= 0 {
+		return 0
+	}
+	days := 365.0
+	if t.DayCount == Act360 {
+		days = 360
+	}
+	return Round(balance * t.RateOn(date) / days)
+}
+
+// IsPeriodEnd reports whether interest is paid out after date
+func (t *Terms) IsPeriodEnd(date time.Time) bool {
+	switch t.PayoutFrequency {
+	case Daily:
+		return true
+	case Weekly:
+		return date.Weekday() == time.Sunday
+	default:
+		return date.AddDate(0, 0, 1).Day() == 1
+	}
+}
+
+// Payable returns the whole cents of accrued; the rest stays accrued
+func Payable(accrued float64) float64 {
+	// Accrued has 8 decimal places, i.e. steps of 1e-6 cents; the smaller
+	// epsilon only keeps binary rounding from losing a whole cent
+	return math.Floor(Round(accrued)*100+1e-7) / 100
+}
+
+// Round rounds to the 8 decimal places interest is kept to
+func Round(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
+
+// Date returns the date of t in UTC, as midnight
+func Date(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
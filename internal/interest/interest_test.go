@@ -0,0 +1,74 @@
+//go:build unit
+// +build unit
+
+package interest
+
+import (
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRateOn(t *testing.T) {
+	terms, err := NewTerms(config.InterestConfig{
+		Rates:    map[string]float64{"2026-07-01": 0.03, "2026-01-01": 0.02},
+		DayCount: "ACT/365",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 0.0, terms.RateOn(day("2025-12-31")))
+	assert.Equal(t, 0.02, terms.RateOn(day("2026-01-01")))
+	assert.Equal(t, 0.02, terms.RateOn(day("2026-06-30")))
+	assert.Equal(t, 0.03, terms.RateOn(day("2026-07-01")))
+	assert.Equal(t, 0.03, terms.RateOn(day("2030-01-01")))
+}
+
+func TestDailyInterest(t *testing.T) {
+	terms := &Terms{Rates: []Rate{{From: day("2026-01-01"), Annual: 0.05}}, DayCount: Act365}
+	assert.Equal(t, 0.13698630, terms.DailyInterest(1000, day("2026-03-01")))
+	// Leap days do not change the ACT/365 divisor
+	assert.Equal(t, 0.13698630, terms.DailyInterest(1000, day("2028-02-29")))
+	assert.Equal(t, 0.0, terms.DailyInterest(-1000, day("2026-03-01")))
+	assert.Equal(t, 0.0, terms.DailyInterest(1000, day("2025-03-01")))
+
+	terms.DayCount = Act360
+	assert.Equal(t, 0.13888889, terms.DailyInterest(1000, day("2026-03-01")))
+}
+
+func TestIsPeriodEnd(t *testing.T) {
+	terms := &Terms{PayoutFrequency: Monthly}
+	assert.True(t, terms.IsPeriodEnd(day("2026-02-28")))
+	assert.False(t, terms.IsPeriodEnd(day("2028-02-28")))
+	assert.True(t, terms.IsPeriodEnd(day("2026-12-31")))
+
+	terms.PayoutFrequency = Weekly
+	assert.True(t, terms.IsPeriodEnd(day("2026-10-18")))
+	assert.False(t, terms.IsPeriodEnd(day("2026-10-19")))
+
+	terms.PayoutFrequency = Daily
+	assert.True(t, terms.IsPeriodEnd(day("2026-10-19")))
+}
+
+func TestPayable(t *testing.T) {
+	assert.Equal(t, 0.29, Payable(0.29999999))
+	assert.Equal(t, 0.3, Payable(0.3))
+	assert.Equal(t, 0.0, Payable(0.00999999))
+	assert.Equal(t, 4.1, Payable(0.13698630*30))
+}
+
+func TestDate(t *testing.T) {
+	at := time.Date(2026, 10, 18, 23, 30, 0, 0, time.FixedZone("", -2*3600))
+	assert.Equal(t, day("2026-10-19"), Date(at))
+}
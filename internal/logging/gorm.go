@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger adapts slog to gorm's logger interface. Failed statements are
+// logged at error, statements slower than SlowThreshold at warn and
+// everything else at debug.
+type GormLogger struct {
+	Logger        *slog.Logger
+	SlowThreshold time.Duration
+	// LogParams controls whether bound values are interpolated into the
+	// logged SQL. When false the statement is logged with placeholders so
+	// user IDs, references and descriptions never reach the logs.
+	LogParams bool
+	level     gormlogger.LogLevel
+}
+
+func NewGormLogger(logger *slog.Logger, slowThreshold time.Duration, logParams bool) *GormLogger {
+	return &GormLogger{
+		Logger:        logger,
+		SlowThreshold: slowThreshold,
+		LogParams:     logParams,
+		level:         gormlogger.Info,
+	}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.Logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.Logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.Logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	attrs := func() []slog.Attr {
+		sql, rows := fc()
+		return []slog.Attr{
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+			slog.Float64("elapsed_ms", float64(elapsed.Microseconds())/1000),
+		}
+	}
+
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		l.Logger.LogAttrs(ctx, slog.LevelError, "query failed", append(attrs(), slog.String("error", err.Error()))...)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.level >= gormlogger.Warn:
+		l.Logger.LogAttrs(ctx, slog.LevelWarn, "slow query", append(attrs(), slog.Duration("threshold", l.SlowThreshold))...)
+	case l.level >= gormlogger.Info && l.Logger.Enabled(ctx, slog.LevelDebug):
+		l.Logger.LogAttrs(ctx, slog.LevelDebug, "query", attrs()...)
+	}
+}
+
+// ParamsFilter is called by gorm before the SQL is rendered for logging.
+// Dropping the params makes gorm log the statement with placeholders.
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.LogParams {
+		return sql, params
+	}
+	return sql, nil
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported values for Config.RedactMode
+const (
+	RedactMask = "mask"
+	RedactHash = "hash"
+)
+
+// Attribute keys that callers should use for sensitive values so that the
+// redaction layer can recognise them.
+const (
+	KeyUserID      = "user_id"
+	KeyReference   = "reference"
+	KeyDescription = "description"
+)
+
+type Config struct {
+	// Level is one of debug, info, warn, error
+	Level string
+	// RedactFields lists attribute keys whose values are masked in every record
+	RedactFields []string
+	// RedactMode is "mask" (replace with a fixed marker) or "hash" (stable
+	// digest, so that lines about the same user can still be correlated)
+	RedactMode string
+}
+
+// New builds a JSON logger that enriches records with request and trace IDs
+// from the context and redacts the configured fields.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	redact := make(map[string]bool, len(cfg.RedactFields))
+	for _, f := range cfg.RedactFields {
+		if f = strings.TrimSpace(f); f != "" {
+			redact[f] = true
+		}
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: ParseLevel(cfg.Level),
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if redact[a.Key] {
+				a.Value = slog.StringValue(redactValue(a.Value.String(), cfg.RedactMode))
+			}
+			return a
+		},
+	}
+
+	return slog.New(&contextHandler{Handler: slog.NewJSONHandler(w, opts)})
+}
+
+// ParseLevel maps a level name to slog.Level, defaulting to info.
+func ParseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func redactValue(v, mode string) string {
+	if v == "" {
+		return v
+	}
+	if mode == RedactHash {
+		sum := sha256.Sum256([]byte(v))
+		return "sha256:" + hex.EncodeToString(sum[:])[:12]
+	}
+	return "[REDACTED]"
+}
+
+// contextHandler adds request_id, trace_id and span_id to records logged
+// with a context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
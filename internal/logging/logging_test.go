@@ -0,0 +1,104 @@
+//go:build unit
+// +build unit
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var m map[string]any
+		require.NoError(t, dec.Decode(&m))
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func TestRedaction(t *testing.T) {
+	t.Run("mask", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&buf, Config{RedactFields: []string{KeyUserID, KeyReference}, RedactMode: RedactMask})
+		logger.Info("credit", KeyUserID, "user-42", KeyReference, "inv-1", KeyDescription, "coffee")
+
+		line := decodeLines(t, &buf)[0]
+		assert.Equal(t, "[REDACTED]", line[KeyUserID])
+		assert.Equal(t, "[REDACTED]", line[KeyReference])
+		assert.Equal(t, "coffee", line[KeyDescription])
+	})
+
+	t.Run("hash is stable", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := New(&buf, Config{RedactFields: []string{KeyUserID}, RedactMode: RedactHash})
+		logger.Info("a", KeyUserID, "user-42")
+		logger.Info("b", KeyUserID, "user-42")
+
+		lines := decodeLines(t, &buf)
+		assert.NotEqual(t, "user-42", lines[0][KeyUserID])
+		assert.Equal(t, lines[0][KeyUserID], lines[1][KeyUserID])
+	})
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	logger := New(&buf, Config{})
+
+	router := gin.New()
+	router.Use(RequestID(), AccessLog(logger))
+	router.GET("/users/:userId/wallet", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("echoes incoming id", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/users/user-42/wallet", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+		line := decodeLines(t, &buf)[0]
+		assert.Equal(t, "abc-123", line["request_id"])
+		assert.Equal(t, "/users/:userId/wallet", line["route"])
+		assert.NotContains(t, buf.String(), "user-42")
+	})
+
+	t.Run("replaces malformed id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users/u/wallet", nil)
+		req.Header.Set(RequestIDHeader, "bad id\n")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		assert.NotEmpty(t, id)
+		assert.NotEqual(t, "bad id\n", id)
+	})
+}
+
+func TestGormLoggerSlowQuery(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewGormLogger(New(&buf, Config{}), 10*time.Millisecond, false)
+
+	sql, vars := l.ParamsFilter(context.Background(), "SELECT * FROM wallets WHERE user_id = $1", "user-42")
+	assert.Nil(t, vars)
+
+	l.Trace(context.Background(), time.Now().Add(-time.Second), func() (string, int64) { return sql, 1 }, nil)
+	line := decodeLines(t, &buf)[0]
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "slow query", line["msg"])
+	assert.NotContains(t, buf.String(), "user-42")
+}
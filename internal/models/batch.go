@@ -0,0 +1,172 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BatchMode decides what happens to the rest of a batch when an item fails
+type BatchMode string
+
+const (
+	// BatchAtomic applies every item or none of them
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort applies every item that can be applied
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+type BatchStatus string
+
+const (
+	BatchPending    BatchStatus = "pending"
+	BatchProcessing BatchStatus = "processing"
+	BatchCompleted  BatchStatus = "completed"
+	// BatchPartiallyCompleted is a best-effort batch with failed items
+	BatchPartiallyCompleted BatchStatus = "partially_completed"
+	// BatchFailed is an atomic batch that was rolled back, or a best-effort
+	// batch in which every item failed
+	BatchFailed BatchStatus = "failed"
+)
+
+type BatchItemStatus string
+
+const (
+	BatchItemPending   BatchItemStatus = "pending"
+	BatchItemSucceeded BatchItemStatus = "succeeded"
+	BatchItemFailed    BatchItemStatus = "failed"
+	// BatchItemAborted is an item of an atomic batch that was rolled back
+	// because another item failed
+	BatchItemAborted BatchItemStatus = "aborted"
+)
+
+type Batch struct {
+	ID        uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;column:id"`
+	Mode      BatchMode   `json:"mode" gorm:"type:varchar(20);not null;column:mode"`
+	Status    BatchStatus `json:"status" gorm:"type:varchar(20);not null;column:status"`
+	ItemCount int         `json:"item_count" gorm:"not null;column:item_count"`
+	// ClaimedAt is when processing last started; a batch left processing
+	// for too long is claimed again. It doubles as the claim token, so it
+	// is set with ClaimTime.
+	ClaimedAt   *time.Time  `json:"-" gorm:"type:timestamp with time zone;column:claimed_at"`
+	CompletedAt *time.Time  `json:"completed_at" gorm:"type:timestamp with time zone;column:completed_at"`
+	CreatedAt   time.Time   `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" gorm:"type:timestamp with time zone;column:updated_at"`
+	Items       []BatchItem `json:"items" gorm:"foreignKey:BatchID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName specifies the table name for Batch
+func (Batch) TableName() string {
+	return "batches"
+}
+
+// BeforeCreate GORM hook to set ID and timestamps if not set
+func (b *Batch) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	now := time.Now()
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = now
+	}
+	if b.UpdatedAt.IsZero() {
+		b.UpdatedAt = now
+	}
+	return nil
+}
+
+// ClaimTime returns t as a batch claim token: in UTC and truncated to the
+// microseconds a timestamp column keeps, so that it compares equal to
+// itself once stored
+func ClaimTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// Done reports whether the batch has finished processing
+func (b *Batch) Done() bool {
+	return b.Status != BatchPending && b.Status != BatchProcessing
+}
+
+// BatchItem is one credit or debit of a batch, in submission order
+type BatchItem struct {
+	ID       uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;column:id"`
+	BatchID  uuid.UUID       `json:"batch_id" gorm:"type:uuid;not null;column:batch_id"`
+	Seq      int             `json:"seq" gorm:"not null;column:seq"`
+	WalletID uuid.UUID       `json:"wallet_id" gorm:"type:uuid;not null;column:wallet_id"`
+	Type     TransactionType `json:"type" gorm:"type:varchar(10);not null;column:type"`
+	Amount   float64         `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
+	// Description and Reference are copied to the resulting transaction
+	Description   string          `json:"description" gorm:"type:text;column:description"`
+	Reference     string          `json:"reference" gorm:"type:varchar(255);column:reference"`
+	Status        BatchItemStatus `json:"status" gorm:"type:varchar(20);not null;column:status"`
+	TransactionID *uuid.UUID      `json:"transaction_id" gorm:"type:uuid;column:transaction_id"`
+	ErrorCode     string          `json:"error_code" gorm:"type:varchar(50);column:error_code"`
+	ErrorMessage  string          `json:"error_message" gorm:"type:text;column:error_message"`
+}
+
+// TableName specifies the table name for BatchItem
+func (BatchItem) TableName() string {
+	return "batch_items"
+}
+
+// BeforeCreate GORM hook to set ID if not set
+func (i *BatchItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// Transaction returns the transaction the item asks for
+func (i *BatchItem) Transaction() *Transaction {
+	return &Transaction{
+		WalletID:    i.WalletID,
+		Type:        i.Type,
+		Amount:      i.Amount,
+		Description: i.Description,
+		Reference:   i.Reference,
+	}
+}
+
+type BatchRequest struct {
+	Mode  BatchMode          `json:"mode" binding:"required,oneof=atomic best_effort"`
+	Items []BatchItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+type BatchItemRequest struct {
+	WalletID    string          `json:"wallet_id" binding:"required,uuid"`
+	Type        TransactionType `json:"type" binding:"required,oneof=CREDIT DEBIT"`
+	Amount      float64         `json:"amount" binding:"required,gt=0"`
+	Description string          `json:"description"`
+	Reference   string          `json:"reference" binding:"max=255"`
+}
+
+type BatchResponse struct {
+	ID          uuid.UUID           `json:"id"`
+	Mode        BatchMode           `json:"mode"`
+	Status      BatchStatus         `json:"status"`
+	ItemCount   int                 `json:"item_count"`
+	Succeeded   int                 `json:"succeeded"`
+	Failed      int                 `json:"failed"`
+	CreatedAt   time.Time           `json:"created_at"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+	Items       []BatchItemResponse `json:"items"`
+}
+
+type BatchItemResponse struct {
+	Index         int             `json:"index"`
+	WalletID      uuid.UUID       `json:"wallet_id"`
+	Type          TransactionType `json:"type"`
+	Amount        float64         `json:"amount"`
+	Status        BatchItemStatus `json:"status"`
+	TransactionID *uuid.UUID      `json:"transaction_id,omitempty"`
+	Error         *ItemFailure    `json:"error,omitempty"`
+}
+
+// ItemFailure explains why an item was not applied, with the same code the
+// API would answer a single credit or debit with
+type ItemFailure struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
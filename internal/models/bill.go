@@ -0,0 +1,157 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BillSplit decides how a bill's total is shared among its participants
+type BillSplit string
+
+const (
+	// BillSplitEqual gives every participant the same share
+	BillSplitEqual BillSplit = "equal"
+	// BillSplitPercentage gives every participant a percentage of the total
+	BillSplitPercentage BillSplit = "percentage"
+	// BillSplitExact gives every participant an amount of its own
+	BillSplitExact BillSplit = "exact"
+)
+
+type BillStatus string
+
+const (
+	// BillOpen has shares left to settle
+	BillOpen BillStatus = "open"
+	// BillSettled has every share settled
+	BillSettled BillStatus = "settled"
+)
+
+type BillShareStatus string
+
+const (
+	BillSharePending BillShareStatus = "pending"
+	BillShareSettled BillShareStatus = "settled"
+)
+
+// Bill is a total the wallet PayerWalletID paid on behalf of its
+// participants. Each share is an obligation of one participant to the payer,
+// settled by moving its amount from the participant's wallet to the payer's.
+type Bill struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;column:id"`
+	PayerWalletID uuid.UUID  `json:"payer_wallet_id" gorm:"type:uuid;not null;column:payer_wallet_id"`
+	Total         float64    `json:"total" gorm:"type:decimal(15,2);not null;column:total"`
+	Description   string     `json:"description" gorm:"type:text;column:description"`
+	Split         BillSplit  `json:"split" gorm:"type:varchar(20);not null;column:split"`
+	Status        BillStatus `json:"status" gorm:"type:varchar(20);not null;column:status"`
+	// SettledAt is when the last share was settled
+	SettledAt *time.Time  `json:"settled_at" gorm:"type:timestamp with time zone;column:settled_at"`
+	CreatedAt time.Time   `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+	UpdatedAt time.Time   `json:"updated_at" gorm:"type:timestamp with time zone;column:updated_at"`
+	Shares    []BillShare `json:"shares" gorm:"foreignKey:BillID;constraint:OnDelete:CASCADE"`
+}
+
+// TableName specifies the table name for Bill
+func (Bill) TableName() string {
+	return "bills"
+}
+
+// BeforeCreate GORM hook to set ID and timestamps if not set
+func (b *Bill) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	now := time.Now().UTC()
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = now
+	}
+	if b.UpdatedAt.IsZero() {
+		b.UpdatedAt = now
+	}
+	return nil
+}
+
+// Reference is the reference of the transactions settling the bill
+func (b *Bill) Reference() string {
+	return "bill:" + b.ID.String()
+}
+
+// BillShare is what one participant owes the payer of a bill, in the order
+// the participants were listed. The payer's own share, and a share rounded
+// down to nothing, are settled from the start without moving money.
+type BillShare struct {
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;primary_key;column:id"`
+	BillID   uuid.UUID `json:"bill_id" gorm:"type:uuid;not null;column:bill_id"`
+	Seq      int       `json:"seq" gorm:"not null;column:seq"`
+	WalletID uuid.UUID `json:"wallet_id" gorm:"type:uuid;not null;column:wallet_id"`
+	// Percentage is the participant's percentage of a percentage split
+	Percentage *float64        `json:"percentage" gorm:"type:decimal(7,4);column:percentage"`
+	Amount     float64         `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
+	Status     BillShareStatus `json:"status" gorm:"type:varchar(20);not null;column:status"`
+	// The transactions of the settlement, when money moved
+	DebitTransactionID  *uuid.UUID `json:"debit_transaction_id" gorm:"type:uuid;column:debit_transaction_id"`
+	CreditTransactionID *uuid.UUID `json:"credit_transaction_id" gorm:"type:uuid;column:credit_transaction_id"`
+	// ErrorCode and ErrorMessage explain why the last attempt to settle a
+	// pending share failed
+	ErrorCode    string     `json:"error_code" gorm:"type:varchar(50);column:error_code"`
+	ErrorMessage string     `json:"error_message" gorm:"type:text;column:error_message"`
+	SettledAt    *time.Time `json:"settled_at" gorm:"type:timestamp with time zone;column:settled_at"`
+}
+
+// TableName specifies the table name for BillShare
+func (BillShare) TableName() string {
+	return "bill_shares"
+}
+
+// BeforeCreate GORM hook to set ID if not set
+func (s *BillShare) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+type CreateBillRequest struct {
+	PayerWalletID string    `json:"payer_wallet_id" binding:"required,uuid"`
+	Total         float64   `json:"total" binding:"required,gt=0"`
+	Description   string    `json:"description"`
+	Split         BillSplit `json:"split" binding:"required,oneof=equal percentage exact"`
+	// Participants may include the payer, whose share is then settled
+	// from the start
+	Participants []BillParticipantRequest `json:"participants" binding:"required,min=1,dive"`
+}
+
+type BillParticipantRequest struct {
+	WalletID string `json:"wallet_id" binding:"required,uuid"`
+	// Percentage is required by a percentage split and Amount by an exact
+	// one; neither is allowed otherwise
+	Percentage *float64 `json:"percentage"`
+	Amount     *float64 `json:"amount"`
+}
+
+type BillResponse struct {
+	ID            uuid.UUID           `json:"id"`
+	PayerWalletID uuid.UUID           `json:"payer_wallet_id"`
+	Total         float64             `json:"total"`
+	Description   string              `json:"description"`
+	Split         BillSplit           `json:"split"`
+	Status        BillStatus          `json:"status"`
+	Shares        []BillShareResponse `json:"shares"`
+	SettledAt     *time.Time          `json:"settled_at,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+type BillShareResponse struct {
+	Index               int             `json:"index"`
+	WalletID            uuid.UUID       `json:"wallet_id"`
+	Percentage          *float64        `json:"percentage,omitempty"`
+	Amount              float64         `json:"amount"`
+	Status              BillShareStatus `json:"status"`
+	DebitTransactionID  *uuid.UUID      `json:"debit_transaction_id,omitempty"`
+	CreditTransactionID *uuid.UUID      `json:"credit_transaction_id,omitempty"`
+	SettledAt           *time.Time      `json:"settled_at,omitempty"`
+	// Error is why the last attempt to settle a pending share failed
+	Error *ItemFailure `json:"error,omitempty"`
+}
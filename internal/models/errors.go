@@ -0,0 +1,81 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Domain errors returned by the repositories and services. Callers match
+// them with errors.Is; handlers.writeError and the gRPC server translate
+// them to status codes. Their messages are safe to show to clients.
+var (
+	ErrWalletNotFound = errors.New("wallet not found")
+	// ErrInsufficientFunds is returned by a debit larger than the balance
+	ErrInsufficientFunds = errors.New("insufficient balance")
+	// ErrDuplicate is returned when a unique key is already taken
+	ErrDuplicate = errors.New("resource already exists")
+	// ErrWalletExists is returned when the user already has a wallet, the
+	// one unique key clients can run into
+	ErrWalletExists = errors.New("wallet already exists for this user")
+	// ErrConflict is returned when a concurrent transaction got in the way,
+	// e.g. a serialization failure or deadlock. Retrying may succeed.
+	ErrConflict      = errors.New("conflicting concurrent update")
+	ErrInvalidAmount = errors.New("amount must be positive")
+	// ErrCurrencyMismatch is returned when money would move between wallets
+	// of different currencies
+	ErrCurrencyMismatch = errors.New("wallets have different currencies")
+	// ErrInvalidState is returned when a resource's state does not allow the
+	// requested change, e.g. resuming a cancelled schedule
+	ErrInvalidState = errors.New("not allowed in the current state")
+	// ErrIdempotencyKeyReused is returned when a write carries the
+	// Idempotency-Key of a different earlier request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+)
+
+// Returned for unknown IDs of resources other than wallets
+var (
+	ErrBatchNotFound          = errors.New("batch not found")
+	ErrScheduleNotFound       = errors.New("schedule not found")
+	ErrEscrowNotFound         = errors.New("escrow not found")
+	ErrPaymentRequestNotFound = errors.New("payment request not found")
+	ErrBillNotFound           = errors.New("bill not found")
+	// ErrInterestAccountNotFound is returned for a wallet that is not
+	// enrolled for interest
+	ErrInterestAccountNotFound = errors.New("wallet does not earn interest")
+)
+
+// ItemError reports which operation of a multi-wallet request failed. It
+// wraps the domain error, so errors.Is still matches the sentinels.
+type ItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError reports request fields that break a rule only the
+// service can check, such as a limit from the configuration
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Violation is one invalid field, named by its JSON path
+type Violation struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + " " + v.Message
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EscrowStatus string
+
+const (
+	// EscrowFunded holds the buyer's money until it is released, refunded
+	// or split
+	EscrowFunded EscrowStatus = "funded"
+	// EscrowReleased, EscrowRefunded and EscrowSplit are final: the money
+	// went to the seller, back to the buyer or partly to each
+	EscrowReleased EscrowStatus = "released"
+	EscrowRefunded EscrowStatus = "refunded"
+	EscrowSplit    EscrowStatus = "split"
+)
+
+// Escrow is an agreement holding money debited from a buyer's wallet until
+// it is paid out. The money sits in the escrow's own wallet, the escrow
+// account, in the meantime. Held is that wallet's balance: Amount while
+// funded and 0 once settled.
+type Escrow struct {
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;column:id"`
+	BuyerWalletID  uuid.UUID    `json:"buyer_wallet_id" gorm:"type:uuid;not null;column:buyer_wallet_id"`
+	SellerWalletID uuid.UUID    `json:"seller_wallet_id" gorm:"type:uuid;not null;column:seller_wallet_id"`
+	EscrowWalletID uuid.UUID    `json:"escrow_wallet_id" gorm:"type:uuid;not null;column:escrow_wallet_id"`
+	Amount         float64      `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
+	Held           float64      `json:"held" gorm:"type:decimal(15,2);not null;column:held"`
+	Description    string       `json:"description" gorm:"type:text;column:description"`
+	Status         EscrowStatus `json:"status" gorm:"type:varchar(20);not null;column:status"`
+	// ReleaseAt is the deadline at which a funded escrow is released to the
+	// seller unless settled before
+	ReleaseAt *time.Time `json:"release_at" gorm:"type:timestamp with time zone;column:release_at"`
+	// SellerAmount and BuyerAmount are what each side was paid on settling
+	SellerAmount         float64    `json:"seller_amount" gorm:"type:decimal(15,2);not null;column:seller_amount"`
+	BuyerAmount          float64    `json:"buyer_amount" gorm:"type:decimal(15,2);not null;column:buyer_amount"`
+	FundingTransactionID uuid.UUID  `json:"funding_transaction_id" gorm:"type:uuid;not null;column:funding_transaction_id"`
+	SellerTransactionID  *uuid.UUID `json:"seller_transaction_id" gorm:"type:uuid;column:seller_transaction_id"`
+	BuyerTransactionID   *uuid.UUID `json:"buyer_transaction_id" gorm:"type:uuid;column:buyer_transaction_id"`
+	// AutoReleased is set when the deadline, not a call, released the escrow
+	AutoReleased bool       `json:"auto_released" gorm:"not null;column:auto_released"`
+	SettledAt    *time.Time `json:"settled_at" gorm:"type:timestamp with time zone;column:settled_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"type:timestamp with time zone;column:updated_at"`
+}
+
+// TableName specifies the table name for Escrow
+func (Escrow) TableName() string {
+	return "escrows"
+}
+
+// BeforeCreate GORM hook to set ID and timestamps if not set
+func (e *Escrow) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	now := time.Now().UTC()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+	if e.UpdatedAt.IsZero() {
+		e.UpdatedAt = now
+	}
+	return nil
+}
+
+// EscrowAccountPrefix starts the user ID of every escrow wallet. Wallets
+// cannot otherwise be created with it.
+const EscrowAccountPrefix = "escrow:"
+
+// Reference is the reference of every transaction moving the escrow's
+// money, and the user ID of its escrow wallet
+func (e *Escrow) Reference() string {
+	return EscrowAccountPrefix + e.ID.String()
+}
+
+// EscrowSettlement says how a funded escrow is paid out
+type EscrowSettlement struct {
+	// Status is the final status: released pays everything held to the
+	// seller, refunded to the buyer
+	Status EscrowStatus
+	// SellerAmount is the seller's share when Status is split; the buyer
+	// gets the rest
+	SellerAmount float64
+	// Auto marks a release at the deadline
+	Auto bool
+}
+
+type CreateEscrowRequest struct {
+	BuyerWalletID  string  `json:"buyer_wallet_id" binding:"required,uuid"`
+	SellerWalletID string  `json:"seller_wallet_id" binding:"required,uuid"`
+	Amount         float64 `json:"amount" binding:"required,gt=0"`
+	Description    string  `json:"description"`
+	// ReleaseAt, if set, releases the escrow to the seller automatically
+	ReleaseAt *time.Time `json:"release_at"`
+}
+
+type SplitEscrowRequest struct {
+	// SellerAmount goes to the seller and the rest back to the buyer
+	SellerAmount float64 `json:"seller_amount" binding:"required,gt=0"`
+}
+
+type EscrowResponse struct {
+	ID                   uuid.UUID    `json:"id"`
+	BuyerWalletID        uuid.UUID    `json:"buyer_wallet_id"`
+	SellerWalletID       uuid.UUID    `json:"seller_wallet_id"`
+	EscrowWalletID       uuid.UUID    `json:"escrow_wallet_id"`
+	Amount               float64      `json:"amount"`
+	Held                 float64      `json:"held"`
+	Description          string       `json:"description"`
+	Status               EscrowStatus `json:"status"`
+	ReleaseAt            *time.Time   `json:"release_at,omitempty"`
+	SellerAmount         float64      `json:"seller_amount"`
+	BuyerAmount          float64      `json:"buyer_amount"`
+	FundingTransactionID uuid.UUID    `json:"funding_transaction_id"`
+	SellerTransactionID  *uuid.UUID   `json:"seller_transaction_id,omitempty"`
+	BuyerTransactionID   *uuid.UUID   `json:"buyer_transaction_id,omitempty"`
+	AutoReleased         bool         `json:"auto_released"`
+	SettledAt            *time.Time   `json:"settled_at,omitempty"`
+	CreatedAt            time.Time    `json:"created_at"`
+	UpdatedAt            time.Time    `json:"updated_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// IdempotencyKey records a write made with an Idempotency-Key header and,
+// once it has finished, the response to replay to retries of it
+type IdempotencyKey struct {
+	Key string `gorm:"type:varchar(255);primary_key;column:key"`
+	// Fingerprint identifies the request the key was first used for; a
+	// different request with the same key is refused
+	Fingerprint string `gorm:"type:varchar(64);not null;column:fingerprint"`
+	// StatusCode is 0 while the first request is in progress
+	StatusCode  int        `gorm:"not null;default:0;column:status_code"`
+	ContentType string     `gorm:"type:varchar(255);column:content_type"`
+	Location    string     `gorm:"type:varchar(255);column:location"`
+	Body        []byte     `gorm:"column:body"`
+	CreatedAt   time.Time  `gorm:"type:timestamp with time zone;column:created_at"`
+	CompletedAt *time.Time `gorm:"type:timestamp with time zone;column:completed_at"`
+	// ReservedUntil is when a request still in progress loses the key, so
+	// that a retry is not locked out if the instance running it died
+	ReservedUntil time.Time `gorm:"type:timestamp with time zone;not null;column:reserved_until"`
+}
+
+// TableName specifies the table name for IdempotencyKey
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// Completed reports whether the response of the first request is stored
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
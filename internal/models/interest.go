@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InterestAccount enrolls a wallet for interest. Interest accrues daily into
+// Accrued, a bucket apart from the wallet's balance, and is paid out from it
+// at the end of every payout period.
+type InterestAccount struct {
+	WalletID uuid.UUID `json:"wallet_id" gorm:"type:uuid;primary_key;column:wallet_id"`
+	// Accrued holds interest not yet paid, to 8 decimal places; payouts
+	// leave fractions of a cent behind
+	Accrued float64 `json:"accrued" gorm:"type:decimal(20,8);not null;column:accrued"`
+	// EnrolledOn is the first day interest accrues for, and AccruedThrough
+	// the last day it has accrued for, nil until the first accrual. Dates
+	// are midnight UTC.
+	EnrolledOn     time.Time  `json:"enrolled_on" gorm:"type:date;not null;column:enrolled_on"`
+	AccruedThrough *time.Time `json:"accrued_through" gorm:"type:date;column:accrued_through"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"type:timestamp with time zone;column:updated_at"`
+}
+
+// TableName specifies the table name for InterestAccount
+func (InterestAccount) TableName() string {
+	return "interest_accounts"
+}
+
+// BeforeCreate GORM hook to set timestamps if not set
+func (a *InterestAccount) BeforeCreate(tx *gorm.DB) error {
+	now := time.Now().UTC()
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = now
+	}
+	if a.UpdatedAt.IsZero() {
+		a.UpdatedAt = now
+	}
+	return nil
+}
+
+// InterestAccrual is the interest earned by a wallet on one day, with the
+// inputs it was computed from
+type InterestAccrual struct {
+	WalletID uuid.UUID `json:"wallet_id" gorm:"type:uuid;primary_key;column:wallet_id"`
+	Date     time.Time `json:"date" gorm:"type:date;primary_key;column:date"`
+	// Balance is the wallet's balance at the end of Date
+	Balance    float64   `json:"balance" gorm:"type:decimal(15,2);not null;column:balance"`
+	AnnualRate float64   `json:"annual_rate" gorm:"type:decimal(9,6);not null;column:annual_rate"`
+	DayCount   string    `json:"day_count" gorm:"type:varchar(10);not null;column:day_count"`
+	Amount     float64   `json:"amount" gorm:"type:decimal(20,8);not null;column:amount"`
+	CreatedAt  time.Time `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+}
+
+// TableName specifies the table name for InterestAccrual
+func (InterestAccrual) TableName() string {
+	return "interest_accruals"
+}
+
+// BeforeCreate GORM hook to set the timestamp if not set
+func (a *InterestAccrual) BeforeCreate(tx *gorm.DB) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// InterestPayout is the credit paying out the interest of the period ending
+// on PeriodEnd. There is at most one per wallet and period.
+type InterestPayout struct {
+	WalletID      uuid.UUID `json:"wallet_id" gorm:"type:uuid;primary_key;column:wallet_id"`
+	PeriodEnd     time.Time `json:"period_end" gorm:"type:date;primary_key;column:period_end"`
+	Amount        float64   `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
+	TransactionID uuid.UUID `json:"transaction_id" gorm:"type:uuid;not null;column:transaction_id"`
+	CreatedAt     time.Time `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+}
+
+// TableName specifies the table name for InterestPayout
+func (InterestPayout) TableName() string {
+	return "interest_payouts"
+}
+
+// BeforeCreate GORM hook to set the timestamp if not set
+func (p *InterestPayout) BeforeCreate(tx *gorm.DB) error {
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// Dates in interest responses are formatted YYYY-MM-DD
+
+type InterestAccountResponse struct {
+	WalletID       uuid.UUID `json:"wallet_id"`
+	Accrued        float64   `json:"accrued"`
+	EnrolledOn     string    `json:"enrolled_on"`
+	AccruedThrough string    `json:"accrued_through,omitempty"`
+	// AnnualRate is the rate in force today
+	AnnualRate float64 `json:"annual_rate"`
+	DayCount   string  `json:"day_count"`
+}
+
+type InterestAccrualResponse struct {
+	Date       string  `json:"date"`
+	Balance    float64 `json:"balance"`
+	AnnualRate float64 `json:"annual_rate"`
+	DayCount   string  `json:"day_count"`
+	Amount     float64 `json:"amount"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OverdraftCharge is what a wallet was charged for being overdrawn at the
+// end of one day, with the inputs it was computed from. Days on which the
+// wallet was not overdrawn are recorded too, with nothing charged, so that
+// every day is charged at most once.
+type OverdraftCharge struct {
+	WalletID uuid.UUID `json:"wallet_id" gorm:"type:uuid;primary_key;column:wallet_id"`
+	Date     time.Time `json:"date" gorm:"type:date;primary_key;column:date"`
+	// Used is the part of the overdraft limit in use at the end of Date
+	Used       float64 `json:"used" gorm:"type:decimal(15,2);not null;column:used"`
+	AnnualRate float64 `json:"annual_rate" gorm:"type:decimal(9,6);not null;column:annual_rate"`
+	Fee        float64 `json:"fee" gorm:"type:decimal(15,2);not null;column:fee"`
+	Amount     float64 `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
+	// TransactionID is the debit charging Amount, nil when it is zero
+	TransactionID *uuid.UUID `json:"transaction_id" gorm:"type:uuid;column:transaction_id"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+}
+
+// TableName specifies the table name for OverdraftCharge
+func (OverdraftCharge) TableName() string {
+	return "overdraft_charges"
+}
+
+// BeforeCreate GORM hook to set the timestamp if not set
+func (c *OverdraftCharge) BeforeCreate(tx *gorm.DB) error {
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
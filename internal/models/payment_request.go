@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentRequestStatus string
+
+const (
+	// PaymentRequestPending waits for the payer until it expires
+	PaymentRequestPending PaymentRequestStatus = "pending"
+	// The other statuses are final: the payer paid or declined, the
+	// request lapsed unanswered or the requester withdrew it
+	PaymentRequestPaid      PaymentRequestStatus = "paid"
+	PaymentRequestDeclined  PaymentRequestStatus = "declined"
+	PaymentRequestExpired   PaymentRequestStatus = "expired"
+	PaymentRequestCancelled PaymentRequestStatus = "cancelled"
+)
+
+// PaymentRequest asks the user PayerUserID to pay Amount to the user
+// RequesterUserID. Paying moves the money between their wallets.
+type PaymentRequest struct {
+	ID              uuid.UUID `json:"id" gorm:"type:uuid;primary_key;column:id"`
+	RequesterUserID string    `json:"requester_user_id" gorm:"type:varchar(255);not null;column:requester_user_id"`
+	PayerUserID     string    `json:"payer_user_id" gorm:"type:varchar(255);not null;column:payer_user_id"`
+	Amount          float64   `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
+	// Currency is the requester's at the time of the request
+	Currency    string               `json:"currency" gorm:"type:varchar(3);not null;column:currency"`
+	Description string               `json:"description" gorm:"type:text;column:description"`
+	Status      PaymentRequestStatus `json:"status" gorm:"type:varchar(20);not null;column:status"`
+	// ExpiresAt is when a pending request lapses; from then on it can no
+	// longer be answered, even before the expiry job marks it expired
+	ExpiresAt time.Time `json:"expires_at" gorm:"type:timestamp with time zone;not null;column:expires_at"`
+	// The wallets and transactions of the payment, once paid
+	FromWalletID        *uuid.UUID `json:"from_wallet_id" gorm:"type:uuid;column:from_wallet_id"`
+	ToWalletID          *uuid.UUID `json:"to_wallet_id" gorm:"type:uuid;column:to_wallet_id"`
+	DebitTransactionID  *uuid.UUID `json:"debit_transaction_id" gorm:"type:uuid;column:debit_transaction_id"`
+	CreditTransactionID *uuid.UUID `json:"credit_transaction_id" gorm:"type:uuid;column:credit_transaction_id"`
+	// ResolvedAt is when the request reached a final status
+	ResolvedAt *time.Time `json:"resolved_at" gorm:"type:timestamp with time zone;column:resolved_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"type:timestamp with time zone;column:updated_at"`
+}
+
+// TableName specifies the table name for PaymentRequest
+func (PaymentRequest) TableName() string {
+	return "payment_requests"
+}
+
+// BeforeCreate GORM hook to set ID and timestamps if not set
+func (r *PaymentRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	now := time.Now().UTC()
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = now
+	}
+	if r.UpdatedAt.IsZero() {
+		r.UpdatedAt = now
+	}
+	return nil
+}
+
+// Lapsed reports whether a pending request can no longer be answered at now
+func (r *PaymentRequest) Lapsed(now time.Time) bool {
+	return r.Status == PaymentRequestPending && !r.ExpiresAt.After(now)
+}
+
+// PaymentRequestFilter selects payment requests by one of their users
+type PaymentRequestFilter struct {
+	// RequesterUserID selects a user's outbox and PayerUserID their inbox
+	RequesterUserID string
+	PayerUserID     string
+	// Status, if set, keeps only requests in that status
+	Status PaymentRequestStatus
+}
+
+type CreatePaymentRequestRequest struct {
+	RequesterUserID string  `json:"requester_user_id" binding:"required,max=255"`
+	PayerUserID     string  `json:"payer_user_id" binding:"required,max=255"`
+	Amount          float64 `json:"amount" binding:"required,gt=0"`
+	Description     string  `json:"description"`
+	// ExpiresAt defaults to the configured expiry from now
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type PaymentRequestResponse struct {
+	ID                  uuid.UUID            `json:"id"`
+	RequesterUserID     string               `json:"requester_user_id"`
+	PayerUserID         string               `json:"payer_user_id"`
+	Amount              float64              `json:"amount"`
+	Currency            string               `json:"currency"`
+	Description         string               `json:"description"`
+	Status              PaymentRequestStatus `json:"status"`
+	ExpiresAt           time.Time            `json:"expires_at"`
+	FromWalletID        *uuid.UUID           `json:"from_wallet_id,omitempty"`
+	ToWalletID          *uuid.UUID           `json:"to_wallet_id,omitempty"`
+	DebitTransactionID  *uuid.UUID           `json:"debit_transaction_id,omitempty"`
+	CreditTransactionID *uuid.UUID           `json:"credit_transaction_id,omitempty"`
+	ResolvedAt          *time.Time           `json:"resolved_at,omitempty"`
+	CreatedAt           time.Time            `json:"created_at"`
+	UpdatedAt           time.Time            `json:"updated_at"`
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleActive ScheduleStatus = "active"
+	SchedulePaused ScheduleStatus = "paused"
+	// ScheduleCancelled and ScheduleCompleted are final: a cancelled
+	// schedule was stopped by the user, a completed one has no occurrences
+	// left
+	ScheduleCancelled ScheduleStatus = "cancelled"
+	ScheduleCompleted ScheduleStatus = "completed"
+)
+
+// InsufficientFundsPolicy decides what happens to an occurrence whose debit
+// fails on insufficient funds
+type InsufficientFundsPolicy string
+
+const (
+	// RetryOnInsufficientFunds tries the occurrence again later, up to a
+	// configured number of times, before skipping it
+	RetryOnInsufficientFunds InsufficientFundsPolicy = "retry"
+	// SkipOnInsufficientFunds moves straight on to the next occurrence
+	SkipOnInsufficientFunds InsufficientFundsPolicy = "skip"
+)
+
+// ScheduledPayment is a standing order moving Amount from one wallet to
+// another on every occurrence of Rule
+type ScheduledPayment struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;column:id"`
+	FromWalletID uuid.UUID `json:"from_wallet_id" gorm:"type:uuid;not null;column:from_wallet_id"`
+	ToWalletID   uuid.UUID `json:"to_wallet_id" gorm:"type:uuid;not null;column:to_wallet_id"`
+	Amount       float64   `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
+	Description  string    `json:"description" gorm:"type:text;column:description"`
+	// Rule is a recurrence rule in canonical form, empty for a one-off
+	// payment at StartAt
+	Rule                string                  `json:"rule" gorm:"type:varchar(255);column:rule"`
+	StartAt             time.Time               `json:"start_at" gorm:"type:timestamp with time zone;not null;column:start_at"`
+	EndAt               *time.Time              `json:"end_at" gorm:"type:timestamp with time zone;column:end_at"`
+	OnInsufficientFunds InsufficientFundsPolicy `json:"on_insufficient_funds" gorm:"type:varchar(10);not null;column:on_insufficient_funds"`
+	Status              ScheduleStatus          `json:"status" gorm:"type:varchar(20);not null;column:status"`
+	// OccurrenceAt is the occurrence to execute next and NextRunAt when to
+	// try it, later than OccurrenceAt while retrying. Both are nil once the
+	// schedule has ended.
+	OccurrenceAt *time.Time `json:"occurrence_at" gorm:"type:timestamp with time zone;column:occurrence_at"`
+	NextRunAt    *time.Time `json:"next_run_at" gorm:"type:timestamp with time zone;column:next_run_at"`
+	// Attempts counts the failed attempts at OccurrenceAt
+	Attempts  int       `json:"attempts" gorm:"not null;column:attempts"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"type:timestamp with time zone;column:updated_at"`
+}
+
+// TableName specifies the table name for ScheduledPayment
+func (ScheduledPayment) TableName() string {
+	return "scheduled_payments"
+}
+
+// BeforeCreate GORM hook to set ID and timestamps if not set
+func (s *ScheduledPayment) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	now := time.Now().UTC()
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = now
+	}
+	if s.UpdatedAt.IsZero() {
+		s.UpdatedAt = now
+	}
+	return nil
+}
+
+type ExecutionStatus string
+
+const (
+	ExecutionSucceeded ExecutionStatus = "succeeded"
+	// ExecutionFailed is a failed attempt that will be retried
+	ExecutionFailed ExecutionStatus = "failed"
+	// ExecutionSkipped is a failed attempt after which the occurrence was
+	// given up
+	ExecutionSkipped ExecutionStatus = "skipped"
+)
+
+// ScheduleExecution records one attempt at an occurrence of a schedule
+type ScheduleExecution struct {
+	ID                  uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;column:id"`
+	ScheduleID          uuid.UUID       `json:"schedule_id" gorm:"type:uuid;not null;column:schedule_id"`
+	OccurrenceAt        time.Time       `json:"occurrence_at" gorm:"type:timestamp with time zone;not null;column:occurrence_at"`
+	Attempt             int             `json:"attempt" gorm:"not null;column:attempt"`
+	Status              ExecutionStatus `json:"status" gorm:"type:varchar(20);not null;column:status"`
+	DebitTransactionID  *uuid.UUID      `json:"debit_transaction_id" gorm:"type:uuid;column:debit_transaction_id"`
+	CreditTransactionID *uuid.UUID      `json:"credit_transaction_id" gorm:"type:uuid;column:credit_transaction_id"`
+	ErrorCode           string          `json:"error_code" gorm:"type:varchar(50);column:error_code"`
+	ErrorMessage        string          `json:"error_message" gorm:"type:text;column:error_message"`
+	ExecutedAt          time.Time       `json:"executed_at" gorm:"type:timestamp with time zone;not null;column:executed_at"`
+}
+
+// TableName specifies the table name for ScheduleExecution
+func (ScheduleExecution) TableName() string {
+	return "schedule_executions"
+}
+
+// BeforeCreate GORM hook to set ID and timestamp if not set
+func (e *ScheduleExecution) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.ExecutedAt.IsZero() {
+		e.ExecutedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+type CreateScheduleRequest struct {
+	FromWalletID string  `json:"from_wallet_id" binding:"required,uuid"`
+	ToWalletID   string  `json:"to_wallet_id" binding:"required,uuid"`
+	Amount       float64 `json:"amount" binding:"required,gt=0"`
+	Description  string  `json:"description"`
+	// Rule is a recurrence rule such as "FREQ=MONTHLY;BYMONTHDAY=1"; without
+	// one the payment is made once, at StartAt
+	Rule string `json:"rule" binding:"max=255"`
+	// StartAt defaults to now
+	StartAt             *time.Time              `json:"start_at"`
+	EndAt               *time.Time              `json:"end_at"`
+	OnInsufficientFunds InsufficientFundsPolicy `json:"on_insufficient_funds" binding:"omitempty,oneof=retry skip"`
+}
+
+type ScheduleResponse struct {
+	ID                  uuid.UUID               `json:"id"`
+	FromWalletID        uuid.UUID               `json:"from_wallet_id"`
+	ToWalletID          uuid.UUID               `json:"to_wallet_id"`
+	Amount              float64                 `json:"amount"`
+	Description         string                  `json:"description"`
+	Rule                string                  `json:"rule,omitempty"`
+	StartAt             time.Time               `json:"start_at"`
+	EndAt               *time.Time              `json:"end_at,omitempty"`
+	OnInsufficientFunds InsufficientFundsPolicy `json:"on_insufficient_funds"`
+	Status              ScheduleStatus          `json:"status"`
+	NextRunAt           *time.Time              `json:"next_run_at,omitempty"`
+	CreatedAt           time.Time               `json:"created_at"`
+	UpdatedAt           time.Time               `json:"updated_at"`
+}
+
+type ScheduleExecutionResponse struct {
+	ID                  uuid.UUID       `json:"id"`
+	OccurrenceAt        time.Time       `json:"occurrence_at"`
+	Attempt             int             `json:"attempt"`
+	Status              ExecutionStatus `json:"status"`
+	DebitTransactionID  *uuid.UUID      `json:"debit_transaction_id,omitempty"`
+	CreditTransactionID *uuid.UUID      `json:"credit_transaction_id,omitempty"`
+	Error               *ItemFailure    `json:"error,omitempty"`
+	ExecutedAt          time.Time       `json:"executed_at"`
+}
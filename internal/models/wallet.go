@@ -8,14 +8,37 @@ import (
 )
 
 type Wallet struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid();column:id"`
-	UserID    string    `json:"user_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_wallets_user_id;column:user_id"`
-	Balance   float64   `json:"balance" gorm:"type:decimal(15,2);not null;default:0.00;column:balance"`
-	Currency  string    `json:"currency" gorm:"type:varchar(3);not null;default:'USD';column:currency"`
-	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp with time zone;default:CURRENT_TIMESTAMP;column:created_at"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"type:timestamp with time zone;default:CURRENT_TIMESTAMP;column:updated_at"`
+	ID      uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid();column:id"`
+	UserID  string    `json:"user_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_wallets_user_id;column:user_id"`
+	Balance float64   `json:"balance" gorm:"type:decimal(15,2);not null;default:0.00;column:balance"`
+	// PromoBalance is the part of Balance held in the promo bucket; the
+	// rest is cash
+	PromoBalance float64    `json:"promo_balance" gorm:"type:decimal(15,2);not null;default:0.00;column:promo_balance"`
+	DebitOrder   DebitOrder `json:"debit_order" gorm:"type:varchar(20);not null;default:'promo_first';column:debit_order"`
+	// OverdraftLimit is how far below zero debits may take the balance
+	OverdraftLimit float64   `json:"overdraft_limit" gorm:"type:decimal(15,2);not null;default:0.00;column:overdraft_limit"`
+	Currency       string    `json:"currency" gorm:"type:varchar(3);not null;default:'USD';column:currency"`
+	CreatedAt      time.Time `json:"created_at" gorm:"type:timestamp with time zone;default:CURRENT_TIMESTAMP;column:created_at"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"type:timestamp with time zone;default:CURRENT_TIMESTAMP;column:updated_at"`
 }
 
+// Bucket is a part of a wallet's balance
+type Bucket string
+
+const (
+	CashBucket Bucket = "cash"
+	// PromoBucket holds promotional credit, which may expire
+	PromoBucket Bucket = "promo"
+)
+
+// DebitOrder decides which bucket a wallet's debits are taken from first
+type DebitOrder string
+
+const (
+	PromoFirst DebitOrder = "promo_first"
+	CashFirst  DebitOrder = "cash_first"
+)
+
 // TableName specifies the table name for Wallet
 func (Wallet) TableName() string {
 	return "wallets"
@@ -32,6 +55,9 @@ func (w *Wallet) BeforeCreate(tx *gorm.DB) error {
 	if w.UpdatedAt.IsZero() {
 		w.UpdatedAt = time.Now()
 	}
+	if w.DebitOrder == "" {
+		w.DebitOrder = PromoFirst
+	}
 	return nil
 }
 
@@ -48,8 +74,50 @@ type Transaction struct {
 	Amount      float64         `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
 	Description string          `json:"description" gorm:"type:text;column:description"`
 	Reference   string          `json:"reference" gorm:"type:varchar(255);column:reference"`
-	CreatedAt   time.Time       `json:"created_at" gorm:"type:timestamp with time zone;default:CURRENT_TIMESTAMP;index:idx_transactions_created_at;column:created_at"`
-	Wallet      Wallet          `json:"wallet" gorm:"foreignKey:WalletID;constraint:OnDelete:CASCADE"`
+	// PromoAmount is the part of Amount credited to or debited from the
+	// promo bucket. Repositories set it; on a credit, set Promo instead.
+	PromoAmount float64   `json:"promo_amount" gorm:"type:decimal(15,2);not null;default:0.00;column:promo_amount"`
+	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamp with time zone;default:CURRENT_TIMESTAMP;index:idx_transactions_created_at;column:created_at"`
+	Wallet      Wallet    `json:"wallet" gorm:"foreignKey:WalletID;constraint:OnDelete:CASCADE"`
+	// Promo makes a credit promotional; it is not stored on the
+	// transaction but as a PromoCredit
+	Promo *PromoTerms `json:"-" gorm:"-"`
+	// Charge makes a debit a charge the wallet owes, such as overdraft
+	// interest. It is taken from cash and may exceed the overdraft limit.
+	Charge bool `json:"-" gorm:"-"`
+	// CashOnly makes a debit leave the promo bucket alone, for money paid
+	// on to another wallet, which receives it as cash
+	CashOnly bool `json:"-" gorm:"-"`
+}
+
+// PromoTerms are the terms of a promotional credit
+type PromoTerms struct {
+	// ExpiresAt is when the credit lapses, nil for never
+	ExpiresAt *time.Time
+}
+
+// PromoCredit tracks what is left of one promotional credit. Debits spend
+// the credits that expire first; the expiry job debits what is left when a
+// credit lapses.
+type PromoCredit struct {
+	// TransactionID is the ID of the credit
+	TransactionID uuid.UUID  `json:"transaction_id" gorm:"type:uuid;primary_key;column:transaction_id"`
+	WalletID      uuid.UUID  `json:"wallet_id" gorm:"type:uuid;not null;column:wallet_id"`
+	Amount        float64    `json:"amount" gorm:"type:decimal(15,2);not null;column:amount"`
+	Remaining     float64    `json:"remaining" gorm:"type:decimal(15,2);not null;column:remaining"`
+	ExpiresAt     *time.Time `json:"expires_at" gorm:"type:timestamp with time zone;column:expires_at"`
+	ExpiredAt     *time.Time `json:"expired_at" gorm:"type:timestamp with time zone;column:expired_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"type:timestamp with time zone;column:created_at"`
+}
+
+// TableName specifies the table name for PromoCredit
+func (PromoCredit) TableName() string {
+	return "promo_credits"
+}
+
+// Lapsed reports whether the credit has expired by now
+func (c *PromoCredit) Lapsed(now time.Time) bool {
+	return c.ExpiresAt != nil && !c.ExpiresAt.After(now)
 }
 
 // TableName specifies the table name for Transaction
@@ -78,19 +146,43 @@ const (
 type CreateWalletRequest struct {
 	UserID   string `json:"user_id" binding:"required"`
 	Currency string `json:"currency"`
+	// DebitOrder defaults to promo_first
+	DebitOrder DebitOrder `json:"debit_order" binding:"omitempty,oneof=promo_first cash_first"`
+	// OverdraftLimit defaults to none on create and to the current limit
+	// on update
+	OverdraftLimit *float64 `json:"overdraft_limit" binding:"omitempty,gte=0"`
 }
 
 type TransactionRequest struct {
 	Amount      float64 `json:"amount" binding:"required,gt=0"`
 	Description string  `json:"description"`
 	Reference   string  `json:"reference"`
+	// Bucket and ExpiresAt only apply to credits. Promo credits expire
+	// after a configured period unless ExpiresAt is set.
+	Bucket    Bucket     `json:"bucket" binding:"omitempty,oneof=cash promo"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 type WalletResponse struct {
-	ID       uuid.UUID `json:"id"`
-	UserID   string    `json:"user_id"`
-	Balance  float64   `json:"balance"`
-	Currency string    `json:"currency"`
+	ID         uuid.UUID     `json:"id"`
+	UserID     string        `json:"user_id"`
+	Balance    float64       `json:"balance"`
+	Buckets    BucketBalance `json:"buckets"`
+	DebitOrder DebitOrder    `json:"debit_order"`
+	Overdraft  Overdraft     `json:"overdraft"`
+	Currency   string        `json:"currency"`
+}
+
+// Overdraft reports how much of a wallet's overdraft limit is in use
+type Overdraft struct {
+	Limit float64 `json:"limit"`
+	Used  float64 `json:"used"`
+}
+
+// BucketBalance splits a wallet's balance by bucket
+type BucketBalance struct {
+	Cash  float64 `json:"cash"`
+	Promo float64 `json:"promo"`
 }
 
 type TransactionResponse struct {
@@ -100,6 +192,7 @@ type TransactionResponse struct {
 	Amount      float64         `json:"amount"`
 	Description string          `json:"description"`
 	Reference   string          `json:"reference"`
+	PromoAmount float64         `json:"promo_amount"`
 	CreatedAt   time.Time       `json:"created_at"`
 }
 
@@ -107,3 +200,19 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
 }
+
+// TransferRequest moves money from one wallet to another of the same
+// currency
+type TransferRequest struct {
+	FromWalletID uuid.UUID
+	ToWalletID   uuid.UUID
+	Amount       float64
+	Description  string
+	Reference    string
+}
+
+// TransferResponse holds both sides of a transfer
+type TransferResponse struct {
+	Debit  TransactionResponse `json:"debit"`
+	Credit TransactionResponse `json:"credit"`
+}
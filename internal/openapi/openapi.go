@@ -0,0 +1,192 @@
+// Package openapi validates HTTP traffic against the OpenAPI specification
+// embedded by package api.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"wallet-microservice/internal/problem"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+var ginParamRe = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// PathFor converts a gin route pattern such as /wallets/:id to the
+// OpenAPI path template /wallets/{id}
+func PathFor(ginPath string) string {
+	return ginParamRe.ReplaceAllString(ginPath, "{$1}")
+}
+
+// Route finds the operation documented for a gin route, or nil
+func Route(doc *openapi3.T, method, ginPath string) *routers.Route {
+	path := PathFor(ginPath)
+	item := doc.Paths.Value(path)
+	if item == nil {
+		return nil
+	}
+	op := item.GetOperation(method)
+	if op == nil {
+		return nil
+	}
+	return &routers.Route{Spec: doc, Path: path, PathItem: item, Method: method, Operation: op}
+}
+
+// Middleware rejects requests that do not match the specification with a
+// 400 validation_error problem.
+// With validateResponses it also buffers each response and replaces one
+// that does not match with a 500 describing the mismatch; that is meant for
+// tests, where a handler drifting from the spec should fail loudly.
+// Requests to routes without a spec entry pass through unchecked.
+func Middleware(doc *openapi3.T, validateResponses bool) gin.HandlerFunc {
+	options := &openapi3filter.Options{
+		// There are no security schemes to check
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(c *gin.Context) {
+		route := Route(doc, c.Request.Method, c.FullPath())
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: params,
+			Route:      route,
+			Options:    options,
+		}
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			problem.Write(c, requestProblem(err))
+			return
+		}
+
+		if !validateResponses {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		responseInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 w.status,
+			Header:                 w.Header(),
+			Options:                options,
+		}
+		responseInput.SetBodyBytes(w.body.Bytes())
+		if err := openapi3filter.ValidateResponse(context.WithoutCancel(c.Request.Context()), responseInput); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Response does not match the OpenAPI spec",
+				"method", route.Method, "path", route.Path, "status", w.status, "error", err)
+			w.Header().Del("Content-Length")
+			w.Header().Del("Content-Type")
+			problem.Write(c, problem.New(http.StatusInternalServerError, problem.CodeInvalidResponse, err.Error()))
+			return
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
+
+// requestProblem describes a request that does not match the spec, with a
+// violation naming the offending parameter or body field when kin-openapi
+// reports one
+func requestProblem(err error) *problem.Details {
+	var reqErr *openapi3filter.RequestError
+	if !errors.As(err, &reqErr) {
+		return problem.New(http.StatusBadRequest, problem.CodeValidation, firstLine(err))
+	}
+
+	var field string
+	if reqErr.Parameter != nil {
+		field = reqErr.Parameter.Name
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(reqErr.Err, &schemaErr) {
+		if path := schemaErr.JSONPointer(); len(path) > 0 {
+			field = strings.Join(path, ".")
+		}
+		if field != "" {
+			return problem.New(http.StatusBadRequest, problem.CodeValidation, "",
+				problem.Violation{Field: field, Message: schemaMessage(schemaErr)})
+		}
+	}
+	var parseErr *openapi3filter.ParseError
+	if errors.As(reqErr.Err, &parseErr) {
+		if field != "" {
+			return problem.New(http.StatusBadRequest, problem.CodeValidation, "",
+				problem.Violation{Field: field, Message: "is malformed"})
+		}
+		return problem.New(http.StatusBadRequest, problem.CodeValidation, "request body is not valid JSON")
+	}
+	return problem.New(http.StatusBadRequest, problem.CodeValidation, firstLine(err))
+}
+
+// schemaMessage phrases a schema violation to follow the field name
+func schemaMessage(err *openapi3.SchemaError) string {
+	switch {
+	case err.SchemaField == "required":
+		return "is required"
+	case err.SchemaField == "format" && err.Schema != nil:
+		return "must be a valid " + err.Schema.Format
+	}
+	return strings.TrimPrefix(err.Reason, "value ")
+}
+
+// firstLine shortens kin-openapi's multi-line messages to their first line
+func firstLine(err error) string {
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	return msg
+}
+
+// bufferedWriter holds back the status and body so the response can be
+// validated before anything reaches the client. Headers go straight to the
+// underlying writer's header map.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return false
+}
@@ -0,0 +1,108 @@
+//go:build unit
+// +build unit
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wallet-microservice/api"
+	"wallet-microservice/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathFor(t *testing.T) {
+	assert.Equal(t, "/api/v1/wallets/{id}/credit", PathFor("/api/v1/wallets/:id/credit"))
+	assert.Equal(t, "/api/v1/users/{userId}/wallet", PathFor("/api/v1/users/:userId/wallet"))
+	assert.Equal(t, "/files/{path}", PathFor("/files/*path"))
+	assert.Equal(t, "/livez", PathFor("/livez"))
+}
+
+func newRouter(t *testing.T, validateResponses bool) *gin.Engine {
+	doc, err := api.Load()
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(doc, validateResponses))
+	router.POST("/api/v1/wallets/:id/credit", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": "not-a-transaction"})
+	})
+	router.GET("/livez", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "up", "service": "test"})
+	})
+	router.GET("/undocumented", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return router
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddlewareValidatesRequests(t *testing.T) {
+	router := newRouter(t, false)
+	const credit = "/api/v1/wallets/6f1c2d4e-8a9b-4c3d-9e8f-0a1b2c3d4e5f/credit"
+
+	tests := []struct {
+		name, path, body string
+		want             problem.Violation
+	}{
+		{"negative amount", credit, `{"amount": -5}`, problem.Violation{Field: "amount", Message: "number must be more than 0"}},
+		{"missing amount", credit, `{"description": "x"}`, problem.Violation{Field: "amount", Message: "is required"}},
+		{"amount as string", credit, `{"amount": "5"}`, problem.Violation{Field: "amount", Message: "must be a number"}},
+		{"malformed wallet ID", "/api/v1/wallets/42/credit", `{"amount": 5}`, problem.Violation{Field: "id", Message: "must be a valid uuid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(router, http.MethodPost, tt.path, tt.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+
+			var p problem.Details
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+			assert.Equal(t, problem.CodeValidation, p.Code)
+			assert.Equal(t, []problem.Violation{tt.want}, p.Errors)
+		})
+	}
+
+	rec := serve(router, http.MethodPost, credit, `{"amount":`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "request body is not valid JSON")
+
+	// A valid request reaches the handler; its response is not checked
+	rec = serve(router, http.MethodPost, credit, `{"amount": 5}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "not-a-transaction")
+
+	rec = serve(router, http.MethodGet, "/undocumented", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMiddlewareValidatesResponses(t *testing.T) {
+	router := newRouter(t, true)
+
+	rec := serve(router, http.MethodPost, "/api/v1/wallets/6f1c2d4e-8a9b-4c3d-9e8f-0a1b2c3d4e5f/credit", `{"amount": 5}`)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "invalid_response")
+
+	rec = serve(router, http.MethodGet, "/livez", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"up","service":"test"}`, rec.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+}
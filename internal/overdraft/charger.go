@@ -0,0 +1,170 @@
+// Package overdraft charges wallets interest and a fee for every day they
+// end overdrawn. Only one instance runs at a time: the one holding a
+// Postgres advisory lock.
+package overdraft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/interest"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lockKey identifies the Postgres advisory lock held while charging
+const lockKey int64 = 7_310_021_554_005
+
+// pageSize is the number of wallets listed at a time
+const pageSize = 100
+
+// Charger charges overdrawn wallets from the end-of-day balances in the
+// ledger
+type Charger struct {
+	db  *gorm.DB
+	cfg config.OverdraftConfig
+	now func() time.Time
+}
+
+func New(db *gorm.DB, cfg config.OverdraftConfig) *Charger {
+	return &Charger{
+		db:  db,
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Run charges through yesterday, the last complete day. It is meant to run
+// periodically.
+func (c *Charger) Run(ctx context.Context) error {
+	return c.ChargeThrough(ctx, interest.Date(c.now()).AddDate(0, 0, -1))
+}
+
+// ChargeThrough charges every wallet with an overdraft limit, or that is
+// overdrawn, for each day through date it has not been charged for yet, in
+// order. A wallet never charged before starts with date. Each day is charged
+// in the same database transaction that records it, so running again for a
+// date, or concurrently with another run, changes nothing.
+func (c *Charger) ChargeThrough(ctx context.Context, date time.Time) error {
+	date = interest.Date(date)
+	ran, err := database.WithTryLock(ctx, c.db, lockKey, func(conn *gorm.DB) error {
+		for ctx.Err() == nil {
+			ids, err := repositories.NewOverdraftRepository(conn).WalletsToCharge(ctx, date, pageSize)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := c.catchUp(ctx, conn, id, date); err != nil {
+					return fmt.Errorf("charge overdraft of wallet %s: %w", id, err)
+				}
+			}
+			// Wallets charged through date are no longer listed, so the next
+			// page starts with the ones left over
+			if len(ids) < pageSize {
+				return nil
+			}
+		}
+		return ctx.Err()
+	})
+	if err == nil && !ran {
+		slog.DebugContext(ctx, "Overdrafts are being charged elsewhere; skipping")
+	}
+	return err
+}
+
+// catchUp charges the days a wallet is behind through date, one transaction
+// per day
+func (c *Charger) catchUp(ctx context.Context, conn *gorm.DB, walletID uuid.UUID, through time.Time) error {
+	for done := false; !done; {
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			repo := repositories.NewOverdraftRepository(tx)
+			if _, err := repo.LockWallet(ctx, walletID); err != nil {
+				if errors.Is(err, models.ErrWalletNotFound) {
+					// Deleted since it was listed
+					done = true
+					return nil
+				}
+				return err
+			}
+			last, err := repo.LastCharge(ctx, walletID)
+			if err != nil {
+				return err
+			}
+			day := through
+			if last != nil {
+				day = interest.Date(last.Date).AddDate(0, 0, 1)
+			}
+			if day.After(through) {
+				done = true
+				return nil
+			}
+			return c.charge(ctx, tx, walletID, day)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// charge charges a wallet for day and records the charge, even when there is
+// nothing to charge
+func (c *Charger) charge(ctx context.Context, tx *gorm.DB, walletID uuid.UUID, day time.Time) error {
+	repo := repositories.NewOverdraftRepository(tx)
+	balance, err := repo.EndOfDayBalance(ctx, walletID, day)
+	if err != nil {
+		return err
+	}
+	charge := &models.OverdraftCharge{
+		WalletID:   walletID,
+		Date:       day,
+		Used:       cents(math.Max(0, -balance)),
+		AnnualRate: c.cfg.Rate,
+	}
+	if charge.Used > 0 {
+		charge.Fee = c.cfg.DailyFee
+		charge.Amount = cents(charge.Used*c.cfg.Rate/365 + c.cfg.DailyFee)
+	}
+
+	if charge.Amount > 0 {
+		// The debit is made within tx, so it commits or rolls back with the
+		// charge record. It is owed, so it may go past the limit.
+		debit := &models.Transaction{
+			Description: "Overdraft charge",
+			Reference:   "overdraft:" + day.Format(time.DateOnly),
+			Charge:      true,
+		}
+		err := repositories.NewWalletRepository(tx).ProcessTransactionWithRollback(ctx, walletID, charge.Amount, models.Debit, debit)
+		if err != nil {
+			return err
+		}
+		charge.TransactionID = &debit.ID
+	}
+
+	if err := repo.RecordCharge(ctx, charge); err != nil {
+		return err
+	}
+	if charge.Amount > 0 {
+		slog.InfoContext(ctx, "Charged overdraft",
+			"wallet_id", walletID,
+			"date", day.Format(time.DateOnly),
+			"used", charge.Used,
+			"amount", charge.Amount,
+		)
+	}
+	return nil
+}
+
+// cents rounds to whole cents, half away from zero
+func cents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,134 @@
+//go:build unit
+// +build unit
+
+package overdraft
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// newCharger returns a charger over a private in-memory SQLite database.
+// 3.65% a year on ACT/365 is a tenth of a thousandth a day.
+func newCharger(t *testing.T) *Charger {
+	t.Helper()
+	cfg := config.Defaults()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	db, err := database.Open(cfg.Database)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Migrate(context.Background()))
+
+	return New(db.Gorm(), config.OverdraftConfig{Rate: 0.0365, DailyFee: 0.5})
+}
+
+func newWallet(t *testing.T, db *gorm.DB, limit float64) uuid.UUID {
+	t.Helper()
+	w := &models.Wallet{UserID: uuid.NewString(), Currency: "USD", OverdraftLimit: limit}
+	require.NoError(t, db.Create(w).Error)
+	return w.ID
+}
+
+// post books a transaction as if it had been made at at
+func post(t *testing.T, db *gorm.DB, walletID uuid.UUID, typ models.TransactionType, amount float64, at time.Time) {
+	t.Helper()
+	signed := amount
+	if typ == models.Debit {
+		signed = -amount
+	}
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Transaction{WalletID: walletID, Type: typ, Amount: amount, CreatedAt: at}).Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE wallets SET balance = balance + ? WHERE id = ?", signed, walletID).Error
+	}))
+}
+
+func charges(t *testing.T, db *gorm.DB, walletID uuid.UUID) []models.OverdraftCharge {
+	t.Helper()
+	var charges []models.OverdraftCharge
+	require.NoError(t, db.Where("wallet_id = ?", walletID).Order("date").Find(&charges).Error)
+	return charges
+}
+
+func balance(t *testing.T, db *gorm.DB, walletID uuid.UUID) float64 {
+	t.Helper()
+	var w models.Wallet
+	require.NoError(t, db.First(&w, "id = ?", walletID).Error)
+	return w.Balance
+}
+
+func TestChargeThroughUsesEndOfDayBalances(t *testing.T) {
+	ctx := context.Background()
+	c := newCharger(t)
+	id := newWallet(t, c.db, 1000)
+	post(t, c.db, id, models.Debit, 500, day("2026-01-01").Add(10*time.Hour))
+	post(t, c.db, id, models.Credit, 500, day("2026-01-03").Add(12*time.Hour))
+
+	// A wallet is first charged for the day the job first runs through
+	require.NoError(t, c.ChargeThrough(ctx, day("2026-01-02")))
+	got := charges(t, c.db, id)
+	require.Len(t, got, 1)
+	assert.Equal(t, 500.0, got[0].Used)
+	assert.Equal(t, 0.5, got[0].Fee)
+	assert.Equal(t, 0.55, got[0].Amount)
+	require.NotNil(t, got[0].TransactionID)
+	var debit models.Transaction
+	require.NoError(t, c.db.First(&debit, "id = ?", *got[0].TransactionID).Error)
+	assert.Equal(t, "overdraft:2026-01-02", debit.Reference)
+	assert.Equal(t, models.Debit, debit.Type)
+	assert.Equal(t, -0.55, balance(t, c.db, id))
+
+	// The charge for a day counts towards the next day's balance, though it
+	// was posted later
+	c.now = func() time.Time { return day("2026-01-05").Add(time.Hour) }
+	require.NoError(t, c.Run(ctx))
+	got = charges(t, c.db, id)
+	require.Len(t, got, 3)
+	assert.Equal(t, 0.55, got[1].Used)
+	assert.Equal(t, 0.5, got[1].Amount)
+	assert.Equal(t, 1.05, got[2].Used)
+	assert.Equal(t, 0.5, got[2].Amount)
+	assert.InDelta(t, -1.55, balance(t, c.db, id), 1e-9)
+
+	// Running again, or for a day already charged, changes nothing
+	require.NoError(t, c.Run(ctx))
+	require.NoError(t, c.ChargeThrough(ctx, day("2026-01-03")))
+	assert.Len(t, charges(t, c.db, id), 3)
+	assert.InDelta(t, -1.55, balance(t, c.db, id), 1e-9)
+}
+
+func TestChargeThroughRecordsDaysInCredit(t *testing.T) {
+	ctx := context.Background()
+	c := newCharger(t)
+	inCredit := newWallet(t, c.db, 100)
+	post(t, c.db, inCredit, models.Credit, 10, day("2026-01-01"))
+	noLimit := newWallet(t, c.db, 0)
+
+	require.NoError(t, c.ChargeThrough(ctx, day("2026-01-02")))
+	got := charges(t, c.db, inCredit)
+	require.Len(t, got, 1)
+	assert.Zero(t, got[0].Amount)
+	assert.Nil(t, got[0].TransactionID)
+	assert.Equal(t, 10.0, balance(t, c.db, inCredit))
+	assert.Empty(t, charges(t, c.db, noLimit))
+}
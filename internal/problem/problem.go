@@ -0,0 +1,133 @@
+// Package problem writes error responses as RFC 9457 problem details
+// (application/problem+json). Clients that accept application/json but not
+// application/problem+json get the legacy {"error", "message"} shape
+// instead.
+package problem
+
+import (
+	"net/http"
+	"strings"
+
+	"wallet-microservice/internal/logging"
+	"wallet-microservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// TypeBase prefixes a problem's code to form its type URI, which is
+// relative to the API's base URL
+const TypeBase = "/problems/"
+
+// Machine-readable problem codes. They are the last segment of the type
+// URI, the code member of problem details and the error member of legacy
+// responses.
+const (
+	CodeValidation        = "validation_error"
+	CodeInvalidID         = "invalid_id"
+	CodeInvalidUserID     = "invalid_user_id"
+	CodeInvalidParameter  = "invalid_parameter"
+	CodeInvalidAmount     = "invalid_amount"
+	CodeWalletNotFound    = "wallet_not_found"
+	CodeBatchNotFound     = "batch_not_found"
+	CodeScheduleNotFound  = "schedule_not_found"
+	CodeEscrowNotFound    = "escrow_not_found"
+	CodeRequestNotFound   = "payment_request_not_found"
+	CodeBillNotFound      = "bill_not_found"
+	CodeNotEnrolled       = "interest_not_enrolled"
+	CodeDuplicate         = "duplicate"
+	CodeConflict          = "conflict"
+	CodeInsufficientFunds = "insufficient_funds"
+	CodeCurrencyMismatch  = "currency_mismatch"
+	CodeInvalidState      = "invalid_state"
+	CodeIdempotencyReused = "idempotency_key_reused"
+	CodeInternal          = "internal_error"
+	CodeInvalidResponse   = "invalid_response"
+	CodeTimeout           = "timeout"
+)
+
+// titles summarise each problem type; unlike detail they do not vary
+// between occurrences
+var titles = map[string]string{
+	CodeValidation:        "Request validation failed",
+	CodeInvalidID:         "Invalid wallet ID",
+	CodeInvalidUserID:     "Invalid user ID",
+	CodeInvalidParameter:  "Invalid query parameter",
+	CodeInvalidAmount:     "Invalid amount",
+	CodeWalletNotFound:    "Wallet not found",
+	CodeBatchNotFound:     "Batch not found",
+	CodeScheduleNotFound:  "Schedule not found",
+	CodeEscrowNotFound:    "Escrow not found",
+	CodeRequestNotFound:   "Payment request not found",
+	CodeBillNotFound:      "Bill not found",
+	CodeNotEnrolled:       "Wallet not enrolled for interest",
+	CodeDuplicate:         "Resource already exists",
+	CodeConflict:          "Concurrent update conflict",
+	CodeInsufficientFunds: "Insufficient funds",
+	CodeCurrencyMismatch:  "Currency mismatch",
+	CodeInvalidState:      "Invalid state",
+	CodeIdempotencyReused: "Idempotency key reused",
+	CodeInternal:          "Internal error",
+	CodeInvalidResponse:   "Response does not match the API specification",
+	CodeTimeout:           "Request timed out",
+}
+
+// Details is a problem details object
+type Details struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Instance is the request ID, under which the service logs the request
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// Errors lists the offending fields of a request that failed validation
+	Errors []Violation `json:"errors,omitempty"`
+}
+
+// Violation is one invalid field or parameter
+type Violation struct {
+	// Field is the JSON name of the field, dotted for nested fields, or the
+	// name of the parameter
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New returns the problem details for code. Without an explicit detail,
+// one is made up from the violations.
+func New(status int, code, detail string, violations ...Violation) *Details {
+	title := titles[code]
+	if title == "" {
+		title = http.StatusText(status)
+	}
+	if detail == "" && len(violations) > 0 {
+		parts := make([]string, len(violations))
+		for i, v := range violations {
+			parts[i] = v.Field + " " + v.Message
+		}
+		detail = strings.Join(parts, "; ")
+	}
+	return &Details{
+		Type:   TypeBase + code,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: violations,
+	}
+}
+
+// Write sends p in the representation the client negotiated and aborts
+// the handler chain
+func Write(c *gin.Context, p *Details) {
+	if c.NegotiateFormat(ContentType, gin.MIMEJSON) == gin.MIMEJSON {
+		c.AbortWithStatusJSON(p.Status, models.ErrorResponse{Error: p.Code, Message: p.Detail})
+		return
+	}
+
+	p.Instance = logging.RequestIDFromContext(c.Request.Context())
+	// gin's JSON renderer keeps a content type that is already set
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
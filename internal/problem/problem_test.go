@@ -0,0 +1,78 @@
+//go:build unit
+// +build unit
+
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-microservice/internal/logging"
+	"wallet-microservice/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, accept string, p *Details) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), "req-1"))
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	Write(c, p)
+	assert.True(t, c.IsAborted())
+	return rec
+}
+
+func TestNew(t *testing.T) {
+	p := New(http.StatusBadRequest, CodeValidation, "",
+		Violation{Field: "amount", Message: "is required"},
+		Violation{Field: "reference", Message: "must have at most 255 characters"})
+	assert.Equal(t, "/problems/validation_error", p.Type)
+	assert.Equal(t, "Request validation failed", p.Title)
+	assert.Equal(t, "amount is required; reference must have at most 255 characters", p.Detail)
+
+	// Unknown codes fall back to the status text
+	assert.Equal(t, "Too Many Requests", New(http.StatusTooManyRequests, "rate_limited", "").Title)
+}
+
+func TestWriteNegotiates(t *testing.T) {
+	newProblem := func() *Details {
+		return New(http.StatusBadRequest, CodeValidation, "", Violation{Field: "amount", Message: "is required"})
+	}
+
+	for _, accept := range []string{"", "*/*", ContentType, "application/problem+json, application/json", "text/html"} {
+		t.Run("problem "+accept, func(t *testing.T) {
+			rec := write(t, accept, newProblem())
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+
+			var p Details
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+			assert.Equal(t, "req-1", p.Instance)
+			assert.Equal(t, CodeValidation, p.Code)
+			assert.Equal(t, http.StatusBadRequest, p.Status)
+			assert.Equal(t, []Violation{{Field: "amount", Message: "is required"}}, p.Errors)
+		})
+	}
+
+	for _, accept := range []string{"application/json", "application/json, application/problem+json"} {
+		t.Run("legacy "+accept, func(t *testing.T) {
+			rec := write(t, accept, newProblem())
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+			var legacy models.ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &legacy))
+			assert.Equal(t, models.ErrorResponse{Error: CodeValidation, Message: "amount is required"}, legacy)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Package promo debits promotional credits once they lapse. Each credit
+// expires under its wallet's lock, so instances may run concurrently and
+// the memory driver needs no coordination.
+package promo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"wallet-microservice/internal/repositories"
+)
+
+// pageSize is the number of lapsed credits listed at a time
+const pageSize = 100
+
+// Expirer posts a DEBIT for what is left of each lapsed promo credit
+type Expirer struct {
+	wallets repositories.WalletRepository
+	now     func() time.Time
+}
+
+func NewExpirer(wallets repositories.WalletRepository) *Expirer {
+	return &Expirer{
+		wallets: wallets,
+		now:     time.Now,
+	}
+}
+
+// Run expires every credit that has lapsed by now. It is meant to run
+// periodically.
+func (e *Expirer) Run(ctx context.Context) error {
+	now := e.now().UTC()
+	for ctx.Err() == nil {
+		ids, err := e.wallets.LapsedPromoCredits(ctx, now, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			t, err := e.wallets.ExpirePromoCredit(ctx, id, now)
+			if err != nil {
+				return fmt.Errorf("expire promo credit %s: %w", id, err)
+			}
+			if t != nil {
+				slog.InfoContext(ctx, "Expired promo credit",
+					"credit_id", id,
+					"wallet_id", t.WalletID,
+					"amount", t.Amount,
+				)
+			}
+		}
+		// Expired credits have nothing left, so the next page starts with
+		// the ones left over
+		if len(ids) < pageSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,43 @@
+//go:build unit
+// +build unit
+
+package promo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExpiresLapsedCredits(t *testing.T) {
+	ctx := context.Background()
+	repo := repositories.NewMemoryWalletRepository()
+	wallet := &models.Wallet{UserID: "promo", Currency: "USD"}
+	require.NoError(t, repo.CreateWallet(ctx, wallet))
+
+	now := time.Now()
+	for _, d := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour} {
+		expiresAt := now.Add(d)
+		require.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 10, models.Credit,
+			&models.Transaction{Promo: &models.PromoTerms{ExpiresAt: &expiresAt}}))
+	}
+
+	e := NewExpirer(repo)
+	e.now = func() time.Time { return now.Add(150 * time.Minute) }
+	require.NoError(t, e.Run(ctx))
+	require.NoError(t, e.Run(ctx))
+
+	got, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, got.Balance)
+	assert.Equal(t, 10.0, got.PromoBalance)
+	count, err := repo.CountTransactionsByWalletID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+}
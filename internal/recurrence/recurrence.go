@@ -0,0 +1,227 @@
+// Package recurrence parses and evaluates recurrence rules: a subset of
+// RFC 5545 RRULEs such as "FREQ=MONTHLY;BYMONTHDAY=1" or
+// "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR". Occurrences fall on the time of day
+// of the rule's start, in UTC.
+package recurrence
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+const maxInterval = 999
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq Frequency
+	// Interval is the number of days, weeks or months between periods
+	// with occurrences
+	Interval int
+	// ByDay restricts daily and weekly rules to these weekdays. A weekly
+	// rule without it recurs on the weekday of the start.
+	ByDay []time.Weekday
+	// ByMonthDay lists the days of a monthly rule, negative ones counting
+	// from the end of the month. Days past the end of a month fall on its
+	// last day. Without it the rule recurs on the day of the start.
+	ByMonthDay []int
+}
+
+// Parse parses a rule, with or without the "RRULE:" prefix. Parts it does
+// not support, such as COUNT or UNTIL, are an error rather than ignored.
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return Rule{}, fmt.Errorf("empty rule")
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(name)
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("malformed part %q", part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%s given more than once", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+			if r.Freq != Daily && r.Freq != Weekly && r.Freq != Monthly {
+				return Rule{}, fmt.Errorf("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxInterval {
+				return Rule{}, fmt.Errorf("INTERVAL must be between 1 and %d", maxInterval)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return Rule{}, fmt.Errorf("BYDAY has unknown weekday %q", d)
+				}
+				if !slices.Contains(r.ByDay, wd) {
+					r.ByDay = append(r.ByDay, wd)
+				}
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return Rule{}, fmt.Errorf("BYMONTHDAY must be between 1 and 31 or -31 and -1")
+				}
+				if !slices.Contains(r.ByMonthDay, n) {
+					r.ByMonthDay = append(r.ByMonthDay, n)
+				}
+			}
+		default:
+			return Rule{}, fmt.Errorf("%s is not supported", name)
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return Rule{}, fmt.Errorf("FREQ is required")
+	case len(r.ByDay) > 0 && r.Freq == Monthly:
+		return Rule{}, fmt.Errorf("BYDAY is only supported with DAILY and WEEKLY")
+	case len(r.ByMonthDay) > 0 && r.Freq != Monthly:
+		return Rule{}, fmt.Errorf("BYMONTHDAY is only supported with MONTHLY")
+	}
+	return r, nil
+}
+
+// String returns the rule in canonical form, without the "RRULE:" prefix
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = strings.ToUpper(wd.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// First returns the first occurrence of a rule starting at start, which is
+// start itself if it matches. It returns the zero time if the rule never
+// occurs, e.g. "FREQ=DAILY;INTERVAL=7;BYDAY=MO" starting on a Tuesday.
+func (r Rule) First(start time.Time) time.Time {
+	return r.Next(start, start.Add(-time.Nanosecond))
+}
+
+// Next returns the first occurrence of a rule starting at start that is
+// after after, or the zero time if there is none
+func (r Rule) Next(start, after time.Time) time.Time {
+	start = start.UTC()
+	after = after.UTC()
+	clock := start.Sub(dateOf(start))
+
+	day := dateOf(start)
+	if d := dateOf(after); d.After(day) {
+		day = d
+	}
+	// Every period of a valid rule has an occurrence unless BYDAY excludes
+	// all of a daily rule's days, which repeats within a year of periods
+	for range 366*max(r.Interval, 1) + 31 {
+		if r.matches(start, day) {
+			if t := day.Add(clock); t.After(after) && !t.Before(start) {
+				return t
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// matches reports whether the rule has an occurrence on day
+func (r Rule) matches(start, day time.Time) bool {
+	interval := max(r.Interval, 1)
+	switch r.Freq {
+	case Daily:
+		return daysBetween(dateOf(start), day)%interval == 0 &&
+			(len(r.ByDay) == 0 || slices.Contains(r.ByDay, day.Weekday()))
+	case Weekly:
+		weeks := daysBetween(weekOf(start), weekOf(day)) / 7
+		if weeks%interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return day.Weekday() == start.Weekday()
+		}
+		return slices.Contains(r.ByDay, day.Weekday())
+	case Monthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())
+		if months%interval != 0 {
+			return false
+		}
+		if len(r.ByMonthDay) == 0 {
+			return day.Day() == monthDay(day, start.Day())
+		}
+		for _, d := range r.ByMonthDay {
+			if day.Day() == monthDay(day, d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// monthDay resolves a BYMONTHDAY value within the month of t
+func monthDay(t time.Time, d int) int {
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if d < 0 {
+		d = max(last+d+1, 1)
+	}
+	return min(d, last)
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// weekOf returns the Monday starting the week of t
+func weekOf(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return dateOf(t).AddDate(0, 0, -offset)
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Hours() / 24)
+}
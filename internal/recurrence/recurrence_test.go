@@ -0,0 +1,129 @@
+//go:build unit
+// +build unit
+
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse("RRULE:FREQ=weekly;INTERVAL=2;BYDAY=MO,fr,MO")
+	require.NoError(t, err)
+	assert.Equal(t, Rule{Freq: Weekly, Interval: 2, ByDay: []time.Weekday{time.Monday, time.Friday}}, r)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", r.String())
+
+	r, err = Parse("FREQ=MONTHLY;BYMONTHDAY=1,-1")
+	require.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=1,-1", r.String())
+
+	for _, s := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;COUNT=3",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ",
+	} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		rule  string
+		start string
+		after string
+		want  []string
+	}{
+		{
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=1",
+			start: "2026-01-15T09:00:00Z",
+			after: "2026-01-15T09:00:00Z",
+			want:  []string{"2026-02-01T09:00:00Z", "2026-03-01T09:00:00Z", "2026-04-01T09:00:00Z"},
+		},
+		{
+			rule:  "FREQ=MONTHLY",
+			start: "2026-01-31T00:00:00Z",
+			after: "2026-01-31T00:00:00Z",
+			want:  []string{"2026-02-28T00:00:00Z", "2026-03-31T00:00:00Z", "2026-04-30T00:00:00Z"},
+		},
+		{
+			rule:  "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=-1",
+			start: "2026-01-10T12:00:00Z",
+			after: "2026-01-10T12:00:00Z",
+			want:  []string{"2026-01-31T12:00:00Z", "2026-04-30T12:00:00Z", "2026-07-31T12:00:00Z"},
+		},
+		{
+			// 2026-01-05 is a Monday; every other week on Monday and Friday
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			start: "2026-01-07T08:30:00Z",
+			after: "2026-01-07T08:30:00Z",
+			want:  []string{"2026-01-09T08:30:00Z", "2026-01-19T08:30:00Z", "2026-01-23T08:30:00Z", "2026-02-02T08:30:00Z"},
+		},
+		{
+			rule:  "FREQ=DAILY;INTERVAL=3",
+			start: "2026-03-01T06:00:00Z",
+			after: "2026-03-01T06:00:00Z",
+			want:  []string{"2026-03-04T06:00:00Z", "2026-03-07T06:00:00Z"},
+		},
+		{
+			// Weekdays only; 2026-01-09 is a Friday
+			rule:  "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			start: "2026-01-08T00:00:00Z",
+			after: "2026-01-08T00:00:00Z",
+			want:  []string{"2026-01-09T00:00:00Z", "2026-01-12T00:00:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			require.NoError(t, err)
+			after := date(tt.after)
+			for _, want := range tt.want {
+				got := r.Next(date(tt.start), after)
+				assert.Equal(t, date(want), got)
+				after = got
+			}
+		})
+	}
+}
+
+func TestNextSkipsMissedOccurrences(t *testing.T) {
+	r, err := Parse("FREQ=DAILY")
+	require.NoError(t, err)
+	got := r.Next(date("2026-01-01T09:00:00Z"), date("2026-01-10T10:00:00Z"))
+	assert.Equal(t, date("2026-01-11T09:00:00Z"), got)
+}
+
+func TestFirst(t *testing.T) {
+	r, err := Parse("FREQ=MONTHLY;BYMONTHDAY=1")
+	require.NoError(t, err)
+	assert.Equal(t, date("2026-02-01T09:00:00Z"), r.First(date("2026-02-01T09:00:00Z")))
+	assert.Equal(t, date("2026-03-01T09:00:00Z"), r.First(date("2026-02-02T09:00:00Z")))
+	// Occurrences are in UTC, at the start's time of day
+	assert.Equal(t, date("2026-02-01T08:00:00Z"), r.First(date("2026-02-01T09:00:00+01:00")))
+
+	never, err := Parse("FREQ=DAILY;INTERVAL=7;BYDAY=MO")
+	require.NoError(t, err)
+	assert.True(t, never.First(date("2026-01-06T00:00:00Z")).IsZero())
+}
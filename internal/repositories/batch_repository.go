@@ -0,0 +1,267 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BatchRepository interface {
+	// CreateBatch stores a batch together with its items
+	CreateBatch(ctx context.Context, batch *models.Batch) error
+	// GetBatch returns a batch with its items in submission order
+	GetBatch(ctx context.Context, id uuid.UUID) (*models.Batch, error)
+	// MissingWallets returns those of ids that match no wallet
+	MissingWallets(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+	// ClaimBatch marks the oldest pending batch, or one left processing
+	// since before staleBefore, as processing and returns it. It returns
+	// nil when there is nothing to claim.
+	//
+	// The batch's ClaimedAt is the claim token. ApplyAtomic, ApplyItem and
+	// FinishBatch only write while the batch is still processing under it,
+	// and return models.ErrConflict once another instance has taken the
+	// batch over.
+	ClaimBatch(ctx context.Context, staleBefore time.Time) (*models.Batch, error)
+	// ApplyAtomic applies every pending item of batch in one database
+	// transaction and completes the batch in the same transaction. When an
+	// item fails nothing is changed and a *models.ItemError is returned.
+	ApplyAtomic(ctx context.Context, batch *models.Batch) error
+	// ApplyItem applies one pending item of batch and records its success
+	// atomically. A failed item is returned the domain error and left
+	// unchanged.
+	ApplyItem(ctx context.Context, batch *models.Batch, item *models.BatchItem) error
+	// FinishBatch saves the outcome of the failed and aborted items and the
+	// batch status; succeeded items were recorded as they were applied
+	FinishBatch(ctx context.Context, batch *models.Batch) error
+}
+
+type batchRepository struct {
+	db *gorm.DB
+}
+
+// NewBatchRepository returns a batch repository backed by db. Batches move
+// money with the same row locks and balance checks as WalletRepository.
+func NewBatchRepository(db *gorm.DB) BatchRepository {
+	return &batchRepository{db: db}
+}
+
+func (r *batchRepository) CreateBatch(ctx context.Context, batch *models.Batch) (err error) {
+	ctx, span := tracer.Start(ctx, "batchRepository.CreateBatch")
+	defer tracing.End(span, &err)
+
+	// Large batches are inserted in chunks to stay below bind parameter limits
+	return r.db.WithContext(ctx).Session(&gorm.Session{CreateBatchSize: 500}).Create(batch).Error
+}
+
+func (r *batchRepository) GetBatch(ctx context.Context, id uuid.UUID) (_ *models.Batch, err error) {
+	ctx, span := tracer.Start(ctx, "batchRepository.GetBatch")
+	defer tracing.End(span, &err)
+
+	var batch models.Batch
+	err = r.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("seq") }).
+		First(&batch, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrBatchNotFound
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (r *batchRepository) MissingWallets(ctx context.Context, ids []uuid.UUID) (_ []uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "batchRepository.MissingWallets")
+	defer tracing.End(span, &err)
+
+	var found []uuid.UUID
+	if err := r.db.WithContext(ctx).Model(&models.Wallet{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	exists := make(map[uuid.UUID]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if !exists[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func (r *batchRepository) ClaimBatch(ctx context.Context, staleBefore time.Time) (_ *models.Batch, err error) {
+	ctx, span := tracer.Start(ctx, "batchRepository.ClaimBatch")
+	defer tracing.End(span, &err)
+
+	claimable := func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ? OR (status = ? AND claimed_at < ?)",
+			models.BatchPending, models.BatchProcessing, staleBefore.UTC())
+	}
+
+	var candidate models.Batch
+	err = r.db.WithContext(ctx).Scopes(claimable).Order("created_at").Select("id").First(&candidate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Another instance may have claimed it since; the conditions make the
+	// update a compare-and-swap
+	now := models.ClaimTime(time.Now())
+	result := r.db.WithContext(ctx).Model(&models.Batch{}).Scopes(claimable).Where("id = ?", candidate.ID).
+		Updates(map[string]any{"status": models.BatchProcessing, "claimed_at": now, "updated_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	batch, err := r.GetBatch(ctx, candidate.ID)
+	if err != nil {
+		return nil, err
+	}
+	// The token as written, whatever the driver reads back
+	batch.ClaimedAt = &now
+	return batch, nil
+}
+
+func (r *batchRepository) ApplyAtomic(ctx context.Context, batch *models.Batch) (err error) {
+	ctx, span := tracer.Start(ctx, "batchRepository.ApplyAtomic")
+	defer tracing.End(span, &err)
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := holdClaim(tx, batch); err != nil {
+			return err
+		}
+		txs := make([]*models.Transaction, len(batch.Items))
+		for i := range batch.Items {
+			txs[i] = batch.Items[i].Transaction()
+		}
+		if err := applyTransactions(tx, txs); err != nil {
+			return err
+		}
+
+		for i := range batch.Items {
+			batch.Items[i].Status = models.BatchItemSucceeded
+			batch.Items[i].TransactionID = &txs[i].ID
+			if err := saveItem(tx, &batch.Items[i]); err != nil {
+				return err
+			}
+		}
+		batch.Status = models.BatchCompleted
+		return saveStatus(tx, batch)
+	})
+	return translateError(r.db, err)
+}
+
+func (r *batchRepository) ApplyItem(ctx context.Context, batch *models.Batch, item *models.BatchItem) (err error) {
+	ctx, span := tracer.Start(ctx, "batchRepository.ApplyItem")
+	defer tracing.End(span, &err)
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := holdClaim(tx, batch); err != nil {
+			return err
+		}
+		txModel := item.Transaction()
+		if err := applyTransactions(tx, []*models.Transaction{txModel}); err != nil {
+			return err
+		}
+		succeeded := *item
+		succeeded.Status = models.BatchItemSucceeded
+		succeeded.TransactionID = &txModel.ID
+		if err := saveItem(tx, &succeeded); err != nil {
+			return err
+		}
+		*item = succeeded
+		return nil
+	})
+	// A single item needs no index; hand back the domain error itself
+	return translateError(r.db, unwrapItemError(err))
+}
+
+func (r *batchRepository) FinishBatch(ctx context.Context, batch *models.Batch) (err error) {
+	ctx, span := tracer.Start(ctx, "batchRepository.FinishBatch")
+	defer tracing.End(span, &err)
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := holdClaim(tx, batch); err != nil {
+			return err
+		}
+		for i := range batch.Items {
+			item := &batch.Items[i]
+			if item.Status == models.BatchItemFailed || item.Status == models.BatchItemAborted {
+				if err := saveItem(tx, item); err != nil {
+					return err
+				}
+			}
+		}
+		return saveStatus(tx, batch)
+	})
+	return translateError(r.db, err)
+}
+
+// holdClaim checks within tx that batch is still processing under the
+// claim it was loaded with, and locks its row until tx ends, so that no
+// other instance can take it over meanwhile. It returns models.ErrConflict
+// when the claim was lost.
+func holdClaim(tx *gorm.DB, batch *models.Batch) error {
+	if batch.ClaimedAt == nil {
+		return models.ErrConflict
+	}
+	result := tx.Model(&models.Batch{}).
+		Where("id = ? AND status = ? AND claimed_at = ?", batch.ID, models.BatchProcessing, *batch.ClaimedAt).
+		Update("updated_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrConflict
+	}
+	return nil
+}
+
+// saveItem writes the outcome of an item within tx. Only a pending item is
+// written; one that is no longer pending was settled by another instance,
+// and tx is rolled back with models.ErrConflict.
+func saveItem(tx *gorm.DB, item *models.BatchItem) error {
+	result := tx.Model(&models.BatchItem{}).
+		Where("id = ? AND status = ?", item.ID, models.BatchItemPending).
+		Updates(map[string]any{
+			"status":         item.Status,
+			"transaction_id": item.TransactionID,
+			"error_code":     item.ErrorCode,
+			"error_message":  item.ErrorMessage,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrConflict
+	}
+	return nil
+}
+
+// saveStatus writes the status of batch within tx and stamps it as
+// completed once it is done
+func saveStatus(tx *gorm.DB, batch *models.Batch) error {
+	now := time.Now().UTC()
+	if batch.Done() {
+		batch.CompletedAt = &now
+	}
+	batch.UpdatedAt = now
+	return tx.Model(batch).Updates(map[string]any{
+		"status":       batch.Status,
+		"completed_at": batch.CompletedAt,
+		"updated_at":   now,
+	}).Error
+}
@@ -0,0 +1,173 @@
+//go:build unit
+// +build unit
+
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatch(mode models.BatchMode, status models.BatchStatus, items ...models.BatchItem) *models.Batch {
+	for i := range items {
+		items[i].Seq = i
+		items[i].Status = models.BatchItemPending
+	}
+	batch := &models.Batch{Mode: mode, Status: status, ItemCount: len(items), Items: items}
+	if status == models.BatchProcessing {
+		claimedAt := models.ClaimTime(time.Now())
+		batch.ClaimedAt = &claimedAt
+	}
+	return batch
+}
+
+func TestBatchRepositoryApplyAtomic(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	wallets := repositories.NewWalletRepository(db.Gorm())
+	batches := repositories.NewBatchRepository(db.Gorm())
+
+	a := &models.Wallet{UserID: "a", Currency: "USD"}
+	b := &models.Wallet{UserID: "b", Currency: "USD"}
+	require.NoError(t, wallets.CreateWallet(ctx, a))
+	require.NoError(t, wallets.CreateWallet(ctx, b))
+
+	// The debit of b fails, so the credit of a before it is rolled back
+	failing := newBatch(models.BatchAtomic, models.BatchProcessing,
+		models.BatchItem{WalletID: a.ID, Type: models.Credit, Amount: 10},
+		models.BatchItem{WalletID: b.ID, Type: models.Debit, Amount: 5},
+	)
+	require.NoError(t, batches.CreateBatch(ctx, failing))
+	err := batches.ApplyAtomic(ctx, failing)
+	var itemErr *models.ItemError
+	require.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 1, itemErr.Index)
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	got, err := wallets.GetWalletByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.Balance)
+
+	ok := newBatch(models.BatchAtomic, models.BatchProcessing,
+		models.BatchItem{WalletID: b.ID, Type: models.Credit, Amount: 10},
+		models.BatchItem{WalletID: a.ID, Type: models.Credit, Amount: 3},
+		models.BatchItem{WalletID: b.ID, Type: models.Debit, Amount: 4},
+	)
+	require.NoError(t, batches.CreateBatch(ctx, ok))
+	require.NoError(t, batches.ApplyAtomic(ctx, ok))
+
+	stored, err := batches.GetBatch(ctx, ok.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchCompleted, stored.Status)
+	assert.NotNil(t, stored.CompletedAt)
+	require.Len(t, stored.Items, 3)
+	for i, item := range stored.Items {
+		assert.Equal(t, i, item.Seq)
+		assert.Equal(t, models.BatchItemSucceeded, item.Status)
+		assert.NotNil(t, item.TransactionID)
+	}
+	got, err = wallets.GetWalletByID(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 6.0, got.Balance)
+}
+
+func TestBatchRepositoryClaimBatch(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	batches := repositories.NewBatchRepository(db.Gorm())
+
+	claimed, err := batches.ClaimBatch(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, claimed, "nothing to claim")
+
+	pending := newBatch(models.BatchBestEffort, models.BatchPending,
+		models.BatchItem{WalletID: uuid.New(), Type: models.Credit, Amount: 1})
+	require.NoError(t, batches.CreateBatch(ctx, pending))
+
+	claimed, err = batches.ClaimBatch(ctx, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, pending.ID, claimed.ID)
+	assert.Equal(t, models.BatchProcessing, claimed.Status)
+	assert.Len(t, claimed.Items, 1)
+
+	// Processing batches are left alone until they go stale
+	again, err := batches.ClaimBatch(ctx, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Nil(t, again)
+	again, err = batches.ClaimBatch(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, again)
+	assert.Equal(t, pending.ID, again.ID)
+}
+
+func TestBatchRepositoryStaleClaim(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	wallets := repositories.NewWalletRepository(db.Gorm())
+	batches := repositories.NewBatchRepository(db.Gorm())
+	a := &models.Wallet{UserID: "a", Currency: "USD"}
+	require.NoError(t, wallets.CreateWallet(ctx, a))
+
+	for _, mode := range []models.BatchMode{models.BatchBestEffort, models.BatchAtomic} {
+		t.Run(string(mode), func(t *testing.T) {
+			require.NoError(t, batches.CreateBatch(ctx, newBatch(mode, models.BatchPending,
+				models.BatchItem{WalletID: a.ID, Type: models.Credit, Amount: 10})))
+			before, err := wallets.GetWalletByID(ctx, a.ID)
+			require.NoError(t, err)
+
+			// The first claim stalls and another instance takes the batch over
+			stale, err := batches.ClaimBatch(ctx, time.Now().Add(-time.Minute))
+			require.NoError(t, err)
+			require.NotNil(t, stale)
+			time.Sleep(time.Millisecond)
+			takeover, err := batches.ClaimBatch(ctx, time.Now())
+			require.NoError(t, err)
+			require.NotNil(t, takeover)
+			require.Equal(t, stale.ID, takeover.ID)
+
+			if mode == models.BatchAtomic {
+				require.NoError(t, batches.ApplyAtomic(ctx, takeover))
+				assert.ErrorIs(t, batches.ApplyAtomic(ctx, stale), models.ErrConflict)
+			} else {
+				require.NoError(t, batches.ApplyItem(ctx, takeover, &takeover.Items[0]))
+				takeover.Status = models.BatchCompleted
+				require.NoError(t, batches.FinishBatch(ctx, takeover))
+				assert.ErrorIs(t, batches.ApplyItem(ctx, stale, &stale.Items[0]), models.ErrConflict)
+				assert.Equal(t, models.BatchItemPending, stale.Items[0].Status)
+				stale.Status = models.BatchFailed
+				assert.ErrorIs(t, batches.FinishBatch(ctx, stale), models.ErrConflict)
+			}
+
+			after, err := wallets.GetWalletByID(ctx, a.ID)
+			require.NoError(t, err)
+			assert.Equal(t, before.Balance+10, after.Balance, "the credit is applied once")
+			stored, err := batches.GetBatch(ctx, stale.ID)
+			require.NoError(t, err)
+			assert.Equal(t, models.BatchCompleted, stored.Status)
+			assert.Equal(t, models.BatchItemSucceeded, stored.Items[0].Status)
+		})
+	}
+}
+
+func TestBatchRepositoryMissingWallets(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	wallet := &models.Wallet{UserID: "present", Currency: "USD"}
+	require.NoError(t, repositories.NewWalletRepository(db.Gorm()).CreateWallet(ctx, wallet))
+
+	unknown := uuid.New()
+	missing, err := repositories.NewBatchRepository(db.Gorm()).MissingWallets(ctx, []uuid.UUID{wallet.ID, unknown})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{unknown}, missing)
+
+	_, err = repositories.NewBatchRepository(db.Gorm()).GetBatch(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrBatchNotFound)
+}
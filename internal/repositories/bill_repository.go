@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BillRepository interface {
+	// CreateBill stores a bill with its shares
+	CreateBill(ctx context.Context, bill *models.Bill) error
+	// GetBill returns a bill with its shares in order
+	GetBill(ctx context.Context, id uuid.UUID) (*models.Bill, error)
+	// ListBillsByWallet returns the bills a wallet paid or has a share in,
+	// with their shares, oldest first
+	ListBillsByWallet(ctx context.Context, walletID uuid.UUID) ([]models.Bill, error)
+	// SettleShare moves the amount of a pending share from the
+	// participant's wallet to the payer's and marks it settled, and the bill
+	// too once no share is left pending, in one database transaction. It
+	// returns models.ErrInvalidState when the share is already settled.
+	SettleShare(ctx context.Context, billID uuid.UUID, seq int) (*models.BillShare, error)
+	// RecordShareFailure records why a pending share could not be settled
+	RecordShareFailure(ctx context.Context, shareID uuid.UUID, failure models.ItemFailure) error
+}
+
+type billRepository struct {
+	db *gorm.DB
+}
+
+// NewBillRepository returns a bill repository backed by db. Settlements
+// move money with the same row locks and balance checks as
+// WalletRepository.
+func NewBillRepository(db *gorm.DB) BillRepository {
+	return &billRepository{db: db}
+}
+
+func (r *billRepository) CreateBill(ctx context.Context, bill *models.Bill) (err error) {
+	ctx, span := tracer.Start(ctx, "billRepository.CreateBill")
+	defer tracing.End(span, &err)
+
+	return r.db.WithContext(ctx).Create(bill).Error
+}
+
+func (r *billRepository) GetBill(ctx context.Context, id uuid.UUID) (_ *models.Bill, err error) {
+	ctx, span := tracer.Start(ctx, "billRepository.GetBill")
+	defer tracing.End(span, &err)
+
+	var bill models.Bill
+	err = r.db.WithContext(ctx).
+		Preload("Shares", func(db *gorm.DB) *gorm.DB { return db.Order("seq") }).
+		First(&bill, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrBillNotFound
+		}
+		return nil, err
+	}
+	return &bill, nil
+}
+
+func (r *billRepository) ListBillsByWallet(ctx context.Context, walletID uuid.UUID) (_ []models.Bill, err error) {
+	ctx, span := tracer.Start(ctx, "billRepository.ListBillsByWallet")
+	defer tracing.End(span, &err)
+
+	var bills []models.Bill
+	err = r.db.WithContext(ctx).
+		Preload("Shares", func(db *gorm.DB) *gorm.DB { return db.Order("seq") }).
+		Where("payer_wallet_id = ? OR id IN (SELECT bill_id FROM bill_shares WHERE wallet_id = ?)", walletID, walletID).
+		Order("created_at, id").
+		Find(&bills).Error
+	return bills, err
+}
+
+func (r *billRepository) SettleShare(ctx context.Context, billID uuid.UUID, seq int) (_ *models.BillShare, err error) {
+	ctx, span := tracer.Start(ctx, "billRepository.SettleShare")
+	defer tracing.End(span, &err)
+
+	var share models.BillShare
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the bill serialises the settlements of its shares, so the
+		// last one to commit sees that none is left pending
+		var bill models.Bill
+		if err := lockForUpdate(tx).First(&bill, "id = ?", billID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrBillNotFound
+			}
+			return err
+		}
+		if err := tx.First(&share, "bill_id = ? AND seq = ?", billID, seq).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrBillNotFound
+			}
+			return err
+		}
+		if share.Status != models.BillSharePending {
+			return models.ErrInvalidState
+		}
+
+		// The payer receives cash, so the participant pays in cash too
+		debit := &models.Transaction{WalletID: share.WalletID, Type: models.Debit, Amount: share.Amount, Description: bill.Description, Reference: bill.Reference(), CashOnly: true}
+		credit := &models.Transaction{WalletID: bill.PayerWalletID, Type: models.Credit, Amount: share.Amount, Description: bill.Description, Reference: bill.Reference()}
+		if err := applyTransactions(tx, []*models.Transaction{debit, credit}); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		share.Status = models.BillShareSettled
+		share.DebitTransactionID = &debit.ID
+		share.CreditTransactionID = &credit.ID
+		share.ErrorCode = ""
+		share.ErrorMessage = ""
+		share.SettledAt = &now
+		err := tx.Model(&share).Updates(map[string]any{
+			"status":                share.Status,
+			"debit_transaction_id":  debit.ID,
+			"credit_transaction_id": credit.ID,
+			"error_code":            "",
+			"error_message":         "",
+			"settled_at":            now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var pending int64
+		err = tx.Model(&models.BillShare{}).
+			Where("bill_id = ? AND status = ?", billID, models.BillSharePending).
+			Count(&pending).Error
+		if err != nil || pending > 0 {
+			return err
+		}
+		return tx.Model(&bill).Updates(map[string]any{
+			"status":     models.BillSettled,
+			"settled_at": now,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, translateError(r.db, unwrapItemError(err))
+	}
+	return &share, nil
+}
+
+func (r *billRepository) RecordShareFailure(ctx context.Context, shareID uuid.UUID, failure models.ItemFailure) (err error) {
+	ctx, span := tracer.Start(ctx, "billRepository.RecordShareFailure")
+	defer tracing.End(span, &err)
+
+	return r.db.WithContext(ctx).Model(&models.BillShare{}).
+		Where("id = ? AND status = ?", shareID, models.BillSharePending).
+		Updates(map[string]any{"error_code": failure.Code, "error_message": failure.Message}).Error
+}
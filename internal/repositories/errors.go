@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"errors"
+	"strings"
+
+	"wallet-microservice/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Postgres error codes that mean a concurrent transaction got in the way
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// walletUserIndex is the unique index that gives each user one wallet
+const walletUserIndex = "idx_wallets_user_id"
+
+// translateError maps driver errors that have a domain meaning to the
+// errors in models and returns any other error unchanged
+func translateError(db *gorm.DB, err error) error {
+	if err == nil {
+		return nil
+	}
+	if t, ok := db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(t.Translate(err), gorm.ErrDuplicatedKey) {
+		if violates(err, walletUserIndex) {
+			return models.ErrWalletExists
+		}
+		return models.ErrDuplicate
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == pgSerializationFailure || pgErr.Code == pgDeadlockDetected) {
+		return models.ErrConflict
+	}
+	return err
+}
+
+// violates reports whether the unique violation err is on index. SQLite
+// does not name the index, only its columns.
+func violates(err error, index string) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.ConstraintName == index
+	}
+	columns := map[string]string{walletUserIndex: "wallets.user_id"}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed: "+columns[index])
+}
+
+// foreignKeyViolated reports whether err is a foreign key violation, e.g.
+// deleting a row that another still references. SQLite reports an ON DELETE
+// RESTRICT violation as a trigger constraint, which is not translated.
+func foreignKeyViolated(db *gorm.DB, err error) bool {
+	if t, ok := db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(t.Translate(err), gorm.ErrForeignKeyViolated) {
+		return true
+	}
+	return db.Dialector.Name() == "sqlite" && strings.Contains(err.Error(), "FOREIGN KEY constraint failed")
+}
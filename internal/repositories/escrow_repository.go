@@ -0,0 +1,237 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EscrowRepository interface {
+	// CreateEscrow creates the escrow's wallet, moves the escrow's amount
+	// into it from the buyer's wallet and stores the escrow as funded, in
+	// one database transaction
+	CreateEscrow(ctx context.Context, escrow *models.Escrow) error
+	GetEscrow(ctx context.Context, id uuid.UUID) (*models.Escrow, error)
+	// ListEscrowsByWallet returns the escrows a wallet buys or sells in,
+	// oldest first
+	ListEscrowsByWallet(ctx context.Context, walletID uuid.UUID) ([]models.Escrow, error)
+	// SettleEscrow pays out what a funded escrow's wallet holds as
+	// settlement says
+	// and moves it to its final status, in one database transaction. It
+	// returns models.ErrInvalidState when the escrow is no longer funded.
+	SettleEscrow(ctx context.Context, id uuid.UUID, settlement models.EscrowSettlement) (*models.Escrow, error)
+	// DueEscrows returns the IDs of up to limit funded escrows whose
+	// release is due at now, most overdue first
+	DueEscrows(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+}
+
+type escrowRepository struct {
+	db *gorm.DB
+}
+
+// NewEscrowRepository returns an escrow repository backed by db. Escrows
+// move money with the same row locks and balance checks as
+// WalletRepository.
+func NewEscrowRepository(db *gorm.DB) EscrowRepository {
+	return &escrowRepository{db: db}
+}
+
+func (r *escrowRepository) CreateEscrow(ctx context.Context, escrow *models.Escrow) (err error) {
+	ctx, span := tracer.Start(ctx, "escrowRepository.CreateEscrow")
+	defer tracing.End(span, &err)
+
+	if escrow.ID == uuid.Nil {
+		escrow.ID = uuid.New()
+	}
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var buyer models.Wallet
+		if err := tx.Select("currency").First(&buyer, "id = ?", escrow.BuyerWalletID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrWalletNotFound
+			}
+			return err
+		}
+		// Every escrow has a wallet of its own, so settling one never waits
+		// on another's lock
+		account := &models.Wallet{UserID: escrow.Reference(), Currency: buyer.Currency}
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+
+		debit := &models.Transaction{
+			WalletID:    escrow.BuyerWalletID,
+			Type:        models.Debit,
+			Amount:      escrow.Amount,
+			Description: "Escrow funded",
+			Reference:   escrow.Reference(),
+			// Whatever the escrow pays out arrives as cash
+			CashOnly: true,
+		}
+		credit := &models.Transaction{
+			WalletID:    account.ID,
+			Type:        models.Credit,
+			Amount:      escrow.Amount,
+			Description: "Escrow funded",
+			Reference:   escrow.Reference(),
+		}
+		if err := applyTransactions(tx, []*models.Transaction{debit, credit}); err != nil {
+			return err
+		}
+		escrow.EscrowWalletID = account.ID
+		escrow.Status = models.EscrowFunded
+		escrow.Held = escrow.Amount
+		escrow.FundingTransactionID = debit.ID
+		return tx.Create(escrow).Error
+	})
+	return translateError(r.db, unwrapItemError(err))
+}
+
+func (r *escrowRepository) GetEscrow(ctx context.Context, id uuid.UUID) (_ *models.Escrow, err error) {
+	ctx, span := tracer.Start(ctx, "escrowRepository.GetEscrow")
+	defer tracing.End(span, &err)
+
+	var escrow models.Escrow
+	if err := r.db.WithContext(ctx).First(&escrow, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrEscrowNotFound
+		}
+		return nil, err
+	}
+	return &escrow, nil
+}
+
+func (r *escrowRepository) ListEscrowsByWallet(ctx context.Context, walletID uuid.UUID) (_ []models.Escrow, err error) {
+	ctx, span := tracer.Start(ctx, "escrowRepository.ListEscrowsByWallet")
+	defer tracing.End(span, &err)
+
+	var escrows []models.Escrow
+	err = r.db.WithContext(ctx).
+		Where("buyer_wallet_id = ? OR seller_wallet_id = ?", walletID, walletID).
+		Order("created_at, id").
+		Find(&escrows).Error
+	return escrows, err
+}
+
+func (r *escrowRepository) SettleEscrow(ctx context.Context, id uuid.UUID, settlement models.EscrowSettlement) (_ *models.Escrow, err error) {
+	ctx, span := tracer.Start(ctx, "escrowRepository.SettleEscrow")
+	defer tracing.End(span, &err)
+
+	var escrow models.Escrow
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The lock keeps a concurrent settlement, say a release at the
+		// deadline racing a refund, from paying out twice
+		if err := lockForUpdate(tx).First(&escrow, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrEscrowNotFound
+			}
+			return err
+		}
+		if escrow.Status != models.EscrowFunded {
+			return models.ErrInvalidState
+		}
+
+		seller := 0.0
+		switch settlement.Status {
+		case models.EscrowReleased:
+			seller = escrow.Held
+		case models.EscrowRefunded:
+		case models.EscrowSplit:
+			seller = cents(settlement.SellerAmount)
+			if seller <= 0 || seller >= escrow.Held {
+				return models.ErrInvalidAmount
+			}
+		default:
+			return models.ErrInvalidState
+		}
+		buyer := cents(escrow.Held - seller)
+
+		description := "Escrow " + string(settlement.Status)
+		var sellerTx, buyerTx *models.Transaction
+		txs := []*models.Transaction{{
+			WalletID:    escrow.EscrowWalletID,
+			Type:        models.Debit,
+			Amount:      escrow.Held,
+			Description: description,
+			Reference:   escrow.Reference(),
+		}}
+		if seller > 0 {
+			sellerTx = &models.Transaction{WalletID: escrow.SellerWalletID, Type: models.Credit, Amount: seller, Description: description, Reference: escrow.Reference()}
+			txs = append(txs, sellerTx)
+		}
+		if buyer > 0 {
+			buyerTx = &models.Transaction{WalletID: escrow.BuyerWalletID, Type: models.Credit, Amount: buyer, Description: description, Reference: escrow.Reference()}
+			txs = append(txs, buyerTx)
+		}
+		if err := applyTransactions(tx, txs); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		escrow.Status = settlement.Status
+		escrow.Held = 0
+		escrow.SellerAmount = seller
+		escrow.BuyerAmount = buyer
+		if sellerTx != nil {
+			escrow.SellerTransactionID = &sellerTx.ID
+		}
+		if buyerTx != nil {
+			escrow.BuyerTransactionID = &buyerTx.ID
+		}
+		escrow.AutoReleased = settlement.Auto
+		escrow.SettledAt = &now
+		escrow.UpdatedAt = now
+		return tx.Model(&escrow).Updates(map[string]any{
+			"status":                escrow.Status,
+			"held":                  escrow.Held,
+			"seller_amount":         escrow.SellerAmount,
+			"buyer_amount":          escrow.BuyerAmount,
+			"seller_transaction_id": escrow.SellerTransactionID,
+			"buyer_transaction_id":  escrow.BuyerTransactionID,
+			"auto_released":         escrow.AutoReleased,
+			"settled_at":            now,
+			"updated_at":            now,
+		}).Error
+	})
+	if err != nil {
+		return nil, translateError(r.db, unwrapItemError(err))
+	}
+	return &escrow, nil
+}
+
+func (r *escrowRepository) DueEscrows(ctx context.Context, now time.Time, limit int) (_ []uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "escrowRepository.DueEscrows")
+	defer tracing.End(span, &err)
+
+	var ids []uuid.UUID
+	err = r.db.WithContext(ctx).Model(&models.Escrow{}).
+		Where("status = ? AND "+r.due(), models.EscrowFunded, now.UTC()).
+		Order("release_at, id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// due is the condition that an escrow's release is at or before a given
+// time, compared as timestamps and not as SQLite's text
+func (r *escrowRepository) due() string {
+	if r.db.Dialector.Name() == "sqlite" {
+		return "julianday(release_at) <= julianday(?)"
+	}
+	return "release_at <= ?"
+}
+
+// unwrapItemError hands back the domain error of an operation made of
+// transactions the caller did not list, where an index means nothing
+func unwrapItemError(err error) error {
+	var itemErr *models.ItemError
+	if errors.As(err, &itemErr) {
+		return itemErr.Err
+	}
+	return err
+}
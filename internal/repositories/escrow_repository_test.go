@@ -0,0 +1,40 @@
+//go:build unit
+// +build unit
+
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscrowRepositoryDueAcrossOffsets(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	wallets := repositories.NewWalletRepository(db.Gorm())
+	escrows := repositories.NewEscrowRepository(db.Gorm())
+	buyer := &models.Wallet{UserID: "buyer", Currency: "USD"}
+	seller := &models.Wallet{UserID: "seller", Currency: "USD"}
+	require.NoError(t, wallets.CreateWallet(ctx, buyer))
+	require.NoError(t, wallets.CreateWallet(ctx, seller))
+	require.NoError(t, wallets.ApplyTransactions(ctx, []*models.Transaction{{WalletID: buyer.ID, Type: models.Credit, Amount: 10}}))
+
+	// Due half an hour ago, but written in a zone ahead of UTC, so its
+	// text sorts after now
+	now := time.Now().UTC().Truncate(time.Second)
+	releaseAt := now.Add(-30 * time.Minute).In(time.FixedZone("UTC+2", 2*60*60))
+	escrow := &models.Escrow{BuyerWalletID: buyer.ID, SellerWalletID: seller.ID, Amount: 5, ReleaseAt: &releaseAt}
+	require.NoError(t, escrows.CreateEscrow(ctx, escrow))
+
+	ids, err := escrows.DueEscrows(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{escrow.ID}, ids)
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository stores the writes made with an Idempotency-Key, so
+// that retries of one are answered with its response instead of being
+// applied again
+type IdempotencyRepository interface {
+	// Reserve claims key for the request with fingerprint, for lease. It
+	// returns nil when the key was free, or held by a request with the same
+	// fingerprint whose lease ran out before it completed, and otherwise
+	// the record of the request that holds it, which may still be in
+	// progress.
+	Reserve(ctx context.Context, key, fingerprint string, now time.Time, lease time.Duration) (*models.IdempotencyKey, error)
+	// Complete stores the response of the request holding key
+	Complete(ctx context.Context, key string, response models.IdempotencyKey) error
+	// Release frees key again, e.g. after the request failed without
+	// effect, so that a retry runs it anew
+	Release(ctx context.Context, key string) error
+	// Purge forgets the keys reserved before, and returns how many there
+	// were
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository returns an idempotency repository backed by db.
+// The key's primary key settles which of two concurrent requests with it
+// goes ahead, across instances.
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, key, fingerprint string, now time.Time, lease time.Duration) (_ *models.IdempotencyKey, err error) {
+	ctx, span := tracer.Start(ctx, "idempotencyRepository.Reserve")
+	defer tracing.End(span, &err)
+
+	now = now.UTC()
+	// lapsed matches a reservation whose lease has run out. SQLite stores
+	// timestamps as text with an offset; julianday normalises them before
+	// comparing.
+	lapsed := "reserved_until <= ?"
+	if r.db.Dialector.Name() == "sqlite" {
+		lapsed = "julianday(reserved_until) <= julianday(?)"
+	}
+	for {
+		record := &models.IdempotencyKey{Key: key, Fingerprint: fingerprint, CreatedAt: now, ReservedUntil: now.Add(lease)}
+		result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		// Take the key over from a request that never completed it
+		result = r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+			Where("key = ? AND fingerprint = ? AND status_code = 0 AND "+lapsed, key, fingerprint, now).
+			Update("reserved_until", now.Add(lease))
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+
+		var existing models.IdempotencyKey
+		err := r.db.WithContext(ctx).First(&existing, "key = ?", key).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released or purged since; try to reserve it again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, key string, response models.IdempotencyKey) (err error) {
+	ctx, span := tracer.Start(ctx, "idempotencyRepository.Complete")
+	defer tracing.End(span, &err)
+
+	now := time.Now().UTC()
+	return r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("key = ?", key).
+		Updates(map[string]any{
+			"status_code":  response.StatusCode,
+			"content_type": response.ContentType,
+			"location":     response.Location,
+			"body":         response.Body,
+			"completed_at": now,
+		}).Error
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key string) (err error) {
+	ctx, span := tracer.Start(ctx, "idempotencyRepository.Release")
+	defer tracing.End(span, &err)
+
+	return r.db.WithContext(ctx).Delete(&models.IdempotencyKey{}, "key = ? AND status_code = 0", key).Error
+}
+
+func (r *idempotencyRepository) Purge(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "idempotencyRepository.Purge")
+	defer tracing.End(span, &err)
+
+	result := r.db.WithContext(ctx).Delete(&models.IdempotencyKey{}, "created_at < ?", before.UTC())
+	return result.RowsAffected, result.Error
+}
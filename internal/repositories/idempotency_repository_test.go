@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyRepository(t *testing.T) {
+	for name, newRepo := range map[string]func(t *testing.T) repositories.IdempotencyRepository{
+		"gorm": func(t *testing.T) repositories.IdempotencyRepository {
+			return repositories.NewIdempotencyRepository(openTestDB(t).Gorm())
+		},
+		"memory": func(*testing.T) repositories.IdempotencyRepository {
+			return repositories.NewMemoryIdempotencyRepository()
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			keys := newRepo(t)
+			now := time.Now().UTC()
+
+			existing, err := keys.Reserve(ctx, "k1", "f1", now.Add(-2*time.Hour), time.Minute)
+			require.NoError(t, err)
+			assert.Nil(t, existing, "a new key is reserved")
+
+			existing, err = keys.Reserve(ctx, "k1", "f2", now, time.Minute)
+			require.NoError(t, err)
+			require.NotNil(t, existing)
+			assert.Equal(t, "f1", existing.Fingerprint)
+			assert.False(t, existing.Completed())
+
+			// A retry of the same request takes the key over once the
+			// reservation has lapsed, and holds it for a new lease
+			existing, err = keys.Reserve(ctx, "k1", "f1", now.Add(-2*time.Hour+30*time.Second), time.Minute)
+			require.NoError(t, err)
+			assert.NotNil(t, existing)
+			existing, err = keys.Reserve(ctx, "k1", "f1", now, time.Minute)
+			require.NoError(t, err)
+			assert.Nil(t, existing)
+			existing, err = keys.Reserve(ctx, "k1", "f1", now.Add(30*time.Second), time.Minute)
+			require.NoError(t, err)
+			assert.NotNil(t, existing)
+
+			require.NoError(t, keys.Complete(ctx, "k1", models.IdempotencyKey{
+				StatusCode: 201, ContentType: "application/json", Location: "/x/1", Body: []byte(`{"id":1}`),
+			}))
+			existing, err = keys.Reserve(ctx, "k1", "f1", now.Add(time.Hour), time.Minute)
+			require.NoError(t, err)
+			require.NotNil(t, existing)
+			assert.True(t, existing.Completed())
+			assert.Equal(t, 201, existing.StatusCode)
+			assert.Equal(t, "application/json", existing.ContentType)
+			assert.Equal(t, "/x/1", existing.Location)
+			assert.Equal(t, `{"id":1}`, string(existing.Body))
+
+			// Only a key still in progress can be released
+			require.NoError(t, keys.Release(ctx, "k1"))
+			existing, err = keys.Reserve(ctx, "k1", "f1", now.Add(time.Hour), time.Minute)
+			require.NoError(t, err)
+			assert.NotNil(t, existing)
+
+			_, err = keys.Reserve(ctx, "k2", "f2", now, time.Minute)
+			require.NoError(t, err)
+			require.NoError(t, keys.Release(ctx, "k2"))
+			existing, err = keys.Reserve(ctx, "k2", "f2", now, time.Minute)
+			require.NoError(t, err)
+			assert.Nil(t, existing, "a released key is free again")
+
+			purged, err := keys.Purge(ctx, now.Add(-time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), purged)
+			existing, err = keys.Reserve(ctx, "k1", "f3", now, time.Minute)
+			require.NoError(t, err)
+			assert.Nil(t, existing, "a purged key is free again")
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InterestRepository interface {
+	// Enroll creates the interest account of a wallet, accruing from
+	// enrolledOn, and returns it. An existing account is returned as it is.
+	Enroll(ctx context.Context, walletID uuid.UUID, enrolledOn time.Time) (*models.InterestAccount, error)
+	GetAccount(ctx context.Context, walletID uuid.UUID) (*models.InterestAccount, error)
+	// AccountsBehind returns the IDs of up to limit wallets that have not
+	// accrued through date yet
+	AccountsBehind(ctx context.Context, date time.Time, limit int) ([]uuid.UUID, error)
+	// LockAccount returns the account locked until the end of the
+	// surrounding transaction
+	LockAccount(ctx context.Context, walletID uuid.UUID) (*models.InterestAccount, error)
+	// EndOfDayBalance returns the cash balance of a wallet at the end of
+	// date, counting the payouts of earlier periods, and the overdraft
+	// charges for earlier days, as made by then however late they were
+	// posted. Promotional credit earns no interest.
+	EndOfDayBalance(ctx context.Context, walletID uuid.UUID, date time.Time) (float64, error)
+	// RecordAccrual stores an accrual and, optionally, the payout made after
+	// it, together with the account's new state
+	RecordAccrual(ctx context.Context, account *models.InterestAccount, accrual *models.InterestAccrual, payout *models.InterestPayout) error
+	// ListAccruals returns the accruals of a wallet, newest first
+	ListAccruals(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]models.InterestAccrual, error)
+}
+
+type interestRepository struct {
+	db *gorm.DB
+}
+
+// NewInterestRepository returns an interest repository backed by db, which
+// may be a transaction
+func NewInterestRepository(db *gorm.DB) InterestRepository {
+	return &interestRepository{db: db}
+}
+
+func (r *interestRepository) Enroll(ctx context.Context, walletID uuid.UUID, enrolledOn time.Time) (_ *models.InterestAccount, err error) {
+	ctx, span := tracer.Start(ctx, "interestRepository.Enroll")
+	defer tracing.End(span, &err)
+
+	account := &models.InterestAccount{WalletID: walletID, EnrolledOn: enrolledOn}
+	err = r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(account).Error
+	if err != nil {
+		return nil, translateError(r.db, err)
+	}
+	return r.GetAccount(ctx, walletID)
+}
+
+func (r *interestRepository) GetAccount(ctx context.Context, walletID uuid.UUID) (_ *models.InterestAccount, err error) {
+	ctx, span := tracer.Start(ctx, "interestRepository.GetAccount")
+	defer tracing.End(span, &err)
+
+	return r.first(r.db.WithContext(ctx), walletID)
+}
+
+func (r *interestRepository) first(db *gorm.DB, walletID uuid.UUID) (*models.InterestAccount, error) {
+	var account models.InterestAccount
+	if err := db.First(&account, "wallet_id = ?", walletID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrInterestAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *interestRepository) AccountsBehind(ctx context.Context, date time.Time, limit int) (_ []uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "interestRepository.AccountsBehind")
+	defer tracing.End(span, &err)
+
+	var ids []uuid.UUID
+	err = r.db.WithContext(ctx).Model(&models.InterestAccount{}).
+		Where("enrolled_on <= ? AND (accrued_through IS NULL OR accrued_through < ?)", date, date).
+		Order("wallet_id").
+		Limit(limit).
+		Pluck("wallet_id", &ids).Error
+	return ids, err
+}
+
+func (r *interestRepository) LockAccount(ctx context.Context, walletID uuid.UUID) (_ *models.InterestAccount, err error) {
+	ctx, span := tracer.Start(ctx, "interestRepository.LockAccount")
+	defer tracing.End(span, &err)
+
+	return r.first(lockForUpdate(r.db.WithContext(ctx)), walletID)
+}
+
+func (r *interestRepository) EndOfDayBalance(ctx context.Context, walletID uuid.UUID, date time.Time) (_ float64, err error) {
+	ctx, span := tracer.Start(ctx, "interestRepository.EndOfDayBalance")
+	defer tracing.End(span, &err)
+
+	return endOfDayBalance(r.db.WithContext(ctx), walletID, date)
+}
+
+// endOfDayBalance reads the cash balance of a wallet at the end of date back
+// from the ledger, for interest and overdraft charges alike
+func endOfDayBalance(db *gorm.DB, walletID uuid.UUID, date time.Time) (float64, error) {
+	// Work back from the current balance, undoing the transactions made
+	// after the day, in one statement so both are read from one snapshot.
+	// SQLite stores timestamps as text with an offset; julianday normalises
+	// them before comparing.
+	after := "t.created_at >= ?"
+	if db.Dialector.Name() == "sqlite" {
+		after = "julianday(t.created_at) >= julianday(?)"
+	}
+	var balance *float64
+	err := db.Raw(`SELECT w.balance - w.promo_balance - COALESCE((
+			SELECT SUM(CASE WHEN t.type = 'CREDIT' THEN t.amount - t.promo_amount ELSE t.promo_amount - t.amount END)
+			FROM transactions t
+			WHERE t.wallet_id = w.id AND `+after+`
+				AND t.id NOT IN (SELECT p.transaction_id FROM interest_payouts p WHERE p.wallet_id = w.id AND p.period_end < ?)
+				AND t.id NOT IN (SELECT c.transaction_id FROM overdraft_charges c
+					WHERE c.wallet_id = w.id AND c.date < ? AND c.transaction_id IS NOT NULL)
+		), 0)
+		FROM wallets w WHERE w.id = ?`, date.AddDate(0, 0, 1), date, date, walletID).
+		Scan(&balance).Error
+	if err != nil {
+		return 0, err
+	}
+	if balance == nil {
+		return 0, models.ErrWalletNotFound
+	}
+	return *balance, nil
+}
+
+func (r *interestRepository) RecordAccrual(ctx context.Context, account *models.InterestAccount, accrual *models.InterestAccrual, payout *models.InterestPayout) (err error) {
+	ctx, span := tracer.Start(ctx, "interestRepository.RecordAccrual")
+	defer tracing.End(span, &err)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(accrual).Error; err != nil {
+			return translateError(tx, err)
+		}
+		if payout != nil {
+			if err := tx.Create(payout).Error; err != nil {
+				return translateError(tx, err)
+			}
+		}
+		account.UpdatedAt = time.Now().UTC()
+		return tx.Model(account).Updates(map[string]any{
+			"accrued":         account.Accrued,
+			"accrued_through": account.AccruedThrough,
+			"updated_at":      account.UpdatedAt,
+		}).Error
+	})
+}
+
+func (r *interestRepository) ListAccruals(ctx context.Context, walletID uuid.UUID, limit, offset int) (_ []models.InterestAccrual, err error) {
+	ctx, span := tracer.Start(ctx, "interestRepository.ListAccruals")
+	defer tracing.End(span, &err)
+
+	var accruals []models.InterestAccrual
+	err = r.db.WithContext(ctx).Where("wallet_id = ?", walletID).
+		Order("date DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&accruals).Error
+	return accruals, err
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"wallet-microservice/internal/models"
+)
+
+// memoryIdempotencyRepository is an IdempotencyRepository kept in process
+// memory, for the memory driver. It is safe for concurrent use.
+type memoryIdempotencyRepository struct {
+	mu   sync.Mutex
+	keys map[string]models.IdempotencyKey
+}
+
+// NewMemoryIdempotencyRepository returns an empty in-memory idempotency
+// repository
+func NewMemoryIdempotencyRepository() IdempotencyRepository {
+	return &memoryIdempotencyRepository{keys: make(map[string]models.IdempotencyKey)}
+}
+
+func (r *memoryIdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string, now time.Time, lease time.Duration) (*models.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now = now.UTC()
+	existing, ok := r.keys[key]
+	if !ok {
+		r.keys[key] = models.IdempotencyKey{Key: key, Fingerprint: fingerprint, CreatedAt: now, ReservedUntil: now.Add(lease)}
+		return nil, nil
+	}
+	if existing.Fingerprint == fingerprint && !existing.Completed() && !existing.ReservedUntil.After(now) {
+		existing.ReservedUntil = now.Add(lease)
+		r.keys[key] = existing
+		return nil, nil
+	}
+	return &existing, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, key string, response models.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.keys[key]
+	if !ok {
+		return nil
+	}
+	now := time.Now().UTC()
+	record.StatusCode = response.StatusCode
+	record.ContentType = response.ContentType
+	record.Location = response.Location
+	record.Body = append([]byte(nil), response.Body...)
+	record.CompletedAt = &now
+	r.keys[key] = record
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, ok := r.keys[key]; ok && !record.Completed() {
+		delete(r.keys, key)
+	}
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var purged int64
+	for key, record := range r.keys {
+		if record.CreatedAt.Before(before) {
+			delete(r.keys, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
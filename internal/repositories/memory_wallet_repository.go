@@ -0,0 +1,464 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"wallet-microservice/internal/consistency"
+	"wallet-microservice/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// memoryWalletRepository is a WalletRepository kept entirely in process
+// memory. It follows the same rules as the database implementation: unique
+// user IDs, per-wallet serialisation of balance changes, balance checks,
+// cascading deletes and newest-first history. It is safe for concurrent use.
+type memoryWalletRepository struct {
+	mu           sync.RWMutex // guards the maps, never held while waiting for a wallet
+	wallets      map[uuid.UUID]*memoryWallet
+	byUser       map[string]uuid.UUID
+	transactions map[uuid.UUID][]models.Transaction
+	// promo holds each wallet's promotional credits. They only change
+	// while their wallet is locked, as working copies that replace the
+	// stored ones once the change has succeeded.
+	promo map[uuid.UUID][]models.PromoCredit
+}
+
+// memoryWallet holds one wallet behind its own lock. The lock is a channel
+// so that waiting for it honours context cancellation, like a row lock.
+type memoryWallet struct {
+	lock    chan struct{}
+	wallet  models.Wallet
+	deleted bool
+}
+
+// NewMemoryWalletRepository returns an empty in-memory repository, useful in
+// tests and demos that should not need a database
+func NewMemoryWalletRepository() WalletRepository {
+	return &memoryWalletRepository{
+		wallets:      make(map[uuid.UUID]*memoryWallet),
+		byUser:       make(map[string]uuid.UUID),
+		transactions: make(map[uuid.UUID][]models.Transaction),
+		promo:        make(map[uuid.UUID][]models.PromoCredit),
+	}
+}
+
+// acquire looks up a wallet and locks it, waiting until it is free or ctx
+// ends. The caller must call release.
+func (r *memoryWalletRepository) acquire(ctx context.Context, id uuid.UUID) (*memoryWallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	w, ok := r.wallets[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, models.ErrWalletNotFound
+	}
+
+	select {
+	case w.lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if w.deleted {
+		w.release()
+		return nil, models.ErrWalletNotFound
+	}
+	return w, nil
+}
+
+func (w *memoryWallet) release() {
+	<-w.lock
+}
+
+func (r *memoryWalletRepository) CreateWallet(ctx context.Context, wallet *models.Wallet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := wallet.BeforeCreate(nil); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byUser[wallet.UserID]; ok {
+		return models.ErrWalletExists
+	}
+	if _, ok := r.wallets[wallet.ID]; ok {
+		return models.ErrDuplicate
+	}
+	r.wallets[wallet.ID] = &memoryWallet{lock: make(chan struct{}, 1), wallet: *wallet}
+	r.byUser[wallet.UserID] = wallet.ID
+	return nil
+}
+
+func (r *memoryWalletRepository) GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	w, err := r.acquire(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer w.release()
+
+	wallet := w.wallet
+	return &wallet, nil
+}
+
+func (r *memoryWalletRepository) GetWalletByUserID(ctx context.Context, userID string) (*models.Wallet, error) {
+	r.mu.RLock()
+	id, ok := r.byUser[userID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, models.ErrWalletNotFound
+	}
+	return r.GetWalletByID(ctx, id)
+}
+
+// UpdateWallet overwrites every column of an existing wallet, like Save
+func (r *memoryWalletRepository) UpdateWallet(ctx context.Context, wallet *models.Wallet) error {
+	w, err := r.acquire(ctx, wallet.ID)
+	if err != nil {
+		return err
+	}
+	defer w.release()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if wallet.UserID != w.wallet.UserID {
+		if _, taken := r.byUser[wallet.UserID]; taken {
+			return models.ErrWalletExists
+		}
+		delete(r.byUser, w.wallet.UserID)
+		r.byUser[wallet.UserID] = wallet.ID
+	}
+	w.wallet.UserID = wallet.UserID
+	w.wallet.Currency = wallet.Currency
+	w.wallet.DebitOrder = wallet.DebitOrder
+	w.wallet.OverdraftLimit = wallet.OverdraftLimit
+	w.wallet.UpdatedAt = time.Now()
+	*wallet = w.wallet
+	return nil
+}
+
+// DeleteWallet removes a wallet and, as ON DELETE CASCADE would, its
+// transactions
+func (r *memoryWalletRepository) DeleteWallet(ctx context.Context, id uuid.UUID) error {
+	w, err := r.acquire(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer w.release()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w.deleted = true
+	delete(r.wallets, id)
+	delete(r.byUser, w.wallet.UserID)
+	delete(r.transactions, id)
+	delete(r.promo, id)
+	return nil
+}
+
+func (r *memoryWalletRepository) CreateTransaction(ctx context.Context, transaction *models.Transaction) error {
+	w, err := r.acquire(ctx, transaction.WalletID)
+	if err != nil {
+		return err
+	}
+	defer w.release()
+
+	return r.appendTransaction(transaction)
+}
+
+// appendTransaction validates and stores a transaction. The caller must hold
+// the wallet's lock.
+func (r *memoryWalletRepository) appendTransaction(transaction *models.Transaction) error {
+	if transaction.Type != models.Credit && transaction.Type != models.Debit {
+		return errors.New("invalid transaction type")
+	}
+	if err := transaction.BeforeCreate(nil); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *transaction
+	stored.Wallet = models.Wallet{}
+	r.transactions[transaction.WalletID] = append(r.transactions[transaction.WalletID], stored)
+	return nil
+}
+
+// GetTransactionsByWalletID returns a page of transactions, newest first
+func (r *memoryWalletRepository) GetTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]models.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	consistency.Record(ctx, 0)
+
+	r.mu.RLock()
+	all := make([]models.Transaction, len(r.transactions[walletID]))
+	copy(all, r.transactions[walletID])
+	r.mu.RUnlock()
+
+	// Stored in insertion order; reverse first so that equal timestamps keep
+	// newest-first order after the stable sort
+	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].CreatedAt.After(all[j].CreatedAt) })
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(all) {
+		return []models.Transaction{}, nil
+	}
+	all = all[offset:]
+	if limit >= 0 && limit < len(all) {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (r *memoryWalletRepository) CountTransactionsByWalletID(ctx context.Context, walletID uuid.UUID) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	consistency.Record(ctx, 0)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.transactions[walletID])), nil
+}
+
+func (r *memoryWalletRepository) UpdateWalletBalance(ctx context.Context, walletID uuid.UUID, amount float64, transactionType models.TransactionType) error {
+	w, err := r.acquire(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	defer w.release()
+
+	credits := r.promoCredits(walletID)
+	if err := w.apply(&models.Transaction{WalletID: walletID, Type: transactionType, Amount: amount}, &credits); err != nil {
+		return err
+	}
+	r.storePromoCredits(walletID, credits)
+	return nil
+}
+
+// apply changes the balance by t, spending from or adding to credits, the
+// working copies of the wallet's promo credits. The caller must hold the
+// wallet's lock.
+func (w *memoryWallet) apply(t *models.Transaction, credits *[]*models.PromoCredit) error {
+	now := time.Now().UTC()
+	var left []*models.PromoCredit
+	for _, c := range *credits {
+		if c.Remaining > 0 {
+			left = append(left, c)
+		}
+	}
+	wallet := w.wallet
+	if _, err := applyToWallet(&wallet, left, t, now); err != nil {
+		return err
+	}
+	if t.Type == models.Credit && t.Promo != nil {
+		if t.ID == uuid.Nil {
+			t.ID = uuid.New()
+		}
+		*credits = append(*credits, newPromoCredit(t, now))
+	}
+	wallet.UpdatedAt = time.Now()
+	w.wallet = wallet
+	return nil
+}
+
+// promoCredits returns working copies of a wallet's promo credits
+func (r *memoryWalletRepository) promoCredits(walletID uuid.UUID) []*models.PromoCredit {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	credits := make([]*models.PromoCredit, len(r.promo[walletID]))
+	for i := range r.promo[walletID] {
+		c := r.promo[walletID][i]
+		credits[i] = &c
+	}
+	return credits
+}
+
+// storePromoCredits replaces a wallet's promo credits with the working
+// copies. The caller must hold the wallet's lock.
+func (r *memoryWalletRepository) storePromoCredits(walletID uuid.UUID, credits []*models.PromoCredit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := make([]models.PromoCredit, len(credits))
+	for i, c := range credits {
+		stored[i] = *c
+	}
+	r.promo[walletID] = stored
+}
+
+func (r *memoryWalletRepository) ProcessTransactionWithRollback(
+	ctx context.Context,
+	walletID uuid.UUID,
+	amount float64,
+	t models.TransactionType,
+	txReq *models.Transaction,
+) error {
+	w, err := r.acquire(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	defer w.release()
+
+	txReq.WalletID = walletID
+	txReq.Type = t
+	txReq.Amount = amount
+	before := w.wallet
+	credits := r.promoCredits(walletID)
+	if err := w.apply(txReq, &credits); err != nil {
+		return err
+	}
+
+	if err := r.appendTransaction(txReq); err != nil {
+		// Roll the balance back so both changes happen or neither does;
+		// the working copies of the promo credits are dropped
+		w.wallet = before
+		return err
+	}
+	r.storePromoCredits(walletID, credits)
+	return nil
+}
+
+// ApplyTransactions locks every wallet involved in ID order, like the
+// database implementation, and restores their balances if any transaction
+// fails
+func (r *memoryWalletRepository) ApplyTransactions(ctx context.Context, txs []*models.Transaction) error {
+	first := make(map[uuid.UUID]int, len(txs))
+	for i, t := range txs {
+		if t.Type != models.Credit && t.Type != models.Debit {
+			return &models.ItemError{Index: i, Err: errors.New("invalid transaction type")}
+		}
+		if _, ok := first[t.WalletID]; !ok {
+			first[t.WalletID] = i
+		}
+	}
+	ids := make([]uuid.UUID, 0, len(first))
+	for id := range first {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	locked := make(map[uuid.UUID]*memoryWallet, len(ids))
+	defer func() {
+		for _, w := range locked {
+			w.release()
+		}
+	}()
+	for _, id := range ids {
+		w, err := r.acquire(ctx, id)
+		if err != nil {
+			return &models.ItemError{Index: first[id], Err: err}
+		}
+		locked[id] = w
+	}
+
+	before := make(map[uuid.UUID]models.Wallet, len(locked))
+	credits := make(map[uuid.UUID][]*models.PromoCredit, len(locked))
+	for id, w := range locked {
+		before[id] = w.wallet
+		credits[id] = r.promoCredits(id)
+	}
+	for i, t := range txs {
+		c := credits[t.WalletID]
+		if err := locked[t.WalletID].apply(t, &c); err != nil {
+			for id, w := range locked {
+				w.wallet = before[id]
+			}
+			return &models.ItemError{Index: i, Err: err}
+		}
+		credits[t.WalletID] = c
+	}
+	// Every type was checked up front, so recording cannot fail
+	for _, t := range txs {
+		if err := r.appendTransaction(t); err != nil {
+			return err
+		}
+	}
+	for id, c := range credits {
+		r.storePromoCredits(id, c)
+	}
+	return nil
+}
+
+func (r *memoryWalletRepository) LapsedPromoCredits(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var lapsed []models.PromoCredit
+	for _, credits := range r.promo {
+		for _, c := range credits {
+			if c.Remaining > 0 && c.Lapsed(now) {
+				lapsed = append(lapsed, c)
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(lapsed, func(i, j int) bool {
+		if !lapsed[i].ExpiresAt.Equal(*lapsed[j].ExpiresAt) {
+			return lapsed[i].ExpiresAt.Before(*lapsed[j].ExpiresAt)
+		}
+		return lapsed[i].TransactionID.String() < lapsed[j].TransactionID.String()
+	})
+	ids := make([]uuid.UUID, 0, len(lapsed))
+	for i := 0; i < len(lapsed) && i < limit; i++ {
+		ids = append(ids, lapsed[i].TransactionID)
+	}
+	return ids, nil
+}
+
+func (r *memoryWalletRepository) ExpirePromoCredit(ctx context.Context, creditID uuid.UUID, now time.Time) (*models.Transaction, error) {
+	var walletID uuid.UUID
+	r.mu.RLock()
+	for id, credits := range r.promo {
+		for _, c := range credits {
+			if c.TransactionID == creditID {
+				walletID = id
+			}
+		}
+	}
+	r.mu.RUnlock()
+	if walletID == uuid.Nil {
+		return nil, ctx.Err()
+	}
+
+	w, err := r.acquire(ctx, walletID)
+	if errors.Is(err, models.ErrWalletNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer w.release()
+
+	credits := r.promoCredits(walletID)
+	for _, c := range credits {
+		if c.TransactionID != creditID || c.Remaining <= 0 || !c.Lapsed(now) {
+			continue
+		}
+		wallet := w.wallet
+		t := expiryDebit(&wallet, c, now.UTC())
+		wallet.UpdatedAt = time.Now()
+		if err := r.appendTransaction(t); err != nil {
+			return nil, err
+		}
+		w.wallet = wallet
+		r.storePromoCredits(walletID, credits)
+		return t, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,17 @@
+//go:build unit
+// +build unit
+
+package repositories_test
+
+import (
+	"testing"
+
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/repositories/repositorytest"
+)
+
+func TestMemoryWalletRepositoryContract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositories.WalletRepository {
+		return repositories.NewMemoryWalletRepository()
+	})
+}
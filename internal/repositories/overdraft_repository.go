@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OverdraftRepository interface {
+	// WalletsToCharge returns the IDs of up to limit wallets that have an
+	// overdraft limit, or are overdrawn, and have not been charged for date
+	// yet
+	WalletsToCharge(ctx context.Context, date time.Time, limit int) ([]uuid.UUID, error)
+	// LockWallet returns the wallet locked until the end of the surrounding
+	// transaction
+	LockWallet(ctx context.Context, walletID uuid.UUID) (*models.Wallet, error)
+	// LastCharge returns the latest day a wallet was charged for, nil if it
+	// never was
+	LastCharge(ctx context.Context, walletID uuid.UUID) (*models.OverdraftCharge, error)
+	// EndOfDayBalance returns the cash balance of a wallet at the end of
+	// date, as InterestRepository.EndOfDayBalance does
+	EndOfDayBalance(ctx context.Context, walletID uuid.UUID, date time.Time) (float64, error)
+	RecordCharge(ctx context.Context, charge *models.OverdraftCharge) error
+}
+
+type overdraftRepository struct {
+	db *gorm.DB
+}
+
+// NewOverdraftRepository returns an overdraft repository backed by db,
+// which may be a transaction
+func NewOverdraftRepository(db *gorm.DB) OverdraftRepository {
+	return &overdraftRepository{db: db}
+}
+
+func (r *overdraftRepository) WalletsToCharge(ctx context.Context, date time.Time, limit int) (_ []uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "overdraftRepository.WalletsToCharge")
+	defer tracing.End(span, &err)
+
+	var ids []uuid.UUID
+	err = r.db.WithContext(ctx).Model(&models.Wallet{}).
+		Where(`(overdraft_limit > 0 OR balance < promo_balance) AND NOT EXISTS (
+			SELECT 1 FROM overdraft_charges c WHERE c.wallet_id = wallets.id AND c.date >= ?)`, date).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *overdraftRepository) LockWallet(ctx context.Context, walletID uuid.UUID) (_ *models.Wallet, err error) {
+	ctx, span := tracer.Start(ctx, "overdraftRepository.LockWallet")
+	defer tracing.End(span, &err)
+
+	return lockWallet(r.db.WithContext(ctx), walletID)
+}
+
+func (r *overdraftRepository) LastCharge(ctx context.Context, walletID uuid.UUID) (_ *models.OverdraftCharge, err error) {
+	ctx, span := tracer.Start(ctx, "overdraftRepository.LastCharge")
+	defer tracing.End(span, &err)
+
+	var charges []models.OverdraftCharge
+	err = r.db.WithContext(ctx).Where("wallet_id = ?", walletID).
+		Order("date DESC").
+		Limit(1).
+		Find(&charges).Error
+	if err != nil || len(charges) == 0 {
+		return nil, err
+	}
+	return &charges[0], nil
+}
+
+func (r *overdraftRepository) EndOfDayBalance(ctx context.Context, walletID uuid.UUID, date time.Time) (_ float64, err error) {
+	ctx, span := tracer.Start(ctx, "overdraftRepository.EndOfDayBalance")
+	defer tracing.End(span, &err)
+
+	return endOfDayBalance(r.db.WithContext(ctx), walletID, date)
+}
+
+func (r *overdraftRepository) RecordCharge(ctx context.Context, charge *models.OverdraftCharge) (err error) {
+	ctx, span := tracer.Start(ctx, "overdraftRepository.RecordCharge")
+	defer tracing.End(span, &err)
+
+	return translateError(r.db, r.db.WithContext(ctx).Create(charge).Error)
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentRequestRepository interface {
+	CreatePaymentRequest(ctx context.Context, request *models.PaymentRequest) error
+	GetPaymentRequest(ctx context.Context, id uuid.UUID) (*models.PaymentRequest, error)
+	// ListPaymentRequests returns the requests matching filter, newest
+	// first
+	ListPaymentRequests(ctx context.Context, filter models.PaymentRequestFilter, limit, offset int) ([]models.PaymentRequest, error)
+	// ResolvePaymentRequest moves a pending request that has not lapsed at
+	// now to status to, declined or cancelled, and returns it. It returns
+	// models.ErrInvalidState when the request cannot be answered any more.
+	ResolvePaymentRequest(ctx context.Context, id uuid.UUID, to models.PaymentRequestStatus, now time.Time) (*models.PaymentRequest, error)
+	// PayPaymentRequest transfers the amount of a pending request that has
+	// not lapsed at now from the payer's wallet to the requester's and marks
+	// it paid, in one database transaction
+	PayPaymentRequest(ctx context.Context, id uuid.UUID, now time.Time) (*models.PaymentRequest, error)
+	// ExpirePaymentRequests marks every pending request that has lapsed at
+	// now expired and returns how many there were
+	ExpirePaymentRequests(ctx context.Context, now time.Time) (int64, error)
+}
+
+type paymentRequestRepository struct {
+	db *gorm.DB
+}
+
+// NewPaymentRequestRepository returns a payment request repository backed
+// by db. Payments move money with the same row locks and balance checks as
+// WalletRepository.
+func NewPaymentRequestRepository(db *gorm.DB) PaymentRequestRepository {
+	return &paymentRequestRepository{db: db}
+}
+
+func (r *paymentRequestRepository) CreatePaymentRequest(ctx context.Context, request *models.PaymentRequest) (err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestRepository.CreatePaymentRequest")
+	defer tracing.End(span, &err)
+
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *paymentRequestRepository) GetPaymentRequest(ctx context.Context, id uuid.UUID) (_ *models.PaymentRequest, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestRepository.GetPaymentRequest")
+	defer tracing.End(span, &err)
+
+	var request models.PaymentRequest
+	if err := r.db.WithContext(ctx).First(&request, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrPaymentRequestNotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *paymentRequestRepository) ListPaymentRequests(ctx context.Context, filter models.PaymentRequestFilter, limit, offset int) (_ []models.PaymentRequest, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestRepository.ListPaymentRequests")
+	defer tracing.End(span, &err)
+
+	query := r.db.WithContext(ctx)
+	if filter.RequesterUserID != "" {
+		query = query.Where("requester_user_id = ?", filter.RequesterUserID)
+	}
+	if filter.PayerUserID != "" {
+		query = query.Where("payer_user_id = ?", filter.PayerUserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var requests []models.PaymentRequest
+	err = query.Order("created_at DESC, id").Limit(limit).Offset(offset).Find(&requests).Error
+	return requests, err
+}
+
+func (r *paymentRequestRepository) ResolvePaymentRequest(ctx context.Context, id uuid.UUID, to models.PaymentRequestStatus, now time.Time) (_ *models.PaymentRequest, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestRepository.ResolvePaymentRequest")
+	defer tracing.End(span, &err)
+
+	// The conditions make the update safe against a concurrent answer
+	// without locking the row first
+	now = now.UTC()
+	result := r.db.WithContext(ctx).Model(&models.PaymentRequest{}).
+		Where("id = ? AND status = ? AND "+r.expiresAt(">"), id, models.PaymentRequestPending, now).
+		Updates(map[string]any{"status": to, "resolved_at": now, "updated_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	request, err := r.GetPaymentRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, models.ErrInvalidState
+	}
+	return request, nil
+}
+
+func (r *paymentRequestRepository) PayPaymentRequest(ctx context.Context, id uuid.UUID, now time.Time) (_ *models.PaymentRequest, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestRepository.PayPaymentRequest")
+	defer tracing.End(span, &err)
+
+	now = now.UTC()
+	var request models.PaymentRequest
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The lock keeps the request from being paid twice, or answered
+		// otherwise while it is paid
+		if err := lockForUpdate(tx).First(&request, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrPaymentRequestNotFound
+			}
+			return err
+		}
+		if request.Status != models.PaymentRequestPending || request.Lapsed(now) {
+			return models.ErrInvalidState
+		}
+
+		from, err := walletOfUser(tx, request.PayerUserID)
+		if err != nil {
+			return err
+		}
+		to, err := walletOfUser(tx, request.RequesterUserID)
+		if err != nil {
+			return err
+		}
+		if from.Currency != request.Currency || to.Currency != request.Currency {
+			return models.ErrCurrencyMismatch
+		}
+
+		reference := "payment-request:" + request.ID.String()
+		// The requester receives cash, so the payer pays in cash too
+		debit := &models.Transaction{WalletID: from.ID, Type: models.Debit, Amount: request.Amount, Description: request.Description, Reference: reference, CashOnly: true}
+		credit := &models.Transaction{WalletID: to.ID, Type: models.Credit, Amount: request.Amount, Description: request.Description, Reference: reference}
+		if err := applyTransactions(tx, []*models.Transaction{debit, credit}); err != nil {
+			return err
+		}
+
+		request.Status = models.PaymentRequestPaid
+		request.FromWalletID = &from.ID
+		request.ToWalletID = &to.ID
+		request.DebitTransactionID = &debit.ID
+		request.CreditTransactionID = &credit.ID
+		request.ResolvedAt = &now
+		request.UpdatedAt = now
+		return tx.Model(&request).Updates(map[string]any{
+			"status":                request.Status,
+			"from_wallet_id":        from.ID,
+			"to_wallet_id":          to.ID,
+			"debit_transaction_id":  debit.ID,
+			"credit_transaction_id": credit.ID,
+			"resolved_at":           now,
+			"updated_at":            now,
+		}).Error
+	})
+	if err != nil {
+		return nil, translateError(r.db, unwrapItemError(err))
+	}
+	return &request, nil
+}
+
+func (r *paymentRequestRepository) ExpirePaymentRequests(ctx context.Context, now time.Time) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestRepository.ExpirePaymentRequests")
+	defer tracing.End(span, &err)
+
+	now = now.UTC()
+	result := r.db.WithContext(ctx).Model(&models.PaymentRequest{}).
+		Where("status = ? AND "+r.expiresAt("<="), models.PaymentRequestPending, now).
+		Updates(map[string]any{"status": models.PaymentRequestExpired, "resolved_at": now, "updated_at": now})
+	return result.RowsAffected, result.Error
+}
+
+// expiresAt is the condition comparing a request's expiry with a given
+// time by op. SQLite stores timestamps as text with an offset; julianday
+// normalises them before comparing.
+func (r *paymentRequestRepository) expiresAt(op string) string {
+	if r.db.Dialector.Name() == "sqlite" {
+		return "julianday(expires_at) " + op + " julianday(?)"
+	}
+	return "expires_at " + op + " ?"
+}
+
+// walletOfUser returns the wallet of a user within tx; applyTransactions
+// locks it
+func walletOfUser(tx *gorm.DB, userID string) (*models.Wallet, error) {
+	var wallet models.Wallet
+	if err := tx.First(&wallet, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrWalletNotFound
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
@@ -0,0 +1,51 @@
+//go:build unit
+// +build unit
+
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentRequestRepositoryExpiryAcrossOffsets(t *testing.T) {
+	ctx := context.Background()
+	requests := repositories.NewPaymentRequestRepository(openTestDB(t).Gorm())
+
+	// Each is written in a zone whose text sorts on the wrong side of now
+	now := time.Now().UTC().Truncate(time.Second)
+	newRequest := func(expiresAt time.Time) *models.PaymentRequest {
+		request := &models.PaymentRequest{
+			RequesterUserID: "bob",
+			PayerUserID:     "alice",
+			Amount:          5,
+			Currency:        "USD",
+			Status:          models.PaymentRequestPending,
+			ExpiresAt:       expiresAt,
+		}
+		require.NoError(t, requests.CreatePaymentRequest(ctx, request))
+		return request
+	}
+	lapsed := newRequest(now.Add(-30 * time.Minute).In(time.FixedZone("UTC+2", 2*60*60)))
+	open := newRequest(now.Add(30 * time.Minute).In(time.FixedZone("UTC-2", -2*60*60)))
+
+	_, err := requests.ResolvePaymentRequest(ctx, lapsed.ID, models.PaymentRequestDeclined, now)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+	declined, err := requests.ResolvePaymentRequest(ctx, open.ID, models.PaymentRequestDeclined, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestDeclined, declined.Status)
+
+	expired, err := requests.ExpirePaymentRequests(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+	got, err := requests.GetPaymentRequest(ctx, lapsed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestExpired, got.Status)
+}
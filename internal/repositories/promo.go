@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"wallet-microservice/internal/models"
+)
+
+var errInvalidType = errors.New("invalid transaction type")
+
+// applyToWallet changes the balance of wallet by t, splitting the amount
+// between its buckets, and sets t.PromoAmount. Debits may take the balance
+// below zero as far as the wallet's overdraft limit; a cash-only debit has
+// only the cash before it. credits are the wallet's promo credits with
+// something left, which a debit spends from; it returns the ones it
+// changed. Both implementations call it with the wallet locked.
+func applyToWallet(wallet *models.Wallet, credits []*models.PromoCredit, t *models.Transaction, now time.Time) ([]*models.PromoCredit, error) {
+	switch t.Type {
+	case models.Credit:
+		wallet.Balance += t.Amount
+		t.PromoAmount = 0
+		if t.Promo != nil {
+			t.PromoAmount = t.Amount
+			wallet.PromoBalance = cents(wallet.PromoBalance + t.Amount)
+		}
+		return nil, nil
+	case models.Debit:
+	default:
+		return nil, errInvalidType
+	}
+
+	// Lapsed credits stay in the balance until the expiry job debits them,
+	// but cannot be spent
+	var live []*models.PromoCredit
+	lapsed, available := 0.0, 0.0
+	for _, c := range credits {
+		if c.Lapsed(now) {
+			lapsed += c.Remaining
+		} else {
+			live = append(live, c)
+			available += c.Remaining
+		}
+	}
+	spendable := wallet.Balance - lapsed
+	if t.CashOnly {
+		spendable = wallet.Balance - wallet.PromoBalance
+	}
+	if !t.Charge && spendable+wallet.OverdraftLimit < t.Amount {
+		return nil, models.ErrInsufficientFunds
+	}
+
+	// Whatever the order, the overdraft is only drawn on once both buckets
+	// are empty
+	promo := math.Min(t.Amount, available)
+	if wallet.DebitOrder == models.CashFirst {
+		cash := wallet.Balance - wallet.PromoBalance
+		promo = math.Min(available, math.Max(0, t.Amount-math.Max(0, cash)))
+	}
+	if t.Charge || t.CashOnly {
+		promo = 0
+	}
+	promo = cents(promo)
+
+	// Spend the credits that expire soonest first, then the oldest
+	sort.SliceStable(live, func(i, j int) bool {
+		a, b := live[i], live[j]
+		if (a.ExpiresAt == nil) != (b.ExpiresAt == nil) {
+			return b.ExpiresAt == nil
+		}
+		if a.ExpiresAt != nil && !a.ExpiresAt.Equal(*b.ExpiresAt) {
+			return a.ExpiresAt.Before(*b.ExpiresAt)
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	var changed []*models.PromoCredit
+	left := promo
+	for _, c := range live {
+		if left <= 0 {
+			break
+		}
+		spend := math.Min(left, c.Remaining)
+		c.Remaining = cents(c.Remaining - spend)
+		left = cents(left - spend)
+		changed = append(changed, c)
+	}
+
+	wallet.Balance -= t.Amount
+	wallet.PromoBalance = cents(wallet.PromoBalance - promo)
+	t.PromoAmount = promo
+	return changed, nil
+}
+
+// expiryDebit returns the debit taking what is left of a lapsed credit out
+// of its wallet, applies it to wallet and empties the credit
+func expiryDebit(wallet *models.Wallet, credit *models.PromoCredit, now time.Time) *models.Transaction {
+	t := &models.Transaction{
+		WalletID:    wallet.ID,
+		Type:        models.Debit,
+		Amount:      credit.Remaining,
+		PromoAmount: credit.Remaining,
+		Description: "Promotional credit expired",
+		Reference:   "promo-expiry:" + credit.TransactionID.String(),
+	}
+	wallet.Balance -= credit.Remaining
+	wallet.PromoBalance = cents(wallet.PromoBalance - credit.Remaining)
+	credit.Remaining = 0
+	credit.ExpiredAt = &now
+	return t
+}
+
+// newPromoCredit returns the promo credit recording a promotional credit t
+func newPromoCredit(t *models.Transaction, now time.Time) *models.PromoCredit {
+	return &models.PromoCredit{
+		TransactionID: t.ID,
+		WalletID:      t.WalletID,
+		Amount:        t.Amount,
+		Remaining:     t.Amount,
+		ExpiresAt:     t.Promo.ExpiresAt,
+		CreatedAt:     now,
+	}
+}
+
+// cents rounds away the float error that adding up promo amounts
+// accumulates
+func cents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,514 @@
+// Package repositorytest holds the behaviour every WalletRepository
+// implementation must share. Implementations run it from their own tests:
+//
+//	repositorytest.Run(t, func(t *testing.T) repositories.WalletRepository {
+//		return repositories.NewMemoryWalletRepository()
+//	})
+package repositorytest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns the repository under test. It may share storage between
+// calls; every test uses fresh user and wallet IDs.
+type Factory func(t *testing.T) repositories.WalletRepository
+
+// Run executes the contract suite against the repositories built by newRepo
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repositories.WalletRepository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"DuplicateUser", testDuplicateUser},
+		{"NotFound", testNotFound},
+		{"UpdateWallet", testUpdateWallet},
+		{"UpdateWalletKeepsBalances", testUpdateWalletKeepsBalances},
+		{"DeleteCascades", testDeleteCascades},
+		{"UpdateWalletBalance", testUpdateWalletBalance},
+		{"ProcessTransaction", testProcessTransaction},
+		{"InsufficientBalanceLeavesNoTrace", testInsufficientBalance},
+		{"HistoryOrderAndPaging", testHistoryOrderAndPaging},
+		{"CountTransactions", testCountTransactions},
+		{"ConcurrentDebits", testConcurrentDebits},
+		{"ApplyTransactions", testApplyTransactions},
+		{"OpposingTransfers", testOpposingTransfers},
+		{"CancelledContext", testCancelledContext},
+		{"PromoSpentFirst", testPromoSpentFirst},
+		{"CashFirst", testCashFirst},
+		{"LapsedPromoExpires", testLapsedPromoExpires},
+		{"Overdraft", testOverdraft},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func newWallet(t *testing.T, repo repositories.WalletRepository, balance float64) *models.Wallet {
+	t.Helper()
+	wallet := &models.Wallet{UserID: "contract-" + uuid.NewString(), Currency: "USD"}
+	require.NoError(t, repo.CreateWallet(context.Background(), wallet))
+	if balance > 0 {
+		require.NoError(t, repo.ProcessTransactionWithRollback(context.Background(), wallet.ID, balance, models.Credit,
+			&models.Transaction{Description: "opening balance"}))
+	}
+	return wallet
+}
+
+func testCreateAndGet(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := &models.Wallet{UserID: "contract-" + uuid.NewString(), Currency: "EUR"}
+	require.NoError(t, repo.CreateWallet(ctx, wallet))
+	assert.NotEqual(t, uuid.Nil, wallet.ID)
+	assert.False(t, wallet.CreatedAt.IsZero())
+
+	byID, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.UserID, byID.UserID)
+	assert.Equal(t, "EUR", byID.Currency)
+	assert.Equal(t, 0.0, byID.Balance)
+
+	byUser, err := repo.GetWalletByUserID(ctx, wallet.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID, byUser.ID)
+}
+
+func testDuplicateUser(t *testing.T, repo repositories.WalletRepository) {
+	wallet := newWallet(t, repo, 0)
+	err := repo.CreateWallet(context.Background(), &models.Wallet{UserID: wallet.UserID, Currency: "USD"})
+	assert.ErrorIs(t, err, models.ErrWalletExists)
+
+	other := newWallet(t, repo, 0)
+	other.UserID = wallet.UserID
+	assert.ErrorIs(t, repo.UpdateWallet(context.Background(), other), models.ErrWalletExists)
+}
+
+func testNotFound(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	missing := uuid.New()
+
+	_, err := repo.GetWalletByID(ctx, missing)
+	assert.ErrorIs(t, err, models.ErrWalletNotFound)
+	_, err = repo.GetWalletByUserID(ctx, "contract-"+uuid.NewString())
+	assert.ErrorIs(t, err, models.ErrWalletNotFound)
+	assert.ErrorIs(t, repo.DeleteWallet(ctx, missing), models.ErrWalletNotFound)
+	assert.ErrorIs(t, repo.UpdateWalletBalance(ctx, missing, 10, models.Credit), models.ErrWalletNotFound)
+	assert.ErrorIs(t, repo.ProcessTransactionWithRollback(ctx, missing, 10, models.Credit, &models.Transaction{}), models.ErrWalletNotFound)
+
+	history, err := repo.GetTransactionsByWalletID(ctx, missing, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func testUpdateWallet(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 0)
+	wallet.Currency = "GBP"
+	require.NoError(t, repo.UpdateWallet(ctx, wallet))
+
+	got, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "GBP", got.Currency)
+}
+
+func testUpdateWalletKeepsBalances(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 0)
+	stale, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+
+	// Credits commit while updates of the wallet read before them run
+	const rounds = 20
+	var wg sync.WaitGroup
+	for i := 0; i < rounds; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 1, models.Credit, &models.Transaction{}))
+		}()
+		go func() {
+			defer wg.Done()
+			update := *stale
+			update.DebitOrder = models.CashFirst
+			update.OverdraftLimit = 50
+			assert.NoError(t, repo.UpdateWallet(ctx, &update))
+		}()
+	}
+	wg.Wait()
+	creditPromo(t, repo, wallet.ID, 5, nil)
+	update := *stale
+	update.DebitOrder = models.CashFirst
+	update.OverdraftLimit = 25
+	require.NoError(t, repo.UpdateWallet(ctx, &update))
+	assert.Equal(t, float64(rounds+5), update.Balance, "the wallet is reloaded")
+
+	got, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(rounds+5), got.Balance)
+	assert.Equal(t, 5.0, got.PromoBalance)
+	assert.Equal(t, models.CashFirst, got.DebitOrder)
+	assert.Equal(t, 25.0, got.OverdraftLimit)
+}
+
+func testDeleteCascades(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 50)
+	require.NoError(t, repo.DeleteWallet(ctx, wallet.ID))
+
+	_, err := repo.GetWalletByID(ctx, wallet.ID)
+	assert.ErrorIs(t, err, models.ErrWalletNotFound)
+	history, err := repo.GetTransactionsByWalletID(ctx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// The user ID is free again
+	assert.NoError(t, repo.CreateWallet(ctx, &models.Wallet{UserID: wallet.UserID, Currency: "USD"}))
+}
+
+func testUpdateWalletBalance(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 0)
+	require.NoError(t, repo.UpdateWalletBalance(ctx, wallet.ID, 30, models.Credit))
+	require.NoError(t, repo.UpdateWalletBalance(ctx, wallet.ID, 10, models.Debit))
+	assert.ErrorIs(t, repo.UpdateWalletBalance(ctx, wallet.ID, 25, models.Debit), models.ErrInsufficientFunds)
+
+	got, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 20.0, got.Balance)
+}
+
+func testProcessTransaction(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 100)
+
+	tx := &models.Transaction{Description: "groceries", Reference: "ref-1"}
+	require.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 40, models.Debit, tx))
+	assert.NotEqual(t, uuid.Nil, tx.ID)
+	assert.Equal(t, wallet.ID, tx.WalletID)
+	assert.Equal(t, models.Debit, tx.Type)
+	assert.Equal(t, 40.0, tx.Amount)
+
+	got, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, got.Balance)
+
+	history, err := repo.GetTransactionsByWalletID(ctx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, tx.ID, history[0].ID)
+	assert.Equal(t, "groceries", history[0].Description)
+	assert.Equal(t, "ref-1", history[0].Reference)
+}
+
+func testInsufficientBalance(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 10)
+
+	err := repo.ProcessTransactionWithRollback(ctx, wallet.ID, 10.01, models.Debit, &models.Transaction{})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+
+	got, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, got.Balance)
+	history, err := repo.GetTransactionsByWalletID(ctx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func testHistoryOrderAndPaging(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 0)
+	for _, d := range []string{"first", "second", "third", "fourth"} {
+		require.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 1, models.Credit, &models.Transaction{Description: d}))
+		// Keep timestamps distinct on databases with coarse clocks
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	page, err := repo.GetTransactionsByWalletID(ctx, wallet.ID, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "fourth", page[0].Description)
+	assert.Equal(t, "third", page[1].Description)
+
+	page, err = repo.GetTransactionsByWalletID(ctx, wallet.ID, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "second", page[0].Description)
+	assert.Equal(t, "first", page[1].Description)
+
+	page, err = repo.GetTransactionsByWalletID(ctx, wallet.ID, 2, 4)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+}
+
+func testCountTransactions(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 10)
+	require.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 3, models.Debit, &models.Transaction{}))
+
+	count, err := repo.CountTransactionsByWalletID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = repo.CountTransactionsByWalletID(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func testConcurrentDebits(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 100)
+
+	// 30 debits of 5 against a balance of 100: exactly 20 may succeed
+	const attempts = 30
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.ProcessTransactionWithRollback(ctx, wallet.ID, 5, models.Debit, &models.Transaction{})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 20, succeeded)
+	got, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, got.Balance)
+	history, err := repo.GetTransactionsByWalletID(ctx, wallet.ID, 100, 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 21)
+}
+
+func transfer(from, to uuid.UUID, amount float64) []*models.Transaction {
+	return []*models.Transaction{
+		{WalletID: from, Type: models.Debit, Amount: amount},
+		{WalletID: to, Type: models.Credit, Amount: amount},
+	}
+}
+
+func testApplyTransactions(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	a := newWallet(t, repo, 10)
+	b := newWallet(t, repo, 0)
+
+	txs := transfer(a.ID, b.ID, 4)
+	require.NoError(t, repo.ApplyTransactions(ctx, txs))
+	for _, tx := range txs {
+		assert.NotEqual(t, uuid.Nil, tx.ID)
+	}
+
+	// The second leg never runs without the first, and vice versa
+	err := repo.ApplyTransactions(ctx, append(transfer(b.ID, a.ID, 1), transfer(a.ID, b.ID, 100)...))
+	var itemErr *models.ItemError
+	require.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 2, itemErr.Index)
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+
+	err = repo.ApplyTransactions(ctx, transfer(a.ID, uuid.New(), 1))
+	require.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 1, itemErr.Index)
+	assert.ErrorIs(t, err, models.ErrWalletNotFound)
+
+	for id, want := range map[uuid.UUID]float64{a.ID: 6, b.ID: 4} {
+		got, err := repo.GetWalletByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, want, got.Balance)
+	}
+	history, err := repo.GetTransactionsByWalletID(ctx, b.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func testOpposingTransfers(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	a := newWallet(t, repo, 100)
+	b := newWallet(t, repo, 100)
+
+	// Transfers in both directions at once lock the same two wallets; they
+	// must neither deadlock nor create or lose money
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		from, to := a.ID, b.ID
+		if i%2 == 1 {
+			from, to = to, from
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.ApplyTransactions(ctx, transfer(from, to, 5))
+			if err != nil {
+				assert.ErrorIs(t, err, models.ErrConflict)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0.0
+	for _, id := range []uuid.UUID{a.ID, b.ID} {
+		got, err := repo.GetWalletByID(ctx, id)
+		require.NoError(t, err)
+		total += got.Balance
+	}
+	assert.Equal(t, 200.0, total)
+}
+
+func testCancelledContext(t *testing.T, repo repositories.WalletRepository) {
+	wallet := newWallet(t, repo, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := repo.ProcessTransactionWithRollback(ctx, wallet.ID, 5, models.Debit, &models.Transaction{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	got, err := repo.GetWalletByID(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, got.Balance)
+}
+
+// creditPromo credits a wallet with promotional credit expiring at
+// expiresAt and returns the credit's ID
+func creditPromo(t *testing.T, repo repositories.WalletRepository, walletID uuid.UUID, amount float64, expiresAt *time.Time) uuid.UUID {
+	t.Helper()
+	tx := &models.Transaction{Promo: &models.PromoTerms{ExpiresAt: expiresAt}}
+	require.NoError(t, repo.ProcessTransactionWithRollback(context.Background(), walletID, amount, models.Credit, tx))
+	assert.Equal(t, amount, tx.PromoAmount)
+	return tx.ID
+}
+
+func debit(t *testing.T, repo repositories.WalletRepository, walletID uuid.UUID, amount float64) *models.Transaction {
+	t.Helper()
+	tx := &models.Transaction{}
+	require.NoError(t, repo.ProcessTransactionWithRollback(context.Background(), walletID, amount, models.Debit, tx))
+	return tx
+}
+
+func assertBuckets(t *testing.T, repo repositories.WalletRepository, walletID uuid.UUID, balance, promo float64) {
+	t.Helper()
+	got, err := repo.GetWalletByID(context.Background(), walletID)
+	require.NoError(t, err)
+	assert.Equal(t, balance, got.Balance)
+	assert.Equal(t, promo, got.PromoBalance)
+}
+
+func testPromoSpentFirst(t *testing.T, repo repositories.WalletRepository) {
+	wallet := newWallet(t, repo, 100)
+	later := time.Now().Add(2 * time.Hour)
+	sooner := time.Now().Add(time.Hour)
+	creditPromo(t, repo, wallet.ID, 20, &later)
+	creditPromo(t, repo, wallet.ID, 30, nil)
+	creditPromo(t, repo, wallet.ID, 10, &sooner)
+	assertBuckets(t, repo, wallet.ID, 160, 60)
+
+	assert.Equal(t, 25.0, debit(t, repo, wallet.ID, 25).PromoAmount)
+	assertBuckets(t, repo, wallet.ID, 135, 35)
+
+	// Promo runs out part way through a debit; the rest is cash
+	assert.Equal(t, 35.0, debit(t, repo, wallet.ID, 50).PromoAmount)
+	assertBuckets(t, repo, wallet.ID, 85, 0)
+	assert.Equal(t, 0.0, debit(t, repo, wallet.ID, 5).PromoAmount)
+}
+
+func testCashFirst(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := &models.Wallet{UserID: "contract-" + uuid.NewString(), Currency: "USD", DebitOrder: models.CashFirst}
+	require.NoError(t, repo.CreateWallet(ctx, wallet))
+	require.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 100, models.Credit, &models.Transaction{}))
+	creditPromo(t, repo, wallet.ID, 50, nil)
+
+	assert.Equal(t, 0.0, debit(t, repo, wallet.ID, 60).PromoAmount)
+	assert.Equal(t, 20.0, debit(t, repo, wallet.ID, 60).PromoAmount)
+	assertBuckets(t, repo, wallet.ID, 30, 30)
+}
+
+func testLapsedPromoExpires(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := newWallet(t, repo, 10)
+	lapsed := time.Now().Add(-time.Minute)
+	live := time.Now().Add(time.Hour)
+	lapsedID := creditPromo(t, repo, wallet.ID, 20, &lapsed)
+	liveID := creditPromo(t, repo, wallet.ID, 5, &live)
+
+	// Lapsed credit stays in the balance until it is expired but cannot be
+	// spent, and a failed debit leaves the credits as they were
+	err := repo.ProcessTransactionWithRollback(ctx, wallet.ID, 16, models.Debit, &models.Transaction{})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	assert.Equal(t, 3.0, debit(t, repo, wallet.ID, 3).PromoAmount)
+	assertBuckets(t, repo, wallet.ID, 32, 22)
+
+	now := time.Now()
+	ids, err := repo.LapsedPromoCredits(ctx, now, 1000)
+	require.NoError(t, err)
+	assert.Contains(t, ids, lapsedID)
+	assert.NotContains(t, ids, liveID)
+
+	expired, err := repo.ExpirePromoCredit(ctx, lapsedID, now)
+	require.NoError(t, err)
+	require.NotNil(t, expired)
+	assert.Equal(t, models.Debit, expired.Type)
+	assert.Equal(t, 20.0, expired.Amount)
+	assert.Equal(t, 20.0, expired.PromoAmount)
+	assertBuckets(t, repo, wallet.ID, 12, 2)
+
+	history, err := repo.GetTransactionsByWalletID(ctx, wallet.ID, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, expired.ID, history[0].ID)
+	assert.Equal(t, "promo-expiry:"+lapsedID.String(), history[0].Reference)
+
+	// Expiring again, or a credit that has not lapsed, does nothing
+	for _, id := range []uuid.UUID{lapsedID, liveID, uuid.New()} {
+		expired, err = repo.ExpirePromoCredit(ctx, id, now)
+		require.NoError(t, err)
+		assert.Nil(t, expired)
+	}
+	ids, err = repo.LapsedPromoCredits(ctx, now, 1000)
+	require.NoError(t, err)
+	assert.NotContains(t, ids, lapsedID)
+	assertBuckets(t, repo, wallet.ID, 12, 2)
+}
+
+func testOverdraft(t *testing.T, repo repositories.WalletRepository) {
+	ctx := context.Background()
+	wallet := &models.Wallet{UserID: "contract-" + uuid.NewString(), Currency: "USD", OverdraftLimit: 50}
+	require.NoError(t, repo.CreateWallet(ctx, wallet))
+	require.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 20, models.Credit, &models.Transaction{}))
+	creditPromo(t, repo, wallet.ID, 10, nil)
+
+	// Both buckets are emptied before the overdraft is drawn on
+	assert.Equal(t, 10.0, debit(t, repo, wallet.ID, 60).PromoAmount)
+	assertBuckets(t, repo, wallet.ID, -30, 0)
+	err := repo.ProcessTransactionWithRollback(ctx, wallet.ID, 20.01, models.Debit, &models.Transaction{})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	debit(t, repo, wallet.ID, 20)
+	assertBuckets(t, repo, wallet.ID, -50, 0)
+
+	// Charges are owed whatever the limit, and never spend promo credit
+	creditPromo(t, repo, wallet.ID, 5, nil)
+	charge := &models.Transaction{Charge: true}
+	require.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 3, models.Debit, charge))
+	assert.Equal(t, 0.0, charge.PromoAmount)
+	assertBuckets(t, repo, wallet.ID, -48, 5)
+}
@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, schedule *models.ScheduledPayment) error
+	GetSchedule(ctx context.Context, id uuid.UUID) (*models.ScheduledPayment, error)
+	// ListSchedulesByWallet returns the schedules paying from or to a
+	// wallet, oldest first
+	ListSchedulesByWallet(ctx context.Context, walletID uuid.UUID) ([]models.ScheduledPayment, error)
+	// TransitionSchedule moves a schedule whose status is one of from to
+	// status to and returns it. Becoming active makes next the occurrence
+	// to execute; becoming paused keeps the current one; any other status
+	// clears it. It returns models.ErrInvalidState when the schedule is in
+	// none of the from statuses.
+	TransitionSchedule(ctx context.Context, id uuid.UUID, from []models.ScheduleStatus, to models.ScheduleStatus, next *time.Time) (*models.ScheduledPayment, error)
+	// DueSchedules returns the IDs of up to limit active schedules due at
+	// now, most overdue first
+	DueSchedules(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+	// LockDueSchedule returns the schedule if it is still due at now, locked
+	// until the end of the surrounding transaction, or nil if it is not
+	LockDueSchedule(ctx context.Context, id uuid.UUID, now time.Time) (*models.ScheduledPayment, error)
+	// RecordExecution stores an execution together with the progress of
+	// its schedule
+	RecordExecution(ctx context.Context, schedule *models.ScheduledPayment, execution *models.ScheduleExecution) error
+	// ListExecutions returns the executions of a schedule, newest first
+	ListExecutions(ctx context.Context, scheduleID uuid.UUID, limit, offset int) ([]models.ScheduleExecution, error)
+}
+
+type scheduleRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduleRepository returns a schedule repository backed by db, which
+// may be a transaction
+func NewScheduleRepository(db *gorm.DB) ScheduleRepository {
+	return &scheduleRepository{db: db}
+}
+
+func (r *scheduleRepository) CreateSchedule(ctx context.Context, schedule *models.ScheduledPayment) (err error) {
+	ctx, span := tracer.Start(ctx, "scheduleRepository.CreateSchedule")
+	defer tracing.End(span, &err)
+
+	return r.db.WithContext(ctx).Create(schedule).Error
+}
+
+func (r *scheduleRepository) GetSchedule(ctx context.Context, id uuid.UUID) (_ *models.ScheduledPayment, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleRepository.GetSchedule")
+	defer tracing.End(span, &err)
+
+	var schedule models.ScheduledPayment
+	if err := r.db.WithContext(ctx).First(&schedule, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrScheduleNotFound
+		}
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *scheduleRepository) ListSchedulesByWallet(ctx context.Context, walletID uuid.UUID) (_ []models.ScheduledPayment, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleRepository.ListSchedulesByWallet")
+	defer tracing.End(span, &err)
+
+	var schedules []models.ScheduledPayment
+	err = r.db.WithContext(ctx).
+		Where("from_wallet_id = ? OR to_wallet_id = ?", walletID, walletID).
+		Order("created_at, id").
+		Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *scheduleRepository) TransitionSchedule(ctx context.Context, id uuid.UUID, from []models.ScheduleStatus, to models.ScheduleStatus, next *time.Time) (_ *models.ScheduledPayment, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleRepository.TransitionSchedule")
+	defer tracing.End(span, &err)
+
+	changes := map[string]any{"status": to, "updated_at": time.Now().UTC()}
+	switch to {
+	case models.SchedulePaused:
+	case models.ScheduleActive:
+		if next != nil {
+			utc := next.UTC()
+			next = &utc
+		}
+		changes["occurrence_at"] = next
+		changes["next_run_at"] = next
+		changes["attempts"] = 0
+	default:
+		changes["occurrence_at"] = nil
+		changes["next_run_at"] = nil
+		changes["attempts"] = 0
+	}
+
+	// The status condition makes the update safe against a concurrent
+	// transition without locking the row first
+	result := r.db.WithContext(ctx).Model(&models.ScheduledPayment{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(changes)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	schedule, err := r.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 && !slices.Contains(from, schedule.Status) {
+		return nil, models.ErrInvalidState
+	}
+	return schedule, nil
+}
+
+// due is the condition that a schedule's next run is at or before a given
+// time. SQLite stores timestamps as text with an offset; julianday
+// normalises them before comparing.
+func (r *scheduleRepository) due() string {
+	if r.db.Dialector.Name() == "sqlite" {
+		return "julianday(next_run_at) <= julianday(?)"
+	}
+	return "next_run_at <= ?"
+}
+
+func (r *scheduleRepository) DueSchedules(ctx context.Context, now time.Time, limit int) (_ []uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleRepository.DueSchedules")
+	defer tracing.End(span, &err)
+
+	var ids []uuid.UUID
+	err = r.db.WithContext(ctx).Model(&models.ScheduledPayment{}).
+		Where("status = ? AND "+r.due(), models.ScheduleActive, now.UTC()).
+		Order("next_run_at, id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *scheduleRepository) LockDueSchedule(ctx context.Context, id uuid.UUID, now time.Time) (_ *models.ScheduledPayment, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleRepository.LockDueSchedule")
+	defer tracing.End(span, &err)
+
+	var schedule models.ScheduledPayment
+	err = lockForUpdate(r.db.WithContext(ctx)).
+		Where("id = ? AND status = ? AND "+r.due(), id, models.ScheduleActive, now.UTC()).
+		First(&schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *scheduleRepository) RecordExecution(ctx context.Context, schedule *models.ScheduledPayment, execution *models.ScheduleExecution) (err error) {
+	ctx, span := tracer.Start(ctx, "scheduleRepository.RecordExecution")
+	defer tracing.End(span, &err)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(execution).Error; err != nil {
+			return err
+		}
+		schedule.UpdatedAt = time.Now().UTC()
+		return tx.Model(schedule).Updates(map[string]any{
+			"status":        schedule.Status,
+			"occurrence_at": schedule.OccurrenceAt,
+			"next_run_at":   schedule.NextRunAt,
+			"attempts":      schedule.Attempts,
+			"updated_at":    schedule.UpdatedAt,
+		}).Error
+	})
+}
+
+func (r *scheduleRepository) ListExecutions(ctx context.Context, scheduleID uuid.UUID, limit, offset int) (_ []models.ScheduleExecution, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleRepository.ListExecutions")
+	defer tracing.End(span, &err)
+
+	var executions []models.ScheduleExecution
+	err = r.db.WithContext(ctx).Where("schedule_id = ?", scheduleID).
+		Order("executed_at DESC, attempt DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&executions).Error
+	return executions, err
+}
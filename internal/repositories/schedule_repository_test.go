@@ -0,0 +1,147 @@
+//go:build unit
+// +build unit
+
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleRepository(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	wallets := repositories.NewWalletRepository(db.Gorm())
+	schedules := repositories.NewScheduleRepository(db.Gorm())
+
+	a := &models.Wallet{UserID: "a", Currency: "USD"}
+	b := &models.Wallet{UserID: "b", Currency: "USD"}
+	require.NoError(t, wallets.CreateWallet(ctx, a))
+	require.NoError(t, wallets.CreateWallet(ctx, b))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	due := now.Add(-time.Hour)
+	later := now.Add(time.Hour)
+	newSchedule := func(next time.Time) *models.ScheduledPayment {
+		return &models.ScheduledPayment{
+			FromWalletID:        a.ID,
+			ToWalletID:          b.ID,
+			Amount:              10,
+			Rule:                "FREQ=DAILY",
+			StartAt:             next,
+			OnInsufficientFunds: models.SkipOnInsufficientFunds,
+			Status:              models.ScheduleActive,
+			OccurrenceAt:        &next,
+			NextRunAt:           &next,
+		}
+	}
+	overdue := newSchedule(due.Add(-time.Minute))
+	dueNow := newSchedule(due)
+	upcoming := newSchedule(later)
+	for _, s := range []*models.ScheduledPayment{dueNow, upcoming, overdue} {
+		require.NoError(t, schedules.CreateSchedule(ctx, s))
+	}
+
+	ids, err := schedules.DueSchedules(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{overdue.ID, dueNow.ID}, ids)
+
+	list, err := schedules.ListSchedulesByWallet(ctx, b.ID)
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	// Pausing keeps the occurrence, so resuming could pick it up again
+	paused, err := schedules.TransitionSchedule(ctx, dueNow.ID, []models.ScheduleStatus{models.ScheduleActive}, models.SchedulePaused, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.SchedulePaused, paused.Status)
+	require.NotNil(t, paused.NextRunAt)
+	assert.True(t, due.Equal(*paused.NextRunAt))
+	locked, err := schedules.LockDueSchedule(ctx, dueNow.ID, now)
+	require.NoError(t, err)
+	assert.Nil(t, locked)
+
+	_, err = schedules.TransitionSchedule(ctx, dueNow.ID, []models.ScheduleStatus{models.ScheduleActive}, models.SchedulePaused, nil)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+	_, err = schedules.TransitionSchedule(ctx, uuid.New(), []models.ScheduleStatus{models.ScheduleActive}, models.SchedulePaused, nil)
+	assert.ErrorIs(t, err, models.ErrScheduleNotFound)
+
+	resumed, err := schedules.TransitionSchedule(ctx, dueNow.ID, []models.ScheduleStatus{models.SchedulePaused}, models.ScheduleActive, &later)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleActive, resumed.Status)
+	assert.True(t, later.Equal(*resumed.NextRunAt))
+
+	cancelled, err := schedules.TransitionSchedule(ctx, upcoming.ID, []models.ScheduleStatus{models.ScheduleActive, models.SchedulePaused}, models.ScheduleCancelled, nil)
+	require.NoError(t, err)
+	assert.Nil(t, cancelled.NextRunAt)
+
+	locked, err = schedules.LockDueSchedule(ctx, overdue.ID, now)
+	require.NoError(t, err)
+	require.NotNil(t, locked)
+	next := locked.OccurrenceAt.Add(24 * time.Hour)
+	locked.OccurrenceAt, locked.NextRunAt = &next, &next
+	for attempt := 1; attempt <= 2; attempt++ {
+		require.NoError(t, schedules.RecordExecution(ctx, locked, &models.ScheduleExecution{
+			ScheduleID:   locked.ID,
+			OccurrenceAt: due,
+			Attempt:      attempt,
+			Status:       models.ExecutionFailed,
+			ErrorCode:    "insufficient_funds",
+		}))
+	}
+	got, err := schedules.GetSchedule(ctx, overdue.ID)
+	require.NoError(t, err)
+	assert.True(t, next.Equal(*got.NextRunAt))
+
+	executions, err := schedules.ListExecutions(ctx, overdue.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, executions, 2)
+	assert.Equal(t, 2, executions[0].Attempt)
+
+	// Schedules go with their wallets
+	require.NoError(t, wallets.DeleteWallet(ctx, a.ID))
+	_, err = schedules.GetSchedule(ctx, overdue.ID)
+	assert.ErrorIs(t, err, models.ErrScheduleNotFound)
+}
+
+func TestScheduleRepositoryDueAcrossOffsets(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	wallets := repositories.NewWalletRepository(db.Gorm())
+	schedules := repositories.NewScheduleRepository(db.Gorm())
+	a := &models.Wallet{UserID: "a", Currency: "USD"}
+	b := &models.Wallet{UserID: "b", Currency: "USD"}
+	require.NoError(t, wallets.CreateWallet(ctx, a))
+	require.NoError(t, wallets.CreateWallet(ctx, b))
+
+	// Due half an hour ago, but written in a zone ahead of UTC, so its
+	// text sorts after now
+	now := time.Now().UTC().Truncate(time.Second)
+	next := now.Add(-30 * time.Minute).In(time.FixedZone("UTC+2", 2*60*60))
+	schedule := &models.ScheduledPayment{
+		FromWalletID:        a.ID,
+		ToWalletID:          b.ID,
+		Amount:              1,
+		Rule:                "FREQ=DAILY",
+		StartAt:             next,
+		OnInsufficientFunds: models.SkipOnInsufficientFunds,
+		Status:              models.ScheduleActive,
+		OccurrenceAt:        &next,
+		NextRunAt:           &next,
+	}
+	require.NoError(t, schedules.CreateSchedule(ctx, schedule))
+
+	ids, err := schedules.DueSchedules(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{schedule.ID}, ids)
+	locked, err := schedules.LockDueSchedule(ctx, schedule.ID, now)
+	require.NoError(t, err)
+	assert.NotNil(t, locked)
+}
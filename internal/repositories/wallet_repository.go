@@ -1,88 +1,183 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"sort"
+	"time"
 
-	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/consistency"
 	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/tracing"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+var tracer = otel.Tracer("wallet-microservice/internal/repositories")
+
 type WalletRepository interface {
-	CreateWallet(wallet *models.Wallet) error
-	GetWalletByID(id uuid.UUID) (*models.Wallet, error)
-	GetWalletByUserID(userID string) (*models.Wallet, error)
-	UpdateWallet(wallet *models.Wallet) error
-	DeleteWallet(id uuid.UUID) error
-	CreateTransaction(transaction *models.Transaction) error
-	GetTransactionsByWalletID(walletID uuid.UUID, limit, offset int) ([]models.Transaction, error)
-	UpdateWalletBalance(walletID uuid.UUID, amount float64, transactionType models.TransactionType) error
-	ProcessTransactionWithRollback(walletID uuid.UUID, amount float64, transactionType models.TransactionType, txModel *models.Transaction) error
+	CreateWallet(ctx context.Context, wallet *models.Wallet) error
+	GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
+	GetWalletByUserID(ctx context.Context, userID string) (*models.Wallet, error)
+	// UpdateWallet writes the fields of wallet a client may change: user
+	// ID, currency, debit order and overdraft limit. The balances are left
+	// to transactions; wallet is reloaded with the current ones.
+	UpdateWallet(ctx context.Context, wallet *models.Wallet) error
+	DeleteWallet(ctx context.Context, id uuid.UUID) error
+	CreateTransaction(ctx context.Context, transaction *models.Transaction) error
+	GetTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]models.Transaction, error)
+	CountTransactionsByWalletID(ctx context.Context, walletID uuid.UUID) (int64, error)
+	UpdateWalletBalance(ctx context.Context, walletID uuid.UUID, amount float64, transactionType models.TransactionType) error
+	ProcessTransactionWithRollback(ctx context.Context, walletID uuid.UUID, amount float64, transactionType models.TransactionType, txModel *models.Transaction) error
+	// ApplyTransactions applies and records txs in order, all or nothing,
+	// e.g. both sides of a transfer. A failure is reported as a
+	// *models.ItemError naming the offending transaction.
+	ApplyTransactions(ctx context.Context, txs []*models.Transaction) error
+	// LapsedPromoCredits returns the transaction IDs of up to limit
+	// promotional credits that have expired by now with something left,
+	// soonest expired first
+	LapsedPromoCredits(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+	// ExpirePromoCredit debits what is left of a lapsed promotional credit
+	// from its wallet and returns the debit, or nil if there is nothing to
+	// expire, e.g. because it was expired concurrently
+	ExpirePromoCredit(ctx context.Context, creditID uuid.UUID, now time.Time) (*models.Transaction, error)
+}
+
+// ReadRouter picks the connection for read-only queries that tolerate
+// replication lag, and bounds how stale that connection's data may be
+type ReadRouter interface {
+	Reader() (*gorm.DB, time.Duration)
 }
 
 type walletRepository struct {
-	db *gorm.DB
+	db    *gorm.DB
+	reads ReadRouter
+}
+
+// Option configures optional behaviour of the wallet repository
+type Option func(*walletRepository)
+
+// WithReadRouter sends lag-tolerant reads, such as transaction history, to
+// the connections chosen by router instead of db. Anything that must see
+// the latest committed state, including balance checks, stays on db.
+func WithReadRouter(router ReadRouter) Option {
+	return func(r *walletRepository) {
+		r.reads = router
+	}
+}
+
+// NewWalletRepository returns a repository backed by db. Passing a
+// transaction instead of the pool makes every call part of that unit of
+// work.
+func NewWalletRepository(db *gorm.DB, opts ...Option) WalletRepository {
+	r := &walletRepository{
+		db: db,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-func NewWalletRepository() WalletRepository {
-	return &walletRepository{
-		db: database.DB,
+// reader returns the connection for a lag-tolerant read and records its
+// staleness against ctx
+func (r *walletRepository) reader(ctx context.Context) *gorm.DB {
+	if r.reads == nil {
+		consistency.Record(ctx, 0)
+		return r.db
 	}
+	db, staleness := r.reads.Reader()
+	consistency.Record(ctx, staleness)
+	return db
 }
 
-func (r *walletRepository) CreateWallet(wallet *models.Wallet) error {
-	return r.db.Create(wallet).Error
+func (r *walletRepository) CreateWallet(ctx context.Context, wallet *models.Wallet) (err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.CreateWallet")
+	defer tracing.End(span, &err)
+
+	return translateError(r.db, r.db.WithContext(ctx).Create(wallet).Error)
 }
 
-func (r *walletRepository) GetWalletByID(id uuid.UUID) (*models.Wallet, error) {
+func (r *walletRepository) GetWalletByID(ctx context.Context, id uuid.UUID) (_ *models.Wallet, err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.GetWalletByID")
+	defer tracing.End(span, &err)
+
 	var wallet models.Wallet
-	err := r.db.First(&wallet, "id = ?", id).Error
+	err = r.db.WithContext(ctx).First(&wallet, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("wallet not found")
+			return nil, models.ErrWalletNotFound
 		}
 		return nil, err
 	}
 	return &wallet, nil
 }
 
-func (r *walletRepository) GetWalletByUserID(userID string) (*models.Wallet, error) {
+func (r *walletRepository) GetWalletByUserID(ctx context.Context, userID string) (_ *models.Wallet, err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.GetWalletByUserID")
+	defer tracing.End(span, &err)
+
 	var wallet models.Wallet
-	err := r.db.First(&wallet, "user_id = ?", userID).Error
+	err = r.db.WithContext(ctx).First(&wallet, "user_id = ?", userID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("wallet not found")
+			return nil, models.ErrWalletNotFound
 		}
 		return nil, err
 	}
 	return &wallet, nil
 }
 
-func (r *walletRepository) UpdateWallet(wallet *models.Wallet) error {
-	return r.db.Save(wallet).Error
+func (r *walletRepository) UpdateWallet(ctx context.Context, wallet *models.Wallet) (err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.UpdateWallet")
+	defer tracing.End(span, &err)
+
+	result := r.db.WithContext(ctx).Model(wallet).
+		Select("user_id", "currency", "debit_order", "overdraft_limit", "updated_at").
+		Updates(wallet)
+	if result.Error != nil {
+		return translateError(r.db, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrWalletNotFound
+	}
+	return r.db.WithContext(ctx).First(wallet, "id = ?", wallet.ID).Error
 }
 
-func (r *walletRepository) DeleteWallet(id uuid.UUID) error {
-	result := r.db.Delete(&models.Wallet{}, "id = ?", id)
+func (r *walletRepository) DeleteWallet(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.DeleteWallet")
+	defer tracing.End(span, &err)
+
+	result := r.db.WithContext(ctx).Delete(&models.Wallet{}, "id = ?", id)
 	if result.Error != nil {
+		// Escrows and bills keep their wallets
+		if foreignKeyViolated(r.db, result.Error) {
+			return models.ErrInvalidState
+		}
 		return result.Error
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("wallet not found")
+		return models.ErrWalletNotFound
 	}
 	return nil
 }
 
-func (r *walletRepository) CreateTransaction(transaction *models.Transaction) error {
-	return r.db.Create(transaction).Error
+func (r *walletRepository) CreateTransaction(ctx context.Context, transaction *models.Transaction) (err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.CreateTransaction")
+	defer tracing.End(span, &err)
+
+	return r.db.WithContext(ctx).Create(transaction).Error
 }
 
-func (r *walletRepository) GetTransactionsByWalletID(walletID uuid.UUID, limit, offset int) ([]models.Transaction, error) {
+func (r *walletRepository) GetTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, limit, offset int) (_ []models.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.GetTransactionsByWalletID")
+	defer tracing.End(span, &err)
+
 	var transactions []models.Transaction
-	err := r.db.Where("wallet_id = ?", walletID).
+	err = r.reader(ctx).WithContext(ctx).Where("wallet_id = ?", walletID).
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -90,118 +185,229 @@ func (r *walletRepository) GetTransactionsByWalletID(walletID uuid.UUID, limit,
 	return transactions, err
 }
 
-func (r *walletRepository) UpdateWalletBalance(walletID uuid.UUID, amount float64, transactionType models.TransactionType) error {
-	// Start transaction explicitly
-	tx := r.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-
-	// Defer rollback in case of panic
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	var wallet models.Wallet
-	// Use GORM v2 locking clause for SELECT ... FOR UPDATE
-	if err := tx.
-		Clauses(clause.Locking{Strength: "UPDATE"}).
-		First(&wallet, "id = ?", walletID).Error; err != nil {
-		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("wallet not found")
-		}
-		return err
-	}
+// CountTransactionsByWalletID counts the transactions still held in the
+// database, reading from the same connection as GetTransactionsByWalletID
+func (r *walletRepository) CountTransactionsByWalletID(ctx context.Context, walletID uuid.UUID) (_ int64, err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.CountTransactionsByWalletID")
+	defer tracing.End(span, &err)
 
-	if transactionType == models.Debit {
-		if wallet.Balance < amount {
-			tx.Rollback()
-			return errors.New("insufficient balance")
-		}
-		wallet.Balance -= amount
-	} else {
-		wallet.Balance += amount
-	}
+	var count int64
+	err = r.reader(ctx).WithContext(ctx).Model(&models.Transaction{}).Where("wallet_id = ?", walletID).Count(&count).Error
+	return count, err
+}
 
-	if err := tx.Save(&wallet).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+func (r *walletRepository) UpdateWalletBalance(ctx context.Context, walletID uuid.UUID, amount float64, transactionType models.TransactionType) (err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.UpdateWalletBalance")
+	defer tracing.End(span, &err)
 
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := applyBalanceChange(tx, &models.Transaction{WalletID: walletID, Type: transactionType, Amount: amount})
 		return err
-	}
-
-	return nil
+	})
+	return translateError(r.db, err)
 }
 
 func (r *walletRepository) ProcessTransactionWithRollback(
+	ctx context.Context,
 	walletID uuid.UUID,
 	amount float64,
 	t models.TransactionType,
 	txReq *models.Transaction,
-) error {
-	// Start transaction explicitly
-	tx := r.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
+) (err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.ProcessTransactionWithRollback")
+	defer tracing.End(span, &err)
+
+	// Transaction commits when fn returns nil and rolls back on error or
+	// panic. On a repository built from a transaction it nests as a savepoint.
+	// It always runs on the primary: the balance check needs the latest
+	// committed balance and the row lock.
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1) Lock the wallet row, validate and persist the new balance
+		txReq.WalletID = walletID
+		txReq.Type = t
+		txReq.Amount = amount
+		if _, err := applyBalanceChange(tx, txReq); err != nil {
+			return err
+		}
+
+		// 2) Insert the transaction record
+		return tx.Create(txReq).Error
+	})
+	return translateError(r.db, err)
+}
+
+func (r *walletRepository) ApplyTransactions(ctx context.Context, txs []*models.Transaction) (err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.ApplyTransactions")
+	defer tracing.End(span, &err)
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return applyTransactions(tx, txs)
+	})
+	return translateError(r.db, err)
+}
+
+func (r *walletRepository) LapsedPromoCredits(ctx context.Context, now time.Time, limit int) (_ []uuid.UUID, err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.LapsedPromoCredits")
+	defer tracing.End(span, &err)
+
+	lapsed := "expires_at <= ?"
+	if r.db.Dialector.Name() == "sqlite" {
+		lapsed = "julianday(expires_at) <= julianday(?)"
 	}
+	var ids []uuid.UUID
+	err = r.db.WithContext(ctx).Model(&models.PromoCredit{}).
+		Where("remaining > 0 AND "+lapsed, now.UTC()).
+		Order("expires_at, transaction_id").
+		Limit(limit).
+		Pluck("transaction_id", &ids).Error
+	return ids, err
+}
 
-	// Defer rollback in case of panic
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+func (r *walletRepository) ExpirePromoCredit(ctx context.Context, creditID uuid.UUID, now time.Time) (_ *models.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "walletRepository.ExpirePromoCredit")
+	defer tracing.End(span, &err)
+
+	var expired *models.Transaction
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var credit models.PromoCredit
+		if err := tx.First(&credit, "transaction_id = ?", creditID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		// Promo credits change under their wallet's lock, so read the
+		// credit again once holding it
+		wallet, err := lockWallet(tx, credit.WalletID)
+		if err != nil {
+			return err
+		}
+		if err := tx.First(&credit, "transaction_id = ?", creditID).Error; err != nil {
+			return err
+		}
+		if credit.Remaining <= 0 || !credit.Lapsed(now) {
+			return nil
 		}
-	}()
 
-	// 1) Lock the target wallet row using SELECT ... FOR UPDATE
-	var wallet models.Wallet
-	if err := tx.
-		Clauses(clause.Locking{Strength: "UPDATE"}).
-		First(&wallet, "id = ?", walletID).Error; err != nil {
-		tx.Rollback()
-		return err
+		t := expiryDebit(wallet, &credit, now.UTC())
+		if err := tx.Save(wallet).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&credit).Updates(map[string]any{"remaining": credit.Remaining, "expired_at": credit.ExpiredAt}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		expired = t
+		return nil
+	})
+	if err != nil {
+		return nil, translateError(r.db, err)
+	}
+	return expired, nil
+}
+
+func (r *walletRepository) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// applyBalanceChange locks the wallet row, checks the balance for debits and
+// saves the new balance within tx, together with the promo credits that t
+// spends or creates. It sets t.PromoAmount but does not record t.
+func applyBalanceChange(tx *gorm.DB, t *models.Transaction) (*models.Wallet, error) {
+	wallet, err := lockWallet(tx, t.WalletID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 2) Business validation and balance math
-	if t == models.Debit {
-		if wallet.Balance < amount {
-			tx.Rollback()
-			return errors.New("insufficient balance")
+	now := time.Now().UTC()
+	var credits []*models.PromoCredit
+	if t.Type == models.Debit && wallet.PromoBalance > 0 {
+		if err := tx.Where("wallet_id = ? AND remaining > 0", wallet.ID).Find(&credits).Error; err != nil {
+			return nil, err
 		}
-		wallet.Balance -= amount
-	} else {
-		wallet.Balance += amount
+	}
+	changed, err := applyToWallet(wallet, credits, t, now)
+	if err != nil {
+		return nil, err
 	}
 
-	// 3) Persist the new balance
-	if err := tx.Save(&wallet).Error; err != nil {
-		tx.Rollback()
-		return err
+	if err := tx.Save(wallet).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range changed {
+		if err := tx.Model(c).Update("remaining", c.Remaining).Error; err != nil {
+			return nil, err
+		}
+	}
+	if t.Type == models.Credit && t.Promo != nil {
+		if t.ID == uuid.Nil {
+			t.ID = uuid.New()
+		}
+		if err := tx.Create(newPromoCredit(t, now)).Error; err != nil {
+			return nil, err
+		}
 	}
+	return wallet, nil
+}
 
-	// 4) Prepare and insert the transaction record
-	txReq.WalletID = wallet.ID
-	txReq.Type = t
-	txReq.Amount = amount
+// lockWallet reads a wallet, locking its row until tx ends
+func lockWallet(tx *gorm.DB, id uuid.UUID) (*models.Wallet, error) {
+	var wallet models.Wallet
+	if err := lockForUpdate(tx).First(&wallet, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrWalletNotFound
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
 
-	if err := tx.Create(txReq).Error; err != nil {
-		tx.Rollback()
-		return err
+// applyTransactions applies and records txs in order within tx, all or
+// nothing. It first locks every wallet involved in ID order, so concurrent
+// multi-wallet operations cannot deadlock on each other. A failure is
+// reported as a *models.ItemError naming the offending transaction.
+func applyTransactions(tx *gorm.DB, txs []*models.Transaction) error {
+	first := make(map[uuid.UUID]int, len(txs))
+	for i, t := range txs {
+		if _, ok := first[t.WalletID]; !ok {
+			first[t.WalletID] = i
+		}
+	}
+	ids := make([]uuid.UUID, 0, len(first))
+	for id := range first {
+		ids = append(ids, id)
 	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
 
-	// 5) Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		return err
+	for _, id := range ids {
+		var wallet models.Wallet
+		if err := lockForUpdate(tx).Select("id").First(&wallet, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				err = models.ErrWalletNotFound
+			}
+			return &models.ItemError{Index: first[id], Err: err}
+		}
 	}
 
+	for i, t := range txs {
+		if _, err := applyBalanceChange(tx, t); err != nil {
+			return &models.ItemError{Index: i, Err: err}
+		}
+		if err := tx.Create(t).Error; err != nil {
+			return &models.ItemError{Index: i, Err: err}
+		}
+	}
 	return nil
 }
 
-func (r *walletRepository) WithTransaction(fn func(tx *gorm.DB) error) error {
-	return r.db.Transaction(fn)
+// lockForUpdate makes the next query lock the rows it reads until tx ends.
+// Postgres uses SELECT ... FOR UPDATE. SQLite has no row locks; there the
+// transaction was begun IMMEDIATE and already holds the database write lock.
+func lockForUpdate(tx *gorm.DB) *gorm.DB {
+	if tx.Dialector.Name() == "sqlite" {
+		return tx
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
 }
@@ -0,0 +1,29 @@
+//go:build integration
+// +build integration
+
+package repositories_test
+
+import (
+	"context"
+	"testing"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/repositories/repositorytest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletRepositoryContract(t *testing.T) {
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+	db, err := database.Open(cfg.Database)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Migrate(context.Background()))
+
+	repositorytest.Run(t, func(t *testing.T) repositories.WalletRepository {
+		return repositories.NewWalletRepository(db.Gorm())
+	})
+}
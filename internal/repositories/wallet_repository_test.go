@@ -0,0 +1,99 @@
+//go:build unit
+// +build unit
+
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/consistency"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/repositories/repositorytest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestWalletRepositorySQLiteContract(t *testing.T) {
+	// Every test gets its own database, so they could run in parallel
+	repositorytest.Run(t, func(t *testing.T) repositories.WalletRepository {
+		return repositories.NewWalletRepository(openTestDB(t).Gorm())
+	})
+}
+
+func TestWalletRepositoryUnitOfWork(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewWalletRepository(db.Gorm())
+
+	wallet := &models.Wallet{UserID: "uow", Currency: "USD"}
+	require.NoError(t, repo.CreateWallet(ctx, wallet))
+
+	// A repository built on a transaction joins it: its changes roll back
+	// with the transaction
+	rollback := errors.New("rollback")
+	err := db.Gorm().Transaction(func(tx *gorm.DB) error {
+		txRepo := repositories.NewWalletRepository(tx)
+		require.NoError(t, txRepo.ProcessTransactionWithRollback(ctx, wallet.ID, 50, models.Credit, &models.Transaction{}))
+
+		got, err := txRepo.GetWalletByID(ctx, wallet.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 50.0, got.Balance)
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+
+	got, err := repo.GetWalletByID(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, got.Balance)
+	history, err := repo.GetTransactionsByWalletID(ctx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+type fixedReader struct {
+	db        *gorm.DB
+	staleness time.Duration
+}
+
+func (f fixedReader) Reader() (*gorm.DB, time.Duration) {
+	return f.db, f.staleness
+}
+
+func TestWalletRepositoryReadRouting(t *testing.T) {
+	primary := openTestDB(t)
+	replica := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewWalletRepository(primary.Gorm(),
+		repositories.WithReadRouter(fixedReader{db: replica.Gorm(), staleness: 2 * time.Second}))
+
+	// Writes and balance checks use the primary
+	wallet := &models.Wallet{UserID: "reader", Currency: "USD"}
+	require.NoError(t, repo.CreateWallet(ctx, wallet))
+	require.NoError(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 10, models.Credit, &models.Transaction{}))
+	assert.ErrorIs(t, repo.ProcessTransactionWithRollback(ctx, wallet.ID, 20, models.Debit, &models.Transaction{}), models.ErrInsufficientFunds)
+
+	// History comes from the replica, which has not caught up, and the
+	// staleness bound is reported through the context
+	trackedCtx, reads := consistency.Track(ctx)
+	history, err := repo.GetTransactionsByWalletID(trackedCtx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	staleness, ok := reads.MaxStaleness()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, staleness)
+
+	// Without a router reads use the primary and report zero staleness
+	trackedCtx, reads = consistency.Track(ctx)
+	history, err = repositories.NewWalletRepository(primary.Gorm()).GetTransactionsByWalletID(trackedCtx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+	staleness, ok = reads.MaxStaleness()
+	assert.True(t, ok)
+	assert.Zero(t, staleness)
+}
@@ -0,0 +1,174 @@
+// Package scheduler executes scheduled payments when they fall due. Only
+// one instance runs them at a time: the one holding a Postgres advisory
+// lock.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/services"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lockKey identifies the Postgres advisory lock held while running due
+// schedules
+const lockKey int64 = 7_310_021_554_003
+
+// Runner executes due schedules through WalletService.Transfer
+type Runner struct {
+	db       *gorm.DB
+	cfg      config.SchedulerConfig
+	timeouts services.Timeouts
+	now      func() time.Time
+}
+
+func New(db *gorm.DB, cfg config.SchedulerConfig, timeouts services.Timeouts) *Runner {
+	return &Runner{
+		db:       db,
+		cfg:      cfg,
+		timeouts: timeouts,
+		now:      time.Now,
+	}
+}
+
+// RunDue executes every schedule due now, most overdue first. It is meant to
+// run periodically. Each execution is committed together with the
+// schedule's progress, so an occurrence is paid at most once; on an error
+// that is not the payment's fault the schedule is left due and RunDue
+// stops.
+func (r *Runner) RunDue(ctx context.Context) error {
+	ran, err := database.WithTryLock(ctx, r.db, lockKey, func(conn *gorm.DB) error {
+		now := r.now().UTC()
+		for ctx.Err() == nil {
+			ids, err := repositories.NewScheduleRepository(conn).DueSchedules(ctx, now, r.cfg.BatchSize)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := r.run(ctx, conn, id, now); err != nil {
+					return fmt.Errorf("run schedule %s: %w", id, err)
+				}
+			}
+			// Executed schedules are no longer due, so the next page starts
+			// with the ones left over
+			if len(ids) < r.cfg.BatchSize {
+				return nil
+			}
+		}
+		return ctx.Err()
+	})
+	if err == nil && !ran {
+		slog.DebugContext(ctx, "Scheduled payments are running elsewhere; skipping")
+	}
+	return err
+}
+
+// run executes the current occurrence of a schedule and moves the schedule
+// on, in one database transaction
+func (r *Runner) run(ctx context.Context, conn *gorm.DB, id uuid.UUID, now time.Time) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		schedules := repositories.NewScheduleRepository(tx)
+		schedule, err := schedules.LockDueSchedule(ctx, id, now)
+		if err != nil || schedule == nil {
+			// Paused, cancelled or run since it was listed
+			return err
+		}
+
+		occurrence := *schedule.OccurrenceAt
+		execution := &models.ScheduleExecution{
+			ScheduleID:   schedule.ID,
+			OccurrenceAt: occurrence,
+			Attempt:      schedule.Attempts + 1,
+			ExecutedAt:   now,
+		}
+
+		// The wallet service works within tx, so the transfer commits or
+		// rolls back with the execution record
+		wallets := services.NewWalletService(repositories.NewWalletRepository(tx), services.WithTimeouts(r.timeouts))
+		resp, err := wallets.Transfer(ctx, models.TransferRequest{
+			FromWalletID: schedule.FromWalletID,
+			ToWalletID:   schedule.ToWalletID,
+			Amount:       schedule.Amount,
+			Description:  schedule.Description,
+			Reference:    fmt.Sprintf("schedule:%s:%s", schedule.ID, occurrence.Format(time.RFC3339)),
+		})
+		if err != nil {
+			failure, ok := services.Failure(err)
+			if !ok {
+				return err
+			}
+			execution.ErrorCode = failure.Code
+			execution.ErrorMessage = failure.Message
+			if retryAt, ok := r.retryAt(schedule, err, now); ok {
+				execution.Status = models.ExecutionFailed
+				schedule.Attempts++
+				schedule.NextRunAt = &retryAt
+				return r.record(ctx, schedules, schedule, execution)
+			}
+			execution.Status = models.ExecutionSkipped
+		} else {
+			execution.Status = models.ExecutionSucceeded
+			execution.DebitTransactionID = &resp.Debit.ID
+			execution.CreditTransactionID = &resp.Credit.ID
+		}
+
+		// Occurrences missed while the runner was down are not made up
+		next, err := services.NextOccurrence(schedule, later(occurrence, now))
+		if err != nil {
+			return err
+		}
+		schedule.Attempts = 0
+		schedule.OccurrenceAt, schedule.NextRunAt = next, next
+		if next == nil {
+			schedule.Status = models.ScheduleCompleted
+		}
+		return r.record(ctx, schedules, schedule, execution)
+	})
+}
+
+// retryAt returns when to retry an occurrence that failed with err, or
+// false to skip it. Only insufficient funds are retried, and not into the
+// next occurrence.
+func (r *Runner) retryAt(schedule *models.ScheduledPayment, err error, now time.Time) (time.Time, bool) {
+	if !errors.Is(err, models.ErrInsufficientFunds) ||
+		schedule.OnInsufficientFunds != models.RetryOnInsufficientFunds ||
+		schedule.Attempts >= r.cfg.MaxRetries {
+		return time.Time{}, false
+	}
+	retryAt := now.Add(r.cfg.RetryInterval)
+	next, err := services.NextOccurrence(schedule, *schedule.OccurrenceAt)
+	if err != nil || (next != nil && !retryAt.Before(*next)) {
+		return time.Time{}, false
+	}
+	return retryAt, true
+}
+
+func (r *Runner) record(ctx context.Context, schedules repositories.ScheduleRepository, schedule *models.ScheduledPayment, execution *models.ScheduleExecution) error {
+	if err := schedules.RecordExecution(ctx, schedule, execution); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Executed scheduled payment",
+		"schedule_id", schedule.ID,
+		"occurrence", execution.OccurrenceAt,
+		"attempt", execution.Attempt,
+		"status", execution.Status,
+		"error_code", execution.ErrorCode)
+	return nil
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,221 @@
+//go:build unit
+// +build unit
+
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	runner    *Runner
+	wallets   services.WalletService
+	schedules services.ScheduleService
+	from, to  uuid.UUID
+}
+
+// newFixture returns a runner over a private in-memory SQLite database with
+// two USD wallets, the first holding balance
+func newFixture(t *testing.T, balance float64) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	cfg := config.Defaults()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	db, err := database.Open(cfg.Database)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Migrate(ctx))
+
+	walletRepo := repositories.NewWalletRepository(db.Gorm())
+	f := &fixture{
+		runner:    New(db.Gorm(), cfg.Scheduler, services.Timeouts{}),
+		wallets:   services.NewWalletService(walletRepo),
+		schedules: services.NewScheduleService(repositories.NewScheduleRepository(db.Gorm()), walletRepo, services.Timeouts{}),
+	}
+	from, err := f.wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "from"})
+	require.NoError(t, err)
+	to, err := f.wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "to"})
+	require.NoError(t, err)
+	f.from, f.to = from.ID, to.ID
+	if balance > 0 {
+		_, err = f.wallets.CreditWallet(ctx, from.ID, models.TransactionRequest{Amount: balance})
+		require.NoError(t, err)
+	}
+	return f
+}
+
+// runAt runs the due schedules as if it were t
+func (f *fixture) runAt(t *testing.T, at time.Time) {
+	t.Helper()
+	f.runner.now = func() time.Time { return at }
+	require.NoError(t, f.runner.RunDue(context.Background()))
+}
+
+func (f *fixture) balance(t *testing.T, id uuid.UUID) float64 {
+	t.Helper()
+	w, err := f.wallets.GetWallet(context.Background(), id)
+	require.NoError(t, err)
+	return w.Balance
+}
+
+func TestRunDueExecutesOccurrences(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, 250)
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	s, err := f.schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID: f.from.String(),
+		ToWalletID:   f.to.String(),
+		Amount:       100,
+		Description:  "savings",
+		Rule:         "FREQ=DAILY",
+		StartAt:      &start,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, s.NextRunAt)
+	assert.True(t, start.Equal(*s.NextRunAt))
+
+	// Not due yet
+	f.runAt(t, start.Add(-time.Minute))
+	assert.Equal(t, 250.0, f.balance(t, f.from))
+
+	f.runAt(t, start.Add(time.Minute))
+	assert.Equal(t, 150.0, f.balance(t, f.from))
+	assert.Equal(t, 100.0, f.balance(t, f.to))
+	// Running again does not pay the occurrence twice
+	f.runAt(t, start.Add(2*time.Minute))
+	assert.Equal(t, 150.0, f.balance(t, f.from))
+
+	got, err := f.schedules.GetSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	assert.True(t, start.Add(24*time.Hour).Equal(*got.NextRunAt))
+
+	// After three days down only the oldest missed occurrence is paid
+	f.runAt(t, start.Add(4*24*time.Hour+time.Minute))
+	assert.Equal(t, 50.0, f.balance(t, f.from))
+	got, err = f.schedules.GetSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	assert.True(t, start.Add(5*24*time.Hour).Equal(*got.NextRunAt))
+
+	executions, err := f.schedules.GetExecutions(ctx, s.ID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 2)
+	assert.Equal(t, models.ExecutionSucceeded, executions[0].Status)
+	assert.True(t, start.Add(24*time.Hour).Equal(executions[0].OccurrenceAt))
+	require.NotNil(t, executions[0].DebitTransactionID)
+
+	history, err := f.wallets.GetTransactionHistory(ctx, f.to, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "savings", history[0].Description)
+	assert.Contains(t, history[0].Reference, "schedule:"+s.ID.String())
+}
+
+func TestRunDueRetriesInsufficientFunds(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, 0)
+	f.runner.cfg.MaxRetries = 1
+	f.runner.cfg.RetryInterval = time.Hour
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	s, err := f.schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID: f.from.String(),
+		ToWalletID:   f.to.String(),
+		Amount:       10,
+		Rule:         "FREQ=WEEKLY",
+		StartAt:      &start,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.RetryOnInsufficientFunds, s.OnInsufficientFunds)
+
+	f.runAt(t, start)
+	got, err := f.schedules.GetSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	assert.True(t, start.Add(time.Hour).Equal(*got.NextRunAt))
+
+	// The one retry fails too, so the occurrence is skipped
+	f.runAt(t, start.Add(time.Hour))
+	got, err = f.schedules.GetSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	assert.True(t, start.Add(7*24*time.Hour).Equal(*got.NextRunAt))
+
+	executions, err := f.schedules.GetExecutions(ctx, s.ID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 2)
+	assert.Equal(t, models.ExecutionSkipped, executions[0].Status)
+	assert.Equal(t, 2, executions[0].Attempt)
+	assert.Equal(t, models.ExecutionFailed, executions[1].Status)
+	require.NotNil(t, executions[1].Error)
+	assert.Equal(t, "insufficient_funds", executions[1].Error.Code)
+
+	// The next occurrence goes through once there is money
+	_, err = f.wallets.CreditWallet(ctx, f.from, models.TransactionRequest{Amount: 10})
+	require.NoError(t, err)
+	f.runAt(t, start.Add(7*24*time.Hour))
+	assert.Equal(t, 10.0, f.balance(t, f.to))
+}
+
+func TestRunDueSkipsAndCompletes(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, 0)
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	// A one-off payment is done after its only occurrence, paid or not
+	s, err := f.schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID:        f.from.String(),
+		ToWalletID:          f.to.String(),
+		Amount:              10,
+		StartAt:             &start,
+		OnInsufficientFunds: models.SkipOnInsufficientFunds,
+	})
+	require.NoError(t, err)
+
+	f.runAt(t, start)
+	got, err := f.schedules.GetSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleCompleted, got.Status)
+	assert.Nil(t, got.NextRunAt)
+
+	executions, err := f.schedules.GetExecutions(ctx, s.ID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, executions, 1)
+	assert.Equal(t, models.ExecutionSkipped, executions[0].Status)
+}
+
+func TestRunDueLeavesPausedSchedules(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, 100)
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	s, err := f.schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID: f.from.String(),
+		ToWalletID:   f.to.String(),
+		Amount:       10,
+		Rule:         "FREQ=DAILY",
+		StartAt:      &start,
+	})
+	require.NoError(t, err)
+	_, err = f.schedules.PauseSchedule(ctx, s.ID)
+	require.NoError(t, err)
+
+	f.runAt(t, start)
+	assert.Equal(t, 100.0, f.balance(t, f.from))
+
+	_, err = f.schedules.CancelSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	_, err = f.schedules.ResumeSchedule(ctx, s.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type BatchService interface {
+	// CreateBatch validates and stores a batch. Batches up to the async
+	// threshold are processed before returning; larger ones are left
+	// pending for ProcessPending.
+	CreateBatch(ctx context.Context, req models.BatchRequest) (*models.BatchResponse, error)
+	GetBatch(ctx context.Context, id uuid.UUID) (*models.BatchResponse, error)
+	// ProcessPending processes batches until none is left to claim
+	ProcessPending(ctx context.Context) error
+}
+
+// BatchLimits bounds the size of batches and how they are processed
+type BatchLimits struct {
+	MaxItems int
+	// AsyncThreshold is the largest batch processed within the request
+	AsyncThreshold int
+	// ProcessingTimeout is how long a batch may stay processing before
+	// another worker takes it over, e.g. after a crash
+	ProcessingTimeout time.Duration
+}
+
+type batchService struct {
+	batches  repositories.BatchRepository
+	limits   BatchLimits
+	timeouts Timeouts
+}
+
+func NewBatchService(batches repositories.BatchRepository, limits BatchLimits, timeouts Timeouts) BatchService {
+	return &batchService{
+		batches:  batches,
+		limits:   limits,
+		timeouts: timeouts,
+	}
+}
+
+func (s *batchService) CreateBatch(ctx context.Context, req models.BatchRequest) (_ *models.BatchResponse, err error) {
+	ctx, span := tracer.Start(ctx, "batchService.CreateBatch")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CreateBatch")
+	defer cancel()
+
+	batch, err := s.validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	async := len(batch.Items) > s.limits.AsyncThreshold
+	if async {
+		batch.Status = models.BatchPending
+	} else {
+		// Claimed from the start, so the worker leaves it alone
+		claimedAt := models.ClaimTime(now)
+		batch.Status = models.BatchProcessing
+		batch.ClaimedAt = &claimedAt
+	}
+	if err := s.batches.CreateBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	if !async {
+		// An interrupted batch stays processing and is taken over by the
+		// worker; the caller gets it back to poll like an async one
+		if err := s.process(ctx, batch); err != nil {
+			span.RecordError(err)
+		}
+	}
+	return toBatchResponse(batch), nil
+}
+
+// validate checks every item up front and builds the batch. Fields are
+// reported by their JSON paths, like request binding does.
+func (s *batchService) validate(ctx context.Context, req models.BatchRequest) (*models.Batch, error) {
+	if s.limits.MaxItems > 0 && len(req.Items) > s.limits.MaxItems {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "items", Message: fmt.Sprintf("must contain at most %d items", s.limits.MaxItems)},
+		}}
+	}
+	if req.Mode != models.BatchAtomic && req.Mode != models.BatchBestEffort {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "mode", Message: "must be one of atomic, best_effort"},
+		}}
+	}
+
+	var violations []models.Violation
+	batch := &models.Batch{Mode: req.Mode, ItemCount: len(req.Items)}
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for i, it := range req.Items {
+		field := fmt.Sprintf("items[%d].", i)
+		walletID, err := uuid.Parse(it.WalletID)
+		if err != nil {
+			violations = append(violations, models.Violation{Field: field + "wallet_id", Message: "must be a UUID"})
+		} else if !seen[walletID] {
+			seen[walletID] = true
+			ids = append(ids, walletID)
+		}
+		if it.Type != models.Credit && it.Type != models.Debit {
+			violations = append(violations, models.Violation{Field: field + "type", Message: "must be one of CREDIT, DEBIT"})
+		}
+		if it.Amount <= 0 {
+			violations = append(violations, models.Violation{Field: field + "amount", Message: "must be greater than 0"})
+		}
+		batch.Items = append(batch.Items, models.BatchItem{
+			Seq:         i,
+			WalletID:    walletID,
+			Type:        it.Type,
+			Amount:      it.Amount,
+			Description: it.Description,
+			Reference:   it.Reference,
+			Status:      models.BatchItemPending,
+		})
+	}
+
+	if len(ids) > 0 {
+		missing, err := s.batches.MissingWallets(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		unknown := make(map[uuid.UUID]bool, len(missing))
+		for _, id := range missing {
+			unknown[id] = true
+		}
+		for i, item := range batch.Items {
+			if unknown[item.WalletID] {
+				violations = append(violations, models.Violation{Field: fmt.Sprintf("items[%d].wallet_id", i), Message: "does not match a wallet"})
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, &models.ValidationError{Violations: violations}
+	}
+	return batch, nil
+}
+
+func (s *batchService) GetBatch(ctx context.Context, id uuid.UUID) (_ *models.BatchResponse, err error) {
+	ctx, span := tracer.Start(ctx, "batchService.GetBatch")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetBatch")
+	defer cancel()
+
+	batch, err := s.batches.GetBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toBatchResponse(batch), nil
+}
+
+func (s *batchService) ProcessPending(ctx context.Context) error {
+	for ctx.Err() == nil {
+		batch, err := s.batches.ClaimBatch(ctx, time.Now().Add(-s.limits.ProcessingTimeout))
+		if err != nil || batch == nil {
+			return err
+		}
+		if err := s.process(ctx, batch); err != nil {
+			return fmt.Errorf("process batch %s: %w", batch.ID, err)
+		}
+	}
+	return ctx.Err()
+}
+
+// process applies a claimed batch and records the outcome. Domain failures
+// of items are part of the outcome; any other error leaves the batch
+// processing, to be claimed again. Once another instance has taken the
+// batch over, the repository refuses every write with models.ErrConflict.
+func (s *batchService) process(ctx context.Context, batch *models.Batch) (err error) {
+	ctx, span := tracer.Start(ctx, "batchService.process")
+	defer tracing.End(span, &err)
+
+	if batch.Mode == models.BatchAtomic {
+		err := s.batches.ApplyAtomic(ctx, batch)
+		var itemErr *models.ItemError
+		if !errors.As(err, &itemErr) {
+			return err
+		}
+		failure, ok := Failure(itemErr.Err)
+		if !ok {
+			return err
+		}
+		for i := range batch.Items {
+			if i == itemErr.Index {
+				fail(&batch.Items[i], failure)
+			} else {
+				batch.Items[i].Status = models.BatchItemAborted
+			}
+		}
+		batch.Status = models.BatchFailed
+		return s.batches.FinishBatch(ctx, batch)
+	}
+
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		if item.Status == models.BatchItemSucceeded {
+			continue
+		}
+		err := s.batches.ApplyItem(ctx, batch, item)
+		if err == nil {
+			continue
+		}
+		failure, ok := Failure(err)
+		if !ok {
+			return err
+		}
+		fail(item, failure)
+	}
+
+	succeeded := 0
+	for _, item := range batch.Items {
+		if item.Status == models.BatchItemSucceeded {
+			succeeded++
+		}
+	}
+	switch succeeded {
+	case len(batch.Items):
+		batch.Status = models.BatchCompleted
+	case 0:
+		batch.Status = models.BatchFailed
+	default:
+		batch.Status = models.BatchPartiallyCompleted
+	}
+	return s.batches.FinishBatch(ctx, batch)
+}
+
+// failureCodes gives failed operations the codes the HTTP API answers a
+// single credit, debit or transfer with. Errors without a code are not the
+// operation's fault.
+var failureCodes = []struct {
+	err  error
+	code string
+}{
+	{models.ErrWalletNotFound, "wallet_not_found"},
+	{models.ErrInsufficientFunds, "insufficient_funds"},
+	{models.ErrInvalidAmount, "invalid_amount"},
+	{models.ErrCurrencyMismatch, "currency_mismatch"},
+}
+
+// Failure describes a domain error of an operation run in the background,
+// e.g. a batch item, for its caller to record. It reports false for other
+// errors, which are worth retrying.
+func Failure(err error) (models.ItemFailure, bool) {
+	for _, f := range failureCodes {
+		if errors.Is(err, f.err) {
+			return models.ItemFailure{Code: f.code, Message: f.err.Error()}, true
+		}
+	}
+	return models.ItemFailure{}, false
+}
+
+func fail(item *models.BatchItem, failure models.ItemFailure) {
+	item.Status = models.BatchItemFailed
+	item.ErrorCode = failure.Code
+	item.ErrorMessage = failure.Message
+}
+
+func toBatchResponse(b *models.Batch) *models.BatchResponse {
+	resp := &models.BatchResponse{
+		ID:          b.ID,
+		Mode:        b.Mode,
+		Status:      b.Status,
+		ItemCount:   b.ItemCount,
+		CreatedAt:   b.CreatedAt,
+		CompletedAt: b.CompletedAt,
+		Items:       make([]models.BatchItemResponse, len(b.Items)),
+	}
+	for i, item := range b.Items {
+		resp.Items[i] = models.BatchItemResponse{
+			Index:         item.Seq,
+			WalletID:      item.WalletID,
+			Type:          item.Type,
+			Amount:        item.Amount,
+			Status:        item.Status,
+			TransactionID: item.TransactionID,
+		}
+		switch item.Status {
+		case models.BatchItemSucceeded:
+			resp.Succeeded++
+		case models.BatchItemFailed:
+			resp.Failed++
+			resp.Items[i].Error = &models.ItemFailure{Code: item.ErrorCode, Message: item.ErrorMessage}
+		}
+	}
+	return resp
+}
@@ -0,0 +1,149 @@
+//go:build unit
+// +build unit
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchTestServices returns a wallet and a batch service sharing a
+// private in-memory SQLite database
+func newBatchTestServices(t *testing.T, limits BatchLimits) (WalletService, BatchService) {
+	t.Helper()
+	db := openTestDB(t)
+
+	wallets := NewWalletService(repositories.NewWalletRepository(db.Gorm()))
+	return wallets, NewBatchService(repositories.NewBatchRepository(db.Gorm()), limits, Timeouts{})
+}
+
+func item(walletID uuid.UUID, t models.TransactionType, amount float64) models.BatchItemRequest {
+	return models.BatchItemRequest{WalletID: walletID.String(), Type: t, Amount: amount}
+}
+
+func TestCreateBatchValidatesUpFront(t *testing.T) {
+	ctx := context.Background()
+	wallets, batches := newBatchTestServices(t, BatchLimits{MaxItems: 2, AsyncThreshold: 10})
+	wallet, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "u1"})
+	require.NoError(t, err)
+
+	_, err = batches.CreateBatch(ctx, models.BatchRequest{Mode: models.BatchAtomic, Items: []models.BatchItemRequest{
+		item(wallet.ID, models.Credit, 1), item(wallet.ID, models.Credit, 1), item(wallet.ID, models.Credit, 1),
+	}})
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "items", Message: "must contain at most 2 items"}}, validationErr.Violations)
+
+	unknown := uuid.New()
+	_, err = batches.CreateBatch(ctx, models.BatchRequest{Mode: models.BatchBestEffort, Items: []models.BatchItemRequest{
+		item(wallet.ID, models.Credit, 0), item(unknown, models.Debit, 1),
+	}})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{
+		{Field: "items[0].amount", Message: "must be greater than 0"},
+		{Field: "items[1].wallet_id", Message: "does not match a wallet"},
+	}, validationErr.Violations)
+
+	// Nothing was applied
+	got, err := wallets.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.Balance)
+}
+
+func TestCreateBatchAtomic(t *testing.T) {
+	ctx := context.Background()
+	wallets, batches := newBatchTestServices(t, BatchLimits{MaxItems: 10, AsyncThreshold: 10})
+	a, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "a"})
+	require.NoError(t, err)
+	b, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "b"})
+	require.NoError(t, err)
+
+	batch, err := batches.CreateBatch(ctx, models.BatchRequest{Mode: models.BatchAtomic, Items: []models.BatchItemRequest{
+		item(a.ID, models.Credit, 10), item(b.ID, models.Debit, 1), item(a.ID, models.Credit, 2),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchFailed, batch.Status)
+	assert.Equal(t, 1, batch.Failed)
+	assert.Equal(t, models.BatchItemAborted, batch.Items[0].Status)
+	assert.Equal(t, models.BatchItemFailed, batch.Items[1].Status)
+	assert.Equal(t, &models.ItemFailure{Code: "insufficient_funds", Message: "insufficient balance"}, batch.Items[1].Error)
+	assert.Equal(t, models.BatchItemAborted, batch.Items[2].Status)
+
+	got, err := wallets.GetWallet(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.Balance, "the whole batch was rolled back")
+
+	// The outcome is persisted
+	stored, err := batches.GetBatch(ctx, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, batch.Status, stored.Status)
+	assert.Equal(t, batch.Items[1].Error, stored.Items[1].Error)
+}
+
+func TestCreateBatchBestEffort(t *testing.T) {
+	ctx := context.Background()
+	wallets, batches := newBatchTestServices(t, BatchLimits{MaxItems: 10, AsyncThreshold: 10})
+	a, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "a"})
+	require.NoError(t, err)
+
+	batch, err := batches.CreateBatch(ctx, models.BatchRequest{Mode: models.BatchBestEffort, Items: []models.BatchItemRequest{
+		item(a.ID, models.Credit, 10), item(a.ID, models.Debit, 50), item(a.ID, models.Debit, 4),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchPartiallyCompleted, batch.Status)
+	assert.Equal(t, 2, batch.Succeeded)
+	assert.Equal(t, 1, batch.Failed)
+	assert.NotNil(t, batch.CompletedAt)
+	assert.NotNil(t, batch.Items[0].TransactionID)
+	assert.Equal(t, "insufficient_funds", batch.Items[1].Error.Code)
+
+	got, err := wallets.GetWallet(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 6.0, got.Balance)
+	history, err := wallets.GetTransactionHistory(ctx, a.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
+
+func TestCreateBatchAsync(t *testing.T) {
+	ctx := context.Background()
+	wallets, batches := newBatchTestServices(t, BatchLimits{MaxItems: 10, AsyncThreshold: 1, ProcessingTimeout: time.Minute})
+	a, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "a"})
+	require.NoError(t, err)
+
+	batch, err := batches.CreateBatch(ctx, models.BatchRequest{Mode: models.BatchAtomic, Items: []models.BatchItemRequest{
+		item(a.ID, models.Credit, 10), item(a.ID, models.Debit, 3),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchPending, batch.Status)
+	got, err := wallets.GetWallet(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.Balance, "not applied before the worker runs")
+
+	require.NoError(t, batches.ProcessPending(ctx))
+	polled, err := batches.GetBatch(ctx, batch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchCompleted, polled.Status)
+	assert.Equal(t, 2, polled.Succeeded)
+	got, err = wallets.GetWallet(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, got.Balance)
+
+	// Completed batches are not processed again
+	require.NoError(t, batches.ProcessPending(ctx))
+	got, err = wallets.GetWallet(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, got.Balance)
+
+	_, err = batches.GetBatch(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrBatchNotFound)
+}
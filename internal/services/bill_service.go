@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// maxBillParticipants bounds the shares of a bill, each settled in a
+// database transaction of its own
+const maxBillParticipants = 100
+
+type BillService interface {
+	// CreateBill validates the request, splits the total into shares and
+	// settles every share it can. Shares that cannot be settled yet, say
+	// for want of funds, stay pending with the reason recorded.
+	CreateBill(ctx context.Context, req models.CreateBillRequest) (*models.BillResponse, error)
+	GetBill(ctx context.Context, id uuid.UUID) (*models.BillResponse, error)
+	// ListBills returns the bills a wallet paid or has a share in
+	ListBills(ctx context.Context, walletID uuid.UUID) ([]models.BillResponse, error)
+	// SettleBill tries again to settle the pending shares of an open bill
+	SettleBill(ctx context.Context, id uuid.UUID) (*models.BillResponse, error)
+}
+
+type billService struct {
+	bills    repositories.BillRepository
+	wallets  repositories.WalletRepository
+	timeouts Timeouts
+	now      func() time.Time
+}
+
+func NewBillService(bills repositories.BillRepository, wallets repositories.WalletRepository, timeouts Timeouts) BillService {
+	return &billService{
+		bills:    bills,
+		wallets:  wallets,
+		timeouts: timeouts,
+		now:      time.Now,
+	}
+}
+
+func (s *billService) CreateBill(ctx context.Context, req models.CreateBillRequest) (_ *models.BillResponse, err error) {
+	ctx, span := tracer.Start(ctx, "billService.CreateBill")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CreateBill")
+	defer cancel()
+
+	bill, err := s.validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.bills.CreateBill(ctx, bill); err != nil {
+		return nil, err
+	}
+	return s.settle(ctx, bill)
+}
+
+// validate checks the request and builds the bill with its shares
+func (s *billService) validate(ctx context.Context, req models.CreateBillRequest) (*models.Bill, error) {
+	totalCents := int64(math.Round(req.Total * 100))
+	if totalCents <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+	if len(req.Participants) == 0 {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "participants", Message: "must contain at least one participant"},
+		}}
+	}
+	if len(req.Participants) > maxBillParticipants {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "participants", Message: fmt.Sprintf("must contain at most %d participants", maxBillParticipants)},
+		}}
+	}
+	if req.Split != models.BillSplitEqual && req.Split != models.BillSplitPercentage && req.Split != models.BillSplitExact {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "split", Message: "must be one of equal, percentage, exact"},
+		}}
+	}
+
+	var violations []models.Violation
+	payerID, err := uuid.Parse(req.PayerWalletID)
+	if err != nil {
+		violations = append(violations, models.Violation{Field: "payer_wallet_id", Message: "must be a UUID"})
+	}
+	walletIDs := make([]uuid.UUID, len(req.Participants))
+	weights := make([]float64, len(req.Participants))
+	seen := make(map[uuid.UUID]bool)
+	var percentages float64
+	var amountCents int64
+	for i, p := range req.Participants {
+		field := fmt.Sprintf("participants[%d].", i)
+		if walletIDs[i], err = uuid.Parse(p.WalletID); err != nil {
+			violations = append(violations, models.Violation{Field: field + "wallet_id", Message: "must be a UUID"})
+		} else if seen[walletIDs[i]] {
+			violations = append(violations, models.Violation{Field: field + "wallet_id", Message: "must not repeat another participant"})
+		}
+		seen[walletIDs[i]] = true
+
+		switch req.Split {
+		case models.BillSplitEqual:
+			weights[i] = 1
+		case models.BillSplitPercentage:
+			if p.Percentage == nil {
+				violations = append(violations, models.Violation{Field: field + "percentage", Message: "is required by a percentage split"})
+			} else if *p.Percentage <= 0 || *p.Percentage > 100 {
+				violations = append(violations, models.Violation{Field: field + "percentage", Message: "must be greater than 0 and at most 100"})
+			} else {
+				weights[i] = *p.Percentage
+				percentages += *p.Percentage
+			}
+		case models.BillSplitExact:
+			if p.Amount == nil {
+				violations = append(violations, models.Violation{Field: field + "amount", Message: "is required by an exact split"})
+			} else if *p.Amount < 0 {
+				violations = append(violations, models.Violation{Field: field + "amount", Message: "must not be negative"})
+			} else {
+				amountCents += int64(math.Round(*p.Amount * 100))
+			}
+		}
+		if p.Percentage != nil && req.Split != models.BillSplitPercentage {
+			violations = append(violations, models.Violation{Field: field + "percentage", Message: "is only allowed in a percentage split"})
+		}
+		if p.Amount != nil && req.Split != models.BillSplitExact {
+			violations = append(violations, models.Violation{Field: field + "amount", Message: "is only allowed in an exact split"})
+		}
+	}
+	if len(violations) > 0 {
+		return nil, &models.ValidationError{Violations: violations}
+	}
+	if req.Split == models.BillSplitPercentage && math.Abs(percentages-100) > 1e-6 {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "participants", Message: "percentages must add up to 100"},
+		}}
+	}
+	if req.Split == models.BillSplitExact && amountCents != totalCents {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "participants", Message: "amounts must add up to total"},
+		}}
+	}
+
+	payer, err := s.wallet(ctx, payerID, "payer_wallet_id")
+	if err != nil {
+		return nil, err
+	}
+	currencyMismatch := false
+	for i, id := range walletIDs {
+		w, err := s.wallets.GetWalletByID(ctx, id)
+		if errors.Is(err, models.ErrWalletNotFound) {
+			violations = append(violations, models.Violation{Field: fmt.Sprintf("participants[%d].wallet_id", i), Message: "does not match a wallet"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		currencyMismatch = currencyMismatch || w.Currency != payer.Currency
+	}
+	if len(violations) > 0 {
+		return nil, &models.ValidationError{Violations: violations}
+	}
+	if currencyMismatch {
+		return nil, models.ErrCurrencyMismatch
+	}
+
+	var shares []int64
+	if req.Split == models.BillSplitExact {
+		shares = make([]int64, len(req.Participants))
+		for i, p := range req.Participants {
+			shares[i] = int64(math.Round(*p.Amount * 100))
+		}
+	} else {
+		shares = splitCents(totalCents, weights)
+	}
+
+	now := s.now().UTC()
+	bill := &models.Bill{
+		PayerWalletID: payerID,
+		Total:         float64(totalCents) / 100,
+		Description:   req.Description,
+		Split:         req.Split,
+		Status:        models.BillSettled,
+		SettledAt:     &now,
+	}
+	for i, p := range req.Participants {
+		share := models.BillShare{
+			Seq:        i,
+			WalletID:   walletIDs[i],
+			Percentage: p.Percentage,
+			Amount:     float64(shares[i]) / 100,
+			Status:     models.BillSharePending,
+		}
+		// The payer owes itself nothing to move, and a share rounded down
+		// to nothing owes nothing at all
+		if share.WalletID == payerID || shares[i] == 0 {
+			share.Status = models.BillShareSettled
+			share.SettledAt = &now
+		} else {
+			bill.Status = models.BillOpen
+			bill.SettledAt = nil
+		}
+		bill.Shares = append(bill.Shares, share)
+	}
+	return bill, nil
+}
+
+// splitCents shares totalCents out in proportion to weights. Every share is
+// rounded down first; the cents this leaves over go one each to the shares
+// that lost the most to rounding, earlier ones first on ties. The shares
+// thus always add up to the total, and a bill always splits the same way.
+func splitCents(totalCents int64, weights []float64) []int64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+
+	shares := make([]int64, len(weights))
+	lost := make([]float64, len(weights))
+	left := totalCents
+	for i, w := range weights {
+		exact := float64(totalCents) * w / sum
+		shares[i] = int64(math.Floor(exact + 1e-9))
+		// Rounding what was lost keeps float noise from deciding ties
+		lost[i] = math.Round((exact-float64(shares[i]))*1e6) / 1e6
+		left -= shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return lost[order[a]] > lost[order[b]] })
+	for i := 0; left > 0; i = (i + 1) % len(order) {
+		shares[order[i]]++
+		left--
+	}
+	return shares
+}
+
+// wallet looks up a wallet named in the request body, reporting an unknown
+// one as a violation of field
+func (s *billService) wallet(ctx context.Context, id uuid.UUID, field string) (*models.Wallet, error) {
+	w, err := s.wallets.GetWalletByID(ctx, id)
+	if errors.Is(err, models.ErrWalletNotFound) {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: field, Message: "does not match a wallet"},
+		}}
+	}
+	return w, err
+}
+
+func (s *billService) GetBill(ctx context.Context, id uuid.UUID) (_ *models.BillResponse, err error) {
+	ctx, span := tracer.Start(ctx, "billService.GetBill")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetBill")
+	defer cancel()
+
+	bill, err := s.bills.GetBill(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toBillResponse(bill), nil
+}
+
+func (s *billService) ListBills(ctx context.Context, walletID uuid.UUID) (_ []models.BillResponse, err error) {
+	ctx, span := tracer.Start(ctx, "billService.ListBills")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "ListBills")
+	defer cancel()
+
+	if _, err := s.wallets.GetWalletByID(ctx, walletID); err != nil {
+		return nil, err
+	}
+	bills, err := s.bills.ListBillsByWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]models.BillResponse, len(bills))
+	for i := range bills {
+		response[i] = *toBillResponse(&bills[i])
+	}
+	return response, nil
+}
+
+func (s *billService) SettleBill(ctx context.Context, id uuid.UUID) (_ *models.BillResponse, err error) {
+	ctx, span := tracer.Start(ctx, "billService.SettleBill")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "SettleBill")
+	defer cancel()
+
+	bill, err := s.bills.GetBill(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if bill.Status != models.BillOpen {
+		return nil, models.ErrInvalidState
+	}
+	return s.settle(ctx, bill)
+}
+
+// settle settles the pending shares of bill one by one, recording why those
+// that fail for a reason of their own did, and returns the bill as it ends up
+func (s *billService) settle(ctx context.Context, bill *models.Bill) (*models.BillResponse, error) {
+	for _, share := range bill.Shares {
+		if share.Status != models.BillSharePending {
+			continue
+		}
+		_, err := s.bills.SettleShare(ctx, bill.ID, share.Seq)
+		// A share settled meanwhile by a concurrent request is done with
+		if err == nil || errors.Is(err, models.ErrInvalidState) {
+			continue
+		}
+		failure, ok := Failure(err)
+		if !ok {
+			return nil, err
+		}
+		if err := s.bills.RecordShareFailure(ctx, share.ID, failure); err != nil {
+			return nil, err
+		}
+	}
+
+	settled, err := s.bills.GetBill(ctx, bill.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toBillResponse(settled), nil
+}
+
+func toBillResponse(b *models.Bill) *models.BillResponse {
+	resp := &models.BillResponse{
+		ID:            b.ID,
+		PayerWalletID: b.PayerWalletID,
+		Total:         b.Total,
+		Description:   b.Description,
+		Split:         b.Split,
+		Status:        b.Status,
+		Shares:        make([]models.BillShareResponse, len(b.Shares)),
+		SettledAt:     b.SettledAt,
+		CreatedAt:     b.CreatedAt,
+		UpdatedAt:     b.UpdatedAt,
+	}
+	for i, share := range b.Shares {
+		resp.Shares[i] = models.BillShareResponse{
+			Index:               share.Seq,
+			WalletID:            share.WalletID,
+			Percentage:          share.Percentage,
+			Amount:              share.Amount,
+			Status:              share.Status,
+			DebitTransactionID:  share.DebitTransactionID,
+			CreditTransactionID: share.CreditTransactionID,
+			SettledAt:           share.SettledAt,
+		}
+		if share.ErrorCode != "" {
+			resp.Shares[i].Error = &models.ItemFailure{Code: share.ErrorCode, Message: share.ErrorMessage}
+		}
+	}
+	return resp
+}
@@ -0,0 +1,187 @@
+//go:build unit
+// +build unit
+
+package services
+
+import (
+	"context"
+	"testing"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBillTestServices returns a wallet and a bill service sharing a private
+// in-memory SQLite database, with wallets for alice, holding 50, bob,
+// holding 5, and carol, holding nothing
+func newBillTestServices(t *testing.T) (WalletService, BillService, []*models.WalletResponse) {
+	t.Helper()
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	walletRepo := repositories.NewWalletRepository(db.Gorm())
+	wallets := NewWalletService(walletRepo)
+	bills := NewBillService(repositories.NewBillRepository(db.Gorm()), walletRepo, Timeouts{})
+	var created []*models.WalletResponse
+	for _, w := range []struct {
+		user    string
+		balance float64
+	}{{"alice", 50}, {"bob", 5}, {"carol", 0}} {
+		wallet, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: w.user})
+		require.NoError(t, err)
+		if w.balance > 0 {
+			_, err = wallets.CreditWallet(ctx, wallet.ID, models.TransactionRequest{Amount: w.balance})
+			require.NoError(t, err)
+		}
+		created = append(created, wallet)
+	}
+	return wallets, bills, created
+}
+
+func TestSplitCents(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		weights []float64
+		want    []int64
+	}{
+		{"even", 900, []float64{1, 1, 1}, []int64{300, 300, 300}},
+		{"remainder to the first", 1000, []float64{1, 1, 1}, []int64{334, 333, 333}},
+		{"remainders in order", 1001, []float64{1, 1, 1}, []int64{334, 334, 333}},
+		{"fewer cents than participants", 2, []float64{1, 1, 1}, []int64{1, 1, 0}},
+		{"largest remainder first", 1000, []float64{33.33, 33.33, 33.34}, []int64{333, 333, 334}},
+		{"ties by order", 1, []float64{50, 50}, []int64{1, 0}},
+		{"uneven", 999, []float64{12.5, 37.5, 50}, []int64{125, 375, 499}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitCents(tt.total, tt.weights)
+			assert.Equal(t, tt.want, got)
+			var sum int64
+			for _, c := range got {
+				sum += c
+			}
+			assert.Equal(t, tt.total, sum)
+		})
+	}
+}
+
+func TestCreateBillValidates(t *testing.T) {
+	ctx := context.Background()
+	_, bills, w := newBillTestServices(t)
+	alice, bob := w[0].ID.String(), w[1].ID.String()
+	pct := func(v float64) *float64 { return &v }
+
+	_, err := bills.CreateBill(ctx, models.CreateBillRequest{
+		PayerWalletID: alice, Total: 10, Split: models.BillSplitPercentage,
+		Participants: []models.BillParticipantRequest{
+			{WalletID: bob, Percentage: pct(50), Amount: pct(5)},
+			{WalletID: bob},
+		},
+	})
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{
+		{Field: "participants[0].amount", Message: "is only allowed in an exact split"},
+		{Field: "participants[1].wallet_id", Message: "must not repeat another participant"},
+		{Field: "participants[1].percentage", Message: "is required by a percentage split"},
+	}, validationErr.Violations)
+
+	_, err = bills.CreateBill(ctx, models.CreateBillRequest{
+		PayerWalletID: alice, Total: 10, Split: models.BillSplitPercentage,
+		Participants: []models.BillParticipantRequest{{WalletID: bob, Percentage: pct(60)}},
+	})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "participants", Message: "percentages must add up to 100"}}, validationErr.Violations)
+
+	_, err = bills.CreateBill(ctx, models.CreateBillRequest{
+		PayerWalletID: alice, Total: 10, Split: models.BillSplitExact,
+		Participants: []models.BillParticipantRequest{{WalletID: bob, Amount: pct(9.99)}},
+	})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "participants", Message: "amounts must add up to total"}}, validationErr.Violations)
+
+	_, err = bills.CreateBill(ctx, models.CreateBillRequest{
+		PayerWalletID: alice, Total: 10, Split: models.BillSplitEqual,
+		Participants: []models.BillParticipantRequest{{WalletID: bob}, {WalletID: uuid.NewString()}},
+	})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "participants[1].wallet_id", Message: "does not match a wallet"}}, validationErr.Violations)
+}
+
+func TestCreateBillSettlesShares(t *testing.T) {
+	ctx := context.Background()
+	wallets, bills, w := newBillTestServices(t)
+	alice, bob, carol := w[0].ID, w[1].ID, w[2].ID
+
+	// Alice paid 10 for the three of them; carol cannot pay her share yet
+	bill, err := bills.CreateBill(ctx, models.CreateBillRequest{
+		PayerWalletID: alice.String(), Total: 10, Description: "dinner", Split: models.BillSplitEqual,
+		Participants: []models.BillParticipantRequest{
+			{WalletID: bob.String()}, {WalletID: carol.String()}, {WalletID: alice.String()},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.BillOpen, bill.Status)
+	require.Len(t, bill.Shares, 3)
+	assert.Equal(t, 3.34, bill.Shares[0].Amount)
+	assert.Equal(t, models.BillShareSettled, bill.Shares[0].Status)
+	require.NotNil(t, bill.Shares[0].DebitTransactionID)
+	assert.Equal(t, 3.33, bill.Shares[1].Amount)
+	assert.Equal(t, models.BillSharePending, bill.Shares[1].Status)
+	assert.Equal(t, &models.ItemFailure{Code: "insufficient_funds", Message: models.ErrInsufficientFunds.Error()}, bill.Shares[1].Error)
+	assert.Equal(t, 3.33, bill.Shares[2].Amount)
+	assert.Equal(t, models.BillShareSettled, bill.Shares[2].Status)
+	assert.Nil(t, bill.Shares[2].DebitTransactionID, "the payer's own share moves no money")
+
+	balance := func(id uuid.UUID) float64 {
+		wallet, err := wallets.GetWallet(ctx, id)
+		require.NoError(t, err)
+		return wallet.Balance
+	}
+	assert.InDelta(t, 53.34, balance(alice), 1e-9)
+	assert.InDelta(t, 1.66, balance(bob), 1e-9)
+	history, err := wallets.GetTransactionHistory(ctx, bob, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, "bill:"+bill.ID.String(), history[0].Reference)
+	assert.Equal(t, "dinner", history[0].Description)
+
+	// Promotional credit does not pay a share; once carol has the cash the
+	// bill can be settled
+	_, err = wallets.CreditWallet(ctx, carol, models.TransactionRequest{Amount: 4, Bucket: models.PromoBucket})
+	require.NoError(t, err)
+	unsettled, err := bills.SettleBill(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BillSharePending, unsettled.Shares[1].Status)
+	_, err = wallets.CreditWallet(ctx, carol, models.TransactionRequest{Amount: 4})
+	require.NoError(t, err)
+	settled, err := bills.SettleBill(ctx, bill.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BillSettled, settled.Status)
+	assert.NotNil(t, settled.SettledAt)
+	assert.Equal(t, models.BillShareSettled, settled.Shares[1].Status)
+	assert.Nil(t, settled.Shares[1].Error)
+	assert.InDelta(t, 56.67, balance(alice), 1e-9)
+	assert.InDelta(t, 4.67, balance(carol), 1e-9)
+
+	_, err = bills.SettleBill(ctx, bill.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+	_, err = bills.SettleBill(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrBillNotFound)
+
+	listed, err := bills.ListBills(ctx, carol)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, bill.ID, listed[0].ID)
+
+	// The bill keeps the payer's wallet and the participants'
+	assert.ErrorIs(t, wallets.DeleteWallet(ctx, alice), models.ErrInvalidState)
+	assert.ErrorIs(t, wallets.DeleteWallet(ctx, carol), models.ErrInvalidState)
+	listed, err = bills.ListBills(ctx, carol)
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+}
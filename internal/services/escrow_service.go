@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type EscrowService interface {
+	// CreateEscrow validates the request and moves the amount from the
+	// buyer's wallet into a new escrow
+	CreateEscrow(ctx context.Context, req models.CreateEscrowRequest) (*models.EscrowResponse, error)
+	GetEscrow(ctx context.Context, id uuid.UUID) (*models.EscrowResponse, error)
+	// ListEscrows returns the escrows a wallet buys or sells in
+	ListEscrows(ctx context.Context, walletID uuid.UUID) ([]models.EscrowResponse, error)
+	// ReleaseEscrow pays everything held to the seller
+	ReleaseEscrow(ctx context.Context, id uuid.UUID) (*models.EscrowResponse, error)
+	// RefundEscrow pays everything held back to the buyer
+	RefundEscrow(ctx context.Context, id uuid.UUID) (*models.EscrowResponse, error)
+	// SplitEscrow pays part of what is held to the seller and the rest back
+	// to the buyer
+	SplitEscrow(ctx context.Context, id uuid.UUID, req models.SplitEscrowRequest) (*models.EscrowResponse, error)
+}
+
+type escrowService struct {
+	escrows  repositories.EscrowRepository
+	wallets  repositories.WalletRepository
+	timeouts Timeouts
+	now      func() time.Time
+}
+
+func NewEscrowService(escrows repositories.EscrowRepository, wallets repositories.WalletRepository, timeouts Timeouts) EscrowService {
+	return &escrowService{
+		escrows:  escrows,
+		wallets:  wallets,
+		timeouts: timeouts,
+		now:      time.Now,
+	}
+}
+
+func (s *escrowService) CreateEscrow(ctx context.Context, req models.CreateEscrowRequest) (_ *models.EscrowResponse, err error) {
+	ctx, span := tracer.Start(ctx, "escrowService.CreateEscrow")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CreateEscrow")
+	defer cancel()
+
+	escrow, err := s.validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.escrows.CreateEscrow(ctx, escrow); err != nil {
+		return nil, err
+	}
+	return toEscrowResponse(escrow), nil
+}
+
+// validate checks the request and builds the escrow
+func (s *escrowService) validate(ctx context.Context, req models.CreateEscrowRequest) (*models.Escrow, error) {
+	if req.Amount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+
+	escrow := &models.Escrow{
+		Amount:      req.Amount,
+		Description: req.Description,
+	}
+	if req.ReleaseAt != nil {
+		releaseAt := req.ReleaseAt.UTC()
+		escrow.ReleaseAt = &releaseAt
+	}
+
+	var violations []models.Violation
+	var err error
+	if escrow.BuyerWalletID, err = uuid.Parse(req.BuyerWalletID); err != nil {
+		violations = append(violations, models.Violation{Field: "buyer_wallet_id", Message: "must be a UUID"})
+	}
+	if escrow.SellerWalletID, err = uuid.Parse(req.SellerWalletID); err != nil {
+		violations = append(violations, models.Violation{Field: "seller_wallet_id", Message: "must be a UUID"})
+	} else if escrow.SellerWalletID == escrow.BuyerWalletID {
+		violations = append(violations, models.Violation{Field: "seller_wallet_id", Message: "must differ from buyer_wallet_id"})
+	}
+	if escrow.ReleaseAt != nil && !escrow.ReleaseAt.After(s.now()) {
+		violations = append(violations, models.Violation{Field: "release_at", Message: "must be in the future"})
+	}
+	if len(violations) > 0 {
+		return nil, &models.ValidationError{Violations: violations}
+	}
+
+	buyer, err := s.wallet(ctx, escrow.BuyerWalletID, "buyer_wallet_id")
+	if err != nil {
+		return nil, err
+	}
+	seller, err := s.wallet(ctx, escrow.SellerWalletID, "seller_wallet_id")
+	if err != nil {
+		return nil, err
+	}
+	if buyer.Currency != seller.Currency {
+		return nil, models.ErrCurrencyMismatch
+	}
+	return escrow, nil
+}
+
+// wallet looks up a wallet named in the request body, reporting an unknown
+// one as a violation of field
+func (s *escrowService) wallet(ctx context.Context, id uuid.UUID, field string) (*models.Wallet, error) {
+	w, err := s.wallets.GetWalletByID(ctx, id)
+	if errors.Is(err, models.ErrWalletNotFound) {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: field, Message: "does not match a wallet"},
+		}}
+	}
+	return w, err
+}
+
+func (s *escrowService) GetEscrow(ctx context.Context, id uuid.UUID) (_ *models.EscrowResponse, err error) {
+	ctx, span := tracer.Start(ctx, "escrowService.GetEscrow")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetEscrow")
+	defer cancel()
+
+	escrow, err := s.escrows.GetEscrow(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toEscrowResponse(escrow), nil
+}
+
+func (s *escrowService) ListEscrows(ctx context.Context, walletID uuid.UUID) (_ []models.EscrowResponse, err error) {
+	ctx, span := tracer.Start(ctx, "escrowService.ListEscrows")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "ListEscrows")
+	defer cancel()
+
+	if _, err := s.wallets.GetWalletByID(ctx, walletID); err != nil {
+		return nil, err
+	}
+	escrows, err := s.escrows.ListEscrowsByWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]models.EscrowResponse, len(escrows))
+	for i := range escrows {
+		response[i] = *toEscrowResponse(&escrows[i])
+	}
+	return response, nil
+}
+
+func (s *escrowService) ReleaseEscrow(ctx context.Context, id uuid.UUID) (_ *models.EscrowResponse, err error) {
+	ctx, span := tracer.Start(ctx, "escrowService.ReleaseEscrow")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "ReleaseEscrow")
+	defer cancel()
+
+	return s.settle(ctx, id, models.EscrowSettlement{Status: models.EscrowReleased})
+}
+
+func (s *escrowService) RefundEscrow(ctx context.Context, id uuid.UUID) (_ *models.EscrowResponse, err error) {
+	ctx, span := tracer.Start(ctx, "escrowService.RefundEscrow")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "RefundEscrow")
+	defer cancel()
+
+	return s.settle(ctx, id, models.EscrowSettlement{Status: models.EscrowRefunded})
+}
+
+func (s *escrowService) SplitEscrow(ctx context.Context, id uuid.UUID, req models.SplitEscrowRequest) (_ *models.EscrowResponse, err error) {
+	ctx, span := tracer.Start(ctx, "escrowService.SplitEscrow")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "SplitEscrow")
+	defer cancel()
+
+	if req.SellerAmount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+	escrow, err := s.escrows.GetEscrow(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if escrow.Status != models.EscrowFunded {
+		return nil, models.ErrInvalidState
+	}
+	if req.SellerAmount >= escrow.Held {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "seller_amount", Message: "must be less than the amount held"},
+		}}
+	}
+	return s.settle(ctx, id, models.EscrowSettlement{Status: models.EscrowSplit, SellerAmount: req.SellerAmount})
+}
+
+func (s *escrowService) settle(ctx context.Context, id uuid.UUID, settlement models.EscrowSettlement) (*models.EscrowResponse, error) {
+	escrow, err := s.escrows.SettleEscrow(ctx, id, settlement)
+	if err != nil {
+		return nil, err
+	}
+	return toEscrowResponse(escrow), nil
+}
+
+func toEscrowResponse(e *models.Escrow) *models.EscrowResponse {
+	return &models.EscrowResponse{
+		ID:                   e.ID,
+		BuyerWalletID:        e.BuyerWalletID,
+		SellerWalletID:       e.SellerWalletID,
+		EscrowWalletID:       e.EscrowWalletID,
+		Amount:               e.Amount,
+		Held:                 e.Held,
+		Description:          e.Description,
+		Status:               e.Status,
+		ReleaseAt:            e.ReleaseAt,
+		SellerAmount:         e.SellerAmount,
+		BuyerAmount:          e.BuyerAmount,
+		FundingTransactionID: e.FundingTransactionID,
+		SellerTransactionID:  e.SellerTransactionID,
+		BuyerTransactionID:   e.BuyerTransactionID,
+		AutoReleased:         e.AutoReleased,
+		SettledAt:            e.SettledAt,
+		CreatedAt:            e.CreatedAt,
+		UpdatedAt:            e.UpdatedAt,
+	}
+}
@@ -0,0 +1,234 @@
+//go:build unit
+// +build unit
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type escrowFixture struct {
+	wallets       WalletService
+	escrows       *escrowService
+	buyer, seller uuid.UUID
+}
+
+// newEscrowFixture returns a wallet and an escrow service sharing a private
+// in-memory SQLite database, with a buyer holding 100 and a seller
+func newEscrowFixture(t *testing.T) *escrowFixture {
+	t.Helper()
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	walletRepo := repositories.NewWalletRepository(db.Gorm())
+	f := &escrowFixture{
+		wallets: NewWalletService(walletRepo),
+		escrows: NewEscrowService(repositories.NewEscrowRepository(db.Gorm()), walletRepo, Timeouts{}).(*escrowService),
+	}
+	buyer, err := f.wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "buyer"})
+	require.NoError(t, err)
+	seller, err := f.wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "seller"})
+	require.NoError(t, err)
+	f.buyer, f.seller = buyer.ID, seller.ID
+	_, err = f.wallets.CreditWallet(ctx, f.buyer, models.TransactionRequest{Amount: 100})
+	require.NoError(t, err)
+	return f
+}
+
+func (f *escrowFixture) create(t *testing.T, amount float64) *models.EscrowResponse {
+	t.Helper()
+	escrow, err := f.escrows.CreateEscrow(context.Background(), models.CreateEscrowRequest{
+		BuyerWalletID:  f.buyer.String(),
+		SellerWalletID: f.seller.String(),
+		Amount:         amount,
+	})
+	require.NoError(t, err)
+	return escrow
+}
+
+func (f *escrowFixture) balance(t *testing.T, id uuid.UUID) float64 {
+	t.Helper()
+	w, err := f.wallets.GetWallet(context.Background(), id)
+	require.NoError(t, err)
+	return w.Balance
+}
+
+func TestCreateEscrowValidates(t *testing.T) {
+	ctx := context.Background()
+	f := newEscrowFixture(t)
+	eur, err := f.wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "eur", Currency: "EUR"})
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = f.escrows.CreateEscrow(ctx, models.CreateEscrowRequest{
+		BuyerWalletID:  f.buyer.String(),
+		SellerWalletID: f.buyer.String(),
+		Amount:         10,
+		ReleaseAt:      &past,
+	})
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{
+		{Field: "seller_wallet_id", Message: "must differ from buyer_wallet_id"},
+		{Field: "release_at", Message: "must be in the future"},
+	}, validationErr.Violations)
+
+	_, err = f.escrows.CreateEscrow(ctx, models.CreateEscrowRequest{
+		BuyerWalletID: f.buyer.String(), SellerWalletID: uuid.NewString(), Amount: 10,
+	})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "seller_wallet_id", Message: "does not match a wallet"}}, validationErr.Violations)
+
+	_, err = f.escrows.CreateEscrow(ctx, models.CreateEscrowRequest{
+		BuyerWalletID: f.buyer.String(), SellerWalletID: eur.ID.String(), Amount: 10,
+	})
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+
+	// Nothing is held when the buyer cannot pay
+	_, err = f.escrows.CreateEscrow(ctx, models.CreateEscrowRequest{
+		BuyerWalletID: f.buyer.String(), SellerWalletID: f.seller.String(), Amount: 150,
+	})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	assert.Equal(t, 100.0, f.balance(t, f.buyer))
+	list, err := f.escrows.ListEscrows(ctx, f.buyer)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestEscrowSettlements(t *testing.T) {
+	ctx := context.Background()
+	f := newEscrowFixture(t)
+
+	released := f.create(t, 30)
+	assert.Equal(t, models.EscrowFunded, released.Status)
+	assert.Equal(t, 30.0, released.Held)
+	assert.Equal(t, 70.0, f.balance(t, f.buyer))
+
+	released, err := f.escrows.ReleaseEscrow(ctx, released.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EscrowReleased, released.Status)
+	assert.Zero(t, released.Held)
+	assert.Equal(t, 30.0, released.SellerAmount)
+	require.NotNil(t, released.SellerTransactionID)
+	assert.Nil(t, released.BuyerTransactionID)
+	assert.NotNil(t, released.SettledAt)
+	assert.Equal(t, 30.0, f.balance(t, f.seller))
+
+	// Settled escrows stay settled
+	_, err = f.escrows.RefundEscrow(ctx, released.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+	_, err = f.escrows.SplitEscrow(ctx, released.ID, models.SplitEscrowRequest{SellerAmount: 1})
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+
+	refunded := f.create(t, 20)
+	refunded, err = f.escrows.RefundEscrow(ctx, refunded.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EscrowRefunded, refunded.Status)
+	assert.Equal(t, 20.0, refunded.BuyerAmount)
+	assert.Equal(t, 70.0, f.balance(t, f.buyer))
+
+	split := f.create(t, 10)
+	_, err = f.escrows.SplitEscrow(ctx, split.ID, models.SplitEscrowRequest{SellerAmount: 10})
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "seller_amount", Message: "must be less than the amount held"}}, validationErr.Violations)
+	split, err = f.escrows.SplitEscrow(ctx, split.ID, models.SplitEscrowRequest{SellerAmount: 7.5})
+	require.NoError(t, err)
+	assert.Equal(t, models.EscrowSplit, split.Status)
+	assert.Equal(t, 7.5, split.SellerAmount)
+	assert.Equal(t, 2.5, split.BuyerAmount)
+	assert.Equal(t, 62.5, f.balance(t, f.buyer))
+	assert.Equal(t, 37.5, f.balance(t, f.seller))
+
+	history, err := f.wallets.GetTransactionHistory(ctx, f.seller, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "escrow:"+split.ID.String(), history[0].Reference)
+
+	list, err := f.escrows.ListEscrows(ctx, f.seller)
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+	_, err = f.escrows.GetEscrow(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrEscrowNotFound)
+	_, err = f.escrows.ReleaseEscrow(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrEscrowNotFound)
+}
+
+func TestWalletsWithEscrowsAreKept(t *testing.T) {
+	ctx := context.Background()
+	f := newEscrowFixture(t)
+	escrow := f.create(t, 40)
+
+	for _, id := range []uuid.UUID{f.buyer, f.seller, escrow.EscrowWalletID} {
+		assert.ErrorIs(t, f.wallets.DeleteWallet(ctx, id), models.ErrInvalidState)
+	}
+	kept, err := f.escrows.GetEscrow(ctx, escrow.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EscrowFunded, kept.Status)
+	assert.Equal(t, 40.0, kept.Held)
+	assert.Equal(t, 60.0, f.balance(t, f.buyer))
+}
+
+func TestEscrowsAreFundedWithCash(t *testing.T) {
+	ctx := context.Background()
+	f := newEscrowFixture(t)
+	_, err := f.wallets.CreditWallet(ctx, f.buyer, models.TransactionRequest{Amount: 50, Bucket: models.PromoBucket})
+	require.NoError(t, err)
+
+	// Promo credit would otherwise come back from a refund as cash
+	_, err = f.escrows.CreateEscrow(ctx, models.CreateEscrowRequest{
+		BuyerWalletID: f.buyer.String(), SellerWalletID: f.seller.String(), Amount: 120,
+	})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	escrow := f.create(t, 80)
+	_, err = f.escrows.RefundEscrow(ctx, escrow.ID)
+	require.NoError(t, err)
+	buyer, err := f.wallets.GetWallet(ctx, f.buyer)
+	require.NoError(t, err)
+	assert.Equal(t, models.BucketBalance{Cash: 100, Promo: 50}, buyer.Buckets)
+}
+
+func TestEscrowWalletHoldsTheMoney(t *testing.T) {
+	ctx := context.Background()
+	f := newEscrowFixture(t)
+
+	// The ledger balances at every step: the 100 credited to the buyer is
+	// all there is, spread over the buyer, the seller and the escrow
+	reconcile := func(escrow *models.EscrowResponse) {
+		t.Helper()
+		held := f.balance(t, escrow.EscrowWalletID)
+		assert.Equal(t, escrow.Held, held)
+		assert.Equal(t, 100.0, f.balance(t, f.buyer)+f.balance(t, f.seller)+held)
+	}
+
+	escrow := f.create(t, 40)
+	account, err := f.wallets.GetWallet(ctx, escrow.EscrowWalletID)
+	require.NoError(t, err)
+	assert.Equal(t, "escrow:"+escrow.ID.String(), account.UserID)
+	reconcile(escrow)
+
+	escrow, err = f.escrows.SplitEscrow(ctx, escrow.ID, models.SplitEscrowRequest{SellerAmount: 15})
+	require.NoError(t, err)
+	reconcile(escrow)
+	history, err := f.wallets.GetTransactionHistory(ctx, escrow.EscrowWalletID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, models.Debit, history[0].Type)
+	assert.Equal(t, 40.0, history[0].Amount)
+	assert.Equal(t, models.Credit, history[1].Type)
+
+	// Nobody else can open an escrow wallet
+	_, err = f.wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "escrow:" + uuid.NewString()})
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "user_id", Message: "must not start with escrow:"}}, validationErr.Violations)
+}
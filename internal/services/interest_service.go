@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"wallet-microservice/internal/interest"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type InterestService interface {
+	// EnrollWallet starts a wallet earning interest from today. Enrolling
+	// again changes nothing.
+	EnrollWallet(ctx context.Context, walletID uuid.UUID) (*models.InterestAccountResponse, error)
+	GetInterest(ctx context.Context, walletID uuid.UUID) (*models.InterestAccountResponse, error)
+	// GetAccruals returns the daily accruals of a wallet, newest first
+	GetAccruals(ctx context.Context, walletID uuid.UUID, page, limit int) ([]models.InterestAccrualResponse, error)
+}
+
+type interestService struct {
+	interest repositories.InterestRepository
+	wallets  repositories.WalletRepository
+	terms    *interest.Terms
+	timeouts Timeouts
+	now      func() time.Time
+}
+
+func NewInterestService(interestRepo repositories.InterestRepository, wallets repositories.WalletRepository, terms *interest.Terms, timeouts Timeouts) InterestService {
+	return &interestService{
+		interest: interestRepo,
+		wallets:  wallets,
+		terms:    terms,
+		timeouts: timeouts,
+		now:      time.Now,
+	}
+}
+
+func (s *interestService) EnrollWallet(ctx context.Context, walletID uuid.UUID) (_ *models.InterestAccountResponse, err error) {
+	ctx, span := tracer.Start(ctx, "interestService.EnrollWallet")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "EnrollWallet")
+	defer cancel()
+
+	if _, err := s.wallets.GetWalletByID(ctx, walletID); err != nil {
+		return nil, err
+	}
+	account, err := s.interest.Enroll(ctx, walletID, interest.Date(s.now()))
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(account), nil
+}
+
+func (s *interestService) GetInterest(ctx context.Context, walletID uuid.UUID) (_ *models.InterestAccountResponse, err error) {
+	ctx, span := tracer.Start(ctx, "interestService.GetInterest")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetInterest")
+	defer cancel()
+
+	account, err := s.account(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(account), nil
+}
+
+func (s *interestService) GetAccruals(ctx context.Context, walletID uuid.UUID, page, limit int) (_ []models.InterestAccrualResponse, err error) {
+	ctx, span := tracer.Start(ctx, "interestService.GetAccruals")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetAccruals")
+	defer cancel()
+
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	if _, err := s.account(ctx, walletID); err != nil {
+		return nil, err
+	}
+	accruals, err := s.interest.ListAccruals(ctx, walletID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]models.InterestAccrualResponse, len(accruals))
+	for i, a := range accruals {
+		response[i] = models.InterestAccrualResponse{
+			Date:       a.Date.Format(time.DateOnly),
+			Balance:    a.Balance,
+			AnnualRate: a.AnnualRate,
+			DayCount:   a.DayCount,
+			Amount:     a.Amount,
+		}
+	}
+	return response, nil
+}
+
+// account returns the interest account of a wallet, telling an unknown
+// wallet from one that is not enrolled
+func (s *interestService) account(ctx context.Context, walletID uuid.UUID) (*models.InterestAccount, error) {
+	if _, err := s.wallets.GetWalletByID(ctx, walletID); err != nil {
+		return nil, err
+	}
+	return s.interest.GetAccount(ctx, walletID)
+}
+
+func (s *interestService) toResponse(a *models.InterestAccount) *models.InterestAccountResponse {
+	response := &models.InterestAccountResponse{
+		WalletID:   a.WalletID,
+		Accrued:    a.Accrued,
+		EnrolledOn: a.EnrolledOn.Format(time.DateOnly),
+		AnnualRate: s.terms.RateOn(interest.Date(s.now())),
+		DayCount:   string(s.terms.DayCount),
+	}
+	if a.AccruedThrough != nil {
+		response.AccruedThrough = a.AccruedThrough.Format(time.DateOnly)
+	}
+	return response
+}
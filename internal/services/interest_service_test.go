@@ -0,0 +1,66 @@
+//go:build unit
+// +build unit
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/config"
+	"wallet-microservice/internal/database"
+	"wallet-microservice/internal/interest"
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterestEnrollment(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Defaults()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	db, err := database.Open(cfg.Database)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Migrate(ctx))
+
+	cfg.Interest.Rates = map[string]float64{"2026-01-01": 0.02, "2026-11-01": 0.025}
+	terms, err := interest.NewTerms(cfg.Interest)
+	require.NoError(t, err)
+	walletRepo := repositories.NewWalletRepository(db.Gorm())
+	wallets := NewWalletService(walletRepo)
+	s := NewInterestService(repositories.NewInterestRepository(db.Gorm()), walletRepo, terms, Timeouts{}).(*interestService)
+	now := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	w, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "saver"})
+	require.NoError(t, err)
+	_, err = s.GetInterest(ctx, w.ID)
+	assert.ErrorIs(t, err, models.ErrInterestAccountNotFound)
+	_, err = s.EnrollWallet(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrWalletNotFound)
+
+	account, err := s.EnrollWallet(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &models.InterestAccountResponse{
+		WalletID:   w.ID,
+		EnrolledOn: "2026-10-18",
+		AnnualRate: 0.02,
+		DayCount:   "ACT/365",
+	}, account)
+
+	// Enrolling again keeps the original date
+	now = now.Add(48 * time.Hour)
+	account, err = s.EnrollWallet(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10-18", account.EnrolledOn)
+
+	accruals, err := s.GetAccruals(ctx, w.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, accruals)
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type PaymentRequestService interface {
+	// CreatePaymentRequest validates and stores a pending request in the
+	// requester's currency
+	CreatePaymentRequest(ctx context.Context, req models.CreatePaymentRequestRequest) (*models.PaymentRequestResponse, error)
+	GetPaymentRequest(ctx context.Context, id uuid.UUID) (*models.PaymentRequestResponse, error)
+	// ListInbox returns the requests a user is asked to pay, newest first,
+	// optionally only those in status
+	ListInbox(ctx context.Context, userID string, status models.PaymentRequestStatus, page, limit int) ([]models.PaymentRequestResponse, error)
+	// ListOutbox returns the requests a user has made, newest first,
+	// optionally only those in status
+	ListOutbox(ctx context.Context, userID string, status models.PaymentRequestStatus, page, limit int) ([]models.PaymentRequestResponse, error)
+	// PayPaymentRequest moves the amount from the payer's wallet to the
+	// requester's
+	PayPaymentRequest(ctx context.Context, id uuid.UUID) (*models.PaymentRequestResponse, error)
+	DeclinePaymentRequest(ctx context.Context, id uuid.UUID) (*models.PaymentRequestResponse, error)
+	CancelPaymentRequest(ctx context.Context, id uuid.UUID) (*models.PaymentRequestResponse, error)
+	// ExpirePaymentRequests marks the pending requests that have lapsed
+	// expired. It is meant to run periodically.
+	ExpirePaymentRequests(ctx context.Context) error
+}
+
+type paymentRequestService struct {
+	requests      repositories.PaymentRequestRepository
+	wallets       repositories.WalletRepository
+	defaultExpiry time.Duration
+	timeouts      Timeouts
+	now           func() time.Time
+}
+
+// NewPaymentRequestService returns a payment request service. Requests that
+// do not say when they expire do so defaultExpiry after they are made.
+func NewPaymentRequestService(requests repositories.PaymentRequestRepository, wallets repositories.WalletRepository, defaultExpiry time.Duration, timeouts Timeouts) PaymentRequestService {
+	return &paymentRequestService{
+		requests:      requests,
+		wallets:       wallets,
+		defaultExpiry: defaultExpiry,
+		timeouts:      timeouts,
+		now:           time.Now,
+	}
+}
+
+func (s *paymentRequestService) CreatePaymentRequest(ctx context.Context, req models.CreatePaymentRequestRequest) (_ *models.PaymentRequestResponse, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestService.CreatePaymentRequest")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CreatePaymentRequest")
+	defer cancel()
+
+	if req.Amount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+
+	now := s.now().UTC()
+	request := &models.PaymentRequest{
+		RequesterUserID: req.RequesterUserID,
+		PayerUserID:     req.PayerUserID,
+		Amount:          req.Amount,
+		Description:     req.Description,
+		Status:          models.PaymentRequestPending,
+		ExpiresAt:       now.Add(s.defaultExpiry),
+	}
+	if req.ExpiresAt != nil {
+		request.ExpiresAt = req.ExpiresAt.UTC()
+	}
+
+	var violations []models.Violation
+	if request.PayerUserID == request.RequesterUserID {
+		violations = append(violations, models.Violation{Field: "payer_user_id", Message: "must differ from requester_user_id"})
+	}
+	if !request.ExpiresAt.After(now) {
+		violations = append(violations, models.Violation{Field: "expires_at", Message: "must be in the future"})
+	}
+	if len(violations) > 0 {
+		return nil, &models.ValidationError{Violations: violations}
+	}
+
+	requester, err := s.wallet(ctx, request.RequesterUserID, "requester_user_id")
+	if err != nil {
+		return nil, err
+	}
+	payer, err := s.wallet(ctx, request.PayerUserID, "payer_user_id")
+	if err != nil {
+		return nil, err
+	}
+	if payer.Currency != requester.Currency {
+		return nil, models.ErrCurrencyMismatch
+	}
+	request.Currency = requester.Currency
+
+	if err := s.requests.CreatePaymentRequest(ctx, request); err != nil {
+		return nil, err
+	}
+	return toPaymentRequestResponse(request, now), nil
+}
+
+// wallet looks up the wallet of a user named in the request body,
+// reporting a user without one as a violation of field
+func (s *paymentRequestService) wallet(ctx context.Context, userID, field string) (*models.Wallet, error) {
+	w, err := s.wallets.GetWalletByUserID(ctx, userID)
+	if errors.Is(err, models.ErrWalletNotFound) {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: field, Message: "has no wallet"},
+		}}
+	}
+	return w, err
+}
+
+func (s *paymentRequestService) GetPaymentRequest(ctx context.Context, id uuid.UUID) (_ *models.PaymentRequestResponse, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestService.GetPaymentRequest")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetPaymentRequest")
+	defer cancel()
+
+	request, err := s.requests.GetPaymentRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toPaymentRequestResponse(request, s.now()), nil
+}
+
+func (s *paymentRequestService) ListInbox(ctx context.Context, userID string, status models.PaymentRequestStatus, page, limit int) (_ []models.PaymentRequestResponse, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestService.ListInbox")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "ListInbox")
+	defer cancel()
+
+	return s.list(ctx, models.PaymentRequestFilter{PayerUserID: userID, Status: status}, page, limit)
+}
+
+func (s *paymentRequestService) ListOutbox(ctx context.Context, userID string, status models.PaymentRequestStatus, page, limit int) (_ []models.PaymentRequestResponse, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestService.ListOutbox")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "ListOutbox")
+	defer cancel()
+
+	return s.list(ctx, models.PaymentRequestFilter{RequesterUserID: userID, Status: status}, page, limit)
+}
+
+func (s *paymentRequestService) list(ctx context.Context, filter models.PaymentRequestFilter, page, limit int) ([]models.PaymentRequestResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	requests, err := s.requests.ListPaymentRequests(ctx, filter, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	response := make([]models.PaymentRequestResponse, len(requests))
+	for i := range requests {
+		response[i] = *toPaymentRequestResponse(&requests[i], now)
+	}
+	return response, nil
+}
+
+func (s *paymentRequestService) PayPaymentRequest(ctx context.Context, id uuid.UUID) (_ *models.PaymentRequestResponse, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestService.PayPaymentRequest")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "PayPaymentRequest")
+	defer cancel()
+
+	now := s.now()
+	request, err := s.requests.PayPaymentRequest(ctx, id, now)
+	if err != nil {
+		return nil, err
+	}
+	return toPaymentRequestResponse(request, now), nil
+}
+
+func (s *paymentRequestService) DeclinePaymentRequest(ctx context.Context, id uuid.UUID) (_ *models.PaymentRequestResponse, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestService.DeclinePaymentRequest")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "DeclinePaymentRequest")
+	defer cancel()
+
+	return s.resolve(ctx, id, models.PaymentRequestDeclined)
+}
+
+func (s *paymentRequestService) CancelPaymentRequest(ctx context.Context, id uuid.UUID) (_ *models.PaymentRequestResponse, err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestService.CancelPaymentRequest")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CancelPaymentRequest")
+	defer cancel()
+
+	return s.resolve(ctx, id, models.PaymentRequestCancelled)
+}
+
+func (s *paymentRequestService) resolve(ctx context.Context, id uuid.UUID, to models.PaymentRequestStatus) (*models.PaymentRequestResponse, error) {
+	now := s.now()
+	request, err := s.requests.ResolvePaymentRequest(ctx, id, to, now)
+	if err != nil {
+		return nil, err
+	}
+	return toPaymentRequestResponse(request, now), nil
+}
+
+func (s *paymentRequestService) ExpirePaymentRequests(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "paymentRequestService.ExpirePaymentRequests")
+	defer tracing.End(span, &err)
+
+	expired, err := s.requests.ExpirePaymentRequests(ctx, s.now())
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		slog.InfoContext(ctx, "Expired payment requests", "count", expired)
+	}
+	return nil
+}
+
+// toPaymentRequestResponse reports a pending request that has lapsed at now
+// as expired, though the expiry job may not have marked it yet
+func toPaymentRequestResponse(r *models.PaymentRequest, now time.Time) *models.PaymentRequestResponse {
+	status := r.Status
+	if r.Lapsed(now) {
+		status = models.PaymentRequestExpired
+	}
+	return &models.PaymentRequestResponse{
+		ID:                  r.ID,
+		RequesterUserID:     r.RequesterUserID,
+		PayerUserID:         r.PayerUserID,
+		Amount:              r.Amount,
+		Currency:            r.Currency,
+		Description:         r.Description,
+		Status:              status,
+		ExpiresAt:           r.ExpiresAt,
+		FromWalletID:        r.FromWalletID,
+		ToWalletID:          r.ToWalletID,
+		DebitTransactionID:  r.DebitTransactionID,
+		CreditTransactionID: r.CreditTransactionID,
+		ResolvedAt:          r.ResolvedAt,
+		CreatedAt:           r.CreatedAt,
+		UpdatedAt:           r.UpdatedAt,
+	}
+}
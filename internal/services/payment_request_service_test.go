@@ -0,0 +1,182 @@
+//go:build unit
+// +build unit
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPaymentRequestTestServices returns a wallet and a payment request
+// service sharing a private in-memory SQLite database, with wallets for
+// alice, holding 50, and bob
+func newPaymentRequestTestServices(t *testing.T) (WalletService, *paymentRequestService) {
+	t.Helper()
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	walletRepo := repositories.NewWalletRepository(db.Gorm())
+	wallets := NewWalletService(walletRepo)
+	requests := NewPaymentRequestService(repositories.NewPaymentRequestRepository(db.Gorm()), walletRepo, 24*time.Hour, Timeouts{})
+	alice, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "alice"})
+	require.NoError(t, err)
+	_, err = wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "bob"})
+	require.NoError(t, err)
+	_, err = wallets.CreditWallet(ctx, alice.ID, models.TransactionRequest{Amount: 50})
+	require.NoError(t, err)
+	return wallets, requests.(*paymentRequestService)
+}
+
+func TestCreatePaymentRequestValidates(t *testing.T) {
+	ctx := context.Background()
+	wallets, requests := newPaymentRequestTestServices(t)
+	_, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "eve", Currency: "EUR"})
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = requests.CreatePaymentRequest(ctx, models.CreatePaymentRequestRequest{
+		RequesterUserID: "bob", PayerUserID: "bob", Amount: 5, ExpiresAt: &past,
+	})
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{
+		{Field: "payer_user_id", Message: "must differ from requester_user_id"},
+		{Field: "expires_at", Message: "must be in the future"},
+	}, validationErr.Violations)
+
+	_, err = requests.CreatePaymentRequest(ctx, models.CreatePaymentRequestRequest{
+		RequesterUserID: "bob", PayerUserID: "carol", Amount: 5,
+	})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "payer_user_id", Message: "has no wallet"}}, validationErr.Violations)
+
+	_, err = requests.CreatePaymentRequest(ctx, models.CreatePaymentRequestRequest{
+		RequesterUserID: "bob", PayerUserID: "eve", Amount: 5,
+	})
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+}
+
+func TestPayPaymentRequest(t *testing.T) {
+	ctx := context.Background()
+	wallets, requests := newPaymentRequestTestServices(t)
+
+	r, err := requests.CreatePaymentRequest(ctx, models.CreatePaymentRequestRequest{
+		RequesterUserID: "bob", PayerUserID: "alice", Amount: 25, Description: "tickets",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestPending, r.Status)
+	assert.Equal(t, "USD", r.Currency)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), r.ExpiresAt, time.Minute)
+
+	paid, err := requests.PayPaymentRequest(ctx, r.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestPaid, paid.Status)
+	require.NotNil(t, paid.DebitTransactionID)
+	require.NotNil(t, paid.CreditTransactionID)
+	assert.NotNil(t, paid.ResolvedAt)
+
+	alice, err := wallets.GetWalletByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, alice.Balance)
+	assert.Equal(t, alice.ID, *paid.FromWalletID)
+	bob, err := wallets.GetWalletByUserID(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, bob.Balance)
+	history, err := wallets.GetTransactionHistory(ctx, bob.ID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, *paid.CreditTransactionID, history[0].ID)
+	assert.Equal(t, "payment-request:"+r.ID.String(), history[0].Reference)
+
+	// A paid request cannot be paid or answered again
+	_, err = requests.PayPaymentRequest(ctx, r.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+	_, err = requests.DeclinePaymentRequest(ctx, r.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+
+	// Nor can alice pay more cash than she has, as bob would get her
+	// promotional credit as cash; the request stays pending
+	_, err = wallets.CreditWallet(ctx, alice.ID, models.TransactionRequest{Amount: 10, Bucket: models.PromoBucket})
+	require.NoError(t, err)
+	big, err := requests.CreatePaymentRequest(ctx, models.CreatePaymentRequestRequest{
+		RequesterUserID: "bob", PayerUserID: "alice", Amount: 30,
+	})
+	require.NoError(t, err)
+	_, err = requests.PayPaymentRequest(ctx, big.ID)
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	big, err = requests.GetPaymentRequest(ctx, big.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestPending, big.Status)
+	declined, err := requests.DeclinePaymentRequest(ctx, big.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestDeclined, declined.Status)
+
+	_, err = requests.PayPaymentRequest(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrPaymentRequestNotFound)
+}
+
+func TestPaymentRequestsExpire(t *testing.T) {
+	ctx := context.Background()
+	_, requests := newPaymentRequestTestServices(t)
+
+	now := time.Now().UTC()
+	requests.now = func() time.Time { return now }
+	expiresAt := now.Add(time.Hour)
+	lapsing, err := requests.CreatePaymentRequest(ctx, models.CreatePaymentRequestRequest{
+		RequesterUserID: "bob", PayerUserID: "alice", Amount: 5, ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	cancelled, err := requests.CreatePaymentRequest(ctx, models.CreatePaymentRequestRequest{
+		RequesterUserID: "bob", PayerUserID: "alice", Amount: 6, ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	_, err = requests.CancelPaymentRequest(ctx, cancelled.ID)
+	require.NoError(t, err)
+	lasting, err := requests.CreatePaymentRequest(ctx, models.CreatePaymentRequestRequest{
+		RequesterUserID: "alice", PayerUserID: "bob", Amount: 7,
+	})
+	require.NoError(t, err)
+
+	// Once lapsed a request is reported expired and cannot be answered,
+	// before the job gets to it
+	now = expiresAt
+	r, err := requests.GetPaymentRequest(ctx, lapsing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentRequestExpired, r.Status)
+	_, err = requests.PayPaymentRequest(ctx, lapsing.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+	_, err = requests.DeclinePaymentRequest(ctx, lapsing.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+
+	require.NoError(t, requests.ExpirePaymentRequests(ctx))
+	expired, err := requests.ListInbox(ctx, "alice", models.PaymentRequestExpired, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, lapsing.ID, expired[0].ID)
+	assert.NotNil(t, expired[0].ResolvedAt)
+
+	inbox, err := requests.ListInbox(ctx, "alice", "", 1, 10)
+	require.NoError(t, err)
+	statuses := map[uuid.UUID]models.PaymentRequestStatus{}
+	for _, r := range inbox {
+		statuses[r.ID] = r.Status
+	}
+	assert.Equal(t, map[uuid.UUID]models.PaymentRequestStatus{
+		lapsing.ID:   models.PaymentRequestExpired,
+		cancelled.ID: models.PaymentRequestCancelled,
+	}, statuses)
+	outbox, err := requests.ListOutbox(ctx, "alice", "", 1, 10)
+	require.NoError(t, err)
+	require.Len(t, outbox, 1)
+	assert.Equal(t, lasting.ID, outbox[0].ID)
+	assert.Equal(t, models.PaymentRequestPending, outbox[0].Status)
+}
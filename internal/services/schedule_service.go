@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/recurrence"
+	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+type ScheduleService interface {
+	// CreateSchedule validates and stores a schedule. Its first occurrence
+	// is the first one at or after both its start and now.
+	CreateSchedule(ctx context.Context, req models.CreateScheduleRequest) (*models.ScheduleResponse, error)
+	GetSchedule(ctx context.Context, id uuid.UUID) (*models.ScheduleResponse, error)
+	// ListSchedules returns the schedules paying from or to a wallet
+	ListSchedules(ctx context.Context, walletID uuid.UUID) ([]models.ScheduleResponse, error)
+	PauseSchedule(ctx context.Context, id uuid.UUID) (*models.ScheduleResponse, error)
+	// ResumeSchedule reactivates a paused schedule from its next occurrence
+	// at or after now; occurrences missed while paused are not made up
+	ResumeSchedule(ctx context.Context, id uuid.UUID) (*models.ScheduleResponse, error)
+	CancelSchedule(ctx context.Context, id uuid.UUID) (*models.ScheduleResponse, error)
+	// GetExecutions returns the execution history of a schedule, newest
+	// first
+	GetExecutions(ctx context.Context, id uuid.UUID, page, limit int) ([]models.ScheduleExecutionResponse, error)
+}
+
+type scheduleService struct {
+	schedules repositories.ScheduleRepository
+	wallets   repositories.WalletRepository
+	timeouts  Timeouts
+	now       func() time.Time
+}
+
+func NewScheduleService(schedules repositories.ScheduleRepository, wallets repositories.WalletRepository, timeouts Timeouts) ScheduleService {
+	return &scheduleService{
+		schedules: schedules,
+		wallets:   wallets,
+		timeouts:  timeouts,
+		now:       time.Now,
+	}
+}
+
+func (s *scheduleService) CreateSchedule(ctx context.Context, req models.CreateScheduleRequest) (_ *models.ScheduleResponse, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleService.CreateSchedule")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CreateSchedule")
+	defer cancel()
+
+	schedule, err := s.validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.schedules.CreateSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+// validate checks the request and builds the schedule, with its first
+// occurrence set
+func (s *scheduleService) validate(ctx context.Context, req models.CreateScheduleRequest) (*models.ScheduledPayment, error) {
+	if req.Amount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+
+	now := s.now().UTC().Truncate(time.Second)
+	schedule := &models.ScheduledPayment{
+		Amount:              req.Amount,
+		Description:         req.Description,
+		StartAt:             now,
+		OnInsufficientFunds: req.OnInsufficientFunds,
+		Status:              models.ScheduleActive,
+	}
+	if req.StartAt != nil {
+		schedule.StartAt = req.StartAt.UTC()
+	}
+	if req.EndAt != nil {
+		end := req.EndAt.UTC()
+		schedule.EndAt = &end
+	}
+	if schedule.OnInsufficientFunds == "" {
+		schedule.OnInsufficientFunds = models.RetryOnInsufficientFunds
+	}
+
+	var violations []models.Violation
+	var err error
+	if schedule.FromWalletID, err = uuid.Parse(req.FromWalletID); err != nil {
+		violations = append(violations, models.Violation{Field: "from_wallet_id", Message: "must be a UUID"})
+	}
+	if schedule.ToWalletID, err = uuid.Parse(req.ToWalletID); err != nil {
+		violations = append(violations, models.Violation{Field: "to_wallet_id", Message: "must be a UUID"})
+	} else if schedule.ToWalletID == schedule.FromWalletID {
+		violations = append(violations, models.Violation{Field: "to_wallet_id", Message: "must differ from from_wallet_id"})
+	}
+	if req.Rule != "" {
+		rule, err := recurrence.Parse(req.Rule)
+		if err != nil {
+			violations = append(violations, models.Violation{Field: "rule", Message: "is invalid: " + err.Error()})
+		} else {
+			schedule.Rule = rule.String()
+		}
+	}
+	if schedule.OnInsufficientFunds != models.RetryOnInsufficientFunds && schedule.OnInsufficientFunds != models.SkipOnInsufficientFunds {
+		violations = append(violations, models.Violation{Field: "on_insufficient_funds", Message: "must be one of retry, skip"})
+	}
+	if schedule.EndAt != nil && !schedule.EndAt.After(schedule.StartAt) {
+		violations = append(violations, models.Violation{Field: "end_at", Message: "must be after start_at"})
+	}
+	if len(violations) > 0 {
+		return nil, &models.ValidationError{Violations: violations}
+	}
+
+	next, err := NextOccurrence(schedule, later(schedule.StartAt, now).Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		violation := models.Violation{Field: "rule", Message: "has no occurrence between start_at and end_at"}
+		if schedule.Rule == "" {
+			violation = models.Violation{Field: "start_at", Message: "must not be in the past"}
+		}
+		return nil, &models.ValidationError{Violations: []models.Violation{violation}}
+	}
+	schedule.OccurrenceAt, schedule.NextRunAt = next, next
+
+	from, err := s.wallet(ctx, schedule.FromWalletID, "from_wallet_id")
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.wallet(ctx, schedule.ToWalletID, "to_wallet_id")
+	if err != nil {
+		return nil, err
+	}
+	if from.Currency != to.Currency {
+		return nil, models.ErrCurrencyMismatch
+	}
+	return schedule, nil
+}
+
+// wallet looks up a wallet named in the request body, reporting an unknown
+// one as a violation of field
+func (s *scheduleService) wallet(ctx context.Context, id uuid.UUID, field string) (*models.Wallet, error) {
+	w, err := s.wallets.GetWalletByID(ctx, id)
+	if errors.Is(err, models.ErrWalletNotFound) {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: field, Message: "does not match a wallet"},
+		}}
+	}
+	return w, err
+}
+
+func (s *scheduleService) GetSchedule(ctx context.Context, id uuid.UUID) (_ *models.ScheduleResponse, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleService.GetSchedule")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetSchedule")
+	defer cancel()
+
+	schedule, err := s.schedules.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+func (s *scheduleService) ListSchedules(ctx context.Context, walletID uuid.UUID) (_ []models.ScheduleResponse, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleService.ListSchedules")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "ListSchedules")
+	defer cancel()
+
+	if _, err := s.wallets.GetWalletByID(ctx, walletID); err != nil {
+		return nil, err
+	}
+	schedules, err := s.schedules.ListSchedulesByWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]models.ScheduleResponse, len(schedules))
+	for i := range schedules {
+		response[i] = *toScheduleResponse(&schedules[i])
+	}
+	return response, nil
+}
+
+func (s *scheduleService) PauseSchedule(ctx context.Context, id uuid.UUID) (_ *models.ScheduleResponse, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleService.PauseSchedule")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "PauseSchedule")
+	defer cancel()
+
+	schedule, err := s.schedules.TransitionSchedule(ctx, id,
+		[]models.ScheduleStatus{models.ScheduleActive}, models.SchedulePaused, nil)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+func (s *scheduleService) ResumeSchedule(ctx context.Context, id uuid.UUID) (_ *models.ScheduleResponse, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleService.ResumeSchedule")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "ResumeSchedule")
+	defer cancel()
+
+	schedule, err := s.schedules.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	next, err := NextOccurrence(schedule, s.now().Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	to := models.ScheduleActive
+	if next == nil {
+		to = models.ScheduleCompleted
+	}
+
+	schedule, err = s.schedules.TransitionSchedule(ctx, id, []models.ScheduleStatus{models.SchedulePaused}, to, next)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+func (s *scheduleService) CancelSchedule(ctx context.Context, id uuid.UUID) (_ *models.ScheduleResponse, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleService.CancelSchedule")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CancelSchedule")
+	defer cancel()
+
+	schedule, err := s.schedules.TransitionSchedule(ctx, id,
+		[]models.ScheduleStatus{models.ScheduleActive, models.SchedulePaused}, models.ScheduleCancelled, nil)
+	if err != nil {
+		return nil, err
+	}
+	return toScheduleResponse(schedule), nil
+}
+
+func (s *scheduleService) GetExecutions(ctx context.Context, id uuid.UUID, page, limit int) (_ []models.ScheduleExecutionResponse, err error) {
+	ctx, span := tracer.Start(ctx, "scheduleService.GetExecutions")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetExecutions")
+	defer cancel()
+
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	if _, err := s.schedules.GetSchedule(ctx, id); err != nil {
+		return nil, err
+	}
+	executions, err := s.schedules.ListExecutions(ctx, id, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]models.ScheduleExecutionResponse, len(executions))
+	for i, e := range executions {
+		response[i] = models.ScheduleExecutionResponse{
+			ID:                  e.ID,
+			OccurrenceAt:        e.OccurrenceAt,
+			Attempt:             e.Attempt,
+			Status:              e.Status,
+			DebitTransactionID:  e.DebitTransactionID,
+			CreditTransactionID: e.CreditTransactionID,
+			ExecutedAt:          e.ExecutedAt,
+		}
+		if e.ErrorCode != "" {
+			response[i].Error = &models.ItemFailure{Code: e.ErrorCode, Message: e.ErrorMessage}
+		}
+	}
+	return response, nil
+}
+
+// NextOccurrence returns the first occurrence of schedule after after, or
+// nil when it has none left before its end
+func NextOccurrence(schedule *models.ScheduledPayment, after time.Time) (*time.Time, error) {
+	var next time.Time
+	if schedule.Rule == "" {
+		if schedule.StartAt.After(after) {
+			next = schedule.StartAt.UTC()
+		}
+	} else {
+		rule, err := recurrence.Parse(schedule.Rule)
+		if err != nil {
+			return nil, err
+		}
+		next = rule.Next(schedule.StartAt, after)
+	}
+	if next.IsZero() || (schedule.EndAt != nil && next.After(*schedule.EndAt)) {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func toScheduleResponse(s *models.ScheduledPayment) *models.ScheduleResponse {
+	return &models.ScheduleResponse{
+		ID:                  s.ID,
+		FromWalletID:        s.FromWalletID,
+		ToWalletID:          s.ToWalletID,
+		Amount:              s.Amount,
+		Description:         s.Description,
+		Rule:                s.Rule,
+		StartAt:             s.StartAt,
+		EndAt:               s.EndAt,
+		OnInsufficientFunds: s.OnInsufficientFunds,
+		Status:              s.Status,
+		NextRunAt:           s.NextRunAt,
+		CreatedAt:           s.CreatedAt,
+		UpdatedAt:           s.UpdatedAt,
+	}
+}
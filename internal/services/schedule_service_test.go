@@ -0,0 +1,131 @@
+//go:build unit
+// +build unit
+
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newScheduleTestServices returns a wallet and a schedule service sharing a
+// private in-memory SQLite database
+func newScheduleTestServices(t *testing.T) (WalletService, *scheduleService) {
+	t.Helper()
+	db := openTestDB(t)
+
+	walletRepo := repositories.NewWalletRepository(db.Gorm())
+	schedules := NewScheduleService(repositories.NewScheduleRepository(db.Gorm()), walletRepo, Timeouts{})
+	return NewWalletService(walletRepo), schedules.(*scheduleService)
+}
+
+func TestCreateScheduleValidates(t *testing.T) {
+	ctx := context.Background()
+	wallets, schedules := newScheduleTestServices(t)
+	a, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "a"})
+	require.NoError(t, err)
+	eur, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "b", Currency: "EUR"})
+	require.NoError(t, err)
+
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(-time.Hour)
+	_, err = schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID: a.ID.String(),
+		ToWalletID:   a.ID.String(),
+		Amount:       10,
+		Rule:         "FREQ=YEARLY",
+		StartAt:      &start,
+		EndAt:        &end,
+	})
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{
+		{Field: "to_wallet_id", Message: "must differ from from_wallet_id"},
+		{Field: "rule", Message: "is invalid: FREQ must be DAILY, WEEKLY or MONTHLY"},
+		{Field: "end_at", Message: "must be after start_at"},
+	}, validationErr.Violations)
+
+	_, err = schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID: a.ID.String(), ToWalletID: uuid.NewString(), Amount: 10,
+	})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "to_wallet_id", Message: "does not match a wallet"}}, validationErr.Violations)
+
+	_, err = schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID: a.ID.String(), ToWalletID: eur.ID.String(), Amount: 10,
+	})
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+
+	// A one-off payment in the past would never run
+	schedules.now = func() time.Time { return start.Add(time.Hour) }
+	b, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "c"})
+	require.NoError(t, err)
+	_, err = schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID: a.ID.String(), ToWalletID: b.ID.String(), Amount: 10, StartAt: &start,
+	})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "start_at", Message: "must not be in the past"}}, validationErr.Violations)
+}
+
+func TestScheduleLifecycle(t *testing.T) {
+	ctx := context.Background()
+	wallets, schedules := newScheduleTestServices(t)
+	a, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "a"})
+	require.NoError(t, err)
+	b, err := wallets.CreateWallet(ctx, models.CreateWalletRequest{UserID: "b"})
+	require.NoError(t, err)
+
+	// Started in the past, so the first occurrence is the next one from now
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	schedules.now = func() time.Time { return now }
+	start := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	s, err := schedules.CreateSchedule(ctx, models.CreateScheduleRequest{
+		FromWalletID: a.ID.String(),
+		ToWalletID:   b.ID.String(),
+		Amount:       100,
+		Rule:         "RRULE:FREQ=MONTHLY;BYMONTHDAY=1",
+		StartAt:      &start,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=1", s.Rule)
+	assert.Equal(t, models.ScheduleActive, s.Status)
+	assert.True(t, time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC).Equal(*s.NextRunAt))
+
+	paused, err := schedules.PauseSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SchedulePaused, paused.Status)
+	_, err = schedules.PauseSchedule(ctx, s.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+
+	// Resumed after the April occurrence, which is not made up
+	now = time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC)
+	resumed, err := schedules.ResumeSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleActive, resumed.Status)
+	assert.True(t, time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC).Equal(*resumed.NextRunAt))
+
+	listed, err := schedules.ListSchedules(ctx, b.ID)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, s.ID, listed[0].ID)
+	_, err = schedules.ListSchedules(ctx, uuid.New())
+	assert.ErrorIs(t, err, models.ErrWalletNotFound)
+
+	cancelled, err := schedules.CancelSchedule(ctx, s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ScheduleCancelled, cancelled.Status)
+	assert.Nil(t, cancelled.NextRunAt)
+	_, err = schedules.CancelSchedule(ctx, s.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidState)
+
+	_, err = schedules.GetExecutions(ctx, uuid.New(), 1, 10)
+	assert.ErrorIs(t, err, models.ErrScheduleNotFound)
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts bounds how long a single service operation may run, including
+// time spent waiting for row locks. A zero duration means no deadline
+// beyond the one already on the caller's context.
+type Timeouts struct {
+	Default time.Duration
+	// Operations overrides Default per method name, e.g. "DebitWallet"
+	Operations map[string]time.Duration
+}
+
+// For returns the timeout that applies to the named operation.
+func (t Timeouts) For(op string) time.Duration {
+	if d, ok := t.Operations[op]; ok {
+		return d
+	}
+	return t.Default
+}
+
+func (t Timeouts) withTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	if d := t.For(op); d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, func() {}
+}
@@ -1,39 +1,92 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"math"
+	"strings"
+	"time"
 	"wallet-microservice/internal/models"
 	"wallet-microservice/internal/repositories"
+	"wallet-microservice/internal/tracing"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("wallet-microservice/internal/services")
+
 type WalletService interface {
-	CreateWallet(req models.CreateWalletRequest) (*models.WalletResponse, error)
-	GetWallet(id uuid.UUID) (*models.WalletResponse, error)
-	GetWalletByUserID(userID string) (*models.WalletResponse, error)
-	UpdateWallet(id uuid.UUID, req models.CreateWalletRequest) (*models.WalletResponse, error)
-	DeleteWallet(id uuid.UUID) error
-	CreditWallet(id uuid.UUID, req models.TransactionRequest) (*models.TransactionResponse, error)
-	DebitWallet(id uuid.UUID, req models.TransactionRequest) (*models.TransactionResponse, error)
-	GetTransactionHistory(walletID uuid.UUID, page, limit int) ([]models.TransactionResponse, error)
+	CreateWallet(ctx context.Context, req models.CreateWalletRequest) (*models.WalletResponse, error)
+	GetWallet(ctx context.Context, id uuid.UUID) (*models.WalletResponse, error)
+	GetWalletByUserID(ctx context.Context, userID string) (*models.WalletResponse, error)
+	UpdateWallet(ctx context.Context, id uuid.UUID, req models.CreateWalletRequest) (*models.WalletResponse, error)
+	DeleteWallet(ctx context.Context, id uuid.UUID) error
+	CreditWallet(ctx context.Context, id uuid.UUID, req models.TransactionRequest) (*models.TransactionResponse, error)
+	DebitWallet(ctx context.Context, id uuid.UUID, req models.TransactionRequest) (*models.TransactionResponse, error)
+	GetTransactionHistory(ctx context.Context, walletID uuid.UUID, page, limit int) ([]models.TransactionResponse, error)
+	// Transfer debits one wallet and credits another atomically
+	Transfer(ctx context.Context, req models.TransferRequest) (*models.TransferResponse, error)
 }
 
 type walletService struct {
-	walletRepo repositories.WalletRepository
+	walletRepo  repositories.WalletRepository
+	timeouts    Timeouts
+	promoExpiry time.Duration
+	now         func() time.Time
+}
+
+// Option configures optional behaviour of the wallet service
+type Option func(*walletService)
+
+// WithTimeouts applies per-operation deadlines to every call
+func WithTimeouts(t Timeouts) Option {
+	return func(s *walletService) {
+		s.timeouts = t
+	}
 }
 
-func NewWalletService(walletRepo repositories.WalletRepository) WalletService {
-	return &walletService{
+// WithPromoExpiry makes promotional credits expire d after they are made
+// unless the request says when; zero means they never do
+func WithPromoExpiry(d time.Duration) Option {
+	return func(s *walletService) {
+		s.promoExpiry = d
+	}
+}
+
+func NewWalletService(walletRepo repositories.WalletRepository, opts ...Option) WalletService {
+	s := &walletService{
 		walletRepo: walletRepo,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *walletService) CreateWallet(req models.CreateWalletRequest) (*models.WalletResponse, error) {
-	// Check if wallet already exists for user
-	existingWallet, _ := s.walletRepo.GetWalletByUserID(req.UserID)
+func (s *walletService) CreateWallet(ctx context.Context, req models.CreateWalletRequest) (_ *models.WalletResponse, err error) {
+	ctx, span := tracer.Start(ctx, "walletService.CreateWallet")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CreateWallet")
+	defer cancel()
+
+	// Escrow wallets are created with their escrows
+	if strings.HasPrefix(req.UserID, models.EscrowAccountPrefix) {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "user_id", Message: "must not start with " + models.EscrowAccountPrefix},
+		}}
+	}
+
+	// Check if wallet already exists for user. A concurrent create that
+	// slips past this check fails on the unique index with ErrWalletExists.
+	existingWallet, err := s.walletRepo.GetWalletByUserID(ctx, req.UserID)
+	if err != nil && !errors.Is(err, models.ErrWalletNotFound) {
+		return nil, err
+	}
 	if existingWallet != nil {
-		return nil, errors.New("wallet already exists for this user")
+		return nil, models.ErrWalletExists
 	}
 
 	currency := req.Currency
@@ -41,55 +94,67 @@ func (s *walletService) CreateWallet(req models.CreateWalletRequest) (*models.Wa
 		currency = "USD"
 	}
 
+	debitOrder := req.DebitOrder
+	if debitOrder == "" {
+		debitOrder = models.PromoFirst
+	}
+
 	wallet := &models.Wallet{
-		UserID:   req.UserID,
-		Balance:  0.0,
-		Currency: currency,
+		UserID:     req.UserID,
+		Balance:    0.0,
+		DebitOrder: debitOrder,
+		Currency:   currency,
+	}
+	if req.OverdraftLimit != nil {
+		wallet.OverdraftLimit = *req.OverdraftLimit
 	}
 
-	err := s.walletRepo.CreateWallet(wallet)
+	err = s.walletRepo.CreateWallet(ctx, wallet)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.WalletResponse{
-		ID:       wallet.ID,
-		UserID:   wallet.UserID,
-		Balance:  wallet.Balance,
-		Currency: wallet.Currency,
-	}, nil
+	return toWalletResponse(wallet), nil
 }
 
-func (s *walletService) GetWallet(id uuid.UUID) (*models.WalletResponse, error) {
-	wallet, err := s.walletRepo.GetWalletByID(id)
+func (s *walletService) GetWallet(ctx context.Context, id uuid.UUID) (_ *models.WalletResponse, err error) {
+	ctx, span := tracer.Start(ctx, "walletService.GetWallet")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetWallet")
+	defer cancel()
+
+	wallet, err := s.walletRepo.GetWalletByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.WalletResponse{
-		ID:       wallet.ID,
-		UserID:   wallet.UserID,
-		Balance:  wallet.Balance,
-		Currency: wallet.Currency,
-	}, nil
+	return toWalletResponse(wallet), nil
 }
 
-func (s *walletService) GetWalletByUserID(userID string) (*models.WalletResponse, error) {
-	wallet, err := s.walletRepo.GetWalletByUserID(userID)
+func (s *walletService) GetWalletByUserID(ctx context.Context, userID string) (_ *models.WalletResponse, err error) {
+	ctx, span := tracer.Start(ctx, "walletService.GetWalletByUserID")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetWalletByUserID")
+	defer cancel()
+
+	wallet, err := s.walletRepo.GetWalletByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.WalletResponse{
-		ID:       wallet.ID,
-		UserID:   wallet.UserID,
-		Balance:  wallet.Balance,
-		Currency: wallet.Currency,
-	}, nil
+	return toWalletResponse(wallet), nil
 }
 
-func (s *walletService) UpdateWallet(id uuid.UUID, req models.CreateWalletRequest) (*models.WalletResponse, error) {
-	wallet, err := s.walletRepo.GetWalletByID(id)
+func (s *walletService) UpdateWallet(ctx context.Context, id uuid.UUID, req models.CreateWalletRequest) (_ *models.WalletResponse, err error) {
+	ctx, span := tracer.Start(ctx, "walletService.UpdateWallet")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "UpdateWallet")
+	defer cancel()
+
+	wallet, err := s.walletRepo.GetWalletByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -98,41 +163,68 @@ func (s *walletService) UpdateWallet(id uuid.UUID, req models.CreateWalletReques
 	if wallet.Currency == "" {
 		wallet.Currency = "USD"
 	}
+	// Leaving the debit order out keeps the current one
+	if req.DebitOrder != "" {
+		wallet.DebitOrder = req.DebitOrder
+	}
+	// A limit below what is in use stops further debits until the wallet is
+	// paid back within it
+	if req.OverdraftLimit != nil {
+		wallet.OverdraftLimit = *req.OverdraftLimit
+	}
 
-	err = s.walletRepo.UpdateWallet(wallet)
+	err = s.walletRepo.UpdateWallet(ctx, wallet)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.WalletResponse{
-		ID:       wallet.ID,
-		UserID:   wallet.UserID,
-		Balance:  wallet.Balance,
-		Currency: wallet.Currency,
-	}, nil
+	return toWalletResponse(wallet), nil
 }
 
-func (s *walletService) DeleteWallet(id uuid.UUID) error {
-	return s.walletRepo.DeleteWallet(id)
+func (s *walletService) DeleteWallet(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "walletService.DeleteWallet")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "DeleteWallet")
+	defer cancel()
+
+	return s.walletRepo.DeleteWallet(ctx, id)
 }
 
-func (s *walletService) CreditWallet(id uuid.UUID, req models.TransactionRequest) (*models.TransactionResponse, error) {
-	return s.processTransaction(id, req, models.Credit)
+func (s *walletService) CreditWallet(ctx context.Context, id uuid.UUID, req models.TransactionRequest) (*models.TransactionResponse, error) {
+	ctx, cancel := s.timeouts.withTimeout(ctx, "CreditWallet")
+	defer cancel()
+
+	return s.processTransaction(ctx, id, req, models.Credit)
 }
 
-func (s *walletService) DebitWallet(id uuid.UUID, req models.TransactionRequest) (*models.TransactionResponse, error) {
-	return s.processTransaction(id, req, models.Debit)
+func (s *walletService) DebitWallet(ctx context.Context, id uuid.UUID, req models.TransactionRequest) (*models.TransactionResponse, error) {
+	ctx, cancel := s.timeouts.withTimeout(ctx, "DebitWallet")
+	defer cancel()
+
+	return s.processTransaction(ctx, id, req, models.Debit)
 }
 
 func (s *walletService) processTransaction(
+	ctx context.Context,
 	walletID uuid.UUID,
 	req models.TransactionRequest,
 	t models.TransactionType,
-) (*models.TransactionResponse, error) {
+) (_ *models.TransactionResponse, err error) {
+	ctx, span := tracer.Start(ctx, "walletService.processTransaction")
+	defer tracing.End(span, &err)
+
+	if req.Amount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+	promo, err := s.promoTerms(req, t)
+	if err != nil {
+		return nil, err
+	}
 
 	// Optional: pre-check that wallet exists to return 404 early;
 	// not strictly required, as repo will return not found too.
-	if _, err := s.walletRepo.GetWalletByID(walletID); err != nil {
+	if _, err := s.walletRepo.GetWalletByID(ctx, walletID); err != nil {
 		return nil, err
 	}
 
@@ -140,27 +232,60 @@ func (s *walletService) processTransaction(
 	txModel := &models.Transaction{
 		Description: req.Description,
 		Reference:   req.Reference,
+		Promo:       promo,
 	}
 
 	// Use ProcessTransactionWithRollback for atomic operations
 	// This ensures both balance update and transaction creation happen in one transaction
 	// If either fails, everything is rolled back automatically
-	if err := s.walletRepo.ProcessTransactionWithRollback(walletID, req.Amount, t, txModel); err != nil {
+	if err := s.walletRepo.ProcessTransactionWithRollback(ctx, walletID, req.Amount, t, txModel); err != nil {
 		return nil, err
 	}
 
-	return &models.TransactionResponse{
-		ID:          txModel.ID,
-		WalletID:    txModel.WalletID,
-		Type:        txModel.Type,
-		Amount:      txModel.Amount,
-		Description: txModel.Description,
-		Reference:   txModel.Reference,
-		CreatedAt:   txModel.CreatedAt,
-	}, nil
+	resp := toTransactionResponse(txModel)
+	return &resp, nil
+}
+
+// promoTerms returns the terms of a promotional credit, or nil if req
+// credits or debits cash
+func (s *walletService) promoTerms(req models.TransactionRequest, t models.TransactionType) (*models.PromoTerms, error) {
+	var violations []models.Violation
+	if t == models.Debit && req.Bucket != "" {
+		violations = append(violations, models.Violation{Field: "bucket", Message: "only applies to credits"})
+	}
+	if req.ExpiresAt != nil {
+		if req.Bucket != models.PromoBucket {
+			violations = append(violations, models.Violation{Field: "expires_at", Message: "only applies to promo credits"})
+		} else if !req.ExpiresAt.After(s.now()) {
+			violations = append(violations, models.Violation{Field: "expires_at", Message: "must be in the future"})
+		}
+	}
+	if len(violations) > 0 {
+		return nil, &models.ValidationError{Violations: violations}
+	}
+	if t != models.Credit || req.Bucket != models.PromoBucket {
+		return nil, nil
+	}
+
+	expiresAt := req.ExpiresAt
+	if expiresAt == nil && s.promoExpiry > 0 {
+		at := s.now().Add(s.promoExpiry)
+		expiresAt = &at
+	}
+	if expiresAt != nil {
+		at := expiresAt.UTC()
+		expiresAt = &at
+	}
+	return &models.PromoTerms{ExpiresAt: expiresAt}, nil
 }
 
-func (s *walletService) GetTransactionHistory(walletID uuid.UUID, page, limit int) ([]models.TransactionResponse, error) {
+func (s *walletService) GetTransactionHistory(ctx context.Context, walletID uuid.UUID, page, limit int) (_ []models.TransactionResponse, err error) {
+	ctx, span := tracer.Start(ctx, "walletService.GetTransactionHistory")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "GetTransactionHistory")
+	defer cancel()
+
 	if page <= 0 {
 		page = 1
 	}
@@ -170,23 +295,88 @@ func (s *walletService) GetTransactionHistory(walletID uuid.UUID, page, limit in
 
 	offset := (page - 1) * limit
 
-	transactions, err := s.walletRepo.GetTransactionsByWalletID(walletID, limit, offset)
+	transactions, err := s.walletRepo.GetTransactionsByWalletID(ctx, walletID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 
 	var response []models.TransactionResponse
 	for _, tx := range transactions {
-		response = append(response, models.TransactionResponse{
-			ID:          tx.ID,
-			WalletID:    tx.WalletID,
-			Type:        tx.Type,
-			Amount:      tx.Amount,
-			Description: tx.Description,
-			Reference:   tx.Reference,
-			CreatedAt:   tx.CreatedAt,
-		})
+		response = append(response, toTransactionResponse(&tx))
 	}
 
 	return response, nil
 }
+
+func (s *walletService) Transfer(ctx context.Context, req models.TransferRequest) (_ *models.TransferResponse, err error) {
+	ctx, span := tracer.Start(ctx, "walletService.Transfer")
+	defer tracing.End(span, &err)
+
+	ctx, cancel := s.timeouts.withTimeout(ctx, "Transfer")
+	defer cancel()
+
+	if req.Amount <= 0 {
+		return nil, models.ErrInvalidAmount
+	}
+	if req.FromWalletID == req.ToWalletID {
+		return nil, &models.ValidationError{Violations: []models.Violation{
+			{Field: "to_wallet_id", Message: "must differ from from_wallet_id"},
+		}}
+	}
+
+	from, err := s.walletRepo.GetWalletByID(ctx, req.FromWalletID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.walletRepo.GetWalletByID(ctx, req.ToWalletID)
+	if err != nil {
+		return nil, err
+	}
+	if from.Currency != to.Currency {
+		return nil, models.ErrCurrencyMismatch
+	}
+
+	// The payee receives cash, so the payer pays in cash too
+	debit := &models.Transaction{WalletID: from.ID, Type: models.Debit, Amount: req.Amount, Description: req.Description, Reference: req.Reference, CashOnly: true}
+	credit := &models.Transaction{WalletID: to.ID, Type: models.Credit, Amount: req.Amount, Description: req.Description, Reference: req.Reference}
+	if err := s.walletRepo.ApplyTransactions(ctx, []*models.Transaction{debit, credit}); err != nil {
+		return nil, err
+	}
+
+	return &models.TransferResponse{
+		Debit:  toTransactionResponse(debit),
+		Credit: toTransactionResponse(credit),
+	}, nil
+}
+
+func toWalletResponse(wallet *models.Wallet) *models.WalletResponse {
+	cash := math.Round((wallet.Balance-wallet.PromoBalance)*100) / 100
+	return &models.WalletResponse{
+		ID:      wallet.ID,
+		UserID:  wallet.UserID,
+		Balance: wallet.Balance,
+		Buckets: models.BucketBalance{
+			Cash:  cash,
+			Promo: wallet.PromoBalance,
+		},
+		DebitOrder: wallet.DebitOrder,
+		Overdraft: models.Overdraft{
+			Limit: wallet.OverdraftLimit,
+			Used:  math.Max(0, -cash),
+		},
+		Currency: wallet.Currency,
+	}
+}
+
+func toTransactionResponse(tx *models.Transaction) models.TransactionResponse {
+	return models.TransactionResponse{
+		ID:          tx.ID,
+		WalletID:    tx.WalletID,
+		Type:        tx.Type,
+		Amount:      tx.Amount,
+		Description: tx.Description,
+		Reference:   tx.Reference,
+		PromoAmount: tx.PromoAmount,
+		CreatedAt:   tx.CreatedAt,
+	}
+}
@@ -4,7 +4,10 @@
 package services
 
 import (
+	"context"
 	"testing"
+	"time"
+	"wallet-microservice/internal/config"
 	"wallet-microservice/internal/database"
 	"wallet-microservice/internal/models"
 	"wallet-microservice/internal/repositories"
@@ -15,19 +18,23 @@ import (
 
 type WalletServiceIntegrationTestSuite struct {
 	suite.Suite
+	db            *database.DB
 	walletService WalletService
 	walletRepo    repositories.WalletRepository
 }
 
 func (suite *WalletServiceIntegrationTestSuite) SetupSuite() {
 	// Connect to test database
-	database.Connect()
-	database.Migrate()
+	cfg, err := config.Load(nil)
+	suite.Require().NoError(err)
+	suite.db, err = database.Open(cfg.Database)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Migrate(context.Background()))
 }
 
 func (suite *WalletServiceIntegrationTestSuite) SetupTest() {
 	// Create fresh repository and service for each test
-	suite.walletRepo = repositories.NewWalletRepository()
+	suite.walletRepo = repositories.NewWalletRepository(suite.db.Gorm())
 	suite.walletService = NewWalletService(suite.walletRepo)
 
 	// Clean up any existing data
@@ -39,7 +46,7 @@ func (suite *WalletServiceIntegrationTestSuite) TearDownTest() {
 }
 
 func (suite *WalletServiceIntegrationTestSuite) TearDownSuite() {
-	// Close database connection if needed
+	suite.NoError(suite.db.Close())
 }
 
 func (suite *WalletServiceIntegrationTestSuite) cleanupTestData() {
@@ -56,7 +63,7 @@ func (suite *WalletServiceIntegrationTestSuite) TestCreateWalletIntegration() {
 		Currency: "EUR",
 	}
 
-	wallet, err := suite.walletService.CreateWallet(req)
+	wallet, err := suite.walletService.CreateWallet(context.Background(), req)
 
 	suite.NoError(err)
 	suite.NotNil(wallet)
@@ -75,7 +82,7 @@ func (suite *WalletServiceIntegrationTestSuite) TestCreateWalletDefaultCurrencyI
 		Currency: "", // Empty currency should default to USD
 	}
 
-	wallet, err := suite.walletService.CreateWallet(req)
+	wallet, err := suite.walletService.CreateWallet(context.Background(), req)
 
 	suite.NoError(err)
 	suite.NotNil(wallet)
@@ -92,12 +99,12 @@ func (suite *WalletServiceIntegrationTestSuite) TestCreateWalletDuplicateUserInt
 	}
 
 	// Create first wallet
-	wallet1, err := suite.walletService.CreateWallet(req)
+	wallet1, err := suite.walletService.CreateWallet(context.Background(), req)
 	suite.NoError(err)
 	suite.NotNil(wallet1)
 
 	// Try to create second wallet for same user
-	wallet2, err := suite.walletService.CreateWallet(req)
+	wallet2, err := suite.walletService.CreateWallet(context.Background(), req)
 	suite.Error(err)
 	suite.Nil(wallet2)
 	suite.Contains(err.Error(), "wallet already exists")
@@ -108,7 +115,7 @@ func (suite *WalletServiceIntegrationTestSuite) TestCreditWalletIntegration() {
 	userID := "test-user-" + uuid.New().String()
 
 	// Create wallet
-	wallet, err := suite.walletService.CreateWallet(models.CreateWalletRequest{
+	wallet, err := suite.walletService.CreateWallet(context.Background(), models.CreateWalletRequest{
 		UserID:   userID,
 		Currency: "USD",
 	})
@@ -121,14 +128,14 @@ func (suite *WalletServiceIntegrationTestSuite) TestCreditWalletIntegration() {
 		Reference:   "ref-123",
 	}
 
-	transaction, err := suite.walletService.CreditWallet(wallet.ID, creditReq)
+	transaction, err := suite.walletService.CreditWallet(context.Background(), wallet.ID, creditReq)
 	suite.NoError(err)
 	suite.NotNil(transaction)
 	suite.Equal(models.Credit, transaction.Type)
 	suite.Equal(100.50, transaction.Amount)
 
 	// Verify wallet balance was updated
-	updatedWallet, err := suite.walletService.GetWallet(wallet.ID)
+	updatedWallet, err := suite.walletService.GetWallet(context.Background(), wallet.ID)
 	suite.NoError(err)
 	suite.Equal(100.50, updatedWallet.Balance)
 }
@@ -138,7 +145,7 @@ func (suite *WalletServiceIntegrationTestSuite) TestDebitWalletIntegration() {
 	userID := "test-user-" + uuid.New().String()
 
 	// Create wallet with initial balance
-	wallet, err := suite.walletService.CreateWallet(models.CreateWalletRequest{
+	wallet, err := suite.walletService.CreateWallet(context.Background(), models.CreateWalletRequest{
 		UserID:   userID,
 		Currency: "USD",
 	})
@@ -150,7 +157,7 @@ func (suite *WalletServiceIntegrationTestSuite) TestDebitWalletIntegration() {
 		Description: "Initial credit",
 		Reference:   "ref-init",
 	}
-	_, err = suite.walletService.CreditWallet(wallet.ID, creditReq)
+	_, err = suite.walletService.CreditWallet(context.Background(), wallet.ID, creditReq)
 	suite.NoError(err)
 
 	// Debit wallet
@@ -160,14 +167,14 @@ func (suite *WalletServiceIntegrationTestSuite) TestDebitWalletIntegration() {
 		Reference:   "ref-debit",
 	}
 
-	transaction, err := suite.walletService.DebitWallet(wallet.ID, debitReq)
+	transaction, err := suite.walletService.DebitWallet(context.Background(), wallet.ID, debitReq)
 	suite.NoError(err)
 	suite.NotNil(transaction)
 	suite.Equal(models.Debit, transaction.Type)
 	suite.Equal(50.25, transaction.Amount)
 
 	// Verify wallet balance was updated
-	updatedWallet, err := suite.walletService.GetWallet(wallet.ID)
+	updatedWallet, err := suite.walletService.GetWallet(context.Background(), wallet.ID)
 	suite.NoError(err)
 	suite.Equal(149.75, updatedWallet.Balance)
 }
@@ -177,7 +184,7 @@ func (suite *WalletServiceIntegrationTestSuite) TestGetTransactionHistoryIntegra
 	userID := "test-user-" + uuid.New().String()
 
 	// Create wallet
-	wallet, err := suite.walletService.CreateWallet(models.CreateWalletRequest{
+	wallet, err := suite.walletService.CreateWallet(context.Background(), models.CreateWalletRequest{
 		UserID:   userID,
 		Currency: "USD",
 	})
@@ -192,15 +199,15 @@ func (suite *WalletServiceIntegrationTestSuite) TestGetTransactionHistoryIntegra
 
 	for _, tx := range transactions {
 		if tx.Description == "Debit 1" {
-			_, err = suite.walletService.DebitWallet(wallet.ID, tx)
+			_, err = suite.walletService.DebitWallet(context.Background(), wallet.ID, tx)
 		} else {
-			_, err = suite.walletService.CreditWallet(wallet.ID, tx)
+			_, err = suite.walletService.CreditWallet(context.Background(), wallet.ID, tx)
 		}
 		suite.NoError(err)
 	}
 
 	// Get transaction history
-	history, err := suite.walletService.GetTransactionHistory(wallet.ID, 1, 10)
+	history, err := suite.walletService.GetTransactionHistory(context.Background(), wallet.ID, 1, 10)
 	suite.NoError(err)
 	suite.Len(history, 3)
 
@@ -210,6 +217,60 @@ func (suite *WalletServiceIntegrationTestSuite) TestGetTransactionHistoryIntegra
 	suite.Equal("Credit 1", history[2].Description)
 }
 
+func (suite *WalletServiceIntegrationTestSuite) TestCancelledDebitRollsBack() {
+	// A debit that gives up while waiting for the row lock must leave no trace
+	wallet, err := suite.walletService.CreateWallet(context.Background(), models.CreateWalletRequest{
+		UserID: "test-user-" + uuid.New().String(),
+	})
+	suite.NoError(err)
+	_, err = suite.walletService.CreditWallet(context.Background(), wallet.ID, models.TransactionRequest{Amount: 100})
+	suite.NoError(err)
+
+	// Hold the wallet's row lock from another transaction. A no-op UPDATE
+	// locks the row on Postgres and takes the write lock on SQLite.
+	holder := suite.db.Gorm().Begin()
+	suite.NoError(holder.Exec("UPDATE wallets SET balance = balance WHERE id = ?", wallet.ID).Error)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = suite.walletService.DebitWallet(ctx, wallet.ID, models.TransactionRequest{Amount: 40})
+	suite.ErrorIs(err, context.DeadlineExceeded)
+
+	suite.NoError(holder.Rollback().Error)
+
+	updated, err := suite.walletService.GetWallet(context.Background(), wallet.ID)
+	suite.NoError(err)
+	suite.Equal(100.0, updated.Balance)
+
+	history, err := suite.walletService.GetTransactionHistory(context.Background(), wallet.ID, 1, 10)
+	suite.NoError(err)
+	suite.Len(history, 1)
+}
+
+func (suite *WalletServiceIntegrationTestSuite) TestCancellationReleasesLock() {
+	// Cancelling the context of a transaction that holds a row lock must
+	// roll it back and let the next request through
+	wallet, err := suite.walletService.CreateWallet(context.Background(), models.CreateWalletRequest{
+		UserID: "test-user-" + uuid.New().String(),
+	})
+	suite.NoError(err)
+
+	lockCtx, cancelLock := context.WithCancel(context.Background())
+	holder := suite.db.Gorm().WithContext(lockCtx).Begin()
+	suite.NoError(holder.Exec("UPDATE wallets SET balance = 999 WHERE id = ?", wallet.ID).Error)
+	cancelLock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	tx, err := suite.walletService.CreditWallet(ctx, wallet.ID, models.TransactionRequest{Amount: 10})
+	suite.NoError(err)
+	suite.NotNil(tx)
+
+	updated, err := suite.walletService.GetWallet(context.Background(), wallet.ID)
+	suite.NoError(err)
+	suite.Equal(10.0, updated.Balance)
+}
+
 // Run the integration test suite
 func TestWalletServiceIntegrationSuite(t *testing.T) {
 	suite.Run(t, new(WalletServiceIntegrationTestSuite))
@@ -5,15 +5,18 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
 	"wallet-microservice/internal/models"
+	"wallet-microservice/internal/repositories"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock for repositories.WalletRepository using testify/mock.
@@ -22,7 +25,7 @@ type MockWalletRepository struct {
 	mock.Mock
 }
 
-func (m *MockWalletRepository) CreateWallet(w *models.Wallet) error {
+func (m *MockWalletRepository) CreateWallet(ctx context.Context, w *models.Wallet) error {
 	args := m.Called(w)
 	// If the mock injects an ID, do it here to mimic DB behavior.
 	if args.Error(0) == nil && w.ID == uuid.Nil {
@@ -31,7 +34,7 @@ func (m *MockWalletRepository) CreateWallet(w *models.Wallet) error {
 	return args.Error(0)
 }
 
-func (m *MockWalletRepository) GetWalletByID(id uuid.UUID) (*models.Wallet, error) {
+func (m *MockWalletRepository) GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
 	args := m.Called(id)
 	if v := args.Get(0); v != nil {
 		return v.(*models.Wallet), args.Error(1)
@@ -39,7 +42,7 @@ func (m *MockWalletRepository) GetWalletByID(id uuid.UUID) (*models.Wallet, erro
 	return nil, args.Error(1)
 }
 
-func (m *MockWalletRepository) GetWalletByUserID(userID string) (*models.Wallet, error) {
+func (m *MockWalletRepository) GetWalletByUserID(ctx context.Context, userID string) (*models.Wallet, error) {
 	args := m.Called(userID)
 	if v := args.Get(0); v != nil {
 		return v.(*models.Wallet), args.Error(1)
@@ -47,22 +50,22 @@ func (m *MockWalletRepository) GetWalletByUserID(userID string) (*models.Wallet,
 	return nil, args.Error(1)
 }
 
-func (m *MockWalletRepository) UpdateWallet(w *models.Wallet) error {
+func (m *MockWalletRepository) UpdateWallet(ctx context.Context, w *models.Wallet) error {
 	args := m.Called(w)
 	return args.Error(0)
 }
 
-func (m *MockWalletRepository) DeleteWallet(id uuid.UUID) error {
+func (m *MockWalletRepository) DeleteWallet(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockWalletRepository) UpdateWalletBalance(id uuid.UUID, amount float64, t models.TransactionType) error {
+func (m *MockWalletRepository) UpdateWalletBalance(ctx context.Context, id uuid.UUID, amount float64, t models.TransactionType) error {
 	args := m.Called(id, amount, t)
 	return args.Error(0)
 }
 
-func (m *MockWalletRepository) CreateTransaction(tx *models.Transaction) error {
+func (m *MockWalletRepository) CreateTransaction(ctx context.Context, tx *models.Transaction) error {
 	args := m.Called(tx)
 	if args.Error(0) == nil {
 		if tx.ID == uuid.Nil {
@@ -75,7 +78,7 @@ func (m *MockWalletRepository) CreateTransaction(tx *models.Transaction) error {
 	return args.Error(0)
 }
 
-func (m *MockWalletRepository) GetTransactionsByWalletID(walletID uuid.UUID, limit, offset int) ([]models.Transaction, error) {
+func (m *MockWalletRepository) GetTransactionsByWalletID(ctx context.Context, walletID uuid.UUID, limit, offset int) ([]models.Transaction, error) {
 	args := m.Called(walletID, limit, offset)
 	if v := args.Get(0); v != nil {
 		return v.([]models.Transaction), args.Error(1)
@@ -83,11 +86,37 @@ func (m *MockWalletRepository) GetTransactionsByWalletID(walletID uuid.UUID, lim
 	return nil, args.Error(1)
 }
 
-func (m *MockWalletRepository) ProcessTransactionWithRollback(walletID uuid.UUID, amount float64, t models.TransactionType, txModel *models.Transaction) error {
+func (m *MockWalletRepository) CountTransactionsByWalletID(ctx context.Context, walletID uuid.UUID) (int64, error) {
+	args := m.Called(walletID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockWalletRepository) ProcessTransactionWithRollback(ctx context.Context, walletID uuid.UUID, amount float64, t models.TransactionType, txModel *models.Transaction) error {
 	args := m.Called(walletID, amount, t, txModel)
 	return args.Error(0)
 }
 
+func (m *MockWalletRepository) ApplyTransactions(ctx context.Context, txs []*models.Transaction) error {
+	args := m.Called(txs)
+	return args.Error(0)
+}
+
+func (m *MockWalletRepository) LapsedPromoCredits(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(now, limit)
+	if v := args.Get(0); v != nil {
+		return v.([]uuid.UUID), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWalletRepository) ExpirePromoCredit(ctx context.Context, creditID uuid.UUID, now time.Time) (*models.Transaction, error) {
+	args := m.Called(creditID, now)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Transaction), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func TestCreateWallet(t *testing.T) {
 	t.Run("creates with default USD when empty currency", func(t *testing.T) {
 		repo := new(MockWalletRepository)
@@ -105,7 +134,7 @@ func TestCreateWallet(t *testing.T) {
 			assert.Equal(t, "USD", w.Currency)
 		}).Return(nil).Once()
 
-		resp, err := svc.CreateWallet(models.CreateWalletRequest{
+		resp, err := svc.CreateWallet(context.Background(), models.CreateWalletRequest{
 			UserID:   userID,
 			Currency: "",
 		})
@@ -123,9 +152,19 @@ func TestCreateWallet(t *testing.T) {
 		userID := "user-1"
 		repo.On("GetWalletByUserID", userID).Return(&models.Wallet{UserID: userID}, nil).Once()
 
-		resp, err := svc.CreateWallet(models.CreateWalletRequest{UserID: userID})
+		resp, err := svc.CreateWallet(context.Background(), models.CreateWalletRequest{UserID: userID})
 		assert.Nil(t, resp)
-		assert.EqualError(t, err, "wallet already exists for this user")
+		assert.ErrorIs(t, err, models.ErrWalletExists)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("propagates lookup errors other than not found", func(t *testing.T) {
+		repo := new(MockWalletRepository)
+		svc := NewWalletService(repo)
+		repo.On("GetWalletByUserID", "user-3").Return((*models.Wallet)(nil), errors.New("db down")).Once()
+
+		_, err := svc.CreateWallet(context.Background(), models.CreateWalletRequest{UserID: "user-3"})
+		assert.EqualError(t, err, "db down")
 		repo.AssertExpectations(t)
 	})
 
@@ -136,7 +175,7 @@ func TestCreateWallet(t *testing.T) {
 		repo.On("GetWalletByUserID", userID).Return((*models.Wallet)(nil), nil).Once()
 		repo.On("CreateWallet", mock.AnythingOfType("*models.Wallet")).Return(errors.New("db error")).Once()
 
-		resp, err := svc.CreateWallet(models.CreateWalletRequest{UserID: userID, Currency: "EUR"})
+		resp, err := svc.CreateWallet(context.Background(), models.CreateWalletRequest{UserID: userID, Currency: "EUR"})
 		assert.Nil(t, resp)
 		assert.EqualError(t, err, "db error")
 		repo.AssertExpectations(t)
@@ -151,7 +190,7 @@ func TestGetWallet(t *testing.T) {
 	w := &models.Wallet{ID: id, UserID: "u", Balance: 10, Currency: "USD"}
 	repo.On("GetWalletByID", id).Return(w, nil).Once()
 
-	resp, err := svc.GetWallet(id)
+	resp, err := svc.GetWallet(context.Background(), id)
 	assert.NoError(t, err)
 	assert.Equal(t, id, resp.ID)
 	assert.Equal(t, "u", resp.UserID)
@@ -167,7 +206,7 @@ func TestGetWallet_Error(t *testing.T) {
 	id := uuid.New()
 	repo.On("GetWalletByID", id).Return((*models.Wallet)(nil), errors.New("not found")).Once()
 
-	resp, err := svc.GetWallet(id)
+	resp, err := svc.GetWallet(context.Background(), id)
 	assert.Nil(t, resp)
 	assert.EqualError(t, err, "not found")
 	repo.AssertExpectations(t)
@@ -181,7 +220,7 @@ func TestGetWalletByUserID(t *testing.T) {
 	w := &models.Wallet{ID: uuid.New(), UserID: userID, Balance: 5, Currency: "INR"}
 	repo.On("GetWalletByUserID", userID).Return(w, nil).Once()
 
-	resp, err := svc.GetWalletByUserID(userID)
+	resp, err := svc.GetWalletByUserID(context.Background(), userID)
 	assert.NoError(t, err)
 	assert.Equal(t, w.ID, resp.ID)
 	assert.Equal(t, userID, resp.UserID)
@@ -204,7 +243,7 @@ func TestUpdateWallet(t *testing.T) {
 			assert.Equal(t, "USD", updated.Currency)
 		}).Return(nil).Once()
 
-		resp, err := svc.UpdateWallet(id, models.CreateWalletRequest{Currency: ""})
+		resp, err := svc.UpdateWallet(context.Background(), id, models.CreateWalletRequest{Currency: ""})
 		assert.NoError(t, err)
 		assert.Equal(t, "USD", resp.Currency)
 
@@ -220,7 +259,7 @@ func TestUpdateWallet(t *testing.T) {
 		repo.On("GetWalletByID", id).Return(w, nil).Once()
 		repo.On("UpdateWallet", mock.AnythingOfType("*models.Wallet")).Return(nil).Once()
 
-		resp, err := svc.UpdateWallet(id, models.CreateWalletRequest{Currency: "GBP"})
+		resp, err := svc.UpdateWallet(context.Background(), id, models.CreateWalletRequest{Currency: "GBP"})
 		assert.NoError(t, err)
 		assert.Equal(t, "GBP", resp.Currency)
 
@@ -235,7 +274,7 @@ func TestDeleteWallet(t *testing.T) {
 	id := uuid.New()
 	repo.On("DeleteWallet", id).Return(nil).Once()
 
-	err := svc.DeleteWallet(id)
+	err := svc.DeleteWallet(context.Background(), id)
 	assert.NoError(t, err)
 	repo.AssertExpectations(t)
 }
@@ -261,7 +300,7 @@ func TestCreditDebitWallet(t *testing.T) {
 			tx.CreatedAt = time.Now()
 		}).Return(nil).Once()
 
-		resp, err := svc.CreditWallet(id, req)
+		resp, err := svc.CreditWallet(context.Background(), id, req)
 		assert.NoError(t, err)
 		assert.Equal(t, id, resp.WalletID)
 		assert.Equal(t, models.Credit, resp.Type)
@@ -291,7 +330,7 @@ func TestCreditDebitWallet(t *testing.T) {
 			tx.CreatedAt = time.Now()
 		}).Return(nil).Once()
 
-		resp, err := svc.DebitWallet(id, req)
+		resp, err := svc.DebitWallet(context.Background(), id, req)
 		assert.NoError(t, err)
 		assert.Equal(t, models.Debit, resp.Type)
 		assert.Equal(t, 40.0, resp.Amount)
@@ -306,7 +345,7 @@ func TestCreditDebitWallet(t *testing.T) {
 			svc := NewWalletService(repo)
 			id := uuid.New()
 			repo.On("GetWalletByID", id).Return((*models.Wallet)(nil), errors.New("not found")).Once()
-			resp, err := svc.CreditWallet(id, models.TransactionRequest{Amount: 1})
+			resp, err := svc.CreditWallet(context.Background(), id, models.TransactionRequest{Amount: 1})
 			assert.Nil(t, resp)
 			assert.EqualError(t, err, "not found")
 			repo.AssertExpectations(t)
@@ -319,7 +358,7 @@ func TestCreditDebitWallet(t *testing.T) {
 			w := &models.Wallet{ID: id}
 			repo.On("GetWalletByID", id).Return(w, nil).Once()
 			repo.On("ProcessTransactionWithRollback", id, 1.0, models.Credit, mock.AnythingOfType("*models.Transaction")).Return(errors.New("balance error")).Once()
-			resp, err := svc.CreditWallet(id, models.TransactionRequest{Amount: 1})
+			resp, err := svc.CreditWallet(context.Background(), id, models.TransactionRequest{Amount: 1})
 			assert.Nil(t, resp)
 			assert.EqualError(t, err, "balance error")
 			repo.AssertExpectations(t)
@@ -332,7 +371,7 @@ func TestCreditDebitWallet(t *testing.T) {
 			w := &models.Wallet{ID: id}
 			repo.On("GetWalletByID", id).Return(w, nil).Once()
 			repo.On("ProcessTransactionWithRollback", id, 2.0, models.Credit, mock.AnythingOfType("*models.Transaction")).Return(errors.New("tx error")).Once()
-			resp, err := svc.CreditWallet(id, models.TransactionRequest{Amount: 2})
+			resp, err := svc.CreditWallet(context.Background(), id, models.TransactionRequest{Amount: 2})
 			assert.Nil(t, resp)
 			assert.EqualError(t, err, "tx error")
 			repo.AssertExpectations(t)
@@ -358,7 +397,7 @@ func TestGetTransactionHistory(t *testing.T) {
 
 		repo.On("GetTransactionsByWalletID", walletID, limit, offset).Return(txs, nil).Once()
 
-		resp, err := svc.GetTransactionHistory(walletID, 0, 0)
+		resp, err := svc.GetTransactionHistory(context.Background(), walletID, 0, 0)
 		assert.NoError(t, err)
 		assert.Len(t, resp, 2)
 		for i := range txs {
@@ -377,10 +416,247 @@ func TestGetTransactionHistory(t *testing.T) {
 		walletID := uuid.New()
 		repo.On("GetTransactionsByWalletID", walletID, 20, 0).Return(nil, errors.New("db")).Once()
 
-		resp, err := svc.GetTransactionHistory(walletID, -1, -10)
+		resp, err := svc.GetTransactionHistory(context.Background(), walletID, -1, -10)
 		assert.Nil(t, resp)
 		assert.EqualError(t, err, "db")
 
 		repo.AssertExpectations(t)
 	})
 }
+
+// blockingWalletRepository waits for the context to finish on lookups so
+// that deadlines applied by the service can be observed.
+type blockingWalletRepository struct {
+	MockWalletRepository
+}
+
+func (r *blockingWalletRepository) GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestOperationTimeouts(t *testing.T) {
+	t.Run("applies per-operation override", func(t *testing.T) {
+		repo := new(blockingWalletRepository)
+		svc := NewWalletService(repo, WithTimeouts(Timeouts{
+			Default:    time.Hour,
+			Operations: map[string]time.Duration{"DebitWallet": 20 * time.Millisecond},
+		}))
+
+		start := time.Now()
+		resp, err := svc.DebitWallet(context.Background(), uuid.New(), models.TransactionRequest{Amount: 1})
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("caller cancellation propagates to repository", func(t *testing.T) {
+		repo := new(blockingWalletRepository)
+		svc := NewWalletService(repo)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		resp, err := svc.GetWallet(ctx, uuid.New())
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+// Behavioural tests against the in-memory repository: these check outcomes
+// rather than the exact sequence of repository calls.
+func TestWalletLifecycleWithMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	svc := NewWalletService(repositories.NewMemoryWalletRepository())
+
+	w, err := svc.CreateWallet(ctx, models.CreateWalletRequest{UserID: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", w.Currency)
+
+	_, err = svc.CreateWallet(ctx, models.CreateWalletRequest{UserID: "alice"})
+	assert.ErrorIs(t, err, models.ErrWalletExists)
+
+	_, err = svc.CreditWallet(ctx, w.ID, models.TransactionRequest{Amount: 100, Description: "salary"})
+	require.NoError(t, err)
+	_, err = svc.DebitWallet(ctx, w.ID, models.TransactionRequest{Amount: 30, Description: "rent"})
+	require.NoError(t, err)
+	_, err = svc.DebitWallet(ctx, w.ID, models.TransactionRequest{Amount: 80})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+
+	got, err := svc.GetWalletByUserID(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 70.0, got.Balance)
+
+	history, err := svc.GetTransactionHistory(ctx, w.ID, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "rent", history[0].Description)
+	assert.Equal(t, "salary", history[1].Description)
+
+	_, err = svc.CreditWallet(ctx, w.ID, models.TransactionRequest{Amount: 0})
+	assert.ErrorIs(t, err, models.ErrInvalidAmount)
+
+	require.NoError(t, svc.DeleteWallet(ctx, w.ID))
+	_, err = svc.GetWallet(ctx, w.ID)
+	assert.ErrorIs(t, err, models.ErrWalletNotFound)
+	_, err = svc.DebitWallet(ctx, w.ID, models.TransactionRequest{Amount: 1})
+	assert.ErrorIs(t, err, models.ErrWalletNotFound)
+}
+
+func TestTransferWithMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	svc := NewWalletService(repositories.NewMemoryWalletRepository())
+
+	alice, err := svc.CreateWallet(ctx, models.CreateWalletRequest{UserID: "alice"})
+	require.NoError(t, err)
+	bob, err := svc.CreateWallet(ctx, models.CreateWalletRequest{UserID: "bob"})
+	require.NoError(t, err)
+	euro, err := svc.CreateWallet(ctx, models.CreateWalletRequest{UserID: "carol", Currency: "EUR"})
+	require.NoError(t, err)
+	_, err = svc.CreditWallet(ctx, alice.ID, models.TransactionRequest{Amount: 100})
+	require.NoError(t, err)
+
+	resp, err := svc.Transfer(ctx, models.TransferRequest{FromWalletID: alice.ID, ToWalletID: bob.ID, Amount: 40, Reference: "rent"})
+	require.NoError(t, err)
+	assert.Equal(t, models.Debit, resp.Debit.Type)
+	assert.Equal(t, alice.ID, resp.Debit.WalletID)
+	assert.Equal(t, models.Credit, resp.Credit.Type)
+	assert.Equal(t, bob.ID, resp.Credit.WalletID)
+	assert.Equal(t, "rent", resp.Credit.Reference)
+
+	got, err := svc.GetWallet(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, got.Balance)
+	got, err = svc.GetWallet(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, got.Balance)
+
+	_, err = svc.Transfer(ctx, models.TransferRequest{FromWalletID: alice.ID, ToWalletID: bob.ID, Amount: 61})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	_, err = svc.Transfer(ctx, models.TransferRequest{FromWalletID: alice.ID, ToWalletID: euro.ID, Amount: 1})
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+	_, err = svc.Transfer(ctx, models.TransferRequest{FromWalletID: alice.ID, ToWalletID: alice.ID, Amount: 1})
+	var validationErr *models.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	_, err = svc.Transfer(ctx, models.TransferRequest{FromWalletID: alice.ID, ToWalletID: bob.ID, Amount: 0})
+	assert.ErrorIs(t, err, models.ErrInvalidAmount)
+
+	got, err = svc.GetWallet(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, got.Balance)
+
+	// Bob receives cash, so alice pays in cash and keeps her promo credit
+	_, err = svc.CreditWallet(ctx, alice.ID, models.TransactionRequest{Amount: 30, Bucket: models.PromoBucket})
+	require.NoError(t, err)
+	_, err = svc.Transfer(ctx, models.TransferRequest{FromWalletID: alice.ID, ToWalletID: bob.ID, Amount: 61})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+	resp, err = svc.Transfer(ctx, models.TransferRequest{FromWalletID: alice.ID, ToWalletID: bob.ID, Amount: 60})
+	require.NoError(t, err)
+	assert.Zero(t, resp.Debit.PromoAmount)
+	got, err = svc.GetWallet(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BucketBalance{Cash: 0, Promo: 30}, got.Buckets)
+}
+
+func TestPromoCreditsWithMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	svc := NewWalletService(repositories.NewMemoryWalletRepository(), WithPromoExpiry(30*24*time.Hour)).(*walletService)
+	now := time.Now()
+
+	w, err := svc.CreateWallet(ctx, models.CreateWalletRequest{UserID: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, models.PromoFirst, w.DebitOrder)
+	_, err = svc.CreditWallet(ctx, w.ID, models.TransactionRequest{Amount: 100})
+	require.NoError(t, err)
+	bonus, err := svc.CreditWallet(ctx, w.ID, models.TransactionRequest{Amount: 25, Bucket: models.PromoBucket})
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, bonus.PromoAmount)
+
+	spent, err := svc.DebitWallet(ctx, w.ID, models.TransactionRequest{Amount: 40})
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, spent.PromoAmount)
+	got, err := svc.GetWallet(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 85.0, got.Balance)
+	assert.Equal(t, models.BucketBalance{Cash: 85, Promo: 0}, got.Buckets)
+
+	got, err = svc.UpdateWallet(ctx, w.ID, models.CreateWalletRequest{UserID: "alice", DebitOrder: models.CashFirst})
+	require.NoError(t, err)
+	assert.Equal(t, models.CashFirst, got.DebitOrder)
+	got, err = svc.UpdateWallet(ctx, w.ID, models.CreateWalletRequest{UserID: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, models.CashFirst, got.DebitOrder, "leaving the order out keeps it")
+
+	past := now.Add(-time.Hour)
+	_, err = svc.CreditWallet(ctx, w.ID, models.TransactionRequest{Amount: 5, Bucket: models.PromoBucket, ExpiresAt: &past})
+	var validationErr *models.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{{Field: "expires_at", Message: "must be in the future"}}, validationErr.Violations)
+
+	_, err = svc.DebitWallet(ctx, w.ID, models.TransactionRequest{Amount: 5, Bucket: models.PromoBucket, ExpiresAt: &now})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []models.Violation{
+		{Field: "bucket", Message: "only applies to credits"},
+		{Field: "expires_at", Message: "must be in the future"},
+	}, validationErr.Violations)
+
+	_, err = svc.CreditWallet(ctx, w.ID, models.TransactionRequest{Amount: 5, ExpiresAt: &now})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "expires_at", validationErr.Violations[0].Field)
+}
+
+func TestPromoTermsDefaultExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	svc := NewWalletService(new(MockWalletRepository), WithPromoExpiry(time.Hour)).(*walletService)
+	svc.now = func() time.Time { return now }
+
+	terms, err := svc.promoTerms(models.TransactionRequest{Amount: 1, Bucket: models.PromoBucket}, models.Credit)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour).UTC(), *terms.ExpiresAt)
+
+	at := now.Add(48 * time.Hour)
+	terms, err = svc.promoTerms(models.TransactionRequest{Amount: 1, Bucket: models.PromoBucket, ExpiresAt: &at}, models.Credit)
+	require.NoError(t, err)
+	assert.Equal(t, at.UTC(), *terms.ExpiresAt)
+
+	terms, err = svc.promoTerms(models.TransactionRequest{Amount: 1, Bucket: models.CashBucket}, models.Credit)
+	require.NoError(t, err)
+	assert.Nil(t, terms)
+
+	svc.promoExpiry = 0
+	terms, err = svc.promoTerms(models.TransactionRequest{Amount: 1, Bucket: models.PromoBucket}, models.Credit)
+	require.NoError(t, err)
+	assert.Nil(t, terms.ExpiresAt, "promo credit never expires without a default")
+}
+
+func TestOverdraftWithMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	svc := NewWalletService(repositories.NewMemoryWalletRepository())
+	limit := 100.0
+
+	w, err := svc.CreateWallet(ctx, models.CreateWalletRequest{UserID: "merchant", OverdraftLimit: &limit})
+	require.NoError(t, err)
+	assert.Equal(t, models.Overdraft{Limit: 100}, w.Overdraft)
+
+	_, err = svc.CreditWallet(ctx, w.ID, models.TransactionRequest{Amount: 20})
+	require.NoError(t, err)
+	_, err = svc.DebitWallet(ctx, w.ID, models.TransactionRequest{Amount: 70})
+	require.NoError(t, err)
+	got, err := svc.GetWallet(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, -50.0, got.Balance)
+	assert.Equal(t, models.Overdraft{Limit: 100, Used: 50}, got.Overdraft)
+	_, err = svc.DebitWallet(ctx, w.ID, models.TransactionRequest{Amount: 51})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+
+	// Leaving the limit out keeps it; lowering it below what is in use
+	// stops further debits
+	got, err = svc.UpdateWallet(ctx, w.ID, models.CreateWalletRequest{UserID: "merchant"})
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, got.Overdraft.Limit)
+	limit = 40
+	got, err = svc.UpdateWallet(ctx, w.ID, models.CreateWalletRequest{UserID: "merchant", OverdraftLimit: &limit})
+	require.NoError(t, err)
+	assert.Equal(t, models.Overdraft{Limit: 40, Used: 50}, got.Overdraft)
+	_, err = svc.DebitWallet(ctx, w.ID, models.TransactionRequest{Amount: 0.01})
+	assert.ErrorIs(t, err, models.ErrInsufficientFunds)
+}
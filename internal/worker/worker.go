@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Worker is a long-running background job. Run must return promptly once
+// ctx is cancelled; any job in progress should be allowed to finish or be
+// rolled back before returning.
+type Worker interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type running struct {
+	worker Worker
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager starts workers and stops them in reverse order of registration,
+// waiting for each one to return before moving on to the next.
+type Manager struct {
+	mu      sync.Mutex
+	workers []Worker
+	running []*running
+	logger  *slog.Logger
+}
+
+func NewManager(logger *slog.Logger) *Manager {
+	return &Manager{logger: logger}
+}
+
+// Add registers a worker. Workers added after Start are not started.
+func (m *Manager) Add(w Worker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.workers = append(m.workers, w)
+}
+
+// Start launches every registered worker in its own goroutine
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range m.workers {
+		ctx, cancel := context.WithCancel(context.Background())
+		r := &running{worker: w, cancel: cancel, done: make(chan struct{})}
+		m.running = append(m.running, r)
+
+		go func() {
+			defer close(r.done)
+			m.logger.Info("Worker started", "worker", w.Name())
+			if err := w.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				m.logger.Error("Worker exited with error", "worker", w.Name(), "error", err)
+				return
+			}
+			m.logger.Info("Worker stopped", "worker", w.Name())
+		}()
+	}
+}
+
+// Stop cancels workers one by one, last started first, and waits for each
+// to return. It gives up when ctx expires and reports which workers were
+// still running.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stuck []string
+	for i := len(m.running) - 1; i >= 0; i-- {
+		r := m.running[i]
+		r.cancel()
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			stuck = append(stuck, r.worker.Name())
+		}
+	}
+	m.running = nil
+
+	if len(stuck) > 0 {
+		return fmt.Errorf("workers did not stop before deadline: %v", stuck)
+	}
+	return nil
+}
+
+// Periodic returns a worker that calls fn every interval until stopped.
+// Errors are logged and do not stop the worker.
+func Periodic(name string, interval time.Duration, fn func(ctx context.Context) error) Worker {
+	return &periodic{name: name, interval: interval, fn: fn}
+}
+
+type periodic struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context) error
+}
+
+func (p *periodic) Name() string {
+	return p.name
+}
+
+func (p *periodic) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := p.fn(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Periodic job failed", "worker", p.name, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+//go:build unit
+// +build unit
+
+package worker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingWorker struct {
+	name    string
+	stopped func(name string)
+	block   bool
+}
+
+func (w *recordingWorker) Name() string { return w.name }
+
+func (w *recordingWorker) Run(ctx context.Context) error {
+	<-ctx.Done()
+	if w.block {
+		select {}
+	}
+	w.stopped(w.name)
+	return ctx.Err()
+}
+
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	m := NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.Add(&recordingWorker{name: "first", stopped: record})
+	m.Add(&recordingWorker{name: "second", stopped: record})
+	m.Add(&recordingWorker{name: "third", stopped: record})
+	m.Start()
+
+	assert.NoError(t, m.Stop(context.Background()))
+	assert.Equal(t, []string{"third", "second", "first"}, order)
+}
+
+func TestManagerStopDeadline(t *testing.T) {
+	m := NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	m.Add(&recordingWorker{name: "stuck", block: true})
+	m.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := m.Stop(ctx)
+	assert.ErrorContains(t, err, "stuck")
+}
+
+func TestPeriodic(t *testing.T) {
+	var calls atomic.Int32
+	w := Periodic("tick", 5*time.Millisecond, func(context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.Run(ctx), context.DeadlineExceeded)
+	assert.Greater(t, calls.Load(), int32(2))
+}
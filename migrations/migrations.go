@@ -0,0 +1,26 @@
+// Package migrations embeds the versioned SQL schema migrations.
+//
+// Each supported dialect has its own directory with files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. Versions are applied
+// in ascending order and must never be edited once released; add a new
+// migration instead, to every dialect, with the same version and name.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// For returns the migrations for the given GORM dialect name
+func For(dialect string) (fs.FS, error) {
+	switch dialect {
+	case "postgres", "sqlite":
+		return fs.Sub(files, dialect)
+	default:
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+}
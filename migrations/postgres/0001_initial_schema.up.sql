@@ -0,0 +1,44 @@
+-- Baseline schema. Written to be idempotent so that databases previously
+-- created by GORM AutoMigrate can be adopted without changes.
+
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS wallets (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    varchar(255) NOT NULL,
+    balance    decimal(15,2) NOT NULL DEFAULT 0.00,
+    currency   varchar(3) NOT NULL DEFAULT 'USD',
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallets_user_id ON wallets (user_id);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id   uuid NOT NULL,
+    type        varchar(10) NOT NULL,
+    amount      decimal(15,2) NOT NULL,
+    description text,
+    reference   varchar(255),
+    created_at  timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_transactions_type CHECK (type IN ('CREDIT', 'DEBIT')),
+    CONSTRAINT fk_transactions_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_wallet_id ON transactions (wallet_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions (type);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions (created_at);
+
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS update_wallets_updated_at ON wallets;
+CREATE TRIGGER update_wallets_updated_at
+BEFORE UPDATE ON wallets
+FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,26 @@
+-- Back to a single table. Archived months are not restored.
+
+CREATE TABLE transactions_unpartitioned (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id   uuid NOT NULL,
+    type        varchar(10) NOT NULL,
+    amount      decimal(15,2) NOT NULL,
+    description text,
+    reference   varchar(255),
+    created_at  timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_transactions_type CHECK (type IN ('CREDIT', 'DEBIT')),
+    CONSTRAINT fk_transactions_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
+
+INSERT INTO transactions_unpartitioned (id, wallet_id, type, amount, description, reference, created_at)
+SELECT id, wallet_id, type, amount, description, reference, created_at
+FROM transactions;
+
+-- Drops every partition with it
+DROP TABLE transactions;
+ALTER TABLE transactions_unpartitioned RENAME TO transactions;
+ALTER INDEX transactions_unpartitioned_pkey RENAME TO transactions_pkey;
+
+CREATE INDEX idx_transactions_wallet_id ON transactions (wallet_id);
+CREATE INDEX idx_transactions_type ON transactions (type);
+CREATE INDEX idx_transactions_created_at ON transactions (created_at);
@@ -0,0 +1,49 @@
+-- Range-partition transactions by calendar month (UTC) of created_at, so old
+-- months can be archived by detaching a partition instead of deleting rows.
+-- The primary key of a partitioned table must include the partition key.
+
+CREATE TABLE transactions_partitioned (
+    id          uuid NOT NULL DEFAULT gen_random_uuid(),
+    wallet_id   uuid NOT NULL,
+    type        varchar(10) NOT NULL,
+    amount      decimal(15,2) NOT NULL,
+    description text,
+    reference   varchar(255),
+    created_at  timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT transactions_partitioned_pkey PRIMARY KEY (id, created_at),
+    CONSTRAINT chk_transactions_type CHECK (type IN ('CREDIT', 'DEBIT')),
+    CONSTRAINT fk_transactions_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+) PARTITION BY RANGE (created_at);
+
+-- Catches rows outside every monthly partition, e.g. if partition
+-- maintenance has not run for months. It should stay empty.
+CREATE TABLE transactions_default PARTITION OF transactions_partitioned DEFAULT;
+
+-- One partition per month from the oldest existing row to three months
+-- ahead; the application creates further months as time passes
+DO $$
+DECLARE
+    month date := date_trunc('month', COALESCE((SELECT min(created_at) FROM transactions), CURRENT_TIMESTAMP) AT TIME ZONE 'UTC')::date;
+    last  date := (date_trunc('month', CURRENT_TIMESTAMP AT TIME ZONE 'UTC') + interval '3 months')::date;
+BEGIN
+    WHILE month <= last LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF transactions_partitioned FOR VALUES FROM (%L) TO (%L)',
+            'transactions_p' || to_char(month, 'YYYYMM'),
+            month::text || ' 00:00:00+00',
+            (month + interval '1 month')::date::text || ' 00:00:00+00');
+        month := (month + interval '1 month')::date;
+    END LOOP;
+END $$;
+
+INSERT INTO transactions_partitioned (id, wallet_id, type, amount, description, reference, created_at)
+SELECT id, wallet_id, type, amount, description, reference, COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM transactions;
+
+DROP TABLE transactions;
+ALTER TABLE transactions_partitioned RENAME TO transactions;
+ALTER INDEX transactions_partitioned_pkey RENAME TO transactions_pkey;
+
+CREATE INDEX idx_transactions_wallet_id ON transactions (wallet_id);
+CREATE INDEX idx_transactions_type ON transactions (type);
+CREATE INDEX idx_transactions_created_at ON transactions (created_at);
+CREATE INDEX idx_transactions_wallet_id_created_at ON transactions (wallet_id, created_at);
@@ -0,0 +1,19 @@
+-- Writes made with an Idempotency-Key header. A key is reserved, with
+-- status_code 0, while its first request runs and then holds the response
+-- replayed to retries. A reservation lapses at reserved_until, after which
+-- a retry of the same request may take the key over. Keys are purged once
+-- they are older than the configured TTL.
+
+CREATE TABLE idempotency_keys (
+    key            varchar(255) PRIMARY KEY,
+    fingerprint    varchar(64) NOT NULL,
+    status_code    integer NOT NULL DEFAULT 0,
+    content_type   varchar(255),
+    location       varchar(255),
+    body           bytea,
+    created_at     timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at   timestamp with time zone,
+    reserved_until timestamp with time zone NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
@@ -0,0 +1,39 @@
+-- Batches of credits and debits, applied all-or-nothing or best-effort.
+-- Items keep their submission order in seq and record their outcome.
+
+CREATE TABLE batches (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    mode         varchar(20) NOT NULL,
+    status       varchar(20) NOT NULL,
+    item_count   integer NOT NULL,
+    claimed_at   timestamp with time zone,
+    completed_at timestamp with time zone,
+    created_at   timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_batches_mode CHECK (mode IN ('atomic', 'best_effort')),
+    CONSTRAINT chk_batches_status CHECK (status IN ('pending', 'processing', 'completed', 'partially_completed', 'failed'))
+);
+
+-- Workers look for pending batches and processing ones past their lease
+CREATE INDEX idx_batches_status_created_at ON batches (status, created_at);
+
+CREATE TABLE batch_items (
+    id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    batch_id       uuid NOT NULL,
+    seq            integer NOT NULL,
+    wallet_id      uuid NOT NULL,
+    type           varchar(10) NOT NULL,
+    amount         decimal(15,2) NOT NULL,
+    description    text,
+    reference      varchar(255),
+    status         varchar(20) NOT NULL,
+    transaction_id uuid,
+    error_code     varchar(50),
+    error_message  text,
+    CONSTRAINT chk_batch_items_type CHECK (type IN ('CREDIT', 'DEBIT')),
+    CONSTRAINT chk_batch_items_status CHECK (status IN ('pending', 'succeeded', 'failed', 'aborted')),
+    CONSTRAINT fk_batch_items_batch FOREIGN KEY (batch_id) REFERENCES batches (id) ON DELETE CASCADE
+);
+
+-- No foreign key to wallets: a batch outlives the wallets it touched
+CREATE UNIQUE INDEX idx_batch_items_batch_id_seq ON batch_items (batch_id, seq);
@@ -0,0 +1,46 @@
+-- Standing orders between wallets and the history of their executions.
+-- A schedule is due when it is active and next_run_at has passed.
+
+CREATE TABLE scheduled_payments (
+    id                    uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_wallet_id        uuid NOT NULL,
+    to_wallet_id          uuid NOT NULL,
+    amount                decimal(15,2) NOT NULL,
+    description           text,
+    rule                  varchar(255),
+    start_at              timestamp with time zone NOT NULL,
+    end_at                timestamp with time zone,
+    on_insufficient_funds varchar(10) NOT NULL,
+    status                varchar(20) NOT NULL,
+    occurrence_at         timestamp with time zone,
+    next_run_at           timestamp with time zone,
+    attempts              integer NOT NULL DEFAULT 0,
+    created_at            timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at            timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_scheduled_payments_amount CHECK (amount > 0),
+    CONSTRAINT chk_scheduled_payments_policy CHECK (on_insufficient_funds IN ('retry', 'skip')),
+    CONSTRAINT chk_scheduled_payments_status CHECK (status IN ('active', 'paused', 'cancelled', 'completed')),
+    CONSTRAINT fk_scheduled_payments_from_wallet FOREIGN KEY (from_wallet_id) REFERENCES wallets (id) ON DELETE CASCADE,
+    CONSTRAINT fk_scheduled_payments_to_wallet FOREIGN KEY (to_wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_scheduled_payments_status_next_run_at ON scheduled_payments (status, next_run_at);
+CREATE INDEX idx_scheduled_payments_from_wallet_id ON scheduled_payments (from_wallet_id);
+CREATE INDEX idx_scheduled_payments_to_wallet_id ON scheduled_payments (to_wallet_id);
+
+CREATE TABLE schedule_executions (
+    id                    uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    schedule_id           uuid NOT NULL,
+    occurrence_at         timestamp with time zone NOT NULL,
+    attempt               integer NOT NULL,
+    status                varchar(20) NOT NULL,
+    debit_transaction_id  uuid,
+    credit_transaction_id uuid,
+    error_code            varchar(50),
+    error_message         text,
+    executed_at           timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_schedule_executions_status CHECK (status IN ('succeeded', 'failed', 'skipped')),
+    CONSTRAINT fk_schedule_executions_schedule FOREIGN KEY (schedule_id) REFERENCES scheduled_payments (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_schedule_executions_schedule_id_executed_at ON schedule_executions (schedule_id, executed_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS promo_credits;
+ALTER TABLE transactions DROP COLUMN IF EXISTS promo_amount;
+ALTER TABLE wallets DROP COLUMN IF EXISTS debit_order;
+ALTER TABLE wallets DROP COLUMN IF EXISTS promo_balance;
@@ -0,0 +1,25 @@
+-- Promotional credits. A wallet's balance includes its promo bucket, which
+-- promo_credits breaks down by the credit that granted it. Debits record how
+-- much of them came out of the promo bucket.
+
+ALTER TABLE wallets ADD COLUMN promo_balance decimal(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD COLUMN debit_order varchar(20) NOT NULL DEFAULT 'promo_first';
+ALTER TABLE wallets ADD CONSTRAINT chk_wallets_promo_balance CHECK (promo_balance >= 0);
+ALTER TABLE wallets ADD CONSTRAINT chk_wallets_debit_order CHECK (debit_order IN ('promo_first', 'cash_first'));
+
+ALTER TABLE transactions ADD COLUMN promo_amount decimal(15,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE promo_credits (
+    transaction_id uuid PRIMARY KEY,
+    wallet_id      uuid NOT NULL,
+    amount         decimal(15,2) NOT NULL,
+    remaining      decimal(15,2) NOT NULL,
+    expires_at     timestamp with time zone,
+    expired_at     timestamp with time zone,
+    created_at     timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_promo_credits_remaining CHECK (remaining >= 0 AND remaining <= amount),
+    CONSTRAINT fk_promo_credits_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_promo_credits_wallet_id ON promo_credits (wallet_id) WHERE remaining > 0;
+CREATE INDEX idx_promo_credits_expires_at ON promo_credits (expires_at) WHERE remaining > 0;
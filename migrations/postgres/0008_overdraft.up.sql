@@ -0,0 +1,20 @@
+-- Overdrafts. Debits may take a wallet's balance below zero as far as its
+-- limit; overdraft_charges records what the wallet was charged for every
+-- day, keyed by wallet and date so that no day is charged twice.
+
+ALTER TABLE wallets ADD COLUMN overdraft_limit decimal(15,2) NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD CONSTRAINT chk_wallets_overdraft_limit CHECK (overdraft_limit >= 0);
+
+CREATE TABLE overdraft_charges (
+    wallet_id      uuid NOT NULL,
+    date           date NOT NULL,
+    used           decimal(15,2) NOT NULL,
+    annual_rate    decimal(9,6) NOT NULL,
+    fee            decimal(15,2) NOT NULL,
+    amount         decimal(15,2) NOT NULL,
+    transaction_id uuid,
+    created_at     timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (wallet_id, date),
+    CONSTRAINT chk_overdraft_charges_amount CHECK (amount >= 0),
+    CONSTRAINT fk_overdraft_charges_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
@@ -0,0 +1,38 @@
+-- Escrow agreements. Each has its own escrow wallet, the escrow account:
+-- funding moves the money from the buyer's wallet into it and settling pays
+-- it out to the seller or buyer, so the ledger always shows where the money
+-- is. held mirrors that wallet's balance. A funded escrow with a release_at
+-- is released to the seller at that time.
+-- Escrows are records of money held and paid out, so a wallet with escrows
+-- cannot be deleted.
+
+CREATE TABLE escrows (
+    id                     uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    buyer_wallet_id        uuid NOT NULL,
+    seller_wallet_id       uuid NOT NULL,
+    escrow_wallet_id       uuid NOT NULL,
+    amount                 decimal(15,2) NOT NULL,
+    held                   decimal(15,2) NOT NULL,
+    description            text,
+    status                 varchar(20) NOT NULL,
+    release_at             timestamp with time zone,
+    seller_amount          decimal(15,2) NOT NULL DEFAULT 0,
+    buyer_amount           decimal(15,2) NOT NULL DEFAULT 0,
+    funding_transaction_id uuid NOT NULL,
+    seller_transaction_id  uuid,
+    buyer_transaction_id   uuid,
+    auto_released          boolean NOT NULL DEFAULT false,
+    settled_at             timestamp with time zone,
+    created_at             timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at             timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_escrows_amount CHECK (amount > 0),
+    CONSTRAINT chk_escrows_held CHECK (held >= 0 AND held <= amount),
+    CONSTRAINT chk_escrows_status CHECK (status IN ('funded', 'released', 'refunded', 'split')),
+    CONSTRAINT fk_escrows_buyer_wallet FOREIGN KEY (buyer_wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_escrows_seller_wallet FOREIGN KEY (seller_wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_escrows_escrow_wallet FOREIGN KEY (escrow_wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_escrows_status_release_at ON escrows (status, release_at);
+CREATE INDEX idx_escrows_buyer_wallet_id ON escrows (buyer_wallet_id);
+CREATE INDEX idx_escrows_seller_wallet_id ON escrows (seller_wallet_id);
@@ -0,0 +1,27 @@
+-- Payment requests between users. A pending request lapses at expires_at;
+-- the expiry job then marks it expired. Once paid it records the wallets
+-- and transactions of the payment.
+
+CREATE TABLE payment_requests (
+    id                    uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    requester_user_id     varchar(255) NOT NULL,
+    payer_user_id         varchar(255) NOT NULL,
+    amount                decimal(15,2) NOT NULL,
+    currency              varchar(3) NOT NULL,
+    description           text,
+    status                varchar(20) NOT NULL,
+    expires_at            timestamp with time zone NOT NULL,
+    from_wallet_id        uuid,
+    to_wallet_id          uuid,
+    debit_transaction_id  uuid,
+    credit_transaction_id uuid,
+    resolved_at           timestamp with time zone,
+    created_at            timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at            timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_payment_requests_amount CHECK (amount > 0),
+    CONSTRAINT chk_payment_requests_status CHECK (status IN ('pending', 'paid', 'declined', 'expired', 'cancelled'))
+);
+
+CREATE INDEX idx_payment_requests_payer_user_id_created_at ON payment_requests (payer_user_id, created_at);
+CREATE INDEX idx_payment_requests_requester_user_id_created_at ON payment_requests (requester_user_id, created_at);
+CREATE INDEX idx_payment_requests_status_expires_at ON payment_requests (status, expires_at);
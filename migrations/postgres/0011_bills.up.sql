@@ -0,0 +1,45 @@
+-- Bills split among participants. Each share is what one participant owes
+-- the payer, in the order the participants were listed; it is settled by a
+-- debit of the participant and a credit of the payer. A bill is settled once
+-- every share is. A wallet with a bill or a share cannot be deleted, so
+-- that no debt vanishes with it.
+
+CREATE TABLE bills (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    payer_wallet_id uuid NOT NULL,
+    total           decimal(15,2) NOT NULL,
+    description     text,
+    split           varchar(20) NOT NULL,
+    status          varchar(20) NOT NULL,
+    settled_at      timestamp with time zone,
+    created_at      timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_bills_total CHECK (total > 0),
+    CONSTRAINT chk_bills_split CHECK (split IN ('equal', 'percentage', 'exact')),
+    CONSTRAINT chk_bills_status CHECK (status IN ('open', 'settled')),
+    CONSTRAINT fk_bills_payer_wallet FOREIGN KEY (payer_wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_bills_payer_wallet_id ON bills (payer_wallet_id);
+
+CREATE TABLE bill_shares (
+    id                    uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    bill_id               uuid NOT NULL,
+    seq                   integer NOT NULL,
+    wallet_id             uuid NOT NULL,
+    percentage            decimal(7,4),
+    amount                decimal(15,2) NOT NULL,
+    status                varchar(20) NOT NULL,
+    debit_transaction_id  uuid,
+    credit_transaction_id uuid,
+    error_code            varchar(50),
+    error_message         text,
+    settled_at            timestamp with time zone,
+    CONSTRAINT chk_bill_shares_amount CHECK (amount >= 0),
+    CONSTRAINT chk_bill_shares_status CHECK (status IN ('pending', 'settled')),
+    CONSTRAINT fk_bill_shares_bill FOREIGN KEY (bill_id) REFERENCES bills (id) ON DELETE CASCADE,
+    CONSTRAINT fk_bill_shares_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT
+);
+
+CREATE UNIQUE INDEX idx_bill_shares_bill_id_seq ON bill_shares (bill_id, seq);
+CREATE INDEX idx_bill_shares_wallet_id ON bill_shares (wallet_id);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS update_wallets_updated_at;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS wallets;
@@ -0,0 +1,43 @@
+-- Baseline schema, SQLite flavour of postgres/0001_initial_schema.up.sql.
+-- UUIDs are generated as random version 4 strings and timestamps are stored
+-- as text in the format the Go driver reads back.
+
+CREATE TABLE IF NOT EXISTS wallets (
+    id         uuid PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' ||
+               substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) ||
+               substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    user_id    varchar(255) NOT NULL,
+    balance    decimal(15,2) NOT NULL DEFAULT 0.00,
+    currency   varchar(3) NOT NULL DEFAULT 'USD',
+    created_at datetime DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at datetime DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallets_user_id ON wallets (user_id);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id          uuid PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' ||
+                substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) ||
+                substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    wallet_id   uuid NOT NULL,
+    type        varchar(10) NOT NULL,
+    amount      decimal(15,2) NOT NULL,
+    description text,
+    reference   varchar(255),
+    created_at  datetime DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_transactions_type CHECK (type IN ('CREDIT', 'DEBIT')),
+    CONSTRAINT fk_transactions_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_wallet_id ON transactions (wallet_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions (type);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions (created_at);
+
+-- SQLite has no BEFORE UPDATE row mutation, so touch the row afterwards. The
+-- WHEN clause skips updates that already set updated_at and stops recursion.
+CREATE TRIGGER IF NOT EXISTS update_wallets_updated_at
+AFTER UPDATE ON wallets
+FOR EACH ROW WHEN NEW.updated_at IS OLD.updated_at
+BEGIN
+    UPDATE wallets SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') WHERE id = NEW.id;
+END;
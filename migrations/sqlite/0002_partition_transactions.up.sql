@@ -0,0 +1,5 @@
+-- SQLite has no table partitioning. Archival removes a month's rows by
+-- created_at range instead, and history reads use the same index as on
+-- Postgres.
+
+CREATE INDEX IF NOT EXISTS idx_transactions_wallet_id_created_at ON transactions (wallet_id, created_at);
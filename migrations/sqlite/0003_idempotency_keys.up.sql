@@ -0,0 +1,15 @@
+-- SQLite flavour of postgres/0003_idempotency_keys.up.sql
+
+CREATE TABLE idempotency_keys (
+    key            varchar(255) PRIMARY KEY,
+    fingerprint    varchar(64) NOT NULL,
+    status_code    integer NOT NULL DEFAULT 0,
+    content_type   varchar(255),
+    location       varchar(255),
+    body           blob,
+    created_at     datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    completed_at   datetime,
+    reserved_until datetime NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys (created_at);
@@ -0,0 +1,37 @@
+-- SQLite flavour of postgres/0004_batches.up.sql. IDs are always set by the
+-- application.
+
+CREATE TABLE batches (
+    id           uuid PRIMARY KEY,
+    mode         varchar(20) NOT NULL,
+    status       varchar(20) NOT NULL,
+    item_count   integer NOT NULL,
+    claimed_at   datetime,
+    completed_at datetime,
+    created_at   datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at   datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_batches_mode CHECK (mode IN ('atomic', 'best_effort')),
+    CONSTRAINT chk_batches_status CHECK (status IN ('pending', 'processing', 'completed', 'partially_completed', 'failed'))
+);
+
+CREATE INDEX idx_batches_status_created_at ON batches (status, created_at);
+
+CREATE TABLE batch_items (
+    id             uuid PRIMARY KEY,
+    batch_id       uuid NOT NULL,
+    seq            integer NOT NULL,
+    wallet_id      uuid NOT NULL,
+    type           varchar(10) NOT NULL,
+    amount         decimal(15,2) NOT NULL,
+    description    text,
+    reference      varchar(255),
+    status         varchar(20) NOT NULL,
+    transaction_id uuid,
+    error_code     varchar(50),
+    error_message  text,
+    CONSTRAINT chk_batch_items_type CHECK (type IN ('CREDIT', 'DEBIT')),
+    CONSTRAINT chk_batch_items_status CHECK (status IN ('pending', 'succeeded', 'failed', 'aborted')),
+    CONSTRAINT fk_batch_items_batch FOREIGN KEY (batch_id) REFERENCES batches (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_batch_items_batch_id_seq ON batch_items (batch_id, seq);
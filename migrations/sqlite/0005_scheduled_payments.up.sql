@@ -0,0 +1,46 @@
+-- SQLite flavour of postgres/0005_scheduled_payments.up.sql. IDs are always
+-- set by the application.
+
+CREATE TABLE scheduled_payments (
+    id                    uuid PRIMARY KEY,
+    from_wallet_id        uuid NOT NULL,
+    to_wallet_id          uuid NOT NULL,
+    amount                decimal(15,2) NOT NULL,
+    description           text,
+    rule                  varchar(255),
+    start_at              datetime NOT NULL,
+    end_at                datetime,
+    on_insufficient_funds varchar(10) NOT NULL,
+    status                varchar(20) NOT NULL,
+    occurrence_at         datetime,
+    next_run_at           datetime,
+    attempts              integer NOT NULL DEFAULT 0,
+    created_at            datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at            datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_scheduled_payments_amount CHECK (amount > 0),
+    CONSTRAINT chk_scheduled_payments_policy CHECK (on_insufficient_funds IN ('retry', 'skip')),
+    CONSTRAINT chk_scheduled_payments_status CHECK (status IN ('active', 'paused', 'cancelled', 'completed')),
+    CONSTRAINT fk_scheduled_payments_from_wallet FOREIGN KEY (from_wallet_id) REFERENCES wallets (id) ON DELETE CASCADE,
+    CONSTRAINT fk_scheduled_payments_to_wallet FOREIGN KEY (to_wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_scheduled_payments_status_next_run_at ON scheduled_payments (status, next_run_at);
+CREATE INDEX idx_scheduled_payments_from_wallet_id ON scheduled_payments (from_wallet_id);
+CREATE INDEX idx_scheduled_payments_to_wallet_id ON scheduled_payments (to_wallet_id);
+
+CREATE TABLE schedule_executions (
+    id                    uuid PRIMARY KEY,
+    schedule_id           uuid NOT NULL,
+    occurrence_at         datetime NOT NULL,
+    attempt               integer NOT NULL,
+    status                varchar(20) NOT NULL,
+    debit_transaction_id  uuid,
+    credit_transaction_id uuid,
+    error_code            varchar(50),
+    error_message         text,
+    executed_at           datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_schedule_executions_status CHECK (status IN ('succeeded', 'failed', 'skipped')),
+    CONSTRAINT fk_schedule_executions_schedule FOREIGN KEY (schedule_id) REFERENCES scheduled_payments (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_schedule_executions_schedule_id_executed_at ON schedule_executions (schedule_id, executed_at);
@@ -0,0 +1,39 @@
+-- SQLite flavour of postgres/0006_interest.up.sql. Dates are stored as the
+-- driver writes midnight UTC timestamps.
+
+CREATE TABLE interest_accounts (
+    wallet_id       uuid PRIMARY KEY,
+    accrued         decimal(20,8) NOT NULL DEFAULT 0,
+    enrolled_on     date NOT NULL,
+    accrued_through date,
+    created_at      datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at      datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_interest_accounts_accrued CHECK (accrued >= 0),
+    CONSTRAINT fk_interest_accounts_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_interest_accounts_accrued_through ON interest_accounts (accrued_through);
+
+CREATE TABLE interest_accruals (
+    wallet_id   uuid NOT NULL,
+    date        date NOT NULL,
+    balance     decimal(15,2) NOT NULL,
+    annual_rate decimal(9,6) NOT NULL,
+    day_count   varchar(10) NOT NULL,
+    amount      decimal(20,8) NOT NULL,
+    created_at  datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (wallet_id, date),
+    CONSTRAINT chk_interest_accruals_amount CHECK (amount >= 0),
+    CONSTRAINT fk_interest_accruals_wallet FOREIGN KEY (wallet_id) REFERENCES interest_accounts (wallet_id) ON DELETE CASCADE
+);
+
+CREATE TABLE interest_payouts (
+    wallet_id      uuid NOT NULL,
+    period_end     date NOT NULL,
+    amount         decimal(15,2) NOT NULL,
+    transaction_id uuid NOT NULL,
+    created_at     datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (wallet_id, period_end),
+    CONSTRAINT chk_interest_payouts_amount CHECK (amount > 0),
+    CONSTRAINT fk_interest_payouts_wallet FOREIGN KEY (wallet_id) REFERENCES interest_accounts (wallet_id) ON DELETE CASCADE
+);
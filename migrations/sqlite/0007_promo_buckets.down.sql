@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS promo_credits;
+ALTER TABLE transactions DROP COLUMN promo_amount;
+ALTER TABLE wallets DROP COLUMN debit_order;
+ALTER TABLE wallets DROP COLUMN promo_balance;
@@ -0,0 +1,22 @@
+-- SQLite flavour of postgres/0007_promo_buckets.up.sql. Constraints added
+-- to existing tables go on the columns, as SQLite cannot add them later.
+
+ALTER TABLE wallets ADD COLUMN promo_balance decimal(15,2) NOT NULL DEFAULT 0 CHECK (promo_balance >= 0);
+ALTER TABLE wallets ADD COLUMN debit_order varchar(20) NOT NULL DEFAULT 'promo_first' CHECK (debit_order IN ('promo_first', 'cash_first'));
+
+ALTER TABLE transactions ADD COLUMN promo_amount decimal(15,2) NOT NULL DEFAULT 0;
+
+CREATE TABLE promo_credits (
+    transaction_id uuid PRIMARY KEY,
+    wallet_id      uuid NOT NULL,
+    amount         decimal(15,2) NOT NULL,
+    remaining      decimal(15,2) NOT NULL,
+    expires_at     datetime,
+    expired_at     datetime,
+    created_at     datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_promo_credits_remaining CHECK (remaining >= 0 AND remaining <= amount),
+    CONSTRAINT fk_promo_credits_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_promo_credits_wallet_id ON promo_credits (wallet_id) WHERE remaining > 0;
+CREATE INDEX idx_promo_credits_expires_at ON promo_credits (expires_at) WHERE remaining > 0;
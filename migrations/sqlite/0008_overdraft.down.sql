@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS overdraft_charges;
+ALTER TABLE wallets DROP COLUMN overdraft_limit;
@@ -0,0 +1,17 @@
+-- SQLite flavour of postgres/0008_overdraft.up.sql
+
+ALTER TABLE wallets ADD COLUMN overdraft_limit decimal(15,2) NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0);
+
+CREATE TABLE overdraft_charges (
+    wallet_id      uuid NOT NULL,
+    date           date NOT NULL,
+    used           decimal(15,2) NOT NULL,
+    annual_rate    decimal(9,6) NOT NULL,
+    fee            decimal(15,2) NOT NULL,
+    amount         decimal(15,2) NOT NULL,
+    transaction_id uuid,
+    created_at     datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (wallet_id, date),
+    CONSTRAINT chk_overdraft_charges_amount CHECK (amount >= 0),
+    CONSTRAINT fk_overdraft_charges_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE CASCADE
+);
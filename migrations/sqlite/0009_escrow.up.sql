@@ -0,0 +1,33 @@
+-- SQLite flavour of postgres/0009_escrow.up.sql. IDs are always set by the
+-- application.
+
+CREATE TABLE escrows (
+    id                     uuid PRIMARY KEY,
+    buyer_wallet_id        uuid NOT NULL,
+    seller_wallet_id       uuid NOT NULL,
+    escrow_wallet_id       uuid NOT NULL,
+    amount                 decimal(15,2) NOT NULL,
+    held                   decimal(15,2) NOT NULL,
+    description            text,
+    status                 varchar(20) NOT NULL,
+    release_at             datetime,
+    seller_amount          decimal(15,2) NOT NULL DEFAULT 0,
+    buyer_amount           decimal(15,2) NOT NULL DEFAULT 0,
+    funding_transaction_id uuid NOT NULL,
+    seller_transaction_id  uuid,
+    buyer_transaction_id   uuid,
+    auto_released          boolean NOT NULL DEFAULT 0,
+    settled_at             datetime,
+    created_at             datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at             datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_escrows_amount CHECK (amount > 0),
+    CONSTRAINT chk_escrows_held CHECK (held >= 0 AND held <= amount),
+    CONSTRAINT chk_escrows_status CHECK (status IN ('funded', 'released', 'refunded', 'split')),
+    CONSTRAINT fk_escrows_buyer_wallet FOREIGN KEY (buyer_wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_escrows_seller_wallet FOREIGN KEY (seller_wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_escrows_escrow_wallet FOREIGN KEY (escrow_wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_escrows_status_release_at ON escrows (status, release_at);
+CREATE INDEX idx_escrows_buyer_wallet_id ON escrows (buyer_wallet_id);
+CREATE INDEX idx_escrows_seller_wallet_id ON escrows (seller_wallet_id);
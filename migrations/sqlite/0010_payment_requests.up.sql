@@ -0,0 +1,26 @@
+-- SQLite flavour of postgres/0010_payment_requests.up.sql. IDs are always
+-- set by the application.
+
+CREATE TABLE payment_requests (
+    id                    uuid PRIMARY KEY,
+    requester_user_id     varchar(255) NOT NULL,
+    payer_user_id         varchar(255) NOT NULL,
+    amount                decimal(15,2) NOT NULL,
+    currency              varchar(3) NOT NULL,
+    description           text,
+    status                varchar(20) NOT NULL,
+    expires_at            datetime NOT NULL,
+    from_wallet_id        uuid,
+    to_wallet_id          uuid,
+    debit_transaction_id  uuid,
+    credit_transaction_id uuid,
+    resolved_at           datetime,
+    created_at            datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at            datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_payment_requests_amount CHECK (amount > 0),
+    CONSTRAINT chk_payment_requests_status CHECK (status IN ('pending', 'paid', 'declined', 'expired', 'cancelled'))
+);
+
+CREATE INDEX idx_payment_requests_payer_user_id_created_at ON payment_requests (payer_user_id, created_at);
+CREATE INDEX idx_payment_requests_requester_user_id_created_at ON payment_requests (requester_user_id, created_at);
+CREATE INDEX idx_payment_requests_status_expires_at ON payment_requests (status, expires_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bill_shares;
+DROP TABLE IF EXISTS bills;
@@ -0,0 +1,42 @@
+-- SQLite flavour of postgres/0011_bills.up.sql. IDs are always set by the
+-- application.
+
+CREATE TABLE bills (
+    id              uuid PRIMARY KEY,
+    payer_wallet_id uuid NOT NULL,
+    total           decimal(15,2) NOT NULL,
+    description     text,
+    split           varchar(20) NOT NULL,
+    status          varchar(20) NOT NULL,
+    settled_at      datetime,
+    created_at      datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at      datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    CONSTRAINT chk_bills_total CHECK (total > 0),
+    CONSTRAINT chk_bills_split CHECK (split IN ('equal', 'percentage', 'exact')),
+    CONSTRAINT chk_bills_status CHECK (status IN ('open', 'settled')),
+    CONSTRAINT fk_bills_payer_wallet FOREIGN KEY (payer_wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_bills_payer_wallet_id ON bills (payer_wallet_id);
+
+CREATE TABLE bill_shares (
+    id                    uuid PRIMARY KEY,
+    bill_id               uuid NOT NULL,
+    seq                   integer NOT NULL,
+    wallet_id             uuid NOT NULL,
+    percentage            decimal(7,4),
+    amount                decimal(15,2) NOT NULL,
+    status                varchar(20) NOT NULL,
+    debit_transaction_id  uuid,
+    credit_transaction_id uuid,
+    error_code            varchar(50),
+    error_message         text,
+    settled_at            datetime,
+    CONSTRAINT chk_bill_shares_amount CHECK (amount >= 0),
+    CONSTRAINT chk_bill_shares_status CHECK (status IN ('pending', 'settled')),
+    CONSTRAINT fk_bill_shares_bill FOREIGN KEY (bill_id) REFERENCES bills (id) ON DELETE CASCADE,
+    CONSTRAINT fk_bill_shares_wallet FOREIGN KEY (wallet_id) REFERENCES wallets (id) ON DELETE RESTRICT
+);
+
+CREATE UNIQUE INDEX idx_bill_shares_bill_id_seq ON bill_shares (bill_id, seq);
+CREATE INDEX idx_bill_shares_wallet_id ON bill_shares (wallet_id);